@@ -0,0 +1,1036 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: processmining/processmining.proto
+
+// API для программных клиентов: загрузка лога потоком, получение графа и отчёта по метрикам.
+// Повторяет REST API (/upload, /jobs/{id}, /graph, /metrics).
+
+package processmining
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UploadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*UploadRequest_Filename
+	//	*UploadRequest_Chunk
+	Data          isUploadRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadRequest) Reset() {
+	*x = UploadRequest{}
+	mi := &file_processmining_processmining_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadRequest) ProtoMessage() {}
+
+func (x *UploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
+func (*UploadRequest) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UploadRequest) GetData() isUploadRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadRequest) GetFilename() string {
+	if x != nil {
+		if x, ok := x.Data.(*UploadRequest_Filename); ok {
+			return x.Filename
+		}
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*UploadRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isUploadRequest_Data interface {
+	isUploadRequest_Data()
+}
+
+type UploadRequest_Filename struct {
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3,oneof"` // Имя файла (первое сообщение потока)
+}
+
+type UploadRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Очередная часть содержимого
+}
+
+func (*UploadRequest_Filename) isUploadRequest_Data() {}
+
+func (*UploadRequest_Chunk) isUploadRequest_Data() {}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_processmining_processmining_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DatasetId     string                 `protobuf:"bytes,2,opt,name=dataset_id,json=datasetId,proto3" json:"dataset_id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // running, done или failed
+	Phase         string                 `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`   // reading, assembling или done
+	RowsRead      int64                  `protobuf:"varint,5,opt,name=rows_read,json=rowsRead,proto3" json:"rows_read,omitempty"`
+	CasesBuilt    int64                  `protobuf:"varint,6,opt,name=cases_built,json=casesBuilt,proto3" json:"cases_built,omitempty"`
+	Percent       float64                `protobuf:"fixed64,7,opt,name=percent,proto3" json:"percent,omitempty"` // Доля прочитанного файла, %
+	Warnings      []string               `protobuf:"bytes,8,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Result        *JobResult             `protobuf:"bytes,12,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_processmining_processmining_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetDatasetId() string {
+	if x != nil {
+		return x.DatasetId
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *Job) GetRowsRead() int64 {
+	if x != nil {
+		return x.RowsRead
+	}
+	return 0
+}
+
+func (x *Job) GetCasesBuilt() int64 {
+	if x != nil {
+		return x.CasesBuilt
+	}
+	return 0
+}
+
+func (x *Job) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *Job) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Job) GetResult() *JobResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type JobResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cases         int64                  `protobuf:"varint,1,opt,name=cases,proto3" json:"cases,omitempty"`
+	Nodes         int64                  `protobuf:"varint,2,opt,name=nodes,proto3" json:"nodes,omitempty"`
+	Edges         int64                  `protobuf:"varint,3,opt,name=edges,proto3" json:"edges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobResult) Reset() {
+	*x = JobResult{}
+	mi := &file_processmining_processmining_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobResult) ProtoMessage() {}
+
+func (x *JobResult) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobResult.ProtoReflect.Descriptor instead.
+func (*JobResult) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *JobResult) GetCases() int64 {
+	if x != nil {
+		return x.Cases
+	}
+	return 0
+}
+
+func (x *JobResult) GetNodes() int64 {
+	if x != nil {
+		return x.Nodes
+	}
+	return 0
+}
+
+func (x *JobResult) GetEdges() int64 {
+	if x != nil {
+		return x.Edges
+	}
+	return 0
+}
+
+type GetGraphRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dataset       string                 `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`                       // Набор данных; пустое значение — текущий набор
+	BatchSize     int32                  `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"` // Узлов и рёбер в одной части (по умолчанию 1000)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGraphRequest) Reset() {
+	*x = GetGraphRequest{}
+	mi := &file_processmining_processmining_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGraphRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGraphRequest) ProtoMessage() {}
+
+func (x *GetGraphRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGraphRequest.ProtoReflect.Descriptor instead.
+func (*GetGraphRequest) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetGraphRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+func (x *GetGraphRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+type GraphChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Edges         []*Edge                `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GraphChunk) Reset() {
+	*x = GraphChunk{}
+	mi := &file_processmining_processmining_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GraphChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GraphChunk) ProtoMessage() {}
+
+func (x *GraphChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GraphChunk.ProtoReflect.Descriptor instead.
+func (*GraphChunk) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GraphChunk) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *GraphChunk) GetEdges() []*Edge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+type Node struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Count         int64                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Total         int64                  `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	HappyPath     bool                   `protobuf:"varint,5,opt,name=happy_path,json=happyPath,proto3" json:"happy_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_processmining_processmining_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Node) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Node) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Node) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Node) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Node) GetHappyPath() bool {
+	if x != nil {
+		return x.HappyPath
+	}
+	return false
+}
+
+type Edge struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Count         int64                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	AvgDuration   float64                `protobuf:"fixed64,4,opt,name=avg_duration,json=avgDuration,proto3" json:"avg_duration,omitempty"`       // сек
+	AvgWaiting    float64                `protobuf:"fixed64,5,opt,name=avg_waiting,json=avgWaiting,proto3" json:"avg_waiting,omitempty"`          // сек
+	AvgProcessing float64                `protobuf:"fixed64,6,opt,name=avg_processing,json=avgProcessing,proto3" json:"avg_processing,omitempty"` // сек
+	Parallel      bool                   `protobuf:"varint,7,opt,name=parallel,proto3" json:"parallel,omitempty"`
+	HappyPath     bool                   `protobuf:"varint,8,opt,name=happy_path,json=happyPath,proto3" json:"happy_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Edge) Reset() {
+	*x = Edge{}
+	mi := &file_processmining_processmining_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Edge) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Edge) ProtoMessage() {}
+
+func (x *Edge) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Edge.ProtoReflect.Descriptor instead.
+func (*Edge) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Edge) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Edge) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Edge) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Edge) GetAvgDuration() float64 {
+	if x != nil {
+		return x.AvgDuration
+	}
+	return 0
+}
+
+func (x *Edge) GetAvgWaiting() float64 {
+	if x != nil {
+		return x.AvgWaiting
+	}
+	return 0
+}
+
+func (x *Edge) GetAvgProcessing() float64 {
+	if x != nil {
+		return x.AvgProcessing
+	}
+	return 0
+}
+
+func (x *Edge) GetParallel() bool {
+	if x != nil {
+		return x.Parallel
+	}
+	return false
+}
+
+func (x *Edge) GetHappyPath() bool {
+	if x != nil {
+		return x.HappyPath
+	}
+	return false
+}
+
+type GetMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dataset       string                 `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"` // Набор данных; пустое значение — текущий набор
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricsRequest) Reset() {
+	*x = GetMetricsRequest{}
+	mi := &file_processmining_processmining_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsRequest) ProtoMessage() {}
+
+func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetMetricsRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type MetricsReport struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	TotalProcessInstances  int64                  `protobuf:"varint,1,opt,name=total_process_instances,json=totalProcessInstances,proto3" json:"total_process_instances,omitempty"`
+	TotalEvents            int64                  `protobuf:"varint,2,opt,name=total_events,json=totalEvents,proto3" json:"total_events,omitempty"`
+	AverageProcessDuration float64                `protobuf:"fixed64,3,opt,name=average_process_duration,json=averageProcessDuration,proto3" json:"average_process_duration,omitempty"`
+	MedianProcessDuration  float64                `protobuf:"fixed64,4,opt,name=median_process_duration,json=medianProcessDuration,proto3" json:"median_process_duration,omitempty"`
+	Metrics                []*Metric              `protobuf:"bytes,5,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *MetricsReport) Reset() {
+	*x = MetricsReport{}
+	mi := &file_processmining_processmining_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsReport) ProtoMessage() {}
+
+func (x *MetricsReport) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsReport.ProtoReflect.Descriptor instead.
+func (*MetricsReport) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MetricsReport) GetTotalProcessInstances() int64 {
+	if x != nil {
+		return x.TotalProcessInstances
+	}
+	return 0
+}
+
+func (x *MetricsReport) GetTotalEvents() int64 {
+	if x != nil {
+		return x.TotalEvents
+	}
+	return 0
+}
+
+func (x *MetricsReport) GetAverageProcessDuration() float64 {
+	if x != nil {
+		return x.AverageProcessDuration
+	}
+	return 0
+}
+
+func (x *MetricsReport) GetMedianProcessDuration() float64 {
+	if x != nil {
+		return x.MedianProcessDuration
+	}
+	return 0
+}
+
+func (x *MetricsReport) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type Metric struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Key                 string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Name                string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Category            string                 `protobuf:"bytes,3,opt,name=category,proto3" json:"category,omitempty"`
+	Threshold           float64                `protobuf:"fixed64,4,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Count               int64                  `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	TotalValue          float64                `protobuf:"fixed64,6,opt,name=total_value,json=totalValue,proto3" json:"total_value,omitempty"`
+	TotalWastedDuration float64                `protobuf:"fixed64,7,opt,name=total_wasted_duration,json=totalWastedDuration,proto3" json:"total_wasted_duration,omitempty"` // сек
+	TotalWastedCost     float64                `protobuf:"fixed64,8,opt,name=total_wasted_cost,json=totalWastedCost,proto3" json:"total_wasted_cost,omitempty"`
+	Exceeded            bool                   `protobuf:"varint,9,opt,name=exceeded,proto3" json:"exceeded,omitempty"`
+	Severity            float64                `protobuf:"fixed64,10,opt,name=severity,proto3" json:"severity,omitempty"`
+	Priority            int64                  `protobuf:"varint,11,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_processmining_processmining_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_processmining_processmining_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_processmining_processmining_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Metric) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Metric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Metric) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Metric) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *Metric) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Metric) GetTotalValue() float64 {
+	if x != nil {
+		return x.TotalValue
+	}
+	return 0
+}
+
+func (x *Metric) GetTotalWastedDuration() float64 {
+	if x != nil {
+		return x.TotalWastedDuration
+	}
+	return 0
+}
+
+func (x *Metric) GetTotalWastedCost() float64 {
+	if x != nil {
+		return x.TotalWastedCost
+	}
+	return 0
+}
+
+func (x *Metric) GetExceeded() bool {
+	if x != nil {
+		return x.Exceeded
+	}
+	return false
+}
+
+func (x *Metric) GetSeverity() float64 {
+	if x != nil {
+		return x.Severity
+	}
+	return 0
+}
+
+func (x *Metric) GetPriority() int64 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+var File_processmining_processmining_proto protoreflect.FileDescriptor
+
+const file_processmining_processmining_proto_rawDesc = "" +
+	"\n" +
+	"!processmining/processmining.proto\x12\rprocessmining\x1a\x1fgoogle/protobuf/timestamp.proto\"M\n" +
+	"\rUploadRequest\x12\x1c\n" +
+	"\bfilename\x18\x01 \x01(\tH\x00R\bfilename\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04data\"\x1f\n" +
+	"\rGetJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x96\x03\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"dataset_id\x18\x02 \x01(\tR\tdatasetId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05phase\x18\x04 \x01(\tR\x05phase\x12\x1b\n" +
+	"\trows_read\x18\x05 \x01(\x03R\browsRead\x12\x1f\n" +
+	"\vcases_built\x18\x06 \x01(\x03R\n" +
+	"casesBuilt\x12\x18\n" +
+	"\apercent\x18\a \x01(\x01R\apercent\x12\x1a\n" +
+	"\bwarnings\x18\b \x03(\tR\bwarnings\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"started_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x120\n" +
+	"\x06result\x18\f \x01(\v2\x18.processmining.JobResultR\x06result\"M\n" +
+	"\tJobResult\x12\x14\n" +
+	"\x05cases\x18\x01 \x01(\x03R\x05cases\x12\x14\n" +
+	"\x05nodes\x18\x02 \x01(\x03R\x05nodes\x12\x14\n" +
+	"\x05edges\x18\x03 \x01(\x03R\x05edges\"J\n" +
+	"\x0fGetGraphRequest\x12\x18\n" +
+	"\adataset\x18\x01 \x01(\tR\adataset\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x02 \x01(\x05R\tbatchSize\"b\n" +
+	"\n" +
+	"GraphChunk\x12)\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x13.processmining.NodeR\x05nodes\x12)\n" +
+	"\x05edges\x18\x02 \x03(\v2\x13.processmining.EdgeR\x05edges\"w\n" +
+	"\x04Node\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\x12\x14\n" +
+	"\x05total\x18\x04 \x01(\x03R\x05total\x12\x1d\n" +
+	"\n" +
+	"happy_path\x18\x05 \x01(\bR\thappyPath\"\xe6\x01\n" +
+	"\x04Edge\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\x12!\n" +
+	"\favg_duration\x18\x04 \x01(\x01R\vavgDuration\x12\x1f\n" +
+	"\vavg_waiting\x18\x05 \x01(\x01R\n" +
+	"avgWaiting\x12%\n" +
+	"\x0eavg_processing\x18\x06 \x01(\x01R\ravgProcessing\x12\x1a\n" +
+	"\bparallel\x18\a \x01(\bR\bparallel\x12\x1d\n" +
+	"\n" +
+	"happy_path\x18\b \x01(\bR\thappyPath\"-\n" +
+	"\x11GetMetricsRequest\x12\x18\n" +
+	"\adataset\x18\x01 \x01(\tR\adataset\"\x8d\x02\n" +
+	"\rMetricsReport\x126\n" +
+	"\x17total_process_instances\x18\x01 \x01(\x03R\x15totalProcessInstances\x12!\n" +
+	"\ftotal_events\x18\x02 \x01(\x03R\vtotalEvents\x128\n" +
+	"\x18average_process_duration\x18\x03 \x01(\x01R\x16averageProcessDuration\x126\n" +
+	"\x17median_process_duration\x18\x04 \x01(\x01R\x15medianProcessDuration\x12/\n" +
+	"\ametrics\x18\x05 \x03(\v2\x15.processmining.MetricR\ametrics\"\xd3\x02\n" +
+	"\x06Metric\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bcategory\x18\x03 \x01(\tR\bcategory\x12\x1c\n" +
+	"\tthreshold\x18\x04 \x01(\x01R\tthreshold\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\x12\x1f\n" +
+	"\vtotal_value\x18\x06 \x01(\x01R\n" +
+	"totalValue\x122\n" +
+	"\x15total_wasted_duration\x18\a \x01(\x01R\x13totalWastedDuration\x12*\n" +
+	"\x11total_wasted_cost\x18\b \x01(\x01R\x0ftotalWastedCost\x12\x1a\n" +
+	"\bexceeded\x18\t \x01(\bR\bexceeded\x12\x1a\n" +
+	"\bseverity\x18\n" +
+	" \x01(\x01R\bseverity\x12\x1a\n" +
+	"\bpriority\x18\v \x01(\x03R\bpriority2\xe0\x02\n" +
+	"\rProcessMining\x12<\n" +
+	"\x06Upload\x12\x1c.processmining.UploadRequest\x1a\x12.processmining.Job(\x01\x12:\n" +
+	"\x06GetJob\x12\x1c.processmining.GetJobRequest\x1a\x12.processmining.Job\x12>\n" +
+	"\bWatchJob\x12\x1c.processmining.GetJobRequest\x1a\x12.processmining.Job0\x01\x12G\n" +
+	"\bGetGraph\x12\x1e.processmining.GetGraphRequest\x1a\x19.processmining.GraphChunk0\x01\x12L\n" +
+	"\n" +
+	"GetMetrics\x12 .processmining.GetMetricsRequest\x1a\x1c.processmining.MetricsReportB\"Z process-mining/api/processminingb\x06proto3"
+
+var (
+	file_processmining_processmining_proto_rawDescOnce sync.Once
+	file_processmining_processmining_proto_rawDescData []byte
+)
+
+func file_processmining_processmining_proto_rawDescGZIP() []byte {
+	file_processmining_processmining_proto_rawDescOnce.Do(func() {
+		file_processmining_processmining_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_processmining_processmining_proto_rawDesc), len(file_processmining_processmining_proto_rawDesc)))
+	})
+	return file_processmining_processmining_proto_rawDescData
+}
+
+var file_processmining_processmining_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_processmining_processmining_proto_goTypes = []any{
+	(*UploadRequest)(nil),         // 0: processmining.UploadRequest
+	(*GetJobRequest)(nil),         // 1: processmining.GetJobRequest
+	(*Job)(nil),                   // 2: processmining.Job
+	(*JobResult)(nil),             // 3: processmining.JobResult
+	(*GetGraphRequest)(nil),       // 4: processmining.GetGraphRequest
+	(*GraphChunk)(nil),            // 5: processmining.GraphChunk
+	(*Node)(nil),                  // 6: processmining.Node
+	(*Edge)(nil),                  // 7: processmining.Edge
+	(*GetMetricsRequest)(nil),     // 8: processmining.GetMetricsRequest
+	(*MetricsReport)(nil),         // 9: processmining.MetricsReport
+	(*Metric)(nil),                // 10: processmining.Metric
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_processmining_processmining_proto_depIdxs = []int32{
+	11, // 0: processmining.Job.started_at:type_name -> google.protobuf.Timestamp
+	11, // 1: processmining.Job.finished_at:type_name -> google.protobuf.Timestamp
+	3,  // 2: processmining.Job.result:type_name -> processmining.JobResult
+	6,  // 3: processmining.GraphChunk.nodes:type_name -> processmining.Node
+	7,  // 4: processmining.GraphChunk.edges:type_name -> processmining.Edge
+	10, // 5: processmining.MetricsReport.metrics:type_name -> processmining.Metric
+	0,  // 6: processmining.ProcessMining.Upload:input_type -> processmining.UploadRequest
+	1,  // 7: processmining.ProcessMining.GetJob:input_type -> processmining.GetJobRequest
+	1,  // 8: processmining.ProcessMining.WatchJob:input_type -> processmining.GetJobRequest
+	4,  // 9: processmining.ProcessMining.GetGraph:input_type -> processmining.GetGraphRequest
+	8,  // 10: processmining.ProcessMining.GetMetrics:input_type -> processmining.GetMetricsRequest
+	2,  // 11: processmining.ProcessMining.Upload:output_type -> processmining.Job
+	2,  // 12: processmining.ProcessMining.GetJob:output_type -> processmining.Job
+	2,  // 13: processmining.ProcessMining.WatchJob:output_type -> processmining.Job
+	5,  // 14: processmining.ProcessMining.GetGraph:output_type -> processmining.GraphChunk
+	9,  // 15: processmining.ProcessMining.GetMetrics:output_type -> processmining.MetricsReport
+	11, // [11:16] is the sub-list for method output_type
+	6,  // [6:11] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_processmining_processmining_proto_init() }
+func file_processmining_processmining_proto_init() {
+	if File_processmining_processmining_proto != nil {
+		return
+	}
+	file_processmining_processmining_proto_msgTypes[0].OneofWrappers = []any{
+		(*UploadRequest_Filename)(nil),
+		(*UploadRequest_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_processmining_processmining_proto_rawDesc), len(file_processmining_processmining_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_processmining_processmining_proto_goTypes,
+		DependencyIndexes: file_processmining_processmining_proto_depIdxs,
+		MessageInfos:      file_processmining_processmining_proto_msgTypes,
+	}.Build()
+	File_processmining_processmining_proto = out.File
+	file_processmining_processmining_proto_goTypes = nil
+	file_processmining_processmining_proto_depIdxs = nil
+}
@@ -0,0 +1,288 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: processmining/processmining.proto
+
+// API для программных клиентов: загрузка лога потоком, получение графа и отчёта по метрикам.
+// Повторяет REST API (/upload, /jobs/{id}, /graph, /metrics).
+
+package processmining
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ProcessMining_Upload_FullMethodName     = "/processmining.ProcessMining/Upload"
+	ProcessMining_GetJob_FullMethodName     = "/processmining.ProcessMining/GetJob"
+	ProcessMining_WatchJob_FullMethodName   = "/processmining.ProcessMining/WatchJob"
+	ProcessMining_GetGraph_FullMethodName   = "/processmining.ProcessMining/GetGraph"
+	ProcessMining_GetMetrics_FullMethodName = "/processmining.ProcessMining/GetMetrics"
+)
+
+// ProcessMiningClient is the client API for ProcessMining service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProcessMiningClient interface {
+	// Upload принимает CSV-лог частями и запускает построение графа в новом наборе данных.
+	// Первое сообщение должно содержать имя файла, следующие — содержимое.
+	Upload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, Job], error)
+	// GetJob возвращает ход и результат задачи построения графа.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob передаёт состояние задачи при каждом изменении до её завершения.
+	WatchJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error)
+	// GetGraph передаёт граф частями, чтобы большие графы не упирались в предельный размер сообщения.
+	GetGraph(ctx context.Context, in *GetGraphRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GraphChunk], error)
+	// GetMetrics возвращает отчёт по метрикам неэффективности.
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*MetricsReport, error)
+}
+
+type processMiningClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProcessMiningClient(cc grpc.ClientConnInterface) ProcessMiningClient {
+	return &processMiningClient{cc}
+}
+
+func (c *processMiningClient) Upload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProcessMining_ServiceDesc.Streams[0], ProcessMining_Upload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadRequest, Job]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_UploadClient = grpc.ClientStreamingClient[UploadRequest, Job]
+
+func (c *processMiningClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, ProcessMining_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *processMiningClient) WatchJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProcessMining_ServiceDesc.Streams[1], ProcessMining_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetJobRequest, Job]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_WatchJobClient = grpc.ServerStreamingClient[Job]
+
+func (c *processMiningClient) GetGraph(ctx context.Context, in *GetGraphRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GraphChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProcessMining_ServiceDesc.Streams[2], ProcessMining_GetGraph_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetGraphRequest, GraphChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_GetGraphClient = grpc.ServerStreamingClient[GraphChunk]
+
+func (c *processMiningClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*MetricsReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MetricsReport)
+	err := c.cc.Invoke(ctx, ProcessMining_GetMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProcessMiningServer is the server API for ProcessMining service.
+// All implementations must embed UnimplementedProcessMiningServer
+// for forward compatibility.
+type ProcessMiningServer interface {
+	// Upload принимает CSV-лог частями и запускает построение графа в новом наборе данных.
+	// Первое сообщение должно содержать имя файла, следующие — содержимое.
+	Upload(grpc.ClientStreamingServer[UploadRequest, Job]) error
+	// GetJob возвращает ход и результат задачи построения графа.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// WatchJob передаёт состояние задачи при каждом изменении до её завершения.
+	WatchJob(*GetJobRequest, grpc.ServerStreamingServer[Job]) error
+	// GetGraph передаёт граф частями, чтобы большие графы не упирались в предельный размер сообщения.
+	GetGraph(*GetGraphRequest, grpc.ServerStreamingServer[GraphChunk]) error
+	// GetMetrics возвращает отчёт по метрикам неэффективности.
+	GetMetrics(context.Context, *GetMetricsRequest) (*MetricsReport, error)
+	mustEmbedUnimplementedProcessMiningServer()
+}
+
+// UnimplementedProcessMiningServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProcessMiningServer struct{}
+
+func (UnimplementedProcessMiningServer) Upload(grpc.ClientStreamingServer[UploadRequest, Job]) error {
+	return status.Error(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedProcessMiningServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedProcessMiningServer) WatchJob(*GetJobRequest, grpc.ServerStreamingServer[Job]) error {
+	return status.Error(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedProcessMiningServer) GetGraph(*GetGraphRequest, grpc.ServerStreamingServer[GraphChunk]) error {
+	return status.Error(codes.Unimplemented, "method GetGraph not implemented")
+}
+func (UnimplementedProcessMiningServer) GetMetrics(context.Context, *GetMetricsRequest) (*MetricsReport, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedProcessMiningServer) mustEmbedUnimplementedProcessMiningServer() {}
+func (UnimplementedProcessMiningServer) testEmbeddedByValue()                       {}
+
+// UnsafeProcessMiningServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProcessMiningServer will
+// result in compilation errors.
+type UnsafeProcessMiningServer interface {
+	mustEmbedUnimplementedProcessMiningServer()
+}
+
+func RegisterProcessMiningServer(s grpc.ServiceRegistrar, srv ProcessMiningServer) {
+	// If the following call panics, it indicates UnimplementedProcessMiningServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ProcessMining_ServiceDesc, srv)
+}
+
+func _ProcessMining_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ProcessMiningServer).Upload(&grpc.GenericServerStream[UploadRequest, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_UploadServer = grpc.ClientStreamingServer[UploadRequest, Job]
+
+func _ProcessMining_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProcessMiningServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProcessMining_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProcessMiningServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProcessMining_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProcessMiningServer).WatchJob(m, &grpc.GenericServerStream[GetJobRequest, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_WatchJobServer = grpc.ServerStreamingServer[Job]
+
+func _ProcessMining_GetGraph_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetGraphRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProcessMiningServer).GetGraph(m, &grpc.GenericServerStream[GetGraphRequest, GraphChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProcessMining_GetGraphServer = grpc.ServerStreamingServer[GraphChunk]
+
+func _ProcessMining_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProcessMiningServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProcessMining_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProcessMiningServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProcessMining_ServiceDesc is the grpc.ServiceDesc for ProcessMining service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProcessMining_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "processmining.ProcessMining",
+	HandlerType: (*ProcessMiningServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetJob",
+			Handler:    _ProcessMining_GetJob_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _ProcessMining_GetMetrics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Upload",
+			Handler:       _ProcessMining_Upload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchJob",
+			Handler:       _ProcessMining_WatchJob_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetGraph",
+			Handler:       _ProcessMining_GetGraph_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "processmining/processmining.proto",
+}
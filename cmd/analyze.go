@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"process-mining/config"
+	"process-mining/internal/domain"
+	"process-mining/internal/domain/metrics"
+	"process-mining/internal/infrastructure"
+	"process-mining/internal/service"
+)
+
+// analysisReport — результат команды analyze: граф процесса и отчёт по метрикам.
+type analysisReport struct {
+	Graph   *domain.Graph          `json:"graph"`
+	Metrics *metrics.MetricsReport `json:"metrics"`
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <log.csv>",
+	Short: "Анализ лога без запуска сервера",
+	Long: "Строит граф процесса и отчёт по метрикам по CSV-логу и записывает их в JSON-файл (или в стандартный вывод),\n" +
+		"не запуская HTTP-сервер; с --output-format table, csv или markdown вместо JSON выводится таблица метрик.\n" +
+		"Настройки анализа читаются из файла конфигурации --config (по умолчанию APP_CONFIG) или из файла APP_ANALYSIS_CONFIG.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		occurrences, _ := cmd.Flags().GetInt("occurrences")
+		format, err := outputFormat(cmd, formatJSON)
+		if err != nil {
+			return err
+		}
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		limits, err := metrics.ParseMetricLimits(failOn)
+		if err != nil {
+			return err
+		}
+
+		svc, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		graph, err := svc.GetGraphData()
+		if err != nil {
+			return err
+		}
+		report, err := svc.GetMetricsReport()
+		if err != nil {
+			return err
+		}
+		violations, err := report.CheckLimits(limits)
+		if err != nil {
+			return err
+		}
+		if occurrences >= 0 {
+			report.TruncateOccurrences(occurrences)
+		}
+
+		err = writeOutput(output, func(w io.Writer) error {
+			if format != formatJSON {
+				// Граф не представляется таблицей: выводятся метрики, как в top
+				return printTop(w, report, 0, 3, format)
+			}
+			return writeJSON(w, analysisReport{Graph: graph, Metrics: report})
+		})
+		if err != nil || len(violations) == 0 {
+			return err
+		}
+		for _, violation := range violations {
+			fmt.Fprintf(os.Stderr, "%s: %d вхождений, условие %s%s%g\n", violation.Limit.Metric, violation.Count,
+				violation.Limit.Metric, violation.Limit.Operator, violation.Limit.Value)
+		}
+		return &exitError{code: ExitCodeLimitsExceeded, err: fmt.Errorf("нарушено условий --fail-on: %d", len(violations))}
+	},
+}
+
+// newAnalysisService создаёт сервис с настройками анализа из файла конфигурации configPath
+// (пустой путь — APP_CONFIG) и окружения. При чтении логов в терминале показывается индикатор хода чтения.
+func newAnalysisService(configPath string) (*service.GraphService, error) {
+	reader := infrastructure.NewCSVReader()
+	if bar := newProgressBar(); bar != nil {
+		reader.SetProgress(bar.update)
+	}
+	graphService := service.NewGraphService(domain.NewGraphBuilder(reader))
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := graphService.ApplySettings(analysisSettings(cfg.Analysis)); err != nil {
+		return nil, fmt.Errorf("некорректные настройки анализа: %v", err)
+	}
+	if err := applyParseCache(graphService, cfg); err != nil {
+		return nil, err
+	}
+	return graphService, nil
+}
+
+// applyParseCache подключает к сервису кэш разбора логов APP_PARSE_CACHE_DIR, если каталог задан.
+func applyParseCache(graphService *service.GraphService, cfg *config.Config) error {
+	if cfg.APP_PARSE_CACHE_DIR == "" {
+		return nil
+	}
+	cache, err := infrastructure.OpenParseCache(cfg.APP_PARSE_CACHE_DIR, cfg.GetAppParseCacheSize())
+	if err != nil {
+		return err
+	}
+	graphService.SetParseCache(cache)
+	return nil
+}
+
+// analyzeLog строит граф по логу path с настройками анализа, как newAnalysisService.
+func analyzeLog(path, configPath string) (*service.GraphService, error) {
+	graphService, err := newAnalysisService(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := graphService.BuildGraphFromCSV(path); err != nil {
+		return nil, fmt.Errorf("ошибка построения графа по %s: %v", path, err)
+	}
+	return graphService, nil
+}
+
+// writeOutput записывает результат команды в файл path или, если путь пуст или равен "-", в стандартный вывод.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// spoolInput для пути infrastructure.StdinPath копирует стандартный ввод во временный файл и возвращает
+// его путь, чтобы команды, читающие лог дважды, могли прочитать его повторно. Другие пути возвращаются
+// как есть. cleanup удаляет временный файл.
+func spoolInput(path string) (spooled string, cleanup func(), err error) {
+	if path != infrastructure.StdinPath {
+		return path, func() {}, nil
+	}
+	file, err := os.CreateTemp("", "process-mining-stdin-*.csv")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.Remove(file.Name()) }
+	_, err = io.Copy(file, os.Stdin)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("ошибка чтения стандартного ввода: %v", err)
+	}
+	return file.Name(), cleanup, nil
+}
+
+// stdinOnce проверяет, что стандартный ввод указан вместо не более чем одного лога.
+func stdinOnce(paths []string) error {
+	count := 0
+	for _, path := range paths {
+		if path == infrastructure.StdinPath {
+			count++
+		}
+	}
+	if count > 1 {
+		return errors.New("стандартный ввод (-) можно указать только вместо одного лога")
+	}
+	return nil
+}
+
+func init() {
+	analyzeCmd.Flags().StringP("output", "o", "", "файл отчёта (по умолчанию — стандартный вывод)")
+	analyzeCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	analyzeCmd.Flags().String("fail-on", "", "условия на число вхождений метрик через запятую, например \"Rework>100,Self-Loop>0\";\n"+
+		"при нарушении команда завершается с кодом 2")
+	analyzeCmd.Flags().Int("occurrences", metrics.DefaultTopOccurrences, "сколько вхождений каждой метрики включить в отчёт (-1 — все)")
+	rootCmd.AddCommand(analyzeCmd)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain"
+)
+
+var anonymizeCmd = &cobra.Command{
+	Use:   "anonymize <log.csv>",
+	Short: "Обезличивание лога",
+	Long: "Заменяет идентификаторы кейсов, ресурсы и (с --activities) названия активностей, чтобы логом можно было\n" +
+		"поделиться с внешними консультантами. --method hash заменяет значения хешами HMAC-SHA256 с солью --salt;\n" +
+		"--method pseudonym — порядковыми псевдонимами (case_1, resource_1, ...), таблица соответствия хранится\n" +
+		"в файле --mapping и дополняется при каждом запуске. При той же соли или таблице замены не меняются.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		configPath, _ := flags.GetString("config")
+		output, _ := flags.GetString("output")
+		mappingPath, _ := flags.GetString("mapping")
+		anonymizer := &domain.Anonymizer{}
+		anonymizer.Method, _ = flags.GetString("method")
+		anonymizer.Salt, _ = flags.GetString("salt")
+		anonymizer.Activities, _ = flags.GetBool("activities")
+
+		switch anonymizer.Method {
+		case domain.AnonymizeHash:
+			if anonymizer.Salt == "" {
+				return errors.New("для хеширования укажите соль --salt")
+			}
+		case domain.AnonymizePseudonym:
+			if mappingPath == "" {
+				return errors.New("для псевдонимов укажите файл таблицы соответствия --mapping")
+			}
+			pseudonyms, err := loadPseudonyms(mappingPath)
+			if err != nil {
+				return err
+			}
+			anonymizer.Pseudonyms = pseudonyms
+		}
+		if err := anonymizer.Validate(); err != nil {
+			return err
+		}
+
+		svc, err := newAnalysisService(configPath)
+		if err != nil {
+			return err
+		}
+		err = writeOutput(output, func(w io.Writer) error {
+			return svc.AnonymizeLog(args[0], w, anonymizer)
+		})
+		if err != nil {
+			return err
+		}
+		if anonymizer.Pseudonyms != nil {
+			return writeOutput(mappingPath, func(w io.Writer) error {
+				encoder := json.NewEncoder(w)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(anonymizer.Pseudonyms)
+			})
+		}
+		return nil
+	},
+}
+
+// loadPseudonyms читает таблицу соответствия псевдонимов; отсутствующий файл означает пустую таблицу.
+func loadPseudonyms(path string) (*domain.Pseudonyms, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &domain.Pseudonyms{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pseudonyms domain.Pseudonyms
+	if err := json.Unmarshal(data, &pseudonyms); err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы псевдонимов %s: %v", path, err)
+	}
+	return &pseudonyms, nil
+}
+
+func init() {
+	flags := anonymizeCmd.Flags()
+	flags.StringP("output", "o", "", "файл обезличенного лога (по умолчанию — стандартный вывод)")
+	flags.String("method", domain.AnonymizeHash, "способ обезличивания: "+domain.AnonymizeHash+" или "+domain.AnonymizePseudonym)
+	flags.String("salt", "", "соль хеширования (для --method "+domain.AnonymizeHash+")")
+	flags.String("mapping", "", "файл таблицы соответствия JSON (для --method "+domain.AnonymizePseudonym+")")
+	flags.Bool("activities", false, "обезличивать и названия активностей")
+	flags.String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(anonymizeCmd)
+}
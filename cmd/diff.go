@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain/metrics"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <before.csv> <after.csv>",
+	Short: "Сравнение двух логов",
+	Long: "Сравнивает показатели двух логов (например, до и после изменения процесса) и выводит таблицу изменений:\n" +
+		"длительность кейсов, доля переделок и завершённых кейсов, вхождения и потери времени по каждой метрике.",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		format, err := outputFormat(cmd, formatTable)
+		if err != nil {
+			return err
+		}
+
+		if err := stdinOnce(args); err != nil {
+			return err
+		}
+
+		before, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		after, err := analyzeLog(args[1], configPath)
+		if err != nil {
+			return err
+		}
+		return printComparison(os.Stdout, before.CompareWith(after), false, format)
+	},
+}
+
+// printComparison выводит изменения показателей и метрик между двумя логами в формате format.
+// С changedOnly выводятся только метрики, число вхождений или потери которых изменились.
+func printComparison(w io.Writer, comparison *metrics.PeriodComparison, changedOnly bool, format string) error {
+	changed := *comparison
+	changed.Metrics = nil
+	for _, metric := range comparison.Metrics {
+		if !changedOnly || metric.CountChange != 0 || metric.WastedChange != 0 {
+			changed.Metrics = append(changed.Metrics, metric)
+		}
+	}
+	if format == formatJSON {
+		return writeJSON(w, changed)
+	}
+
+	summary := newResultTable("Показатель", "До", "После", "Изменение")
+	summary.add("Кейсов", comparison.Before.Cases, comparison.After.Cases, countChange(comparison.After.Cases-comparison.Before.Cases))
+	summary.add("Средняя длительность", hours(comparison.Before.AverageDuration), hours(comparison.After.AverageDuration),
+		durationChange{seconds: comparison.DurationChange, percent: comparison.DurationChangePercent})
+	summary.add("Медианная длительность", hours(comparison.Before.MedianDuration), hours(comparison.After.MedianDuration),
+		hoursChange(comparison.After.MedianDuration-comparison.Before.MedianDuration))
+	summary.add("Кейсы с переделками", percent(comparison.Before.ReworkRate), percent(comparison.After.ReworkRate),
+		pointsChange(comparison.ReworkRateChange))
+	summary.add("Завершённые кейсы", percent(comparison.Before.CompletionRate), percent(comparison.After.CompletionRate),
+		pointsChange(comparison.CompletionRateChange))
+	if err := summary.write(w, format); err != nil {
+		return err
+	}
+
+	if len(changed.Metrics) == 0 {
+		if humanFormat(format) {
+			fmt.Fprintln(w, "\nМетрики не изменились.")
+		}
+		return nil
+	}
+	fmt.Fprintln(w)
+	table := newResultTable("Метрика", "Вхождений до", "После", "Изменение", "Потери до", "После", "Изменение")
+	for _, metric := range changed.Metrics {
+		table.add(metric.Metric, metric.BeforeCount, metric.AfterCount, countChange(metric.CountChange),
+			hours(metric.BeforeWasted), hours(metric.AfterWasted), hoursChange(metric.WastedChange))
+	}
+	return table.write(w, format)
+}
+
+func init() {
+	diffCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <log.csv>",
+	Short: "Выгрузка графа процесса в файл",
+	Long: "Строит граф процесса по CSV-логу и записывает его в формате " + strings.Join(domain.ExportFormats, ", ") + "\n" +
+		"(например, для документации, генерируемой в CI), не запуская HTTP-сервер.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		if !slices.Contains(domain.ExportFormats, format) {
+			return fmt.Errorf("неизвестный формат %q: поддерживаются %s", format, strings.Join(domain.ExportFormats, ", "))
+		}
+
+		svc, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		graph, err := svc.GetGraphData()
+		if err != nil {
+			return err
+		}
+		return writeOutput(output, func(w io.Writer) error {
+			return domain.ExportGraph(w, graph, format)
+		})
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("format", "f", "dot", "формат выгрузки: "+strings.Join(domain.ExportFormats, ", "))
+	exportCmd.Flags().StringP("output", "o", "", "файл выгрузки (по умолчанию — стандартный вывод)")
+	exportCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain"
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter <log.csv>",
+	Short: "Фильтрация лога в новый CSV-файл",
+	Long: "Применяет к CSV-логу фильтры по времени, активностям, вариантам и атрибутам и записывает оставшиеся события\n" +
+		"в новый CSV-файл (или в стандартный вывод), чтобы тяжёлую фильтрацию выполнить до загрузки на сервер.\n" +
+		"Фильтры действуют так же, как параметры from, to, include_activities и др. HTTP API.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		configPath, _ := flags.GetString("config")
+		output, _ := flags.GetString("output")
+
+		var filter domain.LogFilter
+		filter.TimeScope, _ = flags.GetString("time-scope")
+		filter.IncludeActivities, _ = flags.GetStringSlice("include")
+		filter.ExcludeActivities, _ = flags.GetStringSlice("exclude")
+		filter.ActivityScope, _ = flags.GetString("activity-scope")
+		filter.Variants, _ = flags.GetStringSlice("variant")
+		filter.ExcludeHappyPath, _ = flags.GetBool("exclude-happy-path")
+		for _, name := range []string{"from", "to"} {
+			value, _ := flags.GetString(name)
+			if value == "" {
+				continue
+			}
+			t, err := parseFilterTime(value)
+			if err != nil {
+				return fmt.Errorf("некорректное значение --%s: %q", name, value)
+			}
+			if name == "from" {
+				filter.From = t
+			} else {
+				filter.To = t
+			}
+		}
+		conditions, _ := flags.GetStringArray("attribute")
+		for _, condition := range conditions {
+			name, value, ok := strings.Cut(condition, "=")
+			if !ok {
+				return fmt.Errorf("некорректное условие на атрибут %q: ожидается название=значение", condition)
+			}
+			if filter.Attributes == nil {
+				filter.Attributes = make(map[string][]string)
+			}
+			filter.Attributes[name] = append(filter.Attributes[name], value)
+		}
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+
+		svc, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		filtered, err := svc.Filter(filter)
+		if err != nil {
+			return err
+		}
+		return writeOutput(output, filtered.WriteEventsCSV)
+	},
+}
+
+// parseFilterTime разбирает границу временного окна: RFC 3339 или ГГГГ-ММ-ДД.
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func init() {
+	flags := filterCmd.Flags()
+	flags.StringP("output", "o", "", "файл отфильтрованного лога (по умолчанию — стандартный вывод)")
+	flags.String("from", "", "начало временного окна (RFC 3339 или ГГГГ-ММ-ДД)")
+	flags.String("to", "", "конец временного окна, не включая (RFC 3339 или ГГГГ-ММ-ДД)")
+	flags.String("time-scope", "", "case — кейсы, начавшиеся в окне (по умолчанию); event — события в окне")
+	flags.StringSlice("include", nil, "оставить только эти активности (через запятую)")
+	flags.StringSlice("exclude", nil, "убрать эти активности (через запятую)")
+	flags.String("activity-scope", "", "event — убрать события (по умолчанию); case — отобрать кейсы по активностям")
+	flags.StringSlice("variant", nil, "оставить кейсы этих вариантов (идентификаторы из /variants)")
+	flags.Bool("exclude-happy-path", false, "убрать кейсы самого частого варианта")
+	flags.StringArray("attribute", nil, "оставить кейсы с атрибутом название=значение (можно повторять)")
+	flags.String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(filterCmd)
+}
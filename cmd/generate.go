@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"process-mining/utils"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Генерация тестового лога",
+	Long: "Создаёт синтетический CSV-лог процесса с заданным числом кейсов и внедрёнными отклонениями:\n" +
+		"самоциклами, пинг-понгами, задержками, ошибками и незавершёнными кейсами.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		var config utils.LogGeneratorConfig
+		config.OutputFile, _ = flags.GetString("output")
+		config.NumInstances, _ = flags.GetInt("instances")
+		config.MaxEvents, _ = flags.GetInt("max-events")
+		config.AddSelfLoops, _ = flags.GetInt("self-loops")
+		config.AddPingPongs, _ = flags.GetInt("ping-pongs")
+		config.AddAnomalies, _ = flags.GetInt("anomalies")
+		config.AddErrors, _ = flags.GetInt("errors")
+		config.IncompleteRate, _ = flags.GetFloat64("incomplete-rate")
+
+		switch {
+		case config.OutputFile == "":
+			return errors.New("не указан файл лога")
+		case config.NumInstances <= 0:
+			return errors.New("число кейсов должно быть положительным")
+		case config.MaxEvents <= 3:
+			return errors.New("максимальное число событий кейса должно быть больше 3")
+		case config.AddSelfLoops < 0 || config.AddPingPongs < 0 || config.AddAnomalies < 0 || config.AddErrors < 0:
+			return errors.New("число отклонений не может быть отрицательным")
+		case config.IncompleteRate < 0 || config.IncompleteRate > 1:
+			return errors.New("доля незавершённых кейсов должна быть от 0 до 1")
+		}
+
+		if err := utils.GenerateLog(config); err != nil {
+			return err
+		}
+		fmt.Printf("Лог из %d кейсов записан в %s.\n", config.NumInstances, config.OutputFile)
+		return nil
+	},
+}
+
+func init() {
+	flags := generateCmd.Flags()
+	flags.StringP("output", "o", "log.csv", "файл лога")
+	flags.Int("instances", 1000, "число кейсов")
+	flags.Int("max-events", 10, "максимальное число событий кейса (больше 3)")
+	flags.Int("self-loops", 0, "сколько кейсов могут получить самоцикл")
+	flags.Int("ping-pongs", 0, "сколько кейсов могут получить пинг-понг")
+	flags.Int("anomalies", 0, "сколько кейсов могут получить аномальную задержку")
+	flags.Int("errors", 0, "сколько кейсов могут получить событие с ошибкой")
+	flags.Float64("incomplete-rate", 0, "доля кейсов без конечного события (от 0 до 1)")
+	rootCmd.AddCommand(generateCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <log.csv> <log.csv>...",
+	Short: "Объединение нескольких логов в один",
+	Long: "Объединяет CSV-логи в один файл (или стандартный вывод). Столбцы сопоставляются по назначению и названию,\n" +
+		"заголовок результата включает столбцы всех логов. Кейсам, идентификатор которых уже встречался в одном\n" +
+		"из предыдущих логов, присваивается новый идентификатор с суффиксом -<номер лога>; с --keep-case-ids\n" +
+		"совпадающие идентификаторы считаются одним кейсом (например, для выгрузок одного процесса по месяцам).",
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		keepCaseIDs, _ := cmd.Flags().GetBool("keep-case-ids")
+
+		if err := stdinOnce(args); err != nil {
+			return err
+		}
+		paths := make([]string, len(args))
+		for i, path := range args {
+			spooled, cleanup, err := spoolInput(path)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			paths[i] = spooled
+		}
+
+		svc, err := newAnalysisService(configPath)
+		if err != nil {
+			return err
+		}
+		var renamed int
+		err = writeOutput(output, func(w io.Writer) error {
+			renamed, err = svc.MergeLogs(paths, w, keepCaseIDs)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if renamed > 0 {
+			fmt.Fprintf(os.Stderr, "Переименовано кейсов с совпадающими идентификаторами: %d.\n", renamed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	mergeCmd.Flags().StringP("output", "o", "", "файл объединённого лога (по умолчанию — стандартный вывод)")
+	mergeCmd.Flags().Bool("keep-case-ids", false, "не переименовывать кейсы с совпадающими идентификаторами")
+	mergeCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(mergeCmd)
+}
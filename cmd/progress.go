@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+// progressRefresh — как часто перерисовывается индикатор хода чтения.
+const progressRefresh = 100 * time.Millisecond
+
+// progressBarWidth — ширина полосы индикатора в символах.
+const progressBarWidth = 30
+
+// progressBar рисует в терминале индикатор чтения лога: долю прочитанного файла, скорость
+// в строках в секунду и оставшееся время. По окончании файла строка индикатора стирается.
+type progressBar struct {
+	w       io.Writer
+	path    string
+	started time.Time
+	drawn   time.Time
+}
+
+// newProgressBar возвращает индикатор, выводящий в стандартный поток ошибок, или nil, если индикатор
+// отключён флагом --no-progress или поток ошибок не терминал (например, в CI или при перенаправлении).
+func newProgressBar() *progressBar {
+	if disabled, _ := rootCmd.PersistentFlags().GetBool("no-progress"); disabled {
+		return nil
+	}
+	info, err := os.Stderr.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progressBar{w: os.Stderr}
+}
+
+// update получает ход чтения от читателя CSV (см. infrastructure.CSVReader.SetProgress).
+func (b *progressBar) update(progress infrastructure.ReadProgress) {
+	now := time.Now()
+	if progress.Path != b.path {
+		b.path, b.started, b.drawn = progress.Path, now, time.Time{}
+	}
+	if progress.Done {
+		if !b.drawn.IsZero() {
+			fmt.Fprint(b.w, "\r\033[K")
+		}
+		b.path = ""
+		return
+	}
+	if now.Sub(b.drawn) < progressRefresh {
+		return
+	}
+	b.drawn = now
+
+	name := filepath.Base(progress.Path)
+	if progress.Path == infrastructure.StdinPath {
+		name = "stdin"
+	}
+	elapsed := now.Sub(b.started).Seconds()
+	fraction := 0.0
+	line := name
+	// Для канала размер неизвестен: выводятся только число строк и скорость
+	if progress.TotalBytes > 0 {
+		fraction = min(float64(progress.BytesRead)/float64(progress.TotalBytes), 1)
+		filled := int(fraction * progressBarWidth)
+		line += fmt.Sprintf(" [%s%s] %5.1f%%", strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), fraction*100)
+	}
+	line += fmt.Sprintf("  %d строк", progress.Rows)
+	if elapsed > 0 {
+		line += fmt.Sprintf("  %.0f строк/с", float64(progress.Rows)/elapsed)
+	}
+	if fraction > 0 && elapsed > 0 {
+		remaining := time.Duration(elapsed * (1 - fraction) / fraction * float64(time.Second))
+		line += "  осталось " + remaining.Round(time.Second).String()
+	}
+	fmt.Fprint(b.w, "\r\033[K"+line)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain"
+	"process-mining/internal/infrastructure"
+)
+
+// reportHistogramBins — число интервалов гистограммы длительности кейсов в отчёте.
+const reportHistogramBins = 20
+
+var reportCmd = &cobra.Command{
+	Use:   "report <log.csv>",
+	Short: "HTML-отчёт по логу",
+	Long: "Строит граф и метрики по логу и сохраняет отчёт одним HTML-файлом: схема графа, показатели,\n" +
+		"потери времени по метрикам, гистограмма длительности, частые варианты и узкие места.\n" +
+		"Стили, скрипты и диаграммы встроены в файл, поэтому его можно отправить по почте и открыть без сервера.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		title, _ := cmd.Flags().GetString("title")
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit <= 0 {
+			return fmt.Errorf("--limit должен быть положительным, получено %d", limit)
+		}
+		if title == "" {
+			title = "Отчёт по процессу"
+			if args[0] != infrastructure.StdinPath {
+				title += ": " + strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+		}
+
+		svc, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		graph, err := svc.GetGraphData()
+		if err != nil {
+			return err
+		}
+		report, err := svc.GetMetricsReport()
+		if err != nil {
+			return err
+		}
+		htmlReport := domain.HTMLReport{
+			Title:       title,
+			GeneratedAt: time.Now(),
+			Graph:       graph,
+			Metrics:     report,
+			Durations:   svc.GetDurationDistribution(reportHistogramBins),
+			Variants:    svc.GetVariantMetrics(),
+			Limit:       limit,
+		}
+		if err := writeOutput(output, func(w io.Writer) error {
+			return domain.WriteHTMLReport(w, htmlReport)
+		}); err != nil {
+			return err
+		}
+		if output != "" && output != "-" {
+			fmt.Fprintf(os.Stderr, "Отчёт сохранён в %s\n", output)
+		}
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringP("output", "o", "report.html", "файл отчёта (- — стандартный вывод)")
+	reportCmd.Flags().String("title", "", "заголовок отчёта (по умолчанию по имени лога)")
+	reportCmd.Flags().Int("limit", 10, "сколько метрик, вариантов и узких мест показывать")
+	reportCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(reportCmd)
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -13,9 +15,49 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Используйте 'serve' для запуска сервера.")
 		fmt.Println("Используйте 'clear' для очистки данных графа.")
+		fmt.Println("Используйте 'analyze <log.csv>' для анализа лога без запуска сервера.")
+		fmt.Println("Используйте 'generate' для создания тестового лога.")
+		fmt.Println("Используйте 'export <log.csv>' для выгрузки графа в DOT, GraphML, BPMN или JSON.")
+		fmt.Println("Используйте 'diff <before.csv> <after.csv>' для сравнения показателей двух логов.")
+		fmt.Println("Используйте 'top <log.csv>' для просмотра главных неэффективностей лога.")
+		fmt.Println("Используйте 'watch <log.csv|каталог>' для повторного анализа лога при изменении.")
+		fmt.Println("Используйте 'stats <log.csv>' для краткой сводки лога.")
+		fmt.Println("Используйте 'filter <log.csv>' для записи отфильтрованного лога в новый CSV-файл.")
+		fmt.Println("Используйте 'sample <log.csv>' для выборки части кейсов лога.")
+		fmt.Println("Используйте 'anonymize <log.csv>' для обезличивания кейсов, ресурсов и активностей.")
+		fmt.Println("Используйте 'merge <log.csv> <log.csv>...' для объединения логов в один.")
+		fmt.Println("Используйте 'split <log.csv>' для разделения лога по периоду или атрибуту.")
+		fmt.Println("Используйте 'report <log.csv>' для HTML-отчёта по логу.")
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().String("output-format", "", "формат вывода команд анализа: "+strings.Join(outputFormats, ", ")+
+		" (по умолчанию table, у analyze — json)")
+	rootCmd.PersistentFlags().Bool("no-progress", false, "не показывать индикатор хода чтения лога")
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
+
+// ExitCodeLimitsExceeded — код завершения, если нарушены пороги analyze --fail-on;
+// остальные ошибки завершают программу с кодом 1.
+const ExitCodeLimitsExceeded = 2
+
+// exitError — ошибка команды с собственным кодом завершения.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+// ExitCode возвращает код завершения программы для ошибки err, возвращённой Execute.
+func ExitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return 1
+}
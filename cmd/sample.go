@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var sampleCmd = &cobra.Command{
+	Use:   "sample <log.csv>",
+	Short: "Выборка кейсов лога",
+	Long: "Записывает в новый CSV-файл (или в стандартный вывод) случайную долю кейсов лога вместе со всеми их событиями,\n" +
+		"чтобы быстро экспериментировать на очень больших логах. Выборка определяется --seed и не меняется\n" +
+		"от запуска к запуску; файл читается потоком, строки копируются без изменений.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		fraction, _ := cmd.Flags().GetFloat64("fraction")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		if fraction <= 0 || fraction > 1 {
+			return errors.New("доля выборки должна быть больше 0 и не больше 1")
+		}
+
+		svc, err := newAnalysisService(configPath)
+		if err != nil {
+			return err
+		}
+		var sampled, total int
+		err = writeOutput(output, func(w io.Writer) error {
+			sampled, total, err = svc.SampleLog(args[0], w, fraction, seed)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		// Итог — в поток ошибок, чтобы не смешивать его с логом в стандартном выводе
+		fmt.Fprintf(os.Stderr, "В выборку попало %d из %d кейсов.\n", sampled, total)
+		return nil
+	},
+}
+
+func init() {
+	sampleCmd.Flags().StringP("output", "o", "", "файл выборки (по умолчанию — стандартный вывод)")
+	sampleCmd.Flags().Float64("fraction", 0.1, "доля кейсов в выборке (больше 0 и не больше 1)")
+	sampleCmd.Flags().Int64("seed", 42, "начальное значение выбора кейсов")
+	sampleCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(sampleCmd)
+}
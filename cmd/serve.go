@@ -1,16 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/spf13/cobra"
 	"process-mining/config"
 	"process-mining/internal/domain"
 	"process-mining/internal/infrastructure"
 	"process-mining/internal/presentation"
 	"process-mining/internal/service"
-	"github.com/spf13/cobra"
 )
 
 var serveCmd = &cobra.Command{
@@ -18,8 +26,21 @@ var serveCmd = &cobra.Command{
 	Short: "Запуск HTTP-сервера",
 	Long:  "Запускает HTTP-сервер для обработки запросов.",
 	Run: func(cmd *cobra.Command, args []string) {
+		configPath, _ := cmd.Flags().GetString("config")
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			log.Fatalln("can not load config", err)
+		}
+		if err := applyServeFlags(cmd, cfg); err != nil {
+			log.Fatalln("invalid flags", err)
+		}
+
 		// Инициализация инфраструктурного слоя
 		csvReader := infrastructure.NewCSVReader()
+		// Временные файлы загрузок, оставшиеся после аварийного завершения, больше не нужны
+		if err := infrastructure.NewTMPCleaner().ClearUploadedFiles(); err != nil {
+			log.Printf("Ошибка очистки временных файлов: %v", err)
+		}
 
 		// Инициализация доменного слоя
 		graphBuilder := domain.NewGraphBuilder(csvReader)
@@ -30,35 +51,295 @@ var serveCmd = &cobra.Command{
 		// Инициализация слоя представления
 		graphHandler := presentation.NewGraphHandler(graphService)
 
-		// Настройка маршрутов
-		http.Handle("/", http.FileServer(http.Dir("./static"))) // Статические файлы
-		http.HandleFunc("/upload", graphHandler.UploadFile)     // Загрузка CSV
-		http.HandleFunc("/graph", graphHandler.ServeGraphData)  // Получение данных графа
-		http.HandleFunc("/clear", graphHandler.ClearGraph)      // Очистка графа
-		http.HandleFunc("/metrics", graphHandler.GetMetricsReport) // Получение отчета по метрикам
+		// Настройка маршрутов: API доступно по адресам /api/v1/... и, для совместимости, по прежним адресам
+		route := func(pattern string, handler http.HandlerFunc) {
+			presentation.HandleAPI(http.DefaultServeMux, pattern, handler)
+		}
+		http.Handle("/", http.FileServer(http.Dir(cfg.APP_STATIC_DIR)))         // Статические файлы, OpenAPI-спецификация (/openapi.yaml) и Swagger UI (/docs/)
+		route("/upload", graphHandler.UploadFile)                               // Загрузка CSV
+		route("/validate", graphHandler.ValidateUpload)                         // Проверка первых строк CSV перед загрузкой
+		route("/jobs/{id}", graphHandler.GetJob)                                // Ход построения графа по загруженному файлу
+		route("/jobs/{id}/events", graphHandler.StreamJob)                      // Поток хода построения (Server-Sent Events)
+		route("/datasets", graphHandler.ListDatasets)                           // Загруженные наборы данных
+		route("/datasets/{id}", graphHandler.Dataset)                           // Просмотр, переименование и удаление набора данных
+		route("/datasets/{id}/events", graphHandler.AppendEvents)               // Дозагрузка событий в набор данных
+		route("/graph", presentation.Gzip(graphHandler.ServeGraphData))         // Получение данных графа (со сжатием gzip)
+		route("/clear", graphHandler.ClearGraph)                                // Очистка графа
+		route("/metrics", presentation.Gzip(graphHandler.GetMetricsReport))     // Получение отчета по метрикам (со сжатием gzip)
+		route("/metrics/definitions", graphHandler.MetricDefinitions)           // Определения и пороги метрик
+		route("/metrics/{name}", presentation.Gzip(graphHandler.GetMetric))     // Отдельная метрика (без расчёта полного отчёта)
+		route("/metrics/{name}/occurrences", graphHandler.GetMetricOccurrences) // Вхождения метрики постранично
+		route("/subprocesses", graphHandler.Subprocesses)                       // Правила группировки подпроцессов
+		route("/graph/subprocesses", graphHandler.ServeSubprocessGraph)         // Двухуровневый граф подпроцессов
+		route("/replay", graphHandler.ServeReplay)                              // Данные для анимации движения токенов
+		route("/conformance/model", graphHandler.UploadReferenceModel)          // Загрузка эталонной модели (BPMN/PNML)
+		route("/conformance", graphHandler.GetConformance)                      // Проверка соответствия эталонной модели
+		route("/conformance/alignments", graphHandler.GetAlignments)            // Выравнивания кейсов с эталонной моделью
+		route("/roles", graphHandler.GetRoles)                                  // Организационные роли и передачи работы
+		route("/compare", graphHandler.ComparePeriods)                          // Сравнение двух периодов
+		route("/baselines", graphHandler.ListBaselines)                         // Сохранённые эталоны
+		route("/baselines/{name}", graphHandler.Baseline)                       // Сохранение, просмотр и удаление эталона
+		route("/baselines/{name}/compare", graphHandler.CompareWithBaseline)    // Сравнение с эталоном
+		route("/predict/remaining-time", graphHandler.PredictRemainingTime)     // Прогноз оставшегося времени кейса
+		route("/predict/outcome", graphHandler.PredictOutcome)                  // Прогноз вероятности ошибки кейса
+		route("/cases/{id}", graphHandler.GetCaseDetail)                        // Трасса кейса и найденные в нём неэффективности
+		route("/cases/worst", graphHandler.GetWorstCases)                       // Худшие кейсы по потерям, переделкам или длительности
+		route("/cases/stuck", graphHandler.GetStuckCases)                       // Застрявшие незавершённые кейсы
+		route("/automation", graphHandler.Automation)                           // Разметка ручных и автоматических активностей
+		route("/automation/cases", graphHandler.GetCaseAutomation)              // Уровень автоматизации кейсов
+		route("/errors", graphHandler.ErrorSemantics)                           // Правила распознавания ошибок
+		route("/efficiency/cases", graphHandler.GetCaseEfficiencies)            // Touch time / lead time по кейсам
+		route("/variants", graphHandler.GetVariants)                            // Показатели по вариантам процесса
+		route("/bottlenecks", graphHandler.GetBottlenecks)                      // Рейтинг узких мест по времени ожидания
+		route("/sla", graphHandler.SLA)                                         // Предельные длительности (SLA)
+		route("/calendar", graphHandler.Calendar)                               // Рабочий календарь
+		route("/costs", graphHandler.CostModel)                                 // Модель затрат
+		route("/rootcauses", graphHandler.GetRootCauses)                        // Вероятные причины неэффективностей
+		route("/stats/durations", graphHandler.GetDurationStats)                // Распределение длительности кейсов
+		route("/webhooks", graphHandler.Webhooks)                               // Вебхуки уведомлений о превышении порогов
+		route("/config/reload", graphHandler.ReloadConfig)                      // Перечитывание настроек анализа из файла
+		route("/audit", graphHandler.AuditLog)                                  // Журнал аудита операций с наборами данных
+		route("/webhooks/{id}", graphHandler.Webhook)                           // Удаление вебхука
+		route("/share", graphHandler.CreateShare)                               // Снимок графа и отчёта для публичной ссылки
+		route("/share/{token}", presentation.Gzip(graphHandler.GetShare))       // Просмотр снимка по ссылке (без аутентификации)
+
+		// Структурированный журнал; записи log.Printf также проходят через него
+		logOptions := &slog.HandlerOptions{Level: cfg.GetAppLogLevel()}
+		if cfg.APP_LOG_FORMAT == "json" {
+			slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, logOptions)))
+		} else {
+			slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, logOptions)))
+		}
+
+		// Настройки анализа; по SIGHUP и POST /config/reload они перечитываются из файла без перезапуска
+		reloadAnalysisConfig := func() error {
+			analysisCfg, err := cfg.LoadAnalysis()
+			if err != nil {
+				return err
+			}
+			return graphService.ApplySettings(analysisSettings(analysisCfg))
+		}
+		if err := graphService.ApplySettings(analysisSettings(cfg.Analysis)); err != nil {
+			log.Fatalln("invalid analysis config", err)
+		}
+		graphHandler.SetConfigReloader(reloadAnalysisConfig)
+
+		// Рассылка итогов анализа по почте
+		if cfg.APP_SMTP_HOST != "" && len(cfg.APP_ALERT_EMAILS) > 0 {
+			sender := infrastructure.NewSMTPSender(cfg.APP_SMTP_HOST, cfg.APP_SMTP_PORT, cfg.APP_SMTP_USER, cfg.APP_SMTP_PASSWORD, cfg.APP_SMTP_FROM)
+			graphService.AddNotifier(service.NewEmailNotifier(sender, cfg.APP_ALERT_EMAILS, cfg.GetAlertRule(), cfg.APP_PUBLIC_URL))
+		}
+
+		// Рассылка итогов анализа в мессенджеры
+		if cfg.APP_TELEGRAM_BOT_TOKEN != "" {
+			sender := infrastructure.NewTelegramSender(cfg.APP_TELEGRAM_BOT_TOKEN, cfg.APP_TELEGRAM_CHAT_ID)
+			graphService.AddNotifier(service.NewMessengerNotifier(sender, cfg.GetAlertRule(), cfg.APP_PUBLIC_URL))
+		}
+		if cfg.APP_SLACK_WEBHOOK_URL != "" {
+			sender := infrastructure.NewSlackSender(cfg.APP_SLACK_WEBHOOK_URL)
+			graphService.AddNotifier(service.NewMessengerNotifier(sender, cfg.GetAlertRule(), cfg.APP_PUBLIC_URL))
+		}
+
+		if cfg.APP_DATA_PATH != "" {
+			store, err := infrastructure.OpenDatasetStore(cfg.APP_DATA_PATH)
+			if err != nil {
+				log.Fatalln("can not open dataset store", err)
+			}
+			defer store.Close()
+			if err := graphService.SetStore(store); err != nil {
+				log.Fatalln("can not load datasets", err)
+			}
+		}
+
+		// Ограничения размера загрузки и частоты запросов, чтобы один клиент не мог перегрузить сервер
+		graphHandler.SetMaxUploadSize(cfg.GetAppMaxUploadSize())
+		graphHandler.SetGraphBudget(domain.GraphBudget{Nodes: cfg.APP_GRAPH_MAX_NODES, Edges: cfg.APP_GRAPH_MAX_EDGES})
+		graphService.SetMemoryLimit(cfg.GetAppIngestMemoryLimit())
+		if err := applyParseCache(graphService, cfg); err != nil {
+			log.Fatalln("can not open parse cache", err)
+		}
+		rateLimiter := presentation.NewRateLimiter(cfg.APP_RATE_LIMIT, cfg.APP_RATE_BURST)
+
+		// Заголовки CORS для интерфейса, размещённого на другом адресе
+		cors := presentation.NewCORS(cfg.APP_CORS_ORIGINS, cfg.APP_CORS_METHODS)
 
-		cfg, err := config.LoadEnv()
+		// Доступ к API только по ключу или JWT с учётом роли, статические файлы интерфейса открыты
+		authenticator, err := presentation.NewAuthenticator(cfg.APP_API_KEYS, cfg.APP_JWT_SECRET)
 		if err != nil {
-			log.Fatalln("can not load config", err)
+			log.Fatalln("invalid API keys", err)
+		}
+		if !authenticator.Enabled() {
+			log.Println("API-ключи и APP_JWT_SECRET не заданы: API доступно без аутентификации")
+		}
+
+		// HTTPS (с HTTP/2) по сертификату из файлов или с автоматическим получением сертификата
+		tlsConfig, err := infrastructure.NewTLSConfig(cfg.APP_TLS_CERT, cfg.APP_TLS_KEY, cfg.APP_TLS_AUTOCERT_DOMAINS, cfg.APP_TLS_CACHE_DIR)
+		if err != nil {
+			log.Fatalln("invalid TLS config", err)
 		}
 
 		// Настройка сервера с увеличенными таймаутами
 		srv := &http.Server{
 			Addr:         ":" + cfg.APP_PORT,
-			WriteTimeout: cfg.GetAppMaxWriteTime() * time.Minute, // Увеличенный таймаут для записи
-			ReadTimeout:  cfg.GetAppMaxReadTime() * time.Minute,  // Увеличенный таймаут для чтения
+			Handler:      presentation.RequestLogger(cors.Wrap(rateLimiter.Wrap(authenticator.Wrap(http.DefaultServeMux, "/", "/share/{token}")))),
+			WriteTimeout: cfg.GetAppMaxWriteTime(), // Увеличенный таймаут для записи
+			ReadTimeout:  cfg.GetAppMaxReadTime(),  // Увеличенный таймаут для чтения
+			TLSConfig:    tlsConfig,
+		}
+
+		// gRPC API на отдельном порту
+		var grpcServer *grpc.Server
+		if cfg.APP_GRPC_PORT != "" {
+			listener, err := net.Listen("tcp", ":"+cfg.APP_GRPC_PORT)
+			if err != nil {
+				log.Fatalln("can not listen gRPC port", err)
+			}
+			var options []grpc.ServerOption
+			if tlsConfig != nil {
+				options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			}
+			grpcServer = presentation.NewGRPC(graphService, authenticator, options...)
+			go func() {
+				log.Printf("gRPC-сервер запущен на порту %v", listener.Addr())
+				if err := grpcServer.Serve(listener); err != nil {
+					log.Fatalf("Ошибка запуска gRPC-сервера: %v", err)
+				}
+			}()
 		}
 
 		// Логирование запуска сервера
-		log.Printf("Сервер запущен на порту %v", srv.Addr)
+		if tlsConfig != nil {
+			log.Printf("Сервер запущен на порту %v (HTTPS)", srv.Addr)
+		} else {
+			log.Printf("Сервер запущен на порту %v", srv.Addr)
+		}
 
 		// Запуск сервера
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка запуска сервера: %v", err)
+		stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		// Перечитывание настроек анализа по SIGHUP; загруженные наборы данных сохраняются
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
+		go func() {
+			for range hangup {
+				if err := reloadAnalysisConfig(); err != nil {
+					log.Printf("Ошибка перечитывания настроек анализа: %v", err)
+					continue
+				}
+				log.Println("Настройки анализа перечитаны")
+			}
+		}()
+
+		// Автоматическая загрузка логов из каталога до остановки сервера
+		if cfg.APP_WATCH_DIR != "" {
+			next, err := cfg.GetWatchSchedule()
+			if err != nil {
+				log.Fatalln("invalid watch schedule", err)
+			}
+			watcher, err := service.NewFolderWatcher(graphService, cfg.APP_WATCH_DIR, cfg.APP_WATCH_WORKSPACE)
+			if err != nil {
+				log.Fatalln("can not watch directory", err)
+			}
+			go watcher.Run(stop, next)
 		}
+
+		serveErr := make(chan error, 1)
+		go func() {
+			if tlsConfig != nil {
+				// Сертификаты уже заданы в TLSConfig
+				serveErr <- srv.ListenAndServeTLS("", "")
+				return
+			}
+			serveErr <- srv.ListenAndServe()
+		}()
+		select {
+		case err := <-serveErr:
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Ошибка запуска сервера: %v", err)
+			}
+			return
+		case <-stop.Done():
+		}
+
+		// Плавная остановка: новые запросы не принимаются, текущие запросы и построение
+		// загруженных графов завершаются, временные файлы удаляются
+		log.Println("Получен сигнал остановки, завершение текущих запросов и задач...")
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), cfg.GetAppShutdownTimeout())
+		defer cancelShutdown()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Ошибка остановки сервера: %v", err)
+		}
+		if grpcServer != nil {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}
+		if err := graphService.WaitJobs(ctx); err != nil {
+			log.Printf("Построение графов не завершилось до остановки: %v", err)
+		}
+		if err := infrastructure.NewTMPCleaner().ClearUploadedFiles(); err != nil {
+			log.Printf("Ошибка очистки временных файлов: %v", err)
+		}
+		log.Println("Сервер остановлен")
 	},
 }
 
+// analysisSettings преобразует файл настроек анализа в настройки сервиса.
+func analysisSettings(analysisCfg *config.AnalysisConfig) service.AnalysisSettings {
+	return service.AnalysisSettings{
+		Thresholds:    analysisCfg.Thresholds,
+		SLA:           analysisCfg.SLA,
+		Calendar:      analysisCfg.Calendar,
+		Costs:         analysisCfg.Costs,
+		OutlierMethod: analysisCfg.OutlierMethod,
+		EndActivities: analysisCfg.EndActivities,
+		TopK:          analysisCfg.TopK,
+		Errors:        analysisCfg.Errors,
+		Automation:    analysisCfg.Automation,
+		Columns:       analysisCfg.Columns,
+	}
+}
+
+// applyServeFlags переопределяет значения конфигурации из файла и окружения флагами, заданными при запуске.
+func applyServeFlags(cmd *cobra.Command, cfg *config.Config) error {
+	flags := cmd.Flags()
+	if flags.Changed("port") {
+		cfg.APP_PORT, _ = flags.GetString("port")
+	}
+	if flags.Changed("static-dir") {
+		cfg.APP_STATIC_DIR, _ = flags.GetString("static-dir")
+	}
+	if flags.Changed("max-upload") {
+		cfg.APP_MAX_UPLOAD_SIZE, _ = flags.GetInt("max-upload")
+	}
+	if flags.Changed("read-timeout") {
+		cfg.APP_MAX_READ_TIME, _ = flags.GetInt("read-timeout")
+	}
+	if flags.Changed("memory-limit") {
+		cfg.APP_INGEST_MEMORY_LIMIT, _ = flags.GetInt("memory-limit")
+	}
+	if flags.Changed("parse-cache") {
+		cfg.APP_PARSE_CACHE_DIR, _ = flags.GetString("parse-cache")
+	}
+	return cfg.Validate()
+}
+
 func init() {
+	serveCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (вместо APP_CONFIG)")
+	serveCmd.Flags().String("port", "", "порт HTTP-сервера (вместо APP_PORT)")
+	serveCmd.Flags().String("static-dir", "", "каталог статических файлов интерфейса (вместо APP_STATIC_DIR)")
+	serveCmd.Flags().Int("max-upload", 0, "наибольший размер загружаемого лога, МБ (вместо APP_MAX_UPLOAD_SIZE)")
+	serveCmd.Flags().Int("read-timeout", 0, "таймаут чтения запроса, сек (вместо APP_MAX_READ_TIME)")
+	serveCmd.Flags().Int("memory-limit", 0, "бюджет памяти под события при построении графа, МБ (вместо APP_INGEST_MEMORY_LIMIT)")
+	serveCmd.Flags().String("parse-cache", "", "каталог кэша разобранных логов (вместо APP_PARSE_CACHE_DIR)")
 	rootCmd.AddCommand(serveCmd)
 }
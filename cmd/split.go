@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain"
+	"process-mining/internal/infrastructure"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split <log.csv>",
+	Short: "Разделение лога на файлы по периоду или атрибуту",
+	Long: "Делит CSV-лог на файлы <имя лога>_<часть>.csv в каталоге -o: по периоду начала кейса (--period " +
+		strings.Join(domain.SplitPeriods, ", ") + ")\n" +
+		"или по значению атрибута кейса (--attribute region). Кейс целиком попадает в один файл, строки копируются без изменений.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		dir, _ := cmd.Flags().GetString("output")
+		period, _ := cmd.Flags().GetString("period")
+		attribute, _ := cmd.Flags().GetString("attribute")
+		if (period == "") == (attribute == "") {
+			return errors.New("укажите либо --period, либо --attribute")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		path, cleanup, err := spoolInput(args[0])
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		svc, err := newAnalysisService(configPath)
+		if err != nil {
+			return err
+		}
+		stem := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		if args[0] == infrastructure.StdinPath {
+			stem = "stdin"
+		}
+		paths := make(map[string]string) // часть → файл
+		used := make(map[string]bool)
+		var files []*os.File
+		defer func() {
+			for _, file := range files {
+				file.Close()
+			}
+		}()
+		counts, err := svc.SplitLog(path, period, attribute, func(part string) (io.Writer, error) {
+			name := stem + "_" + safeFileName(part)
+			for used[name] {
+				name += "_"
+			}
+			used[name] = true
+			path := filepath.Join(dir, name+".csv")
+			file, err := os.Create(path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+			paths[part] = path
+			return file, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+		files = nil
+
+		parts := make([]string, 0, len(counts))
+		for part := range counts {
+			parts = append(parts, part)
+		}
+		sort.Strings(parts)
+		table := newTable(os.Stdout)
+		fmt.Fprintln(table, "Часть\tКейсов\tФайл")
+		for _, part := range parts {
+			fmt.Fprintf(table, "%s\t%d\t%s\n", part, counts[part], paths[part])
+		}
+		return table.Flush()
+	},
+}
+
+// safeFileName заменяет в названии части символы, недопустимые или неудобные в имени файла.
+func safeFileName(part string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, part)
+}
+
+func init() {
+	splitCmd.Flags().StringP("output", "o", ".", "каталог для файлов частей")
+	splitCmd.Flags().String("period", "", "период начала кейса: "+strings.Join(domain.SplitPeriods, ", "))
+	splitCmd.Flags().String("attribute", "", "столбец, по значению которого делится лог")
+	splitCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(splitCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <log.csv>",
+	Short: "Краткая сводка лога",
+	Long: "Выводит число кейсов, событий, активностей и вариантов, период лога и процентили длительности кейсов.\n" +
+		"Файл читается потоком за один проход, граф процесса не строится.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		format, err := outputFormat(cmd, formatTable)
+		if err != nil {
+			return err
+		}
+
+		svc, err := newAnalysisService(configPath)
+		if err != nil {
+			return err
+		}
+		stats, err := svc.LogStats(args[0])
+		if err != nil {
+			return err
+		}
+
+		if format == formatJSON {
+			return writeJSON(os.Stdout, stats)
+		}
+
+		table := newResultTable("Показатель", "Значение")
+		table.add("Кейсов", stats.Cases)
+		table.add("Событий", stats.Events)
+		table.add("Записей", stats.Rows)
+		if stats.Cases > 0 {
+			table.add("Начало", stats.From.Format(time.RFC3339))
+			table.add("Конец", stats.To.Format(time.RFC3339))
+		}
+		table.add("Активностей", stats.Activities)
+		table.add("Вариантов", stats.Variants)
+		table.add("Длительность кейса p50", hours(stats.Durations.P50))
+		table.add("Длительность кейса p75", hours(stats.Durations.P75))
+		table.add("Длительность кейса p90", hours(stats.Durations.P90))
+		table.add("Длительность кейса p95", hours(stats.Durations.P95))
+		table.add("Длительность кейса p99", hours(stats.Durations.P99))
+		table.add("Наибольшая длительность кейса", hours(stats.MaxDuration))
+		return table.write(os.Stdout, format)
+	},
+}
+
+func init() {
+	statsCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(statsCmd)
+}
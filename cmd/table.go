@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Форматы вывода результатов команд анализа (глобальный флаг --output-format).
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+)
+
+var outputFormats = []string{formatTable, formatJSON, formatCSV, formatMarkdown}
+
+// outputFormat возвращает формат вывода из флага --output-format или defaultFormat, если флаг не задан.
+func outputFormat(cmd *cobra.Command, defaultFormat string) (string, error) {
+	format, _ := cmd.Flags().GetString("output-format")
+	if format == "" {
+		return defaultFormat, nil
+	}
+	if !slices.Contains(outputFormats, format) {
+		return "", fmt.Errorf("неизвестный формат вывода %q: поддерживаются %s", format, strings.Join(outputFormats, ", "))
+	}
+	return format, nil
+}
+
+// humanFormat сообщает, что формат предназначен для чтения человеком и в него можно добавлять пояснения.
+func humanFormat(format string) bool {
+	return format == formatTable || format == formatMarkdown
+}
+
+// writeJSON записывает v в w как JSON с отступами.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// newTable возвращает писатель таблицы с выровненными столбцами, разделёнными табуляцией.
+// После вывода строк нужно вызвать Flush.
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// Значения ячеек, которые выводятся по-разному для человека и для машинной обработки:
+// в формате csv — числом без единиц измерения.
+type (
+	hours        float64 // длительность в секундах, выводится в часах
+	hoursChange  float64 // изменение длительности в секундах, выводится в часах со знаком
+	percent      float64 // доля, %
+	pointsChange float64 // изменение доли, п.п.
+	countChange  int     // изменение количества со знаком
+)
+
+// durationChange — изменение длительности в секундах вместе с изменением в процентах.
+type durationChange struct {
+	seconds, percent float64
+}
+
+// resultTable — таблица результата команды для форматов table, csv и markdown.
+type resultTable struct {
+	header []string
+	rows   [][]any
+}
+
+func newResultTable(header ...string) *resultTable {
+	return &resultTable{header: header}
+}
+
+// add добавляет строку таблицы.
+func (t *resultTable) add(cells ...any) {
+	t.rows = append(t.rows, cells)
+}
+
+// write выводит таблицу в формате format: table, csv или markdown.
+func (t *resultTable) write(w io.Writer, format string) error {
+	rows := make([][]string, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = make([]string, len(row))
+		for j, cell := range row {
+			rows[i][j] = formatCell(cell, format)
+		}
+	}
+
+	switch format {
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(t.header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	case formatMarkdown:
+		escape := strings.NewReplacer("|", `\|`, "\n", " ")
+		line := func(cells []string) {
+			for i := range cells {
+				cells[i] = escape.Replace(cells[i])
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		}
+		line(slices.Clone(t.header))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(t.header)))
+		for _, row := range rows {
+			line(row)
+		}
+		return nil
+	case formatTable:
+		table := newTable(w)
+		fmt.Fprintln(table, strings.Join(t.header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(table, strings.Join(row, "\t"))
+		}
+		return table.Flush()
+	}
+	return errors.New("формат " + format + " не поддерживается для таблиц")
+}
+
+// formatCell форматирует значение ячейки для формата format.
+func formatCell(cell any, format string) string {
+	machine := format == formatCSV
+	switch v := cell.(type) {
+	case string:
+		return v
+	case hours:
+		if machine {
+			return strconv.FormatFloat(float64(v)/3600, 'f', 1, 64)
+		}
+		return formatHours(float64(v))
+	case hoursChange:
+		if machine {
+			return strconv.FormatFloat(float64(v)/3600, 'f', 1, 64)
+		}
+		return formatHoursChange(float64(v))
+	case durationChange:
+		if machine {
+			return strconv.FormatFloat(v.seconds/3600, 'f', 1, 64)
+		}
+		return fmt.Sprintf("%s (%+.1f%%)", formatHoursChange(v.seconds), v.percent)
+	case percent:
+		if machine {
+			return strconv.FormatFloat(float64(v), 'f', 1, 64)
+		}
+		return fmt.Sprintf("%.1f%%", float64(v))
+	case pointsChange:
+		if machine {
+			return strconv.FormatFloat(float64(v), 'f', 1, 64)
+		}
+		return fmt.Sprintf("%+.1f п.п.", float64(v))
+	case countChange:
+		if machine {
+			return strconv.Itoa(int(v))
+		}
+		return fmt.Sprintf("%+d", int(v))
+	case float64:
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return fmt.Sprint(cell)
+}
+
+// formatHours форматирует длительность в секундах как часы.
+func formatHours(seconds float64) string {
+	return fmt.Sprintf("%.1f ч", seconds/3600)
+}
+
+// formatHoursChange форматирует изменение длительности в секундах как часы со знаком.
+func formatHoursChange(seconds float64) string {
+	return fmt.Sprintf("%+.1f ч", seconds/3600)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/domain/metrics"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top <log.csv>",
+	Short: "Главные неэффективности лога",
+	Long: "Выводит таблицу самых критичных метрик неэффективности лога: количество вхождений, потерянное время\n" +
+		"и кейсы с наибольшими потерями — для быстрого разбора без веб-интерфейса.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		limit, _ := cmd.Flags().GetInt("limit")
+		cases, _ := cmd.Flags().GetInt("cases")
+		format, err := outputFormat(cmd, formatTable)
+		if err != nil {
+			return err
+		}
+
+		svc, err := analyzeLog(args[0], configPath)
+		if err != nil {
+			return err
+		}
+		report, err := svc.GetMetricsReport()
+		if err != nil {
+			return err
+		}
+
+		return printTop(os.Stdout, report, limit, cases, format)
+	},
+}
+
+// topMetric — метрика в выводе top в формате json.
+type topMetric struct {
+	Priority       int      `json:"priority"`
+	Key            string   `json:"key"`
+	Name           string   `json:"name"`
+	Count          int      `json:"count"`
+	WastedDuration float64  `json:"wasted_duration"` // сек
+	Severity       float64  `json:"severity"`
+	Exceeded       bool     `json:"exceeded"`
+	Cases          []string `json:"cases"` // кейсы с наибольшими потерями
+}
+
+// topReport — вывод top в формате json.
+type topReport struct {
+	Cases   int         `json:"cases"`
+	Events  int         `json:"events"`
+	Metrics []topMetric `json:"metrics"`
+}
+
+// printTop выводит в формате format limit самых критичных метрик отчёта (0 — всех с вхождениями)
+// с cases кейсами, в которых потеряно больше всего времени.
+func printTop(w io.Writer, report *metrics.MetricsReport, limit, cases int, format string) error {
+	top := topReport{Cases: report.TotalProcessInstances, Events: report.TotalEvents, Metrics: []topMetric{}}
+	for _, metric := range report.Metrics {
+		if metric.Count == 0 {
+			continue
+		}
+		if limit > 0 && len(top.Metrics) == limit {
+			break
+		}
+		top.Metrics = append(top.Metrics, topMetric{
+			Priority:       metric.Priority,
+			Key:            metric.Key,
+			Name:           metric.Definition.Name,
+			Count:          metric.Count,
+			WastedDuration: metric.TotalWastedDuration,
+			Severity:       metric.Severity,
+			Exceeded:       metric.Exceeded,
+			Cases:          topCases(metric, cases),
+		})
+	}
+	if format == formatJSON {
+		return writeJSON(w, top)
+	}
+
+	if humanFormat(format) {
+		fmt.Fprintf(w, "Кейсов: %d, событий: %d\n\n", top.Cases, top.Events)
+	}
+	table := newResultTable("#", "Метрика", "Вхождений", "Потери", "Критичность", "Порог", "Кейсы с наибольшими потерями")
+	for _, metric := range top.Metrics {
+		threshold := ""
+		if metric.Exceeded {
+			threshold = "превышен"
+		}
+		table.add(metric.Priority, metric.Name, metric.Count, hours(metric.WastedDuration), metric.Severity,
+			threshold, strings.Join(metric.Cases, ", "))
+	}
+	if err := table.write(w, format); err != nil {
+		return err
+	}
+	if len(top.Metrics) == 0 && humanFormat(format) {
+		fmt.Fprintln(w, "Неэффективностей не найдено.")
+	}
+	return nil
+}
+
+// topCases возвращает до limit различных кейсов из вхождений метрики в порядке убывания потерь.
+// Вхождения, относящиеся ко всему логу, пропускаются.
+func topCases(metric metrics.InefficiencyMetric, limit int) []string {
+	var cases []string
+	seen := make(map[string]bool)
+	for _, occurrence := range metric.Occurrences {
+		if len(cases) == limit {
+			break
+		}
+		if occurrence.InstanceID == "ALL" || seen[occurrence.InstanceID] {
+			continue
+		}
+		seen[occurrence.InstanceID] = true
+		cases = append(cases, occurrence.InstanceID)
+	}
+	return cases
+}
+
+func init() {
+	topCmd.Flags().Int("limit", 10, "сколько метрик вывести (0 — все с вхождениями)")
+	topCmd.Flags().Int("cases", 3, "сколько кейсов с наибольшими потерями показать для каждой метрики")
+	topCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(topCmd)
+}
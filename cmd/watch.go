@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"process-mining/internal/infrastructure"
+	"process-mining/internal/service"
+)
+
+// fileVersion — состояние файла лога, по которому выполнен анализ.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <log.csv|каталог>",
+	Short: "Повторный анализ лога при изменении",
+	Long: "Следит за CSV-файлом или каталогом с CSV-файлами и анализирует каждый новый или изменённый лог,\n" +
+		"выводя изменения показателей и метрик по сравнению с предыдущим анализом. Остановка — Ctrl+C.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			return fmt.Errorf("интервал проверки должен быть положительным")
+		}
+		format, err := outputFormat(cmd, formatTable)
+		if err != nil {
+			return err
+		}
+		// Заголовки запусков не должны попадать в машиночитаемый вывод
+		banner := os.Stdout
+		if !humanFormat(format) {
+			banner = os.Stderr
+		}
+		target := args[0]
+		if target == infrastructure.StdinPath {
+			return errors.New("наблюдать можно только за файлом или каталогом, а не за стандартным вводом")
+		}
+		if _, err := os.Stat(target); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		analyzed := make(map[string]fileVersion)
+		var previous *service.GraphService
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		fmt.Fprintf(banner, "Наблюдение за %s (проверка каждые %s)\n", target, interval)
+		for {
+			paths, err := watchedLogs(target)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Ошибка:", err)
+			}
+			for _, path := range paths {
+				info, err := os.Stat(path)
+				if err != nil {
+					continue
+				}
+				version := fileVersion{modTime: info.ModTime(), size: info.Size()}
+				// Файл, изменявшийся в последний интервал, может быть ещё не дописан
+				if analyzed[path] == version || time.Since(version.modTime) < interval {
+					continue
+				}
+				analyzed[path] = version
+
+				fmt.Fprintf(banner, "\n=== %s: %s ===\n", time.Now().Format("15:04:05"), path)
+				current, err := analyzeLog(path, configPath)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Ошибка:", err)
+					continue
+				}
+				if previous == nil {
+					report, err := current.GetMetricsReport()
+					if err != nil {
+						return err
+					}
+					err = printTop(os.Stdout, report, 0, 3, format)
+				} else {
+					err = printComparison(os.Stdout, previous.CompareWith(current), true, format)
+				}
+				if err != nil {
+					return err
+				}
+				previous = current
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// watchedLogs возвращает отслеживаемые логи: сам файл target или CSV-файлы каталога target
+// в порядке времени изменения, чтобы новые выгрузки сравнивались с предыдущими.
+func watchedLogs(target string) ([]string, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{target}, nil
+	}
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	modTimes := make(map[string]time.Time)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(target, entry.Name())
+		paths = append(paths, path)
+		modTimes[path] = info.ModTime()
+	}
+	sort.Slice(paths, func(i, j int) bool { return modTimes[paths[i]].Before(modTimes[paths[j]]) })
+	return paths, nil
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", 2*time.Second, "интервал проверки изменений")
+	watchCmd.Flags().String("config", "", "файл конфигурации YAML или JSON (по умолчанию APP_CONFIG)")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/domain/metrics"
+)
+
+// AnalysisConfig содержит настройки анализа, загружаемые из раздела analysis файла конфигурации
+// или из отдельного файла APP_ANALYSIS_CONFIG (JSON или YAML).
+type AnalysisConfig struct {
+	// Thresholds переопределяет пороговые значения метрик: ключ метрики → порог.
+	Thresholds map[string]float64 `json:"thresholds"`
+	// SLA задаёт предельные длительности кейса, переходов и активностей.
+	SLA metrics.SLA `json:"sla"`
+	// Calendar задаёт рабочие дни, часы и праздники для расчёта рабочего времени.
+	Calendar *metrics.Calendar `json:"calendar"`
+	// Costs задаёт стоимость часа работы для расчёта финансового эффекта.
+	Costs metrics.CostModel `json:"costs"`
+	// OutlierMethod — метод поиска аномальных длительностей: iqr (по умолчанию), mad, log_zscore, isolation_forest.
+	OutlierMethod string `json:"outlier_method"`
+	// EndActivities — активности, которыми завершается процесс (для завершённости и застрявших кейсов).
+	EndActivities []string `json:"end_activities"`
+	// TopK — сколько самых частых активностей и путей попадает в отчёт (по умолчанию 5).
+	TopK int `json:"top_k"`
+	// Errors задаёт значения результата (или регулярное выражение), которые считаются ошибкой.
+	Errors metrics.ErrorSemantics `json:"errors"`
+	// Automation размечает активности как ручные (manual) или автоматические (automated).
+	Automation metrics.AutomationMapping `json:"automation"`
+	// Columns задаёт названия столбцов лога для полей события: case, timestamp, activity, result,
+	// resource, lifecycle, start, processing (если они не распознаются по заголовку).
+	Columns domain.ColumnNames `json:"columns"`
+}
+
+// LoadAnalysisConfig читает настройки анализа из файла JSON или YAML. Пустой путь означает настройки по умолчанию.
+func LoadAnalysisConfig(path string) (*AnalysisConfig, error) {
+	if path == "" {
+		return &AnalysisConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the analysis config: %v", err)
+	}
+	var section map[string]any
+	if err := yaml.Unmarshal(data, &section); err != nil {
+		return nil, fmt.Errorf("failed to parse the analysis config: %v", err)
+	}
+	return decodeAnalysisConfig(section)
+}
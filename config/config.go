@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/caarlos0/env/v9"
 	"github.com/go-playground/validator/v10"
@@ -11,21 +12,136 @@ type Config struct {
 	APP_PORT           string `env:"APP_PORT" envDefault:"8085" validate:"required,numeric,gte=1"`
 	APP_MAX_READ_TIME  int    `env:"APP_MAX_READ_TIME" envDefault:"60" validate:"required,gte=1"`
 	APP_MAX_WRITE_TIME int    `env:"APP_MAX_WRITE_TIME" envDefault:"60" validate:"required,gte=1"`
+	// Каталог статических файлов интерфейса
+	APP_STATIC_DIR string `env:"APP_STATIC_DIR" envDefault:"./static" validate:"required"`
+	// Путь к JSON-файлу с настройками анализа (пороги метрик и т.д.)
+	APP_ANALYSIS_CONFIG string `env:"APP_ANALYSIS_CONFIG"`
+	// Путь к файлу базы наборов данных и отчётов; пустой путь — хранение только в памяти
+	APP_DATA_PATH string `env:"APP_DATA_PATH"`
+	// API-ключи через запятую (ключ или ключ:пользователь:роль[:рабочая область]) и секрет JWT (HS256)
+	// для доступа к API; если не заданы, API открыт
+	APP_API_KEYS   []string `env:"APP_API_KEYS" envSeparator:","`
+	APP_JWT_SECRET string   `env:"APP_JWT_SECRET"`
+	// Порт gRPC API; если не задан, gRPC-сервер не запускается
+	APP_GRPC_PORT string `env:"APP_GRPC_PORT" validate:"omitempty,numeric,gte=1"`
+	// Сколько секунд при остановке сервера ждать завершения запросов и построения загруженных графов
+	APP_SHUTDOWN_TIMEOUT int `env:"APP_SHUTDOWN_TIMEOUT" envDefault:"1800" validate:"gte=0"`
+	// Наибольший размер загружаемого лога, МБ
+	APP_MAX_UPLOAD_SIZE int `env:"APP_MAX_UPLOAD_SIZE" envDefault:"3072" validate:"gte=1"`
+	// Бюджет памяти под события, прочитанные при построении графа по одному логу, МБ; сверх него
+	// события кейсов временно выгружаются на диск. 0 — без ограничения
+	APP_INGEST_MEMORY_LIMIT int `env:"APP_INGEST_MEMORY_LIMIT" envDefault:"0" validate:"gte=0"`
+	// Каталог кэша разобранных логов и его бюджет, МБ (0 — без ограничения); повторное построение графа
+	// по логу с тем же содержимым берёт события из кэша без разбора CSV. Пустой каталог — без кэша
+	APP_PARSE_CACHE_DIR  string `env:"APP_PARSE_CACHE_DIR"`
+	APP_PARSE_CACHE_SIZE int    `env:"APP_PARSE_CACHE_SIZE" envDefault:"1024" validate:"gte=0"`
+	// Наибольшее число узлов и переходов графа в ответе /graph: больший граф упрощается (редкие активности
+	// сливаются в один узел, редкие переходы убираются). 0 — без ограничения
+	APP_GRAPH_MAX_NODES int `env:"APP_GRAPH_MAX_NODES" envDefault:"300" validate:"gte=0"`
+	APP_GRAPH_MAX_EDGES int `env:"APP_GRAPH_MAX_EDGES" envDefault:"3000" validate:"gte=0"`
+	// Число запросов в минуту с одного IP-адреса и допустимый всплеск; 0 — без ограничения
+	APP_RATE_LIMIT int `env:"APP_RATE_LIMIT" envDefault:"0" validate:"gte=0"`
+	APP_RATE_BURST int `env:"APP_RATE_BURST" envDefault:"0" validate:"gte=0"`
+	// Источники (через запятую, "*" — любой) и методы, разрешённые для запросов интерфейса
+	// с другого адреса (CORS); без источников CORS отключён
+	APP_CORS_ORIGINS []string `env:"APP_CORS_ORIGINS" envSeparator:","`
+	APP_CORS_METHODS []string `env:"APP_CORS_METHODS" envSeparator:","`
+	// Сертификат и ключ TLS (PEM) для работы по HTTPS; вместо них можно указать домены через запятую,
+	// сертификаты для которых автоматически получаются у Let's Encrypt и хранятся в APP_TLS_CACHE_DIR
+	APP_TLS_CERT             string   `env:"APP_TLS_CERT" validate:"required_with=APP_TLS_KEY"`
+	APP_TLS_KEY              string   `env:"APP_TLS_KEY" validate:"required_with=APP_TLS_CERT"`
+	APP_TLS_AUTOCERT_DOMAINS []string `env:"APP_TLS_AUTOCERT_DOMAINS" envSeparator:","`
+	APP_TLS_CACHE_DIR        string   `env:"APP_TLS_CACHE_DIR" envDefault:"certs"`
+	// Каталог, новые CSV-логи из которого загружаются автоматически, и рабочая область для них;
+	// каталог проверяется каждые APP_WATCH_INTERVAL секунд или по расписанию cron APP_WATCH_SCHEDULE
+	APP_WATCH_DIR       string `env:"APP_WATCH_DIR"`
+	APP_WATCH_WORKSPACE string `env:"APP_WATCH_WORKSPACE"`
+	APP_WATCH_INTERVAL  int    `env:"APP_WATCH_INTERVAL" envDefault:"60" validate:"gte=1"`
+	APP_WATCH_SCHEDULE  string `env:"APP_WATCH_SCHEDULE"`
+	// SMTP-сервер и отправитель писем об итогах анализа; без сервера письма не отправляются
+	APP_SMTP_HOST     string `env:"APP_SMTP_HOST"`
+	APP_SMTP_PORT     int    `env:"APP_SMTP_PORT" envDefault:"587" validate:"gte=1,lte=65535"`
+	APP_SMTP_USER     string `env:"APP_SMTP_USER"`
+	APP_SMTP_PASSWORD string `env:"APP_SMTP_PASSWORD"`
+	APP_SMTP_FROM     string `env:"APP_SMTP_FROM" validate:"required_with=APP_SMTP_HOST"`
+	// Адреса через запятую, на которые после анализа отправляется сводка, если впервые превышен порог
+	// одной из метрик APP_ALERT_METRICS (пустой список — любой) с критичностью не ниже APP_ALERT_MIN_SEVERITY;
+	// то же правило действует для сообщений в Telegram и Slack
+	APP_ALERT_EMAILS       []string `env:"APP_ALERT_EMAILS" envSeparator:"," validate:"omitempty,dive,email"`
+	APP_ALERT_METRICS      []string `env:"APP_ALERT_METRICS" envSeparator:","`
+	APP_ALERT_MIN_SEVERITY float64  `env:"APP_ALERT_MIN_SEVERITY" envDefault:"0" validate:"gte=0"`
+	// Токен бота и чат Telegram, входящий вебхук Slack для сводок анализа
+	APP_TELEGRAM_BOT_TOKEN string `env:"APP_TELEGRAM_BOT_TOKEN" validate:"required_with=APP_TELEGRAM_CHAT_ID"`
+	APP_TELEGRAM_CHAT_ID   string `env:"APP_TELEGRAM_CHAT_ID" validate:"required_with=APP_TELEGRAM_BOT_TOKEN"`
+	APP_SLACK_WEBHOOK_URL  string `env:"APP_SLACK_WEBHOOK_URL" validate:"omitempty,url"`
+	// Внешний адрес сервера для ссылок в уведомлениях (например, https://pm.example.com)
+	APP_PUBLIC_URL string `env:"APP_PUBLIC_URL" validate:"omitempty,url"`
+	// Формат (text или json) и уровень (debug, info, warn, error) журнала
+	APP_LOG_FORMAT string `env:"APP_LOG_FORMAT" envDefault:"text" validate:"oneof=text json"`
+	APP_LOG_LEVEL  string `env:"APP_LOG_LEVEL" envDefault:"info" validate:"oneof=debug info warn error"`
+
+	// Настройки анализа: раздел analysis файла конфигурации или, если задан, файл APP_ANALYSIS_CONFIG
+	Analysis *AnalysisConfig
+
+	path string // файл конфигурации, из которого загружены значения (пустой — только окружение)
 }
 
-var Conf Config
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
 }
 
-func LoadEnv() (*Config, error) {
-	if err := env.Parse(&Conf); err != nil {
+// Load загружает конфигурацию слоями: значения файла path (YAML или JSON; пустой путь — файл из
+// переменной APP_CONFIG, если она задана) переопределяются переменными окружения, а для незаданных
+// используются значения по умолчанию. Флаги командной строки применяются вызывающим поверх результата,
+// после чего конфигурация проверяется ещё раз (см. Validate).
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("APP_CONFIG")
+	}
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	environment, err := file.environment()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range envMap() {
+		environment[name] = value
+	}
+	cfg := &Config{path: path}
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: environment}); err != nil {
 		return nil, fmt.Errorf("failed to load the env: %v", err)
 	}
-	if err := validate.Struct(Conf); err != nil {
-		return nil, fmt.Errorf("validation failed: %v", err)
+	if cfg.Analysis, err = cfg.LoadAnalysis(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadAnalysis заново читает настройки анализа: из файла APP_ANALYSIS_CONFIG, если он задан,
+// иначе из раздела analysis файла конфигурации. Используется и для перечитывания настроек без перезапуска.
+func (c *Config) LoadAnalysis() (*AnalysisConfig, error) {
+	if c.APP_ANALYSIS_CONFIG != "" {
+		return LoadAnalysisConfig(c.APP_ANALYSIS_CONFIG)
+	}
+	file, err := readConfigFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAnalysisConfig(file.Analysis)
+}
+
+// Validate проверяет значения конфигурации, например после переопределения флагами командной строки.
+func (c *Config) Validate() error {
+	if err := validate.Struct(c); err != nil {
+		return fmt.Errorf("validation failed: %v", err)
 	}
-	return &Conf, nil
+	return nil
 }
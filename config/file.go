@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile — файл конфигурации APP_CONFIG. Раздел server содержит параметры сервера под именами
+// переменных окружения без префикса APP_ в нижнем регистре (port, max_upload_size, api_keys и т.д.),
+// раздел analysis — настройки анализа в том же виде, что и файл APP_ANALYSIS_CONFIG.
+type configFile struct {
+	Server   map[string]any `yaml:"server"`
+	Analysis map[string]any `yaml:"analysis"`
+}
+
+// readConfigFile читает файл конфигурации. Пустой путь означает пустую конфигурацию, пустой файл — тоже.
+func readConfigFile(path string) (*configFile, error) {
+	file := &configFile{}
+	if path == "" {
+		return file, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse the config file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// environment возвращает значения раздела server в виде переменных окружения: списки
+// записываются через запятую, как в APP_API_KEYS.
+func (f *configFile) environment() (map[string]string, error) {
+	known := envNames()
+	environment := make(map[string]string, len(f.Server))
+	for key, value := range f.Server {
+		name := "APP_" + strings.ToUpper(key)
+		if !known[name] {
+			return nil, fmt.Errorf("unknown server setting %q in the config file", key)
+		}
+		switch value := value.(type) {
+		case nil:
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			environment[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("server setting %q must be a value or a list", key)
+		default:
+			environment[name] = fmt.Sprint(value)
+		}
+	}
+	return environment, nil
+}
+
+// envNames возвращает имена переменных окружения, читаемых в Config.
+func envNames() map[string]bool {
+	names := make(map[string]bool)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := configType.Field(i).Tag.Get("env"); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// envMap возвращает переменные окружения процесса.
+func envMap() map[string]string {
+	environment := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			environment[name] = value
+		}
+	}
+	return environment
+}
+
+// decodeAnalysisConfig преобразует раздел настроек анализа, прочитанный из YAML, в AnalysisConfig
+// по JSON-тегам полей, чтобы YAML- и JSON-файлы описывали настройки одинаково.
+func decodeAnalysisConfig(section map[string]any) (*AnalysisConfig, error) {
+	analysis := &AnalysisConfig{}
+	if len(section) == 0 {
+		return analysis, nil
+	}
+	data, err := json.Marshal(section)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the analysis config: %v", err)
+	}
+	if err := json.Unmarshal(data, analysis); err != nil {
+		return nil, fmt.Errorf("failed to parse the analysis config: %v", err)
+	}
+	return analysis, nil
+}
@@ -1,6 +1,13 @@
 package config
 
-import "time"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"process-mining/internal/domain/metrics"
+)
 
 func (c *Config) GetAppMaxReadTime() time.Duration {
 	return time.Duration(c.APP_MAX_READ_TIME) * time.Second
@@ -9,3 +16,46 @@ func (c *Config) GetAppMaxReadTime() time.Duration {
 func (c *Config) GetAppMaxWriteTime() time.Duration {
 	return time.Duration(c.APP_MAX_WRITE_TIME) * time.Second
 }
+
+func (c *Config) GetAppShutdownTimeout() time.Duration {
+	return time.Duration(c.APP_SHUTDOWN_TIMEOUT) * time.Second
+}
+
+func (c *Config) GetAppLogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.APP_LOG_LEVEL))
+	return level
+}
+
+func (c *Config) GetAppMaxUploadSize() int64 {
+	return int64(c.APP_MAX_UPLOAD_SIZE) * 1024 * 1024
+}
+
+func (c *Config) GetAppIngestMemoryLimit() int64 {
+	return int64(c.APP_INGEST_MEMORY_LIMIT) * 1024 * 1024
+}
+
+func (c *Config) GetAppParseCacheSize() int64 {
+	return int64(c.APP_PARSE_CACHE_SIZE) * 1024 * 1024
+}
+
+// GetWatchSchedule возвращает функцию, вычисляющую следующую проверку каталога APP_WATCH_DIR:
+// по расписанию cron APP_WATCH_SCHEDULE (например, "0 3 * * *") или через APP_WATCH_INTERVAL секунд.
+func (c *Config) GetWatchSchedule() (func(time.Time) time.Time, error) {
+	if c.APP_WATCH_SCHEDULE != "" {
+		schedule, err := cron.ParseStandard(c.APP_WATCH_SCHEDULE)
+		if err != nil {
+			return nil, err
+		}
+		return schedule.Next, nil
+	}
+	interval := time.Duration(c.APP_WATCH_INTERVAL) * time.Second
+	return func(now time.Time) time.Time {
+		return now.Add(interval)
+	}, nil
+}
+
+// GetAlertRule возвращает правило, по которому превышения порогов попадают в письма.
+func (c *Config) GetAlertRule() metrics.AlertRule {
+	return metrics.AlertRule{Metrics: c.APP_ALERT_METRICS, MinSeverity: c.APP_ALERT_MIN_SEVERITY}
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"process-mining/internal/infrastructure"
+)
+
+// Способы обезличивания лога.
+const (
+	AnonymizeHash      = "hash"      // значения заменяются хешами HMAC-SHA256 с солью
+	AnonymizePseudonym = "pseudonym" // значения заменяются порядковыми псевдонимами из таблицы соответствия
+)
+
+// Pseudonyms — таблица соответствия исходных значений псевдонимам. Её сохраняют между запусками,
+// чтобы одинаковые кейсы, ресурсы и активности разных выгрузок получали одинаковые псевдонимы.
+type Pseudonyms struct {
+	Cases      map[string]string `json:"cases"`
+	Resources  map[string]string `json:"resources"`
+	Activities map[string]string `json:"activities"`
+}
+
+// Anonymizer заменяет идентификаторы кейсов, ресурсы и, если задано Activities, названия активностей.
+// Одинаковые значения всегда получают одинаковую замену, поэтому граф и метрики обезличенного лога
+// совпадают с исходными.
+type Anonymizer struct {
+	Method     string      // AnonymizeHash или AnonymizePseudonym
+	Salt       string      // соль для AnonymizeHash
+	Pseudonyms *Pseudonyms // таблица для AnonymizePseudonym, дополняется новыми значениями
+	Activities bool        // обезличивать и названия активностей
+}
+
+// Validate проверяет, что для выбранного способа заданы соль или таблица псевдонимов.
+func (a *Anonymizer) Validate() error {
+	switch a.Method {
+	case AnonymizeHash:
+		if a.Salt == "" {
+			// Без соли хеши коротких идентификаторов легко подобрать перебором
+			return errors.New("для хеширования нужна соль")
+		}
+	case AnonymizePseudonym:
+		if a.Pseudonyms == nil {
+			return errors.New("для псевдонимов нужна таблица соответствия")
+		}
+	default:
+		return fmt.Errorf("неизвестный способ обезличивания %q: ожидается %s или %s", a.Method, AnonymizeHash, AnonymizePseudonym)
+	}
+	return nil
+}
+
+// replace возвращает замену значения value вида kind (case, resource, activity). Пустые значения не меняются.
+func (a *Anonymizer) replace(kind, value string) string {
+	if value == "" {
+		return value
+	}
+	if a.Method == AnonymizeHash {
+		mac := hmac.New(sha256.New, []byte(a.Salt))
+		mac.Write([]byte(kind + "\x00" + value))
+		return kind + "_" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+
+	var names *map[string]string
+	switch kind {
+	case "case":
+		names = &a.Pseudonyms.Cases
+	case "resource":
+		names = &a.Pseudonyms.Resources
+	default:
+		names = &a.Pseudonyms.Activities
+	}
+	if *names == nil {
+		*names = make(map[string]string)
+	}
+	pseudonym, ok := (*names)[value]
+	if !ok {
+		pseudonym = kind + "_" + strconv.Itoa(len(*names)+1)
+		(*names)[value] = pseudonym
+	}
+	return pseudonym
+}
+
+// AnonymizeLog записывает в w лог filePath, в котором идентификаторы кейсов, ресурсы и (по настройке)
+// активности заменены обезличенными значениями. Файл читается потоком, остальные столбцы не меняются.
+func AnonymizeLog(csvReader *infrastructure.CSVReader, filePath string, w io.Writer, columns ColumnNames, anonymizer *Anonymizer) error {
+	if err := anonymizer.Validate(); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	mapping := defaultColumnMapping()
+
+	err := csvReader.ReadAndProcessWithHeader(filePath, func(header []string) error {
+		mapping = DetectColumnsWith(header, columns)
+		return writer.Write(header)
+	}, func(record []string) error {
+		if len(record) < mapping.minRecordLength() {
+			return fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", mapping.minRecordLength(), record)
+		}
+		record[mapping.CaseID] = anonymizer.replace("case", record[mapping.CaseID])
+		if mapping.Resource >= 0 && mapping.Resource < len(record) {
+			record[mapping.Resource] = anonymizer.replace("resource", record[mapping.Resource])
+		}
+		if anonymizer.Activities {
+			record[mapping.Activity] = anonymizer.replace("activity", record[mapping.Activity])
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// columnAliases — известные названия столбцов лога для каждого поля события.
+var columnAliases = map[string][]string{
+	"case":       {"case_id", "caseid", "case", "session_id", "sessionid", "case:concept:name"},
+	"timestamp":  {"timestamp", "time", "time:timestamp", "datetime", "date"},
+	"activity":   {"activity", "description", "desc", "event", "concept:name", "activity_name"},
+	"result":     {"result", "status", "outcome"},
+	"resource":   {"resource", "org:resource", "user", "performer", "employee", "executor"},
+	"lifecycle":  {"lifecycle", "lifecycle:transition", "transition", "event_type"},
+	"start":      {"start_timestamp", "start_time", "started_at", "start"},
+	"processing": {"processing_time", "processing_seconds", "duration", "service_time"},
+}
+
+// ColumnNames задаёт названия столбцов лога для полей события (case, timestamp, activity, result,
+// resource, lifecycle, start, processing), если они не распознаются по известным названиям.
+type ColumnNames map[string]string
+
+// Validate проверяет, что названия заданы только для известных полей события.
+func (c ColumnNames) Validate() error {
+	for field, name := range c {
+		if _, ok := columnAliases[field]; !ok {
+			return fmt.Errorf("неизвестное поле события %q", field)
+		}
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("не задано название столбца для поля %q", field)
+		}
+	}
+	return nil
+}
+
+// ColumnMapping содержит индексы столбцов CSV с полями события (-1 — столбец отсутствует).
+type ColumnMapping struct {
+	CaseID     int
+	Timestamp  int
+	Activity   int
+	Result     int
+	Resource   int
+	Lifecycle  int            // тип события жизненного цикла (start/complete)
+	Start      int            // время начала обработки активности
+	Processing int            // длительность обработки активности (секунды или формат 1h30m)
+	Attributes map[int]string // прочие столбцы: индекс → название атрибута
+	Recognized bool           // обязательные столбцы распознаны по заголовку
+}
+
+// defaultColumnMapping соответствует исходному формату: ID сессии, время, описание.
+func defaultColumnMapping() ColumnMapping {
+	return ColumnMapping{CaseID: 0, Timestamp: 1, Activity: 2, Result: -1, Resource: -1, Lifecycle: -1, Start: -1, Processing: -1}
+}
+
+// DetectColumns определяет назначение столбцов по заголовку. Если обязательные столбцы
+// не распознаны по названию, используется исходный порядок: ID сессии, время, описание.
+func DetectColumns(header []string) ColumnMapping {
+	return DetectColumnsWith(header, nil)
+}
+
+// DetectColumnsWith определяет назначение столбцов по заголовку, как DetectColumns, но поля,
+// для которых в names задано название столбца, ищутся сначала по нему.
+func DetectColumnsWith(header []string, names ColumnNames) ColumnMapping {
+	found := map[string]int{}
+	for i, name := range header {
+		normalized := normalizeColumn(name)
+		for field, column := range names {
+			if _, ok := found[field]; !ok && normalized == normalizeColumn(column) {
+				found[field] = i
+			}
+		}
+	}
+	for i, name := range header {
+		normalized := normalizeColumn(name)
+		for field, aliases := range columnAliases {
+			if _, ok := found[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if normalized == alias {
+					found[field] = i
+					break
+				}
+			}
+		}
+	}
+
+	mapping := defaultColumnMapping()
+	_, hasCase := found["case"]
+	_, hasTimestamp := found["timestamp"]
+	_, hasActivity := found["activity"]
+	if hasCase && hasTimestamp && hasActivity {
+		mapping.CaseID = found["case"]
+		mapping.Timestamp = found["timestamp"]
+		mapping.Activity = found["activity"]
+		mapping.Recognized = true
+	}
+	if i, ok := found["result"]; ok && !isCoreColumn(mapping, i) {
+		mapping.Result = i
+	}
+	if i, ok := found["resource"]; ok && !isCoreColumn(mapping, i) {
+		mapping.Resource = i
+	}
+	if i, ok := found["lifecycle"]; ok && !isCoreColumn(mapping, i) {
+		mapping.Lifecycle = i
+	}
+	if i, ok := found["start"]; ok && !isCoreColumn(mapping, i) {
+		mapping.Start = i
+	}
+	if i, ok := found["processing"]; ok && !isCoreColumn(mapping, i) {
+		mapping.Processing = i
+	}
+
+	mapping.Attributes = make(map[int]string)
+	for i, name := range header {
+		if isCoreColumn(mapping, i) || i == mapping.Result || i == mapping.Resource ||
+			i == mapping.Lifecycle || i == mapping.Start || i == mapping.Processing {
+			continue
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			mapping.Attributes[i] = name
+		}
+	}
+
+	return mapping
+}
+
+// normalizeColumn приводит название столбца к виду, в котором оно сравнивается с известными названиями.
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+// minRecordLength возвращает минимальное количество столбцов, необходимое для разбора записи.
+func (m ColumnMapping) minRecordLength() int {
+	return max(m.CaseID, m.Timestamp, m.Activity) + 1
+}
+
+// field возвращает значение необязательного столбца или пустую строку.
+func (m ColumnMapping) field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+// fieldAt возвращает поле события, которое содержит столбец i (case, timestamp, activity, result,
+// resource, lifecycle, start, processing), или пустую строку для столбца атрибута.
+func (m ColumnMapping) fieldAt(i int) string {
+	switch i {
+	case m.CaseID:
+		return "case"
+	case m.Timestamp:
+		return "timestamp"
+	case m.Activity:
+		return "activity"
+	case m.Result:
+		return "result"
+	case m.Resource:
+		return "resource"
+	case m.Lifecycle:
+		return "lifecycle"
+	case m.Start:
+		return "start"
+	case m.Processing:
+		return "processing"
+	}
+	return ""
+}
+
+func isCoreColumn(m ColumnMapping, i int) bool {
+	return i == m.CaseID || i == m.Timestamp || i == m.Activity
+}
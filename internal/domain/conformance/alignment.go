@@ -0,0 +1,221 @@
+package conformance
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Стоимость хода только в логе или только в модели. Невидимые переходы стоят 1,
+	// чтобы из равных по стоимости выравниваний выбиралось самое короткое.
+	deviationCost = 10000
+	silentCost    = 1
+
+	// maxAlignmentStates ограничивает количество раскрытых состояний поиска для одной трассы.
+	maxAlignmentStates = 100000
+)
+
+// Типы ходов выравнивания.
+const (
+	MoveSync  = "sync"  // событие лога совпало с переходом модели
+	MoveLog   = "log"   // событие есть в логе, но модель его не допускает
+	MoveModel = "model" // модель требует активность, которой нет в логе
+)
+
+// AlignmentMove — один ход выравнивания трассы с моделью.
+type AlignmentMove struct {
+	Type     string `json:"type"`
+	Activity string `json:"activity"`
+}
+
+// CaseAlignment — оптимальное выравнивание одного кейса.
+type CaseAlignment struct {
+	CaseID     string          `json:"case_id"`
+	Fitness    float64         `json:"fitness"`
+	LogMoves   int             `json:"log_moves"`
+	ModelMoves int             `json:"model_moves"`
+	Moves      []AlignmentMove `json:"moves"`
+	Complete   bool            `json:"complete"` // false, если поиск остановлен по лимиту состояний
+}
+
+// AlignmentResult содержит выравнивания всех кейсов лога.
+type AlignmentResult struct {
+	AverageFitness float64         `json:"average_fitness"`
+	Cases          []CaseAlignment `json:"cases"`
+}
+
+type alignmentState struct {
+	position int
+	marking  Marking
+	cost     int
+	moves    []AlignmentMove
+	index    int
+}
+
+type alignmentQueue []*alignmentState
+
+func (q alignmentQueue) Len() int { return len(q) }
+func (q alignmentQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].position > q[j].position
+}
+func (q alignmentQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *alignmentQueue) Push(x any) {
+	state := x.(*alignmentState)
+	state.index = len(*q)
+	*q = append(*q, state)
+}
+func (q *alignmentQueue) Pop() any {
+	old := *q
+	n := len(old)
+	state := old[n-1]
+	*q = old[:n-1]
+	return state
+}
+
+// Align строит оптимальные выравнивания трасс с моделью алгоритмом Дейкстры по пространству
+// (позиция в трассе, разметка). Одинаковые варианты выравниваются один раз.
+func Align(net *Net, traces []Trace) *AlignmentResult {
+	result := &AlignmentResult{Cases: []CaseAlignment{}}
+	if len(traces) == 0 {
+		return result
+	}
+
+	// Минимальное число ходов модели без лога нужно для нормировки fitness
+	emptyMoves, _ := net.align(nil)
+	modelOnly := countDeviations(emptyMoves)
+
+	byVariant := make(map[string]CaseAlignment)
+	var fitnessSum float64
+	for _, trace := range traces {
+		key := strings.Join(trace.Activities, "\x00")
+		alignment, ok := byVariant[key]
+		if !ok {
+			moves, complete := net.align(trace.Activities)
+			alignment = CaseAlignment{Moves: moves, Complete: complete}
+			for _, move := range moves {
+				switch move.Type {
+				case MoveLog:
+					alignment.LogMoves++
+				case MoveModel:
+					alignment.ModelMoves++
+				}
+			}
+			worst := len(trace.Activities) + modelOnly
+			alignment.Fitness = 1.0
+			if worst > 0 {
+				alignment.Fitness -= float64(alignment.LogMoves+alignment.ModelMoves) / float64(worst)
+			}
+			byVariant[key] = alignment
+		}
+
+		alignment.CaseID = trace.CaseID
+		fitnessSum += alignment.Fitness
+		result.Cases = append(result.Cases, alignment)
+	}
+
+	result.AverageFitness = fitnessSum / float64(len(traces))
+	sort.Slice(result.Cases, func(i, j int) bool {
+		if result.Cases[i].Fitness != result.Cases[j].Fitness {
+			return result.Cases[i].Fitness < result.Cases[j].Fitness
+		}
+		return result.Cases[i].CaseID < result.Cases[j].CaseID
+	})
+
+	return result
+}
+
+// align ищет выравнивание минимальной стоимости для одной трассы.
+func (n *Net) align(activities []string) ([]AlignmentMove, bool) {
+	finalKey := n.FinalMarking.key()
+	start := &alignmentState{marking: n.InitialMarking.clone()}
+
+	queue := &alignmentQueue{start}
+	best := map[string]int{stateKey(0, start.marking): 0}
+	closed := make(map[string]bool)
+	var fallback *alignmentState
+
+	for queue.Len() > 0 && len(closed) < maxAlignmentStates {
+		current := heap.Pop(queue).(*alignmentState)
+		key := stateKey(current.position, current.marking)
+		if closed[key] {
+			continue
+		}
+		closed[key] = true
+
+		if current.position == len(activities) && current.marking.key() == finalKey {
+			return current.moves, true
+		}
+		if fallback == nil || current.position > fallback.position {
+			fallback = current
+		}
+
+		push := func(position int, marking Marking, cost int, move *AlignmentMove) {
+			nextKey := stateKey(position, marking)
+			if closed[nextKey] {
+				return
+			}
+			if prev, ok := best[nextKey]; ok && prev <= cost {
+				return
+			}
+			best[nextKey] = cost
+			moves := current.moves
+			if move != nil {
+				moves = append(moves[:len(moves):len(moves)], *move)
+			}
+			heap.Push(queue, &alignmentState{position: position, marking: marking, cost: cost, moves: moves})
+		}
+
+		for _, t := range n.Transitions {
+			if !current.marking.enables(t) {
+				continue
+			}
+			next := current.marking.fire(t)
+			if t.Silent() {
+				push(current.position, next, current.cost+silentCost, nil)
+				continue
+			}
+			if current.position < len(activities) && activities[current.position] == t.Label {
+				push(current.position+1, next, current.cost, &AlignmentMove{Type: MoveSync, Activity: t.Label})
+			}
+			push(current.position, next, current.cost+deviationCost, &AlignmentMove{Type: MoveModel, Activity: t.Label})
+		}
+
+		if current.position < len(activities) {
+			push(current.position+1, current.marking, current.cost+deviationCost,
+				&AlignmentMove{Type: MoveLog, Activity: activities[current.position]})
+		}
+	}
+
+	// Лимит исчерпан: возвращаем лучшее частичное выравнивание, дополняя его ходами лога
+	if fallback == nil {
+		return nil, false
+	}
+	moves := fallback.moves
+	for _, activity := range activities[fallback.position:] {
+		moves = append(moves, AlignmentMove{Type: MoveLog, Activity: activity})
+	}
+	return moves, false
+}
+
+func stateKey(position int, marking Marking) string {
+	return strconv.Itoa(position) + "|" + marking.key()
+}
+
+func countDeviations(moves []AlignmentMove) int {
+	var count int
+	for _, move := range moves {
+		if move.Type != MoveSync {
+			count++
+		}
+	}
+	return count
+}
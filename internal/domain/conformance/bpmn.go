@@ -0,0 +1,202 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	sourcePlace = "source"
+	sinkPlace   = "sink"
+)
+
+// bpmnTaskTypes — элементы BPMN, которые соответствуют видимым активностям.
+var bpmnTaskTypes = map[string]bool{
+	"task":             true,
+	"userTask":         true,
+	"serviceTask":      true,
+	"manualTask":       true,
+	"scriptTask":       true,
+	"sendTask":         true,
+	"receiveTask":      true,
+	"businessRuleTask": true,
+	"callActivity":     true,
+	"subProcess":       true,
+}
+
+type bpmnNode struct {
+	id       string
+	kind     string
+	name     string
+	incoming []string
+	outgoing []string
+}
+
+// ParseModel разбирает эталонную модель в формате BPMN 2.0 XML или PNML, определяя формат по корневому элементу.
+func ParseModel(r io.Reader) (*Net, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения модели: %w", err)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось определить формат модели: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "definitions":
+				return ParseBPMN(bytes.NewReader(data))
+			case "pnml":
+				return ParsePNML(bytes.NewReader(data))
+			default:
+				return nil, fmt.Errorf("неизвестный формат модели: корневой элемент <%s>", start.Name.Local)
+			}
+		}
+	}
+}
+
+// ParseBPMN преобразует BPMN-модель в сеть Петри: потоки управления становятся позициями,
+// задачи — видимыми переходами, события и шлюзы — невидимыми переходами.
+// Включающие (inclusive) и событийные шлюзы приближаются исключающими.
+func ParseBPMN(r io.Reader) (*Net, error) {
+	nodes := make(map[string]*bpmnNode)
+	var order []string
+	var flows []string
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора BPMN: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		attrs := make(map[string]string, len(start.Attr))
+		for _, attr := range start.Attr {
+			attrs[attr.Name.Local] = attr.Value
+		}
+
+		kind := start.Name.Local
+		switch {
+		case kind == "sequenceFlow":
+			source, target := attrs["sourceRef"], attrs["targetRef"]
+			if source == "" || target == "" {
+				return nil, fmt.Errorf("поток %q без sourceRef/targetRef", attrs["id"])
+			}
+			id := attrs["id"]
+			if id == "" {
+				id = source + "->" + target
+			}
+			flows = append(flows, id)
+			node := getBPMNNode(nodes, &order, source)
+			node.outgoing = append(node.outgoing, id)
+			node = getBPMNNode(nodes, &order, target)
+			node.incoming = append(node.incoming, id)
+		case bpmnTaskTypes[kind], strings.HasSuffix(kind, "Event"), strings.HasSuffix(kind, "Gateway"):
+			if attrs["id"] == "" {
+				continue
+			}
+			node := getBPMNNode(nodes, &order, attrs["id"])
+			node.kind = kind
+			node.name = strings.TrimSpace(attrs["name"])
+		}
+	}
+
+	if len(flows) == 0 {
+		return nil, errors.New("модель BPMN не содержит потоков управления (sequenceFlow)")
+	}
+
+	net := &Net{
+		InitialMarking: Marking{sourcePlace: 1},
+		FinalMarking:   Marking{sinkPlace: 1},
+	}
+	net.Places = append(net.Places, sourcePlace, sinkPlace)
+	for _, flow := range flows {
+		net.Places = append(net.Places, flowPlace(flow))
+	}
+
+	addSilent := func(id string, inputs, outputs []string) {
+		net.Transitions = append(net.Transitions, &Transition{ID: id, Inputs: inputs, Outputs: outputs})
+	}
+
+	for _, id := range order {
+		node := nodes[id]
+		in := flowPlaces(node.incoming)
+		out := flowPlaces(node.outgoing)
+
+		switch {
+		case node.kind == "":
+			return nil, fmt.Errorf("поток ссылается на неизвестный элемент %q", id)
+		case bpmnTaskTypes[node.kind]:
+			label := node.name
+			if label == "" {
+				label = node.id
+			}
+			inputs := in
+			if len(in) > 1 {
+				// Несколько входящих потоков у задачи — неявное исключающее слияние
+				join := "join:" + node.id
+				net.Places = append(net.Places, join)
+				for i, p := range in {
+					addSilent(fmt.Sprintf("%s:join:%d", node.id, i), []string{p}, []string{join})
+				}
+				inputs = []string{join}
+			}
+			net.Transitions = append(net.Transitions, &Transition{ID: node.id, Label: label, Inputs: inputs, Outputs: out})
+		case node.kind == "startEvent":
+			addSilent(node.id, []string{sourcePlace}, out)
+		case node.kind == "endEvent":
+			for i, p := range in {
+				addSilent(fmt.Sprintf("%s:%d", node.id, i), []string{p}, []string{sinkPlace})
+			}
+		case node.kind == "parallelGateway":
+			addSilent(node.id, in, out)
+		default:
+			// Исключающие шлюзы и промежуточные события: любой вход может перейти в любой выход
+			for i, pIn := range in {
+				for j, pOut := range out {
+					addSilent(fmt.Sprintf("%s:%d:%d", node.id, i, j), []string{pIn}, []string{pOut})
+				}
+			}
+		}
+	}
+
+	net.index()
+	return net, nil
+}
+
+func getBPMNNode(nodes map[string]*bpmnNode, order *[]string, id string) *bpmnNode {
+	node := nodes[id]
+	if node == nil {
+		node = &bpmnNode{id: id}
+		nodes[id] = node
+		*order = append(*order, id)
+	}
+	return node
+}
+
+func flowPlace(flow string) string {
+	return "flow:" + flow
+}
+
+func flowPlaces(flows []string) []string {
+	places := make([]string, len(flows))
+	for i, flow := range flows {
+		places[i] = flowPlace(flow)
+	}
+	return places
+}
@@ -0,0 +1,132 @@
+package conformance
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Transition — переход сети Петри. Переход с пустой меткой считается невидимым (silent).
+type Transition struct {
+	ID      string   `json:"id"`
+	Label   string   `json:"label"`
+	Inputs  []string `json:"inputs"`
+	Outputs []string `json:"outputs"`
+}
+
+// Silent сообщает, является ли переход невидимым (не соответствует активности лога).
+func (t *Transition) Silent() bool {
+	return t.Label == ""
+}
+
+// Marking — разметка сети: количество токенов в каждой позиции.
+type Marking map[string]int
+
+// Net — эталонная модель процесса в виде сети Петри.
+type Net struct {
+	Places         []string      `json:"places"`
+	Transitions    []*Transition `json:"transitions"`
+	InitialMarking Marking       `json:"initial_marking"`
+	FinalMarking   Marking       `json:"final_marking"`
+
+	byLabel map[string][]*Transition
+	silent  []*Transition
+}
+
+// index строит вспомогательные индексы переходов; вызывается после заполнения сети.
+func (n *Net) index() {
+	n.byLabel = make(map[string][]*Transition)
+	n.silent = nil
+	for _, t := range n.Transitions {
+		if t.Silent() {
+			n.silent = append(n.silent, t)
+			continue
+		}
+		n.byLabel[t.Label] = append(n.byLabel[t.Label], t)
+	}
+}
+
+// Activities возвращает отсортированный список видимых активностей модели.
+func (n *Net) Activities() []string {
+	activities := make([]string, 0, len(n.byLabel))
+	for label := range n.byLabel {
+		activities = append(activities, label)
+	}
+	sort.Strings(activities)
+	return activities
+}
+
+func (m Marking) clone() Marking {
+	c := make(Marking, len(m))
+	for p, n := range m {
+		if n > 0 {
+			c[p] = n
+		}
+	}
+	return c
+}
+
+// key возвращает каноническое строковое представление разметки.
+func (m Marking) key() string {
+	places := make([]string, 0, len(m))
+	for p, n := range m {
+		if n > 0 {
+			places = append(places, p)
+		}
+	}
+	sort.Strings(places)
+
+	var sb strings.Builder
+	for _, p := range places {
+		sb.WriteString(p)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Itoa(m[p]))
+		sb.WriteByte(';')
+	}
+	return sb.String()
+}
+
+func (m Marking) enables(t *Transition) bool {
+	need := make(map[string]int, len(t.Inputs))
+	for _, p := range t.Inputs {
+		need[p]++
+	}
+	for p, n := range need {
+		if m[p] < n {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Marking) covers(other Marking) bool {
+	for p, n := range other {
+		if m[p] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// fire срабатывает переход, не проверяя его активность; возвращает новую разметку.
+func (m Marking) fire(t *Transition) Marking {
+	next := m.clone()
+	for _, p := range t.Inputs {
+		next[p]--
+		if next[p] <= 0 {
+			delete(next, p)
+		}
+	}
+	for _, p := range t.Outputs {
+		next[p]++
+	}
+	return next
+}
+
+func (m Marking) total() int {
+	var total int
+	for _, n := range m {
+		total += n
+	}
+	return total
+}
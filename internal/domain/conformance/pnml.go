@@ -0,0 +1,129 @@
+package conformance
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type pnmlText struct {
+	Text string `xml:"text"`
+}
+
+type pnmlPlace struct {
+	ID             string   `xml:"id,attr"`
+	InitialMarking pnmlText `xml:"initialMarking"`
+}
+
+type pnmlTransition struct {
+	ID           string   `xml:"id,attr"`
+	Name         pnmlText `xml:"name"`
+	ToolSpecific []struct {
+		Activity string `xml:"activity,attr"`
+	} `xml:"toolspecific"`
+}
+
+type pnmlArc struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// pnmlDocument допускает элементы как внутри <page>, так и непосредственно в <net>.
+type pnmlDocument struct {
+	Places          []pnmlPlace      `xml:"net>place"`
+	PagePlaces      []pnmlPlace      `xml:"net>page>place"`
+	Transitions     []pnmlTransition `xml:"net>transition"`
+	PageTransitions []pnmlTransition `xml:"net>page>transition"`
+	Arcs            []pnmlArc        `xml:"net>arc"`
+	PageArcs        []pnmlArc        `xml:"net>page>arc"`
+	FinalMarkings   []struct {
+		Places []struct {
+			IDRef string `xml:"idref,attr"`
+			Text  string `xml:"text"`
+		} `xml:"marking>place"`
+	} `xml:"net>finalmarkings"`
+}
+
+// ParsePNML разбирает сеть Петри в формате PNML (в том числе экспорт ProM/PM4Py).
+// Переходы без имени, с именем "tau…" или помеченные как $invisible$ считаются невидимыми.
+// Если конечная разметка не задана, ею считаются позиции без исходящих дуг.
+func ParsePNML(r io.Reader) (*Net, error) {
+	var doc pnmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("ошибка разбора PNML: %w", err)
+	}
+	doc.Places = append(doc.Places, doc.PagePlaces...)
+	doc.Transitions = append(doc.Transitions, doc.PageTransitions...)
+	doc.Arcs = append(doc.Arcs, doc.PageArcs...)
+	if len(doc.Transitions) == 0 {
+		return nil, errors.New("модель PNML не содержит переходов")
+	}
+
+	net := &Net{
+		InitialMarking: Marking{},
+		FinalMarking:   Marking{},
+	}
+
+	places := make(map[string]bool, len(doc.Places))
+	for _, p := range doc.Places {
+		places[p.ID] = true
+		net.Places = append(net.Places, p.ID)
+		if tokens, err := strconv.Atoi(strings.TrimSpace(p.InitialMarking.Text)); err == nil && tokens > 0 {
+			net.InitialMarking[p.ID] = tokens
+		}
+	}
+
+	transitions := make(map[string]*Transition, len(doc.Transitions))
+	for _, t := range doc.Transitions {
+		label := strings.TrimSpace(t.Name.Text)
+		for _, ts := range t.ToolSpecific {
+			if ts.Activity == "$invisible$" {
+				label = ""
+			}
+		}
+		if strings.HasPrefix(strings.ToLower(label), "tau") {
+			label = ""
+		}
+		transition := &Transition{ID: t.ID, Label: label}
+		transitions[t.ID] = transition
+		net.Transitions = append(net.Transitions, transition)
+	}
+
+	hasOutgoing := make(map[string]bool)
+	for _, arc := range doc.Arcs {
+		switch {
+		case places[arc.Source] && transitions[arc.Target] != nil:
+			transitions[arc.Target].Inputs = append(transitions[arc.Target].Inputs, arc.Source)
+			hasOutgoing[arc.Source] = true
+		case transitions[arc.Source] != nil && places[arc.Target]:
+			transitions[arc.Source].Outputs = append(transitions[arc.Source].Outputs, arc.Target)
+		default:
+			return nil, fmt.Errorf("дуга %s → %s должна соединять позицию и переход", arc.Source, arc.Target)
+		}
+	}
+
+	for _, fm := range doc.FinalMarkings {
+		for _, p := range fm.Places {
+			if tokens, err := strconv.Atoi(strings.TrimSpace(p.Text)); err == nil && tokens > 0 {
+				net.FinalMarking[p.IDRef] = tokens
+			}
+		}
+	}
+	if len(net.FinalMarking) == 0 {
+		for _, p := range net.Places {
+			if !hasOutgoing[p] {
+				net.FinalMarking[p] = 1
+			}
+		}
+	}
+
+	if len(net.InitialMarking) == 0 {
+		return nil, errors.New("модель PNML не содержит начальной разметки")
+	}
+
+	net.index()
+	return net, nil
+}
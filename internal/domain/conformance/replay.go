@@ -0,0 +1,306 @@
+package conformance
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSilentStates ограничивает перебор разметок, достижимых через невидимые переходы.
+const maxSilentStates = 256
+
+// Trace — последовательность активностей одного кейса.
+type Trace struct {
+	CaseID     string
+	Activities []string
+}
+
+// CaseDeviation описывает отклонение кейса от эталонной модели.
+type CaseDeviation struct {
+	CaseID            string   `json:"case_id"`
+	Fitness           float64  `json:"fitness"`
+	MissingTokens     int      `json:"missing_tokens"`
+	RemainingTokens   int      `json:"remaining_tokens"`
+	UnknownActivities []string `json:"unknown_activities,omitempty"` // активности, отсутствующие в модели
+	FailedActivities  []string `json:"failed_activities,omitempty"`  // активности, выполненные без разрешения модели
+}
+
+// ReplayResult содержит результат проверки соответствия методом воспроизведения токенов.
+type ReplayResult struct {
+	Fitness           float64         `json:"fitness"`
+	Precision         float64         `json:"precision"`
+	TotalCases        int             `json:"total_cases"`
+	FittingCases      int             `json:"fitting_cases"`
+	DeviatingCases    []CaseDeviation `json:"deviating_cases"`
+	MissingActivities []string        `json:"missing_activities"` // есть в модели, но не встречаются в логе
+	ExtraActivities   []string        `json:"extra_activities"`   // встречаются в логе, но отсутствуют в модели
+}
+
+type replayCounters struct {
+	produced, consumed, missing, remaining int
+	unknown, failed                        []string
+}
+
+// prefixState накапливает для префикса трассы разрешённые моделью и наблюдаемые в логе продолжения.
+type prefixState struct {
+	weight   int
+	enabled  map[string]struct{}
+	observed map[string]struct{}
+}
+
+// Replay воспроизводит трассы на сети Петри и вычисляет fitness (по токенам) и precision
+// (по «убегающим» переходам: доля разрешённых моделью продолжений, встречающихся в логе).
+// Одинаковые варианты воспроизводятся один раз.
+func Replay(net *Net, traces []Trace) *ReplayResult {
+	result := &ReplayResult{
+		TotalCases:        len(traces),
+		DeviatingCases:    []CaseDeviation{},
+		MissingActivities: []string{},
+		ExtraActivities:   []string{},
+	}
+
+	variants := make(map[string][]Trace)
+	logActivities := make(map[string]struct{})
+	for _, trace := range traces {
+		key := strings.Join(trace.Activities, "\x00")
+		variants[key] = append(variants[key], trace)
+		for _, activity := range trace.Activities {
+			logActivities[activity] = struct{}{}
+		}
+	}
+
+	prefixes := make(map[string]*prefixState)
+	var total replayCounters
+	for _, cases := range variants {
+		counters := net.replayTrace(cases[0].Activities, len(cases), prefixes)
+		fitness := traceFitness(counters)
+
+		total.produced += counters.produced * len(cases)
+		total.consumed += counters.consumed * len(cases)
+		total.missing += counters.missing * len(cases)
+		total.remaining += counters.remaining * len(cases)
+
+		if counters.missing == 0 && counters.remaining == 0 && len(counters.unknown) == 0 {
+			result.FittingCases += len(cases)
+			continue
+		}
+		for _, trace := range cases {
+			result.DeviatingCases = append(result.DeviatingCases, CaseDeviation{
+				CaseID:            trace.CaseID,
+				Fitness:           fitness,
+				MissingTokens:     counters.missing,
+				RemainingTokens:   counters.remaining,
+				UnknownActivities: counters.unknown,
+				FailedActivities:  counters.failed,
+			})
+		}
+	}
+
+	sort.Slice(result.DeviatingCases, func(i, j int) bool {
+		if result.DeviatingCases[i].Fitness != result.DeviatingCases[j].Fitness {
+			return result.DeviatingCases[i].Fitness < result.DeviatingCases[j].Fitness
+		}
+		return result.DeviatingCases[i].CaseID < result.DeviatingCases[j].CaseID
+	})
+
+	if len(traces) > 0 {
+		result.Fitness = traceFitness(total)
+	}
+	result.Precision = precision(prefixes)
+
+	for _, activity := range net.Activities() {
+		if _, ok := logActivities[activity]; !ok {
+			result.MissingActivities = append(result.MissingActivities, activity)
+		}
+	}
+	for activity := range logActivities {
+		if _, ok := net.byLabel[activity]; !ok {
+			result.ExtraActivities = append(result.ExtraActivities, activity)
+		}
+	}
+	sort.Strings(result.ExtraActivities)
+
+	return result
+}
+
+// replayTrace воспроизводит одну трассу и регистрирует её префиксы для расчёта precision.
+func (n *Net) replayTrace(activities []string, weight int, prefixes map[string]*prefixState) replayCounters {
+	var c replayCounters
+	marking := n.InitialMarking.clone()
+	c.produced = marking.total()
+	fitting := true
+
+	for i, activity := range activities {
+		if fitting {
+			key := strings.Join(activities[:i], "\x00")
+			state := prefixes[key]
+			if state == nil {
+				state = &prefixState{enabled: n.enabledLabels(marking), observed: make(map[string]struct{})}
+				prefixes[key] = state
+			}
+			state.weight += weight
+			state.observed[activity] = struct{}{}
+		}
+
+		candidates := n.byLabel[activity]
+		if len(candidates) == 0 {
+			c.unknown = append(c.unknown, activity)
+			fitting = false
+			continue
+		}
+
+		var chosen *Transition
+		for _, t := range candidates {
+			if marking.enables(t) {
+				chosen = t
+				break
+			}
+		}
+		if chosen == nil {
+			reached, produced, consumed, ok := n.silentClosure(marking, func(m Marking) bool {
+				for _, t := range candidates {
+					if m.enables(t) {
+						return true
+					}
+				}
+				return false
+			})
+			if ok {
+				marking = reached
+				c.produced += produced
+				c.consumed += consumed
+				for _, t := range candidates {
+					if marking.enables(t) {
+						chosen = t
+						break
+					}
+				}
+			}
+		}
+		if chosen == nil {
+			// Принудительное срабатывание: недостающие токены считаются пропущенными
+			chosen = candidates[0]
+			c.failed = append(c.failed, activity)
+			fitting = false
+			for _, p := range chosen.Inputs {
+				if marking[p] == 0 {
+					c.missing++
+					marking[p]++
+				}
+			}
+		}
+
+		marking = marking.fire(chosen)
+		c.consumed += len(chosen.Inputs)
+		c.produced += len(chosen.Outputs)
+	}
+
+	if reached, produced, consumed, ok := n.silentClosure(marking, func(m Marking) bool {
+		return m.covers(n.FinalMarking)
+	}); ok {
+		marking = reached
+		c.produced += produced
+		c.consumed += consumed
+	}
+
+	for p, tokens := range n.FinalMarking {
+		if marking[p] < tokens {
+			c.missing += tokens - marking[p]
+			marking[p] = tokens
+		}
+		marking[p] -= tokens
+		c.consumed += tokens
+	}
+	c.remaining = marking.total()
+
+	return c
+}
+
+// silentClosure ищет в ширину разметку, достижимую только невидимыми переходами и удовлетворяющую goal.
+func (n *Net) silentClosure(start Marking, goal func(Marking) bool) (Marking, int, int, bool) {
+	type node struct {
+		marking            Marking
+		produced, consumed int
+	}
+
+	if goal(start) {
+		return start, 0, 0, true
+	}
+
+	visited := map[string]bool{start.key(): true}
+	queue := []node{{marking: start}}
+	for len(queue) > 0 && len(visited) < maxSilentStates {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, t := range n.silent {
+			if !current.marking.enables(t) {
+				continue
+			}
+			next := node{
+				marking:  current.marking.fire(t),
+				produced: current.produced + len(t.Outputs),
+				consumed: current.consumed + len(t.Inputs),
+			}
+			key := next.marking.key()
+			if visited[key] {
+				continue
+			}
+			if goal(next.marking) {
+				return next.marking, next.produced, next.consumed, true
+			}
+			visited[key] = true
+			queue = append(queue, next)
+		}
+	}
+
+	return start, 0, 0, false
+}
+
+// enabledLabels возвращает активности, которые модель разрешает выполнить из разметки
+// (с учётом невидимых переходов).
+func (n *Net) enabledLabels(start Marking) map[string]struct{} {
+	labels := make(map[string]struct{})
+	n.silentClosure(start, func(m Marking) bool {
+		for label, transitions := range n.byLabel {
+			for _, t := range transitions {
+				if m.enables(t) {
+					labels[label] = struct{}{}
+				}
+			}
+		}
+		return false
+	})
+	return labels
+}
+
+func traceFitness(c replayCounters) float64 {
+	fitness := 1.0
+	if c.consumed > 0 {
+		fitness -= 0.5 * float64(c.missing) / float64(c.consumed)
+	}
+	if c.produced > 0 {
+		fitness -= 0.5 * float64(c.remaining) / float64(c.produced)
+	}
+	return fitness
+}
+
+func precision(prefixes map[string]*prefixState) float64 {
+	var allowed, used float64
+	for _, state := range prefixes {
+		if len(state.enabled) == 0 {
+			continue
+		}
+		var observedEnabled int
+		for label := range state.observed {
+			if _, ok := state.enabled[label]; ok {
+				observedEnabled++
+			}
+		}
+		allowed += float64(state.weight * len(state.enabled))
+		used += float64(state.weight * observedEnabled)
+	}
+	if allowed == 0 {
+		return 1.0
+	}
+	return used / allowed
+}
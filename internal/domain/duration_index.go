@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// DurationIndex возвращает отсортированные длительности этапов и кейсов построителя для анализа
+// (см. metrics.Analyzer.AnalyzeIndexed). Индекс строится один раз после загрузки событий, при первом
+// обращении, и сохраняется до следующего изменения данных; номера кейсов в нём — номера кейсов хранилища.
+func (gb *GraphBuilder) DurationIndex() *metrics.DurationIndex {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	return gb.durationIndexLocked()
+}
+
+// durationIndexLocked — DurationIndex под блокировкой записи gb.mu.
+func (gb *GraphBuilder) durationIndexLocked() *metrics.DurationIndex {
+	if gb.durations == nil {
+		gb.durations = gb.events.durationIndex()
+	}
+	return gb.durations
+}
+
+// durationIndex собирает длительности этапов и кейсов за один проход по столбцам времени.
+// Этапы отбираются так же, как при проходе анализатора по кейсам: пропускаются этапы
+// с нулевым временем и с обратным порядком событий.
+func (s *eventStore) durationIndex() *metrics.DurationIndex {
+	index := metrics.NewDurationIndex()
+	for c, rows := range s.caseRows {
+		if len(rows) < 2 {
+			continue
+		}
+		index.AddCase(int32(c), s.endTime(rows[len(rows)-1]).Sub(s.endTime(rows[0])).Seconds())
+		for i := 1; i < len(rows); i++ {
+			prev, curr := s.timestamp[rows[i-1]], s.timestamp[rows[i]]
+			if prev == noTime || curr == noTime || curr < prev {
+				continue
+			}
+			index.AddStage(int32(c), s.activity[rows[i-1]], s.activity[rows[i]], time.Duration(curr-prev).Seconds())
+		}
+	}
+	index.Sort()
+	return index
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"time"
+)
+
+// WriteEventsCSV записывает события в CSV-лог, который распознаётся при загрузке: case_id, timestamp
+// и activity, а также start_timestamp, result, resource и дополнительные атрибуты, если они заданы
+// хотя бы у одного события. Кейсы упорядочиваются по идентификатору, события внутри кейса сохраняют порядок.
+func WriteEventsCSV(w io.Writer, events []Event) error {
+	var hasStart, hasResult, hasResource bool
+	attributeSet := make(map[string]bool)
+	for _, event := range events {
+		hasStart = hasStart || !event.Start.IsZero()
+		hasResult = hasResult || event.Result != ""
+		hasResource = hasResource || event.Resource != ""
+		for name := range event.Attributes {
+			attributeSet[name] = true
+		}
+	}
+	attributes := make([]string, 0, len(attributeSet))
+	for name := range attributeSet {
+		attributes = append(attributes, name)
+	}
+	sort.Strings(attributes)
+
+	header := []string{"case_id", "timestamp", "activity"}
+	if hasStart {
+		header = append(header, "start_timestamp")
+	}
+	if hasResult {
+		header = append(header, "result")
+	}
+	if hasResource {
+		header = append(header, "resource")
+	}
+	header = append(header, attributes...)
+
+	sorted := append([]Event{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SessionID < sorted[j].SessionID })
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, event := range sorted {
+		record := []string{event.SessionID, event.Timestamp.Format(time.RFC3339Nano), event.Desc}
+		if hasStart {
+			start := ""
+			if !event.Start.IsZero() {
+				start = event.Start.Format(time.RFC3339Nano)
+			}
+			record = append(record, start)
+		}
+		if hasResult {
+			record = append(record, event.Result)
+		}
+		if hasResource {
+			record = append(record, event.Resource)
+		}
+		for _, name := range attributes {
+			record = append(record, event.Attributes[name])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,291 @@
+package domain
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// noTime — значение столбцов времени для нулевого времени (например, неизвестного начала обработки).
+const noTime = math.MinInt64
+
+// eventStore хранит события набора данных по столбцам: у события есть номер кейса, номер активности,
+// время завершения и начала (наносекунды Unix и номер часового пояса), номера результата, исполнителя
+// и идентификатора события в словаре значений. Строки событий кейса перечислены в caseRows в порядке
+// добавления. По сравнению с отдельной структурой на событие столбцы занимают в несколько раз меньше
+// памяти, а проходы по времени событий идут по непрерывным массивам чисел.
+type eventStore struct {
+	labels      *metrics.Labels // справочник активностей
+	caseNumbers map[string]int32
+	caseIDs     []string  // номер кейса → идентификатор
+	caseRows    [][]int32 // номер кейса → строки его событий
+
+	caseIndex  []int32
+	activity   []metrics.ActivityID
+	timestamp  []int64 // время завершения, нс Unix (noTime — нулевое)
+	start      []int64 // время начала обработки, нс Unix (noTime — неизвестно)
+	endZone    []uint16
+	startZone  []uint16
+	result     []int32
+	resource   []int32
+	eventID    []int32
+	attributes []map[string]string // nil, пока ни у одного события нет атрибутов
+
+	values      valueDict
+	zoneNumbers map[zoneKey]uint16
+	zones       []*time.Location // номер часового пояса → пояс; 0 — UTC
+}
+
+// zoneKey — часовой пояс времени: локальный пояс процесса или фиксированное смещение с названием.
+type zoneKey struct {
+	local  bool
+	name   string
+	offset int
+}
+
+// valueDict — словарь строковых значений столбцов: значение хранится один раз, событие — его номер.
+type valueDict struct {
+	numbers map[string]int32
+	values  []string
+}
+
+func newEventStore() *eventStore {
+	return &eventStore{
+		labels:      metrics.NewLabels(),
+		caseNumbers: make(map[string]int32),
+		values:      valueDict{numbers: map[string]int32{"": 0}, values: []string{""}},
+		zoneNumbers: make(map[zoneKey]uint16),
+		zones:       []*time.Location{time.UTC},
+	}
+}
+
+// number возвращает номер значения, добавляя его в словарь. Новое значение копируется, чтобы
+// не удерживать в памяти строку, из которой оно вырезано.
+func (d *valueDict) number(value string) int32 {
+	if n, ok := d.numbers[value]; ok {
+		return n
+	}
+	value = strings.Clone(value)
+	n := int32(len(d.values))
+	d.numbers[value] = n
+	d.values = append(d.values, value)
+	return n
+}
+
+// cases возвращает число кейсов.
+func (s *eventStore) cases() int {
+	return len(s.caseIDs)
+}
+
+// rows возвращает число событий.
+func (s *eventStore) rows() int {
+	return len(s.caseIndex)
+}
+
+// caseOf возвращает номер кейса id.
+func (s *eventStore) caseOf(id string) (int32, bool) {
+	c, ok := s.caseNumbers[id]
+	return c, ok
+}
+
+// append добавляет событие в конец кейса event.SessionID и возвращает номер его строки.
+// Активность события должна быть занесена в справочник labels (см. GraphBuilder.intern).
+func (s *eventStore) append(event *Event) int32 {
+	row := s.add(event)
+	c := s.caseIndex[row]
+	s.caseRows[c] = append(s.caseRows[c], row)
+	return row
+}
+
+// prepend добавляет события events в начало кейса id (в том же порядке).
+func (s *eventStore) prepend(id string, events []Event) {
+	rows := make([]int32, len(events), len(events)+len(s.caseRows[s.caseNumber(id)]))
+	for i := range events {
+		rows[i] = s.add(&events[i])
+	}
+	c := s.caseNumber(id)
+	s.caseRows[c] = append(rows, s.caseRows[c]...)
+}
+
+// add записывает событие в столбцы, не добавляя его строку в список событий кейса.
+func (s *eventStore) add(event *Event) int32 {
+	row := int32(len(s.caseIndex))
+	s.caseIndex = append(s.caseIndex, s.caseNumber(event.SessionID))
+	s.activity = append(s.activity, event.Activity)
+	end, endZone := s.encodeTime(event.Timestamp)
+	start, startZone := s.encodeTime(event.Start)
+	s.timestamp = append(s.timestamp, end)
+	s.endZone = append(s.endZone, endZone)
+	s.start = append(s.start, start)
+	s.startZone = append(s.startZone, startZone)
+	s.result = append(s.result, s.values.number(event.Result))
+	s.resource = append(s.resource, s.values.number(event.Resource))
+	s.eventID = append(s.eventID, s.values.number(event.ID))
+	if len(event.Attributes) > 0 && s.attributes == nil {
+		s.attributes = make([]map[string]string, row, cap(s.caseIndex))
+	}
+	if s.attributes != nil {
+		s.attributes = append(s.attributes, event.Attributes)
+	}
+	return row
+}
+
+// caseNumber возвращает номер кейса id, добавляя кейс, если его ещё нет.
+func (s *eventStore) caseNumber(id string) int32 {
+	if c, ok := s.caseNumbers[id]; ok {
+		return c
+	}
+	id = strings.Clone(id)
+	c := int32(len(s.caseIDs))
+	s.caseNumbers[id] = c
+	s.caseIDs = append(s.caseIDs, id)
+	s.caseRows = append(s.caseRows, nil)
+	return c
+}
+
+// setTimestamp меняет время завершения события в строке row.
+func (s *eventStore) setTimestamp(row int32, t time.Time) {
+	s.timestamp[row], s.endZone[row] = s.encodeTime(t)
+}
+
+// encodeTime переводит время в наносекунды Unix и номер часового пояса.
+func (s *eventStore) encodeTime(t time.Time) (int64, uint16) {
+	if t.IsZero() {
+		return noTime, 0
+	}
+	loc := t.Location()
+	if loc == time.UTC {
+		return t.UnixNano(), 0
+	}
+	name, offset := t.Zone()
+	key := zoneKey{local: loc == time.Local, name: name, offset: offset}
+	zone, ok := s.zoneNumbers[key]
+	if !ok {
+		zone = uint16(len(s.zones))
+		location := time.Local
+		if !key.local {
+			location = time.FixedZone(name, offset)
+		}
+		s.zoneNumbers[key] = zone
+		s.zones = append(s.zones, location)
+	}
+	return t.UnixNano(), zone
+}
+
+// decodeTime восстанавливает время, записанное encodeTime.
+func (s *eventStore) decodeTime(nanos int64, zone uint16) time.Time {
+	if nanos == noTime {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).In(s.zones[zone])
+}
+
+// activityName возвращает название активности события в строке row.
+func (s *eventStore) activityName(row int32) string {
+	return s.labels.Name(s.activity[row])
+}
+
+// endTime возвращает время завершения события в строке row.
+func (s *eventStore) endTime(row int32) time.Time {
+	return s.decodeTime(s.timestamp[row], s.endZone[row])
+}
+
+// event возвращает событие строки row.
+func (s *eventStore) event(row int32) Event {
+	event := Event{
+		ID:        s.values.values[s.eventID[row]],
+		SessionID: s.caseIDs[s.caseIndex[row]],
+		Timestamp: s.decodeTime(s.timestamp[row], s.endZone[row]),
+		Start:     s.decodeTime(s.start[row], s.startZone[row]),
+		Desc:      s.labels.Name(s.activity[row]),
+		Activity:  s.activity[row],
+		Result:    s.values.values[s.result[row]],
+		Resource:  s.values.values[s.resource[row]],
+	}
+	if s.attributes != nil {
+		event.Attributes = s.attributes[row]
+	}
+	return event
+}
+
+// metricsEvent возвращает событие строки row в представлении анализатора метрик.
+func (s *eventStore) metricsEvent(row int32) metrics.Event {
+	event := metrics.Event{
+		SessionID:   s.caseIDs[s.caseIndex[row]],
+		Timestamp:   s.decodeTime(s.timestamp[row], s.endZone[row]),
+		Start:       s.decodeTime(s.start[row], s.startZone[row]),
+		Description: s.labels.Name(s.activity[row]),
+		Activity:    s.activity[row],
+		Result:      s.values.values[s.result[row]],
+		Resource:    s.values.values[s.resource[row]],
+	}
+	if s.attributes != nil {
+		event.Attributes = s.attributes[row]
+	}
+	return event
+}
+
+// removeCases удаляет кейсы removed и переписывает столбцы без их событий; события оставшихся
+// кейсов при этом располагаются подряд. Возвращает новые номера строк (-1 — событие удалено).
+func (s *eventStore) removeCases(removed map[string]struct{}) []int32 {
+	compacted := &eventStore{
+		labels:      s.labels,
+		caseNumbers: make(map[string]int32, len(s.caseIDs)-len(removed)),
+		values:      s.values,
+		zoneNumbers: s.zoneNumbers,
+		zones:       s.zones,
+	}
+	kept := s.rows()
+	for id := range removed {
+		if c, ok := s.caseNumbers[id]; ok {
+			kept -= len(s.caseRows[c])
+		}
+	}
+	compacted.caseIndex = make([]int32, 0, kept)
+	compacted.activity = make([]metrics.ActivityID, 0, kept)
+	compacted.timestamp = make([]int64, 0, kept)
+	compacted.start = make([]int64, 0, kept)
+	compacted.endZone = make([]uint16, 0, kept)
+	compacted.startZone = make([]uint16, 0, kept)
+	compacted.result = make([]int32, 0, kept)
+	compacted.resource = make([]int32, 0, kept)
+	compacted.eventID = make([]int32, 0, kept)
+	if s.attributes != nil {
+		compacted.attributes = make([]map[string]string, 0, kept)
+	}
+
+	moved := make([]int32, s.rows())
+	for i := range moved {
+		moved[i] = -1
+	}
+	for c, id := range s.caseIDs {
+		if _, ok := removed[id]; ok {
+			continue
+		}
+		number := int32(len(compacted.caseIDs))
+		compacted.caseNumbers[id] = number
+		compacted.caseIDs = append(compacted.caseIDs, id)
+		rows := make([]int32, len(s.caseRows[c]))
+		for i, row := range s.caseRows[c] {
+			rows[i] = int32(len(compacted.caseIndex))
+			moved[row] = rows[i]
+			compacted.caseIndex = append(compacted.caseIndex, number)
+			compacted.activity = append(compacted.activity, s.activity[row])
+			compacted.timestamp = append(compacted.timestamp, s.timestamp[row])
+			compacted.start = append(compacted.start, s.start[row])
+			compacted.endZone = append(compacted.endZone, s.endZone[row])
+			compacted.startZone = append(compacted.startZone, s.startZone[row])
+			compacted.result = append(compacted.result, s.result[row])
+			compacted.resource = append(compacted.resource, s.resource[row])
+			compacted.eventID = append(compacted.eventID, s.eventID[row])
+			if s.attributes != nil {
+				compacted.attributes = append(compacted.attributes, s.attributes[row])
+			}
+		}
+		compacted.caseRows = append(compacted.caseRows, rows)
+	}
+	*s = *compacted
+	return moved
+}
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFormats — форматы выгрузки графа процесса.
+var ExportFormats = []string{"dot", "graphml", "bpmn", "json"}
+
+// ExportGraph записывает граф в w в формате format (см. ExportFormats). Узлы и рёбра упорядочиваются
+// по идентификаторам, чтобы выгрузка одного и того же лога не менялась от запуска к запуску.
+func ExportGraph(w io.Writer, graph *Graph, format string) error {
+	sorted := sortedGraph(graph)
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case "dot":
+		writeDOT(bw, sorted)
+	case "graphml":
+		writeGraphML(bw, sorted)
+	case "bpmn":
+		writeBPMN(bw, sorted)
+	case "json":
+		encoder := json.NewEncoder(bw)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(sorted)
+	default:
+		return fmt.Errorf("неизвестный формат %q: поддерживаются %s", format, strings.Join(ExportFormats, ", "))
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// sortedGraph возвращает копию графа с узлами, упорядоченными по ID, и рёбрами — по началу и концу.
+func sortedGraph(graph *Graph) *Graph {
+	sorted := &Graph{
+		Nodes: append([]*Node{}, graph.Nodes...),
+		Edges: append([]*Edge{}, graph.Edges...),
+	}
+	sort.Slice(sorted.Nodes, func(i, j int) bool { return sorted.Nodes[i].ID < sorted.Nodes[j].ID })
+	sort.Slice(sorted.Edges, func(i, j int) bool {
+		a, b := sorted.Edges[i], sorted.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return sorted
+}
+
+// writeDOT записывает граф в формате Graphviz DOT в том же оформлении, что и выгрузка DOT в браузере.
+func writeDOT(w *bufio.Writer, graph *Graph) {
+	w.WriteString("digraph G {\n")
+	w.WriteString("  rankdir=LR;\n")
+	w.WriteString("  node [shape=rect style=filled];\n")
+	w.WriteString("  edge [fontsize=12];\n")
+	for _, node := range graph.Nodes {
+		color := node.Color
+		if color == "" {
+			color = "#add8e6"
+		}
+		fmt.Fprintf(w, "  %s [label=%s fillcolor=%s];\n",
+			strconv.Quote(node.ID), strconv.Quote(fmt.Sprintf("%s (%d)", node.Label, node.Count)), strconv.Quote(color))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "  %s -> %s ", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		label := strconv.Itoa(edge.Count)
+		switch {
+		case edge.Parallel:
+			// Параллельные активности рисуем одним ненаправленным ребром
+			fmt.Fprintf(w, "[label=%s dir=none style=dotted];\n", strconv.Quote(label+" ∥"))
+		case edge.HappyPath:
+			fmt.Fprintf(w, "[label=%s color=%s penwidth=3];\n", strconv.Quote(label), strconv.Quote(edge.Color))
+		case edge.Style != "":
+			fmt.Fprintf(w, "[label=%s style=%s];\n", strconv.Quote(label), edge.Style)
+		default:
+			fmt.Fprintf(w, "[label=%s];\n", strconv.Quote(label))
+		}
+	}
+	w.WriteString("}\n")
+}
+
+// writeGraphML записывает граф в формате GraphML с атрибутами узлов и рёбер.
+func writeGraphML(w *bufio.Writer, graph *Graph) {
+	w.WriteString(xml.Header)
+	w.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, key := range []struct{ id, domain, name, kind string }{
+		{"label", "node", "label", "string"},
+		{"count", "node", "count", "int"},
+		{"total", "node", "total", "int"},
+		{"color", "node", "color", "string"},
+		{"happy_path", "node", "happy_path", "boolean"},
+		{"subprocess", "node", "subprocess", "boolean"},
+		{"e_count", "edge", "count", "int"},
+		{"avg_duration", "edge", "avg_duration", "double"},
+		{"avg_waiting", "edge", "avg_waiting", "double"},
+		{"avg_processing", "edge", "avg_processing", "double"},
+		{"parallel", "edge", "parallel", "boolean"},
+		{"e_happy_path", "edge", "happy_path", "boolean"},
+	} {
+		fmt.Fprintf(w, `  <key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", key.id, key.domain, key.name, key.kind)
+	}
+	w.WriteString(`  <graph id="process" edgedefault="directed">` + "\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "    <node id=\"%s\">\n", xmlEscape(node.ID))
+		fmt.Fprintf(w, "      <data key=\"label\">%s</data>\n", xmlEscape(node.Label))
+		fmt.Fprintf(w, "      <data key=\"count\">%d</data>\n", node.Count)
+		fmt.Fprintf(w, "      <data key=\"total\">%d</data>\n", node.Total)
+		fmt.Fprintf(w, "      <data key=\"color\">%s</data>\n", xmlEscape(node.Color))
+		fmt.Fprintf(w, "      <data key=\"happy_path\">%t</data>\n", node.HappyPath)
+		fmt.Fprintf(w, "      <data key=\"subprocess\">%t</data>\n", node.Subprocess)
+		w.WriteString("    </node>\n")
+	}
+	for i, edge := range graph.Edges {
+		fmt.Fprintf(w, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i+1, xmlEscape(edge.From), xmlEscape(edge.To))
+		fmt.Fprintf(w, "      <data key=\"e_count\">%d</data>\n", edge.Count)
+		fmt.Fprintf(w, "      <data key=\"avg_duration\">%.2f</data>\n", edge.AvgDuration)
+		fmt.Fprintf(w, "      <data key=\"avg_waiting\">%.2f</data>\n", edge.AvgWaiting)
+		fmt.Fprintf(w, "      <data key=\"avg_processing\">%.2f</data>\n", edge.AvgProcessing)
+		fmt.Fprintf(w, "      <data key=\"parallel\">%t</data>\n", edge.Parallel)
+		fmt.Fprintf(w, "      <data key=\"e_happy_path\">%t</data>\n", edge.HappyPath)
+		w.WriteString("    </edge>\n")
+	}
+	w.WriteString("  </graph>\n</graphml>\n")
+}
+
+// writeBPMN записывает граф как процесс BPMN 2.0: узлы "start" и "end" становятся начальным
+// и конечным событиями, активности — задачами, переходы — потоками управления. Ветвления
+// и слияния графа обозначаются исключающими шлюзами: в BPMN несколько исходящих потоков задачи
+// означали бы параллельное выполнение. Результат можно загрузить обратно как эталонную модель.
+func writeBPMN(w *bufio.Writer, graph *Graph) {
+	ids := make(map[string]string, len(graph.Nodes))
+	outgoing := make(map[string][]*Edge)
+	incoming := make(map[string][]*Edge)
+	for _, edge := range graph.Edges {
+		outgoing[edge.From] = append(outgoing[edge.From], edge)
+		incoming[edge.To] = append(incoming[edge.To], edge)
+	}
+
+	w.WriteString(xml.Header)
+	w.WriteString(`<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL" id="definitions" targetNamespace="http://process-mining/bpmn">` + "\n")
+	w.WriteString(`  <process id="process" isExecutable="false">` + "\n")
+	for i, node := range graph.Nodes {
+		kind, id := "task", fmt.Sprintf("task_%d", i+1)
+		switch node.ID {
+		case "start":
+			kind, id = "startEvent", "start"
+		case "end":
+			kind, id = "endEvent", "end"
+		}
+		ids[node.ID] = id
+		fmt.Fprintf(w, "    <%s id=\"%s\" name=\"%s\"/>\n", kind, id, xmlEscape(node.Label))
+	}
+
+	// Шлюзы: ветвление после узла с несколькими исходящими переходами и слияние перед узлом
+	// с несколькими входящими. Поток от узла к переходу идёт через них.
+	flow := 0
+	writeFlow := func(from, to string) {
+		flow++
+		fmt.Fprintf(w, "    <sequenceFlow id=\"flow_%d\" sourceRef=\"%s\" targetRef=\"%s\"/>\n", flow, from, to)
+	}
+	for _, node := range graph.Nodes {
+		id := ids[node.ID]
+		if len(outgoing[node.ID]) > 1 {
+			fmt.Fprintf(w, "    <exclusiveGateway id=\"%s_split\"/>\n", id)
+			writeFlow(id, id+"_split")
+		}
+		if len(incoming[node.ID]) > 1 {
+			fmt.Fprintf(w, "    <exclusiveGateway id=\"%s_join\"/>\n", id)
+			writeFlow(id+"_join", id)
+		}
+	}
+	for _, edge := range graph.Edges {
+		from, to := ids[edge.From], ids[edge.To]
+		if len(outgoing[edge.From]) > 1 {
+			from += "_split"
+		}
+		if len(incoming[edge.To]) > 1 {
+			to += "_join"
+		}
+		writeFlow(from, to)
+	}
+	w.WriteString("  </process>\n</definitions>\n")
+}
+
+// xmlEscape экранирует текст для вставки в XML.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
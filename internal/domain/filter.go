@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// Области применения условий фильтра.
+const (
+	FilterScopeCase  = "case"  // условие отбирает кейсы целиком
+	FilterScopeEvent = "event" // условие отбирает отдельные события
+)
+
+var (
+	// ErrUnknownTimeScope возвращается для неизвестной области применения временного окна.
+	ErrUnknownTimeScope = errors.New("неизвестная область временного фильтра: ожидается case или event")
+	// ErrUnknownActivityScope возвращается для неизвестной области применения фильтра активностей.
+	ErrUnknownActivityScope = errors.New("неизвестная область фильтра активностей: ожидается case или event")
+)
+
+// LogFilter ограничивает анализ частью лога. Пустой фильтр оставляет лог без изменений.
+type LogFilter struct {
+	From      time.Time // начало окна (нулевое — без ограничения)
+	To        time.Time // конец окна, не включая (нулевое — без ограничения)
+	TimeScope string    // FilterScopeCase (по умолчанию) — кейсы, начавшиеся в окне; FilterScopeEvent — события в окне
+
+	IncludeActivities []string // оставить только эти активности
+	ExcludeActivities []string // убрать эти активности
+	// ActivityScope — FilterScopeEvent (по умолчанию): убираются сами события; FilterScopeCase: остаются
+	// кейсы, проходящие через одну из IncludeActivities, и убираются кейсы, проходящие через ExcludeActivities.
+	ActivityScope string
+
+	Variants         []string // оставить только кейсы этих вариантов (идентификаторы из рейтинга вариантов)
+	ExcludeHappyPath bool     // убрать кейсы самого частого варианта
+
+	// Attributes оставляет кейсы, у событий которых атрибут принимает одно из перечисленных значений
+	// (условия по разным атрибутам должны выполняться одновременно). Кроме столбцов лога, доступны
+	// атрибуты resource и result.
+	Attributes map[string][]string
+}
+
+// Empty сообщает, что фильтр ничего не отбрасывает.
+func (f LogFilter) Empty() bool {
+	return f.From.IsZero() && f.To.IsZero() && len(f.IncludeActivities) == 0 && len(f.ExcludeActivities) == 0 &&
+		len(f.Variants) == 0 && !f.ExcludeHappyPath && len(f.Attributes) == 0
+}
+
+// Validate проверяет параметры фильтра.
+func (f LogFilter) Validate() error {
+	switch f.TimeScope {
+	case "", FilterScopeCase, FilterScopeEvent:
+	default:
+		return ErrUnknownTimeScope
+	}
+	switch f.ActivityScope {
+	case "", FilterScopeCase, FilterScopeEvent:
+	default:
+		return ErrUnknownActivityScope
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return errors.New("начало временного окна должно быть раньше конца")
+	}
+	for name, values := range f.Attributes {
+		if name == "" {
+			return errors.New("не задано название атрибута")
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("не заданы значения атрибута %q", name)
+		}
+	}
+	return nil
+}
+
+// inWindow проверяет, что момент t попадает во временное окно фильтра.
+func (f LogFilter) inWindow(t time.Time) bool {
+	return (f.From.IsZero() || !t.Before(f.From)) && (f.To.IsZero() || t.Before(f.To))
+}
+
+// Apply возвращает события, прошедшие фильтр. Порядок событий внутри кейса сохраняется.
+func (f LogFilter) Apply(events []Event) []Event {
+	if f.Empty() {
+		return events
+	}
+
+	var order []string
+	cases := make(map[string][]Event)
+	for _, event := range events {
+		if _, ok := cases[event.SessionID]; !ok {
+			order = append(order, event.SessionID)
+		}
+		cases[event.SessionID] = append(cases[event.SessionID], event)
+	}
+
+	var filtered [][]Event
+	for _, id := range order {
+		if caseEvents := f.applyAttributes(f.applyActivities(f.applyTime(cases[id]))); len(caseEvents) > 0 {
+			filtered = append(filtered, caseEvents)
+		}
+	}
+	filtered = f.applyVariants(filtered)
+
+	var result []Event
+	for _, caseEvents := range filtered {
+		result = append(result, caseEvents...)
+	}
+	return result
+}
+
+// applyTime применяет временное окно к событиям одного кейса.
+func (f LogFilter) applyTime(events []Event) []Event {
+	if f.From.IsZero() && f.To.IsZero() {
+		return events
+	}
+	if f.TimeScope == FilterScopeEvent {
+		var inWindow []Event
+		for _, event := range events {
+			if f.inWindow(event.Timestamp) {
+				inWindow = append(inWindow, event)
+			}
+		}
+		return inWindow
+	}
+	if f.inWindow(caseStart(events)) {
+		return events
+	}
+	return nil
+}
+
+// applyActivities применяет фильтр активностей к событиям одного кейса.
+func (f LogFilter) applyActivities(events []Event) []Event {
+	include := stringSet(f.IncludeActivities)
+	exclude := stringSet(f.ExcludeActivities)
+	if include == nil && exclude == nil {
+		return events
+	}
+	if f.ActivityScope == FilterScopeCase {
+		if passesThrough(events, include, true) && !passesThrough(events, exclude, false) {
+			return events
+		}
+		return nil
+	}
+	var kept []Event
+	for _, event := range events {
+		if (include == nil || include[event.Desc]) && !exclude[event.Desc] {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// applyAttributes оставляет кейс, если его события (оставшиеся после фильтров времени и активностей)
+// удовлетворяют всем условиям на атрибуты.
+func (f LogFilter) applyAttributes(events []Event) []Event {
+	for name, values := range f.Attributes {
+		allowed := stringSet(values)
+		matched := false
+		for _, event := range events {
+			if allowed[eventAttribute(event, name)] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	}
+	return events
+}
+
+// eventAttribute возвращает значение атрибута события: ресурса, результата или дополнительного столбца лога.
+func eventAttribute(event Event, name string) string {
+	switch name {
+	case "resource":
+		return event.Resource
+	case "result":
+		return event.Result
+	}
+	return event.Attributes[name]
+}
+
+// applyVariants оставляет кейсы выбранных вариантов и убирает кейсы самого частого варианта,
+// если задан ExcludeHappyPath. Варианты определяются по уже отфильтрованным событиям кейсов.
+func (f LogFilter) applyVariants(cases [][]Event) [][]Event {
+	if len(f.Variants) == 0 && !f.ExcludeHappyPath {
+		return cases
+	}
+	ids := make([]string, len(cases))
+	counts := make(map[string]int)
+	for i, events := range cases {
+		activities := make([]string, len(events))
+		for j, event := range events {
+			activities[j] = event.Desc
+		}
+		ids[i] = metrics.VariantID(activities)
+		counts[ids[i]]++
+	}
+
+	// Самый частый вариант; при равенстве — с меньшим идентификатором, как в рейтинге вариантов
+	var happyPath string
+	for id, count := range counts {
+		if count > counts[happyPath] || (count == counts[happyPath] && id < happyPath) {
+			happyPath = id
+		}
+	}
+	selected := stringSet(f.Variants)
+
+	var kept [][]Event
+	for i, events := range cases {
+		if selected != nil && !selected[ids[i]] {
+			continue
+		}
+		if f.ExcludeHappyPath && ids[i] == happyPath {
+			continue
+		}
+		kept = append(kept, events)
+	}
+	return kept
+}
+
+// activitySet строит множество значений (активностей или вариантов); для пустого списка возвращает nil.
+func stringSet(activities []string) map[string]bool {
+	if len(activities) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(activities))
+	for _, activity := range activities {
+		set[activity] = true
+	}
+	return set
+}
+
+// passesThrough проверяет, что кейс содержит одну из активностей; для пустого множества возвращает empty.
+func passesThrough(events []Event, activities map[string]bool, empty bool) bool {
+	if activities == nil {
+		return empty
+	}
+	for _, event := range events {
+		if activities[event.Desc] {
+			return true
+		}
+	}
+	return false
+}
+
+// caseStart возвращает время начала кейса: самое раннее время начала или завершения его событий.
+func caseStart(events []Event) time.Time {
+	var start time.Time
+	for _, event := range events {
+		t := event.Timestamp
+		if !event.Start.IsZero() && event.Start.Before(t) {
+			t = event.Start
+		}
+		if start.IsZero() || t.Before(start) {
+			start = t
+		}
+	}
+	return start
+}
+
+// keepsCases сообщает, что фильтр оставляет или убирает кейсы целиком, не меняя их событий.
+func (f LogFilter) keepsCases() bool {
+	wholeTime := (f.From.IsZero() && f.To.IsZero()) || f.TimeScope != FilterScopeEvent
+	wholeActivities := (len(f.IncludeActivities) == 0 && len(f.ExcludeActivities) == 0) || f.ActivityScope == FilterScopeCase
+	return wholeTime && wholeActivities
+}
+
+// Filtered возвращает новый построитель с событиями, прошедшими фильтр, и построенным по ним графом.
+// Если фильтр оставляет кейсы целиком, индекс длительностей нового построителя выбирается из индекса
+// исходного, без повторного прохода по событиям и сортировки.
+func (gb *GraphBuilder) Filtered(filter LogFilter) *GraphBuilder {
+	filtered := NewGraphBuilder(gb.csvReader)
+	if !filter.keepsCases() {
+		filtered.LoadEvents(filter.Apply(gb.Events()))
+		return filtered
+	}
+
+	gb.mu.Lock()
+	durations := gb.durationIndexLocked()
+	events, caseIDs := gb.events.events(), gb.events.caseIDs
+	gb.mu.Unlock()
+
+	filtered.LoadEvents(filter.Apply(events))
+	filtered.durations = durations.Subset(func(c int32) (int32, bool) {
+		return filtered.events.caseOf(caseIDs[c])
+	})
+	return filtered
+}
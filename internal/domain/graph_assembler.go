@@ -0,0 +1,351 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// parallelDependencyThreshold — максимальная мера зависимости |A→B - B→A| / (A→B + B→A + 1),
+// при которой пара активностей, встречающихся в обоих порядках, считается параллельной.
+const parallelDependencyThreshold = 0.5
+
+// happyPathColor — цвет узлов и рёбер самого частого варианта процесса.
+const happyPathColor = "gold"
+
+// graphAssembler строит граф прямого следования (DFG) по набору сессий. Вклад каждой сессии
+// (шаги, переходы, циклы, вариант) накапливается в счётчиках: при изменении части сессий
+// достаточно вычесть их прежний вклад (remove) и добавить новый (add), после чего graph собирает
+// граф по счётчикам, не проходя заново по событиям остальных сессий.
+type graphAssembler struct {
+	sessions      int
+	nodeCounts    map[string]int
+	edgeStats     map[string]*edgeStats // переходы по ключу "A_B"
+	shortLoopMap  map[string]int        // количество циклов длины два (A→B→A) по ключу "A_B"
+	startCounts   map[string]int        // первый шаг → число сессий
+	endCounts     map[string]int        // последний шаг → число сессий
+	variantCounts map[string]int        // вариант (ключи шагов через \x00) → число сессий
+	variantKeys   map[string][]string   // вариант → ключи его шагов
+
+	// nodeKey возвращает ключ узла для активности события (nil — название активности).
+	nodeKey func(activity string) string
+	// collapse сообщает, нужно ли схлопывать подряд идущие события с одинаковым ключом
+	// в один шаг (используется для свёрнутых подпроцессов).
+	collapse func(key string) bool
+}
+
+// edgeStats — накопленные суммы по переходам между двумя шагами.
+type edgeStats struct {
+	from, to   string
+	count      int
+	duration   float64 // сек, сумма по всем переходам
+	waiting    float64
+	processing float64
+}
+
+// step — шаг сессии: одно событие или несколько подряд идущих событий одного свёрнутого узла.
+// Время — в наносекундах Unix, как в столбцах eventStore.
+type step struct {
+	key   string
+	start int64 // начало обработки первого события (или его завершение, если начало неизвестно)
+	end   int64 // завершение последнего события
+}
+
+func newGraphAssembler(nodeKey func(string) string, collapse func(string) bool) *graphAssembler {
+	return &graphAssembler{
+		nodeCounts:    make(map[string]int),
+		edgeStats:     make(map[string]*edgeStats),
+		shortLoopMap:  make(map[string]int),
+		startCounts:   make(map[string]int),
+		endCounts:     make(map[string]int),
+		variantCounts: make(map[string]int),
+		variantKeys:   make(map[string][]string),
+		nodeKey:       nodeKey,
+		collapse:      collapse,
+	}
+}
+
+func (ga *graphAssembler) assemble(events *eventStore) *Graph {
+	for c := range events.caseRows {
+		ga.add(events, int32(c))
+	}
+	return ga.graph()
+}
+
+// add добавляет вклад кейса c в счётчики.
+func (ga *graphAssembler) add(events *eventStore, c int32) {
+	ga.processSession(ga.steps(events, events.caseRows[c]), 1)
+}
+
+// remove вычитает вклад кейса c, добавленный ранее add. События кейса не должны меняться между этими вызовами.
+func (ga *graphAssembler) remove(events *eventStore, c int32) {
+	ga.processSession(ga.steps(events, events.caseRows[c]), -1)
+}
+
+// graph собирает граф по накопленным счётчикам. Каждый вызов возвращает новый граф.
+func (ga *graphAssembler) graph() *Graph {
+	graph := &Graph{}
+	nodeMap := make(map[string]*Node, len(ga.nodeCounts))
+	for key, count := range ga.nodeCounts {
+		nodeMap[key] = &Node{
+			ID:    key,
+			Label: key,
+			Count: count,
+			Total: count,
+			Color: "blue", // Устанавливаем значение по умолчанию
+		}
+	}
+	edgeMap := make(map[string]*Edge, len(ga.edgeStats))
+	for key, stats := range ga.edgeStats {
+		count := float64(stats.count)
+		edgeMap[key] = &Edge{
+			From:          stats.from,
+			To:            stats.to,
+			Count:         stats.count,
+			AvgDuration:   stats.duration / count,
+			AvgWaiting:    stats.waiting / count,
+			AvgProcessing: stats.processing / count,
+		}
+	}
+
+	ga.detectParallelism(edgeMap)
+
+	for _, node := range nodeMap {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, edge := range edgeMap {
+		edge.Label = fmt.Sprintf("%d\n%.2f sec avg", edge.Count, edge.AvgDuration)
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	// Добавляем специальные узлы "Начало" и "Конец"
+	startNode := &Node{
+		ID:    "start",
+		Label: "Начало процесса",
+		Count: ga.sessions,
+		Total: ga.sessions,
+		Color: "green", // Цвет для начального узла
+	}
+	graph.Nodes = append(graph.Nodes, startNode)
+
+	endNode := &Node{
+		ID:    "end",
+		Label: "Конец",
+		Count: ga.sessions,
+		Total: ga.sessions,
+		Color: "red", // Цвет для конечного узла
+	}
+	graph.Nodes = append(graph.Nodes, endNode)
+
+	// Связи "Начало" -> первый узел и последний узел -> "Конец" (пунктирные)
+	for key, count := range ga.startCounts {
+		startEdge := getEdge(edgeMap, "start_"+key, "start", key)
+		startEdge.Count += count
+		startEdge.Style = "dashed"
+		if startEdge.Count == count {
+			// Если это новая связь, добавляем ее в граф
+			graph.Edges = append(graph.Edges, startEdge)
+		}
+	}
+	for key, count := range ga.endCounts {
+		endEdge := getEdge(edgeMap, key+"_end", key, "end")
+		endEdge.Count += count
+		endEdge.Style = "dashed"
+		if endEdge.Count == count {
+			graph.Edges = append(graph.Edges, endEdge)
+		}
+	}
+
+	ga.markHappyPath(nodeMap, edgeMap)
+
+	return graph
+}
+
+// markHappyPath находит самый частый сквозной вариант (последовательность шагов)
+// и помечает его узлы и рёбра флагом HappyPath и отдельным цветом.
+func (ga *graphAssembler) markHappyPath(nodeMap map[string]*Node, edgeMap map[string]*Edge) {
+	var happyVariant string
+	for variant, count := range ga.variantCounts {
+		best := ga.variantCounts[happyVariant]
+		if count > best || (count == best && variant < happyVariant) {
+			happyVariant = variant
+		}
+	}
+	steps := ga.variantKeys[happyVariant]
+	if len(steps) == 0 {
+		return
+	}
+
+	keys := []string{"start"}
+	keys = append(keys, steps...)
+	keys = append(keys, "end")
+
+	for _, key := range keys[1 : len(keys)-1] {
+		if node := nodeMap[key]; node != nil {
+			node.HappyPath = true
+			node.Color = happyPathColor
+		}
+	}
+	for i := 1; i < len(keys); i++ {
+		edge := edgeMap[keys[i-1]+"_"+keys[i]]
+		if edge == nil {
+			// Ребро могло быть объединено со встречным как параллельное
+			edge = edgeMap[keys[i]+"_"+keys[i-1]]
+		}
+		if edge != nil {
+			edge.HappyPath = true
+			edge.Color = happyPathColor
+		}
+	}
+}
+
+// steps преобразует события кейса (строки rows хранилища events) в шаги, схлопывая повторы свёрнутых узлов.
+func (ga *graphAssembler) steps(events *eventStore, rows []int32) []step {
+	steps := make([]step, 0, len(rows))
+	for _, row := range rows {
+		key := events.activityName(row)
+		if ga.nodeKey != nil {
+			key = ga.nodeKey(key)
+		}
+
+		end := events.timestamp[row]
+		if n := len(steps); n > 0 && steps[n-1].key == key && ga.collapse != nil && ga.collapse(key) {
+			steps[n-1].end = end
+			continue
+		}
+		start := end
+		if s := events.start[row]; s != noTime && s < end {
+			start = s
+		}
+		steps = append(steps, step{key: key, start: start, end: end})
+	}
+	return steps
+}
+
+// processSession добавляет вклад шагов сессии в счётчики (sign = 1) или вычитает его (sign = -1).
+func (ga *graphAssembler) processSession(steps []step, sign int) {
+	ga.sessions += sign
+	if len(steps) == 0 {
+		return
+	}
+
+	for _, s := range steps {
+		addCount(ga.nodeCounts, s.key, sign)
+	}
+
+	if len(steps) > 1 {
+		prevStep := steps[0]
+		for i := 1; i < len(steps); i++ {
+			currStep := steps[i]
+
+			duration := time.Duration(currStep.end - prevStep.end).Seconds()
+			waiting := duration
+			if currStep.start > prevStep.end {
+				waiting = time.Duration(currStep.start - prevStep.end).Seconds()
+			}
+			processing := duration - waiting
+			key := prevStep.key + "_" + currStep.key
+
+			stats := ga.edgeStats[key]
+			if stats == nil {
+				stats = &edgeStats{from: prevStep.key, to: currStep.key}
+				ga.edgeStats[key] = stats
+			}
+			stats.count += sign
+			stats.duration += float64(sign) * duration
+			stats.waiting += float64(sign) * waiting
+			stats.processing += float64(sign) * processing
+			if stats.count == 0 {
+				delete(ga.edgeStats, key)
+			}
+
+			prevStep = currStep
+		}
+	}
+
+	// Циклы длины два (A→B→A) отличают повторную обработку от параллельного выполнения
+	for i := 2; i < len(steps); i++ {
+		if steps[i].key == steps[i-2].key && steps[i].key != steps[i-1].key {
+			addCount(ga.shortLoopMap, steps[i-2].key+"_"+steps[i-1].key, sign)
+		}
+	}
+
+	addCount(ga.startCounts, steps[0].key, sign)
+	addCount(ga.endCounts, steps[len(steps)-1].key, sign)
+
+	keys := make([]string, len(steps))
+	for i, s := range steps {
+		keys[i] = s.key
+	}
+	variant := strings.Join(keys, "\x00")
+	if addCount(ga.variantCounts, variant, sign) == 0 {
+		delete(ga.variantKeys, variant)
+	} else if ga.variantKeys[variant] == nil {
+		ga.variantKeys[variant] = keys
+	}
+}
+
+// detectParallelism находит пары активностей, которые следуют друг за другом в обоих порядках
+// и не образуют циклов длины два, и заменяет два встречных ребра одним ребром с флагом Parallel.
+func (ga *graphAssembler) detectParallelism(edgeMap map[string]*Edge) {
+	for key, edge := range edgeMap {
+		if edge.From == edge.To || edge.Parallel {
+			continue
+		}
+
+		reverseKey := edge.To + "_" + edge.From
+		reverse := edgeMap[reverseKey]
+		if reverse == nil {
+			continue
+		}
+
+		if ga.shortLoopMap[key] > 0 || ga.shortLoopMap[reverseKey] > 0 {
+			continue
+		}
+
+		dependency := math.Abs(float64(edge.Count-reverse.Count)) / float64(edge.Count+reverse.Count+1)
+		if dependency > parallelDependencyThreshold {
+			continue
+		}
+
+		// Сохраняем ребро более частого направления, объединяя статистику; при равенстве — ребро
+		// из активности, меньшей по названию, чтобы граф не зависел от порядка обхода
+		kept, removedKey := edge, reverseKey
+		if reverse.Count > edge.Count || reverse.Count == edge.Count && reverse.From < edge.From {
+			kept, removedKey = reverse, key
+		}
+		total := edge.Count + reverse.Count
+		kept.AvgDuration = (edge.AvgDuration*float64(edge.Count) + reverse.AvgDuration*float64(reverse.Count)) / float64(total)
+		kept.AvgWaiting = (edge.AvgWaiting*float64(edge.Count) + reverse.AvgWaiting*float64(reverse.Count)) / float64(total)
+		kept.AvgProcessing = (edge.AvgProcessing*float64(edge.Count) + reverse.AvgProcessing*float64(reverse.Count)) / float64(total)
+		kept.Count = total
+		kept.Parallel = true
+		kept.Style = "dotted"
+		delete(edgeMap, removedKey)
+	}
+}
+
+// addCount прибавляет delta к счётчику key и удаляет обнулившийся счётчик. Возвращает новое значение.
+func addCount(counts map[string]int, key string, delta int) int {
+	count := counts[key] + delta
+	if count == 0 {
+		delete(counts, key)
+	} else {
+		counts[key] = count
+	}
+	return count
+}
+
+func getEdge(edgeMap map[string]*Edge, key, from, to string) *Edge {
+	edge := edgeMap[key]
+	if edge == nil {
+		edge = &Edge{
+			From: from,
+			To:   to,
+		}
+		edgeMap[key] = edge
+	}
+	return edge
+}
@@ -2,7 +2,14 @@ package domain
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unique"
 
 	"process-mining/internal/domain/metrics"
 	"process-mining/internal/infrastructure"
@@ -14,11 +21,14 @@ type Graph struct {
 }
 
 type Node struct {
-	ID    string `json:"id"`
-	Label string `json:"label"`
-	Count int    `json:"count"`
-	Total int    `json:"total"`
-	Color string `json:"color"`
+	ID         string   `json:"id"`
+	Label      string   `json:"label"`
+	Count      int      `json:"count"`
+	Total      int      `json:"total"`
+	Color      string   `json:"color"`
+	HappyPath  bool     `json:"happy_path"`           // узел входит в самый частый вариант процесса
+	Subprocess bool     `json:"subprocess,omitempty"` // узел представляет свёрнутый подпроцесс
+	Children   []string `json:"children,omitempty"`   // активности, входящие в подпроцесс
 }
 
 type Edge struct {
@@ -26,36 +36,55 @@ type Edge struct {
 	To          string  `json:"to"`
 	Count       int     `json:"count"`
 	AvgDuration float64 `json:"-"`
+	// Разложение перехода: ожидание до начала следующей активности и её обработка (сек).
+	// Без данных о начале обработки всё время перехода считается ожиданием.
+	AvgWaiting    float64 `json:"avg_waiting"`
+	AvgProcessing float64 `json:"avg_processing"`
 	Label       string  `json:"label"`
-	Style       string  `json:"style"` // стиль линии (solid, dashed и т.д.)
+	Style       string  `json:"style"`           // стиль линии (solid, dashed и т.д.)
+	Parallel    bool    `json:"parallel"`        // активности выполняются параллельно (в любом порядке)
+	HappyPath   bool    `json:"happy_path"`      // ребро входит в самый частый вариант процесса
+	Color       string  `json:"color,omitempty"` // цвет линии
 }
 
 type Event struct {
-	ID        string
-	SessionID string
-	Timestamp time.Time
-	Desc      string
-}
-
-type Session struct {
-	Events []*Event
+	ID         string
+	SessionID  string
+	Timestamp  time.Time // время завершения активности
+	Start      time.Time // время начала обработки (нулевое, если неизвестно)
+	Desc       string
+	Activity   metrics.ActivityID // номер активности в справочнике построителя
+	Result     string
+	Resource   string
+	Attributes map[string]string // дополнительные столбцы лога (регион, продукт и т.д.)
 }
 
+// GraphBuilder безопасен для одновременного использования: построение и очистка графа
+// выполняются под блокировкой записи, остальные методы — под блокировкой чтения.
+// Граф, возвращаемый GetGraph, не изменяется после построения и не должен изменяться вызывающим.
 type GraphBuilder struct {
-	graph      *Graph
-	nodeMap    map[string]*Node
-	edgeMap    map[string]*Edge
-	sessionMap map[string]*Session
-	csvReader  *infrastructure.CSVReader
+	mu            sync.RWMutex
+	graph         *Graph
+	events        *eventStore
+	assembler     *graphAssembler  // вклад кейсов events в граф
+	pendingStarts map[string]int32 // начатые, но ещё не завершённые активности: кейс + активность → строка события
+	csvReader     *infrastructure.CSVReader
+	columns       ColumnNames                     // названия столбцов, заданные в настройках (nil — распознавание по заголовку)
+	memoryLimit   int64                           // бюджет памяти под события, прочитанные при построении, байт (0 — без ограничения)
+	ingested      int64                           // оценка памяти под события, прочитанные после последней выгрузки на диск
+	spill         *eventSpill                     // события, выгруженные на диск при текущем построении (nil — не выгружались)
+	durations     *metrics.DurationIndex          // индекс длительностей events (nil — ещё не построен, см. DurationIndex)
+	cache         *infrastructure.ParseCache      // кэш разбора логов (nil — без кэша, см. SetParseCache)
+	simplified    atomic.Pointer[simplifiedGraph] // граф, упрощённый до бюджета (см. SimplifiedGraph)
 }
 
 func NewGraphBuilder(csvReader *infrastructure.CSVReader) *GraphBuilder {
 	return &GraphBuilder{
-		graph:      &Graph{},
-		nodeMap:    make(map[string]*Node),
-		edgeMap:    make(map[string]*Edge),
-		sessionMap: make(map[string]*Session),
-		csvReader:  csvReader,
+		graph:         &Graph{},
+		events:        newEventStore(),
+		assembler:     newGraphAssembler(nil, nil),
+		pendingStarts: make(map[string]int32),
+		csvReader:     csvReader,
 	}
 }
 
@@ -79,187 +108,213 @@ func parseTime(timeStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("не удалось распознать формат времени: %s", timeStr)
 }
 
+// parseProcessingTime разбирает длительность обработки: число секунд или формат Go (1h30m).
+func parseProcessingTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("не удалось распознать длительность обработки: %s", value)
+}
+
+// SetColumns задаёт названия столбцов, используемые при следующих построениях графа по файлу.
+func (gb *GraphBuilder) SetColumns(columns ColumnNames) {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	gb.columns = columns
+}
+
 func (gb *GraphBuilder) BuildGraph(filePath string) error {
-	err := gb.csvReader.ReadAndProcess(filePath, func(record []string) error {
-		// Проверяем, что в записи достаточно столбцов
-		if len(record) < 3 {
-			return fmt.Errorf("ошибка: запись содержит меньше 3 столбцов: %v", record)
-		}
+	return gb.BuildGraphWithProgress(filePath, nil)
+}
 
-		timestamp, err := parseTime(record[1])
-		if err != nil {
-			return err // Ошибка уже содержит достаточно контекста
+// BuildGraphWithProgress строит граф по файлу лога и сообщает о ходе построения в progress (если задана).
+// Если построитель уже содержит события, события файла добавляются к ним: вклад в граф пересчитывается
+// только для кейсов, в которые попали новые события, остальные кейсы повторно не обходятся.
+// Активности, начатые в одном файле, могут быть завершены событием complete в следующем.
+func (gb *GraphBuilder) BuildGraphWithProgress(filePath string, progress ProgressFunc) error {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	gb.durations = nil
+	if progress == nil {
+		progress = func(BuildProgress) {}
+	}
+	state := BuildProgress{Phase: PhaseReading, Warnings: []string{}}
+	if info, err := os.Stat(filePath); err == nil {
+		state.TotalBytes = info.Size()
+	}
+	report := func(phase string) {
+		state.Phase = phase
+		state.CasesBuilt = gb.events.cases()
+		if gb.spill != nil {
+			// Оценка: кейс, выгружавшийся несколько раз, учитывается каждый раз
+			state.CasesBuilt += gb.spill.cases
 		}
-
-		event := &Event{
-			ID:        record[0],
-			SessionID: record[0],
-			Timestamp: timestamp,
-			Desc:      record[2],
+		if state.TotalBytes > 0 {
+			state.Percent = math.Round(float64(state.BytesRead)/float64(state.TotalBytes)*1000) / 10
 		}
+		progress(state)
+	}
+	report(PhaseReading)
 
-		gb.processEvent(event)
-		return nil
-	})
+	mapping := defaultColumnMapping()
+	touched := make(map[string]struct{})
+	cacheKey, err := gb.parseCacheKey(filePath)
+	if err != nil {
+		return err
+	}
+	// Лог, уже разобранный с теми же настройками, загружается из кэша без разбора CSV
+	loaded := cacheKey != "" && gb.loadParsed(cacheKey, &state, touched)
+	if !loaded {
+		// Записи разбираются в события параллельно, частями; события частей добавляются по порядку
+		err = infrastructure.ReadChunks(gb.csvReader, filePath, func(header []string) error {
+			mapping = DetectColumnsWith(header, gb.columns)
+			if !mapping.Recognized {
+				state.Warnings = append(state.Warnings, "заголовок не распознан: используется порядок столбцов ID сессии, время, описание")
+			}
+			return nil
+		}, func(records [][]string) (*logChunk, error) {
+			return parseLogChunk(mapping, records)
+		}, func(chunk *logChunk, offset int64) error {
+			if err := gb.mergeChunk(chunk, touched); err != nil {
+				return err
+			}
+			state.BytesRead = offset
+			previous := state.RowsRead
+			if state.RowsRead += len(chunk.events); state.RowsRead/progressInterval > previous/progressInterval {
+				report(PhaseReading)
+			}
+			return nil
+		})
+	}
+	readWarnings := len(state.Warnings)
+	spillRounds := 0
+	if gb.spill != nil {
+		spillRounds = gb.spill.rounds
+	}
+	restoreErr := gb.restoreSpilled()
+	gb.ingested = 0
+	gb.attach(touched)
 
 	if err != nil {
 		return err
 	}
+	if restoreErr != nil {
+		return restoreErr
+	}
+	if spillRounds > 0 {
+		state.Warnings = append(state.Warnings, fmt.Sprintf("прочитанные события превысили бюджет памяти и выгружались на диск (%d раз)", spillRounds))
+	}
+
+	if cacheKey != "" && !loaded {
+		if err := gb.storeParsed(cacheKey, state.RowsRead, state.Warnings[:readWarnings]); err != nil {
+			state.Warnings = append(state.Warnings, err.Error())
+		}
+	}
 
-	gb.finalizeGraph()
+	state.BytesRead = state.TotalBytes
+	if len(gb.pendingStarts) > 0 {
+		state.Warnings = append(state.Warnings, fmt.Sprintf("%d активностей начаты, но не завершены (нет события complete)", len(gb.pendingStarts)))
+	}
+	report(PhaseAssembling)
+	gb.graph = gb.assembler.graph()
+	report(PhaseDone)
 	return nil
 }
 
+// CSVReader возвращает читатель CSV, которым построитель читает логи.
+func (gb *GraphBuilder) CSVReader() *infrastructure.CSVReader {
+	return gb.csvReader
+}
+
 func (gb *GraphBuilder) GetGraph() *Graph {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
 	return gb.graph
 }
 
 func (gb *GraphBuilder) ClearGraph() {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
 	gb.graph = &Graph{}
-	gb.nodeMap = make(map[string]*Node)
-	gb.edgeMap = make(map[string]*Edge)
-	gb.sessionMap = make(map[string]*Session)
+	gb.events = newEventStore()
+	gb.durations = nil
+	gb.assembler = newGraphAssembler(nil, nil)
+	gb.pendingStarts = make(map[string]int32)
 }
 
-func (gb *GraphBuilder) processEvent(event *Event) {
-	session := gb.sessionMap[event.SessionID]
-	if session == nil {
-		session = &Session{}
-		gb.sessionMap[event.SessionID] = session
+// intern заносит активность события в справочник построителя (с номером активности), а значения
+// атрибутов заменяет общими экземплярами через unique. Так атрибуты не удерживают в памяти строки CSV,
+// из которых вырезаны, а повторяющиеся значения хранятся один раз; остальные поля события хранятся
+// в словаре значений eventStore.
+func (gb *GraphBuilder) intern(event *Event) {
+	event.Activity, event.Desc = gb.events.labels.Intern(event.Desc)
+	for name, value := range event.Attributes {
+		event.Attributes[name] = unique.Make(value).Value()
 	}
-	session.Events = append(session.Events, event)
 }
 
-func (gb *GraphBuilder) finalizeGraph() {
-	for _, session := range gb.sessionMap {
-		gb.processSession(session)
-	}
-
-	for _, node := range gb.nodeMap {
-		gb.graph.Nodes = append(gb.graph.Nodes, node)
-	}
-
-	for _, edge := range gb.edgeMap {
-		edge.Label = fmt.Sprintf("%d\n%.2f sec avg", edge.Count, edge.AvgDuration)
-		gb.graph.Edges = append(gb.graph.Edges, edge)
-	}
-
-	// Добавляем специальные узлы "Начало" и "Конец"
-	startNode := &Node{
-		ID:    "start",
-		Label: "Начало процесса",
-		Count: len(gb.sessionMap),
-		Total: len(gb.sessionMap),
-		Color: "green", // Цвет для начального узла
-	}
-	gb.graph.Nodes = append(gb.graph.Nodes, startNode)
-
-	endNode := &Node{
-		ID:    "end",
-		Label: "Конец",
-		Count: len(gb.sessionMap),
-		Total: len(gb.sessionMap),
-		Color: "red", // Цвет для конечного узла
-	}
-	gb.graph.Nodes = append(gb.graph.Nodes, endNode)
-
-	// Добавляем связи между "Начало" -> первый узел и последний узел -> "Конец"
-	for _, session := range gb.sessionMap {
-		events := session.Events
-		if len(events) == 0 {
-			continue
-		}
-
-		// Связь "Начало" -> первый узел
-		firstEvent := events[0]
-		startKey := "start_" + firstEvent.Desc
-		startEdge := gb.getEdge(startKey, "start", firstEvent.Desc)
-		startEdge.Count++
-		startEdge.Style = "dashed" // Устанавливаем стиль линии как пунктирный
-		if startEdge.Count == 1 {
-			// Если это новая связь, добавляем ее в граф
-			gb.graph.Edges = append(gb.graph.Edges, startEdge)
-		}
-
-		// Связь последний узел -> "Конец"
-		lastEvent := events[len(events)-1]
-		endKey := lastEvent.Desc + "_end"
-		endEdge := gb.getEdge(endKey, lastEvent.Desc, "end")
-		endEdge.Count++
-		endEdge.Style = "dashed" // Устанавливаем стиль линии как пунктирный
-		if endEdge.Count == 1 {
-			// Если это новая связь, добавляем ее в граф
-			gb.graph.Edges = append(gb.graph.Edges, endEdge)
+// processLifecycleEvent объединяет события начала и завершения одной активности в одно событие
+// с заполненным временем начала. События без типа жизненного цикла добавляются как есть.
+func (gb *GraphBuilder) processLifecycleEvent(event *Event, lifecycle string) {
+	key := event.SessionID + "\x00" + event.Desc
+	switch lifecycle {
+	case "start":
+		event.Start = event.Timestamp
+		gb.pendingStarts[key] = gb.events.append(event)
+	case "complete":
+		if started, ok := gb.pendingStarts[key]; ok {
+			gb.events.setTimestamp(started, event.Timestamp)
+			delete(gb.pendingStarts, key)
+			return
 		}
+		gb.events.append(event)
+	case "schedule", "assign", "reassign", "suspend", "resume", "withdraw", "ate_abort", "pi_abort", "autoskip", "manualskip":
+		// Эти переходы жизненного цикла не являются выполнением активности
+	default:
+		gb.events.append(event)
 	}
 }
 
-func (gb *GraphBuilder) processSession(session *Session) {
-	events := session.Events
-	if len(events) == 0 {
+// touch вычитает из графа прежний вклад кейса sessionID перед первым изменением его событий
+// при построении; touched — кейсы, уже изменённые при этом построении.
+func (gb *GraphBuilder) touch(sessionID string, touched map[string]struct{}) {
+	if _, ok := touched[sessionID]; ok {
 		return
 	}
-
-	for _, event := range events {
-		node := gb.getNode(event.Desc)
-		node.Count++
-		node.Total++
-	}
-
-	if len(events) > 1 {
-		prevEvent := events[0]
-		for i := 1; i < len(events); i++ {
-			currEvent := events[i]
-
-			duration := currEvent.Timestamp.Sub(prevEvent.Timestamp).Seconds()
-			key := prevEvent.Desc + "_" + currEvent.Desc
-
-			edge := gb.getEdge(key, prevEvent.Desc, currEvent.Desc)
-			edge.Count++
-			edge.AvgDuration = (edge.AvgDuration*float64(edge.Count-1) + duration) / float64(edge.Count)
-
-			prevEvent = currEvent
-		}
+	touched[sessionID] = struct{}{}
+	if c, ok := gb.events.caseOf(sessionID); ok {
+		gb.assembler.remove(gb.events, c)
 	}
 }
 
-func (gb *GraphBuilder) GetProcessInstances() []metrics.ProcessInstance {
-	var processInstances []metrics.ProcessInstance
-	for _, session := range gb.sessionMap {
-		var events []metrics.Event
-		for _, event := range session.Events {
-			events = append(events, metrics.Event{
-					SessionID: event.SessionID,
-					Timestamp: event.Timestamp,
-					Description: event.Desc,
-				})
-			}
-			processInstances = append(processInstances, metrics.ProcessInstance{Events: events})
+// attach добавляет в граф вклад изменённых кейсов touched.
+func (gb *GraphBuilder) attach(touched map[string]struct{}) {
+	for sessionID := range touched {
+		if c, ok := gb.events.caseOf(sessionID); ok {
+			gb.assembler.add(gb.events, c)
 		}
-		return processInstances
 	}
+}
 
-func (gb *GraphBuilder) getNode(desc string) *Node {
-	node := gb.nodeMap[desc]
-	if node == nil {
-		node = &Node{
-			ID:    desc,
-			Label: desc,
-			Color: "blue", // Устанавливаем значение по умолчанию
-		}
-		gb.nodeMap[desc] = node
-	}
-	return node
+func (gb *GraphBuilder) finalizeGraph() {
+	gb.assembler = newGraphAssembler(nil, nil)
+	gb.graph = gb.assembler.assemble(gb.events)
 }
 
-func (gb *GraphBuilder) getEdge(key, from, to string) *Edge {
-	edge := gb.edgeMap[key]
-	if edge == nil {
-		edge = &Edge{
-			From: from,
-			To:   to,
+func (gb *GraphBuilder) GetProcessInstances() []metrics.ProcessInstance {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	processInstances := make([]metrics.ProcessInstance, gb.events.cases())
+	for c, rows := range gb.events.caseRows {
+		events := make([]metrics.Event, len(rows))
+		for i, row := range rows {
+			events[i] = gb.events.metricsEvent(row)
 		}
-		gb.edgeMap[key] = edge
+		processInstances[c] = metrics.ProcessInstance{ID: gb.events.caseIDs[c], Events: events}
 	}
-	return edge
+	return processInstances
 }
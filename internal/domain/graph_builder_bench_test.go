@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+// benchmarkSizes — число кейсов в логах бенчмарков. С -short выполняются только логи
+// не больше shortBenchmarkCases кейсов.
+var benchmarkSizes = []int{10_000, 100_000, 1_000_000}
+
+const shortBenchmarkCases = 10_000
+
+// forEachBenchmarkSize запускает run отдельным подбенчмарком для каждого размера лога.
+func forEachBenchmarkSize(b *testing.B, run func(b *testing.B, cases int)) {
+	for _, cases := range benchmarkSizes {
+		b.Run(fmt.Sprintf("cases=%d", cases), func(b *testing.B) {
+			if testing.Short() && cases > shortBenchmarkCases {
+				b.Skip("большой лог пропускается с -short")
+			}
+			run(b, cases)
+		})
+	}
+}
+
+// writeBenchmarkLog записывает в каталог dir синтетический CSV-лог из cases кейсов: случайные маршруты
+// по десятку активностей с возвратами и повторами (как в бенчмарках metrics). Возвращает путь к файлу.
+func writeBenchmarkLog(b *testing.B, dir string, cases int) string {
+	b.Helper()
+	activities := []string{"Регистрация", "Проверка", "Согласование", "Доработка", "Оплата", "Отгрузка", "Доставка", "Возврат", "Закрытие", "Отмена"}
+	results := []string{"success", "success", "success", "error"}
+	random := rand.New(rand.NewPCG(1, 2))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	path := filepath.Join(dir, fmt.Sprintf("log-%d.csv", cases))
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	fmt.Fprintln(writer, "case_id,timestamp,activity,result,resource")
+	for c := 0; c < cases; c++ {
+		at := start.Add(time.Duration(random.IntN(90*24)) * time.Hour)
+		for i, n := 0, 3+random.IntN(10); i < n; i++ {
+			activity := activities[random.IntN(len(activities))]
+			if i == 0 {
+				activity = activities[0]
+			}
+			at = at.Add(time.Duration(1+random.ExpFloat64()*3600) * time.Second)
+			fmt.Fprintf(writer, "case-%d,%s,%s,%s,user-%d\n", c, at.Format(time.RFC3339), activity, results[random.IntN(len(results))], random.IntN(20))
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkIngest измеряет построение графа по файлу лога в пустой построитель: чтение и разбор CSV,
+// добавление событий и сборку графа.
+func BenchmarkIngest(b *testing.B) {
+	forEachBenchmarkSize(b, func(b *testing.B, cases int) {
+		path := writeBenchmarkLog(b, b.TempDir(), cases)
+		if info, err := os.Stat(path); err == nil {
+			b.SetBytes(info.Size())
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := NewGraphBuilder(infrastructure.NewCSVReader()).BuildGraph(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGraphBuild измеряет сборку графа по уже загруженным событиям, без чтения лога.
+func BenchmarkGraphBuild(b *testing.B) {
+	forEachBenchmarkSize(b, func(b *testing.B, cases int) {
+		gb := NewGraphBuilder(infrastructure.NewCSVReader())
+		if err := gb.BuildGraph(writeBenchmarkLog(b, b.TempDir(), cases)); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			newGraphAssembler(nil, nil).assemble(gb.events)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "sort"
+
+// GraphPage — часть графа: самые частые переходы с offset по offset+limit и их узлы.
+type GraphPage struct {
+	Graph
+	TotalNodes int `json:"total_nodes"`
+	TotalEdges int `json:"total_edges"`
+	Offset     int `json:"offset"`
+	Limit      int `json:"limit"`
+}
+
+// GraphSummary — сводка графа без узлов и рёбер, чтобы оценить его размер до загрузки.
+type GraphSummary struct {
+	Nodes        int `json:"nodes"`
+	Edges        int `json:"edges"`
+	Events       int `json:"events"`         // сумма частот узлов
+	Transitions  int `json:"transitions"`    // сумма частот переходов
+	MaxNodeCount int `json:"max_node_count"` // частота самой частой активности
+	MaxEdgeCount int `json:"max_edge_count"` // частота самого частого перехода
+}
+
+// Page возвращает переходы графа, упорядоченные по убыванию частоты, с offset по offset+limit,
+// и узлы, которые они соединяют. Узлы без переходов попадают на первую страницу.
+func (g *Graph) Page(offset, limit int) *GraphPage {
+	edges := make([]*Edge, len(g.Edges))
+	copy(edges, g.Edges)
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].Count > edges[j].Count })
+
+	start := min(offset, len(edges))
+	end := min(start+limit, len(edges))
+	page := &GraphPage{
+		Graph:      Graph{Nodes: []*Node{}, Edges: edges[start:end]},
+		TotalNodes: len(g.Nodes),
+		TotalEdges: len(g.Edges),
+		Offset:     offset,
+		Limit:      limit,
+	}
+
+	connected := make(map[string]bool, len(g.Nodes))
+	for _, edge := range g.Edges {
+		connected[edge.From] = true
+		connected[edge.To] = true
+	}
+	used := make(map[string]bool)
+	for _, edge := range page.Edges {
+		used[edge.From] = true
+		used[edge.To] = true
+	}
+	for _, node := range g.Nodes {
+		if used[node.ID] || (offset == 0 && !connected[node.ID]) {
+			page.Nodes = append(page.Nodes, node)
+		}
+	}
+	return page
+}
+
+// Summary возвращает сводку графа.
+func (g *Graph) Summary() GraphSummary {
+	summary := GraphSummary{Nodes: len(g.Nodes), Edges: len(g.Edges)}
+	for _, node := range g.Nodes {
+		summary.Events += node.Count
+		summary.MaxNodeCount = max(summary.MaxNodeCount, node.Count)
+	}
+	for _, edge := range g.Edges {
+		summary.Transitions += edge.Count
+		summary.MaxEdgeCount = max(summary.MaxEdgeCount, edge.Count)
+	}
+	return summary
+}
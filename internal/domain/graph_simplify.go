@@ -0,0 +1,140 @@
+package domain
+
+import "sort"
+
+// otherNodeID — идентификатор узла, в который упрощение графа сливает редкие активности.
+const otherNodeID = "simplified:other"
+
+// GraphBudget — наибольший размер графа, который ещё можно отобразить: число узлов и переходов
+// (0 — без ограничения).
+type GraphBudget struct {
+	Nodes int
+	Edges int
+}
+
+// fits сообщает, что граф укладывается в бюджет.
+func (b GraphBudget) fits(graph *Graph) bool {
+	return (b.Nodes <= 0 || len(graph.Nodes) <= b.Nodes) && (b.Edges <= 0 || len(graph.Edges) <= b.Edges)
+}
+
+// GraphSimplification описывает, как граф был уменьшен до бюджета.
+type GraphSimplification struct {
+	TotalNodes   int `json:"total_nodes"`    // узлов в исходном графе
+	TotalEdges   int `json:"total_edges"`    // переходов в исходном графе
+	MergedNodes  int `json:"merged_nodes"`   // активностей, слитых в узел «Прочие активности»
+	PrunedEdges  int `json:"pruned_edges"`   // редких переходов, убранных из графа
+	MinEdgeCount int `json:"min_edge_count"` // порог частоты оставленных переходов (0 — переходы не убирались)
+}
+
+// simplifiedGraph — упрощённый граф построителя, запомненный для бюджета budget.
+type simplifiedGraph struct {
+	source  *Graph // граф построителя, по которому получен упрощённый
+	budget  GraphBudget
+	graph   *Graph
+	changes *GraphSimplification
+}
+
+// SimplifiedGraph возвращает граф, уменьшенный до бюджета budget. Если активностей больше, чем помещается,
+// самые редкие сливаются в один узел «Прочие активности» (граф перестраивается по событиям, подряд идущие
+// слитые активности становятся одним шагом); если и после этого переходов больше бюджета, убираются
+// переходы реже порога, подобранного по бюджету (переходы самого частого варианта остаются всегда).
+// Граф, который укладывается в бюджет, возвращается как есть, без описания изменений (nil).
+// Упрощённый граф запоминается до следующего построения и, как и GetGraph, не должен изменяться вызывающим.
+func (gb *GraphBuilder) SimplifiedGraph(budget GraphBudget) (*Graph, *GraphSimplification) {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	graph := gb.graph
+	if budget.fits(graph) {
+		return graph, nil
+	}
+	if cached := gb.simplified.Load(); cached != nil && cached.source == graph && cached.budget == budget {
+		return cached.graph, cached.changes
+	}
+
+	changes := &GraphSimplification{TotalNodes: len(graph.Nodes), TotalEdges: len(graph.Edges)}
+	simplified := graph
+	if budget.Nodes > 0 && len(simplified.Nodes) > budget.Nodes {
+		simplified, changes.MergedNodes = gb.mergeRareActivities(simplified, budget.Nodes)
+	}
+	if budget.Edges > 0 && len(simplified.Edges) > budget.Edges {
+		simplified, changes.PrunedEdges, changes.MinEdgeCount = pruneRareEdges(simplified, budget.Edges)
+	}
+	gb.simplified.Store(&simplifiedGraph{source: graph, budget: budget, graph: simplified, changes: changes})
+	return simplified, changes
+}
+
+// mergeRareActivities перестраивает граф так, чтобы в нём было не больше nodes узлов: самые частые
+// активности остаются, остальные сливаются в узел otherNodeID. Возвращает граф и число слитых активностей.
+func (gb *GraphBuilder) mergeRareActivities(graph *Graph, nodes int) (*Graph, int) {
+	activities := make([]*Node, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		if node.ID != "start" && node.ID != "end" {
+			activities = append(activities, node)
+		}
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].Count != activities[j].Count {
+			return activities[i].Count > activities[j].Count
+		}
+		return activities[i].ID < activities[j].ID
+	})
+	// Кроме оставленных активностей в графе узлы начала, конца и «Прочие активности»
+	keep := max(nodes-3, 1)
+	if keep >= len(activities) {
+		return graph, 0
+	}
+	kept := make(map[string]bool, keep)
+	for _, node := range activities[:keep] {
+		kept[node.ID] = true
+	}
+	nodeKey := func(activity string) string {
+		if kept[activity] {
+			return activity
+		}
+		return otherNodeID
+	}
+	collapse := func(key string) bool {
+		return key == otherNodeID
+	}
+
+	merged := newGraphAssembler(nodeKey, collapse).assemble(gb.events)
+	for _, node := range merged.Nodes {
+		if node.ID == otherNodeID {
+			node.Label = "Прочие активности"
+			if !node.HappyPath {
+				node.Color = "gray"
+			}
+		}
+	}
+	return merged, len(activities) - keep
+}
+
+// pruneRareEdges оставляет в графе не больше edges переходов: переходы самого частого варианта
+// и самые частые из остальных, чаще порога minCount. Переходы с частотой, равной частоте первого
+// не поместившегося, убираются все, чтобы результат не зависел от порядка переходов.
+// Возвращает граф, число убранных переходов и порог.
+func pruneRareEdges(graph *Graph, edges int) (*Graph, int, int) {
+	var counts []int
+	happy := 0
+	for _, edge := range graph.Edges {
+		if edge.HappyPath {
+			happy++
+		} else {
+			counts = append(counts, edge.Count)
+		}
+	}
+	room := max(edges-happy, 0)
+	if len(counts) <= room {
+		return graph, 0, 0
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	minCount := counts[room] + 1
+
+	pruned := &Graph{Nodes: graph.Nodes, Edges: make([]*Edge, 0, min(edges, len(graph.Edges)))}
+	for _, edge := range graph.Edges {
+		if edge.HappyPath || edge.Count >= minCount {
+			pruned.Edges = append(pruned.Edges, edge)
+		}
+	}
+	return pruned, len(graph.Edges) - len(pruned.Edges), minCount
+}
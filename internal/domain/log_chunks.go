@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logChunk — события части лога, разобранные в рабочей горутине чтения (см. infrastructure.ReadChunks):
+// в порядке записей и по кейсам.
+type logChunk struct {
+	events     []Event
+	lifecycles []string           // тип жизненного цикла события (в нижнем регистре)
+	order      []string           // кейсы в порядке их первого события в части
+	sessions   map[string][]int32 // кейс → номера его событий в events
+}
+
+// parseLogChunk разбирает записи части лога со столбцами mapping. При ошибке возвращаются события,
+// разобранные до неё.
+func parseLogChunk(mapping ColumnMapping, records [][]string) (*logChunk, error) {
+	chunk := &logChunk{
+		events:     make([]Event, 0, len(records)),
+		lifecycles: make([]string, 0, len(records)),
+		sessions:   make(map[string][]int32),
+	}
+	for _, record := range records {
+		event, lifecycle, err := mapping.parseRecord(record)
+		if err != nil {
+			return chunk, err
+		}
+		rows, seen := chunk.sessions[event.SessionID]
+		if !seen {
+			chunk.order = append(chunk.order, event.SessionID)
+		}
+		chunk.sessions[event.SessionID] = append(rows, int32(len(chunk.events)))
+		chunk.events = append(chunk.events, event)
+		chunk.lifecycles = append(chunk.lifecycles, lifecycle)
+	}
+	return chunk, nil
+}
+
+// parseRecord разбирает запись лога в событие и тип его жизненного цикла (в нижнем регистре).
+func (m ColumnMapping) parseRecord(record []string) (Event, string, error) {
+	// Проверяем, что в записи достаточно столбцов
+	if len(record) < m.minRecordLength() {
+		return Event{}, "", fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", m.minRecordLength(), record)
+	}
+
+	timestamp, err := parseTime(record[m.Timestamp])
+	if err != nil {
+		return Event{}, "", err // Ошибка уже содержит достаточно контекста
+	}
+
+	event := Event{
+		ID:        record[m.CaseID],
+		SessionID: record[m.CaseID],
+		Timestamp: timestamp,
+		Desc:      record[m.Activity],
+		Result:    m.field(record, m.Result),
+		Resource:  m.field(record, m.Resource),
+	}
+	if m.Start >= 0 {
+		if value := m.field(record, m.Start); value != "" {
+			start, err := parseTime(value)
+			if err != nil {
+				return Event{}, "", err
+			}
+			event.Start = start
+		}
+	}
+	if m.Processing >= 0 && event.Start.IsZero() {
+		if value := m.field(record, m.Processing); value != "" {
+			processing, err := parseProcessingTime(value)
+			if err != nil {
+				return Event{}, "", err
+			}
+			event.Start = timestamp.Add(-processing)
+		}
+	}
+	if len(m.Attributes) > 0 {
+		event.Attributes = make(map[string]string, len(m.Attributes))
+		for i, name := range m.Attributes {
+			if value := m.field(record, i); value != "" {
+				event.Attributes[name] = value
+			}
+		}
+	}
+	return event, strings.ToLower(m.field(record, m.Lifecycle)), nil
+}
+
+// mergeChunk добавляет события части лога в построитель. Активности заносятся в справочник в порядке
+// записей, а события — по кейсам: прежний вклад кейса в граф вычитается один раз на часть.
+func (gb *GraphBuilder) mergeChunk(chunk *logChunk, touched map[string]struct{}) error {
+	for i := range chunk.events {
+		gb.intern(&chunk.events[i])
+	}
+	for _, sessionID := range chunk.order {
+		gb.touch(sessionID, touched)
+		for _, i := range chunk.sessions[sessionID] {
+			gb.processLifecycleEvent(&chunk.events[i], chunk.lifecycles[i])
+			if err := gb.trackMemory(&chunk.events[i], touched); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"process-mining/internal/infrastructure"
+)
+
+// mergeSource — лог, участвующий в объединении: назначение его столбцов и кейсы в порядке появления.
+type mergeSource struct {
+	mapping ColumnMapping
+	header  []string
+	cases   []string
+	renamed map[string]string // исходный идентификатор кейса → новый
+}
+
+// MergeLogs объединяет логи paths в один CSV-лог w. Столбцы сопоставляются по назначению (кейс, время,
+// активность и т.д. — даже если в разных логах они названы по-разному) и по названию (атрибуты);
+// заголовок результата — объединение столбцов всех логов. Если кейс с тем же идентификатором уже есть
+// в одном из предыдущих логов, ему присваивается новый идентификатор с суффиксом -<номер лога>;
+// с keepCaseIDs совпадающие идентификаторы считаются одним кейсом. Каждый файл читается потоком
+// дважды. Возвращает число переименованных кейсов.
+func MergeLogs(csvReader *infrastructure.CSVReader, paths []string, w io.Writer, columns ColumnNames, keepCaseIDs bool) (renamed int, err error) {
+	sources := make([]*mergeSource, len(paths))
+	allCases := make(map[string]bool)
+	for i, path := range paths {
+		source := &mergeSource{mapping: defaultColumnMapping()}
+		seen := make(map[string]bool)
+		err := csvReader.ReadAndProcessWithHeader(path, func(header []string) error {
+			source.header = header
+			source.mapping = DetectColumnsWith(header, columns)
+			return nil
+		}, func(record []string) error {
+			if len(record) < source.mapping.minRecordLength() {
+				return fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", source.mapping.minRecordLength(), record)
+			}
+			if caseID := record[source.mapping.CaseID]; !seen[caseID] {
+				seen[caseID] = true
+				source.cases = append(source.cases, caseID)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, caseID := range source.cases {
+			allCases[caseID] = true
+		}
+		sources[i] = source
+	}
+
+	// Новые идентификаторы не должны совпадать ни с одним исходным, в том числе из следующих логов
+	if !keepCaseIDs {
+		earlier := make(map[string]bool)
+		for i, source := range sources {
+			suffix := "-" + strconv.Itoa(i+1)
+			source.renamed = make(map[string]string)
+			for _, caseID := range source.cases {
+				if !earlier[caseID] {
+					continue
+				}
+				newID := caseID + suffix
+				for allCases[newID] {
+					newID += suffix
+				}
+				allCases[newID] = true
+				source.renamed[caseID] = newID
+				renamed++
+			}
+			for _, caseID := range source.cases {
+				earlier[caseID] = true
+			}
+		}
+	}
+
+	// Столбец результата определяется полем события или названием атрибута
+	var header []string
+	positions := make(map[string]int)
+	targets := make([][]int, len(sources)) // лог → столбец лога → столбец результата
+	for i, source := range sources {
+		targets[i] = make([]int, len(source.header))
+		for j, name := range source.header {
+			key := source.mapping.fieldAt(j)
+			if key == "" {
+				key = "attribute:" + normalizeColumn(name)
+			}
+			position, ok := positions[key]
+			if !ok {
+				position = len(header)
+				positions[key] = position
+				header = append(header, name)
+			}
+			targets[i][j] = position
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+	for i, path := range paths {
+		source := sources[i]
+		err := csvReader.ReadAndProcessWithHeader(path, nil, func(record []string) error {
+			merged := make([]string, len(header))
+			for j, value := range record {
+				if j < len(targets[i]) {
+					merged[targets[i][j]] = value
+				}
+			}
+			if newID, ok := source.renamed[record[source.mapping.CaseID]]; ok {
+				merged[targets[i][source.mapping.CaseID]] = newID
+			}
+			return writer.Write(merged)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	writer.Flush()
+	return renamed, writer.Error()
+}
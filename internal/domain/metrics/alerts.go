@@ -0,0 +1,35 @@
+package metrics
+
+// AlertRule задаёт, о каких превышениях порогов сообщать: только о метриках Metrics
+// (пустой список — обо всех) с критичностью не ниже MinSeverity.
+type AlertRule struct {
+	Metrics     []string `json:"metrics"`
+	MinSeverity float64  `json:"min_severity"`
+}
+
+// Matches проверяет, что превышение порога метрики подпадает под правило.
+func (r AlertRule) Matches(metric InefficiencyMetric) bool {
+	if !metric.Exceeded || metric.Severity < r.MinSeverity {
+		return false
+	}
+	if len(r.Metrics) == 0 {
+		return true
+	}
+	for _, key := range r.Metrics {
+		if key == metric.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// Select возвращает метрики, подпадающие под правило, в исходном порядке.
+func (r AlertRule) Select(metrics []InefficiencyMetric) []InefficiencyMetric {
+	var selected []InefficiencyMetric
+	for _, metric := range metrics {
+		if r.Matches(metric) {
+			selected = append(selected, metric)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkInstances строит синтетический лог из cases кейсов: случайные маршруты по десятку активностей
+// с возвратами и повторами, чтобы находились вхождения всех семейств метрик.
+func benchmarkInstances(cases int) map[string]*ProcessInstance {
+	activities := []string{"Регистрация", "Проверка", "Согласование", "Доработка", "Оплата", "Отгрузка", "Доставка", "Возврат", "Закрытие", "Отмена"}
+	results := []string{"success", "success", "success", "error"}
+	random := rand.New(rand.NewPCG(1, 2))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	instances := make(map[string]*ProcessInstance, cases)
+	for c := 0; c < cases; c++ {
+		id := fmt.Sprintf("case-%d", c)
+		at := start.Add(time.Duration(random.IntN(90*24)) * time.Hour)
+		events := make([]Event, 0, 12)
+		for i, n := 0, 3+random.IntN(10); i < n; i++ {
+			activity := activities[random.IntN(len(activities))]
+			if i == 0 {
+				activity = activities[0]
+			}
+			at = at.Add(time.Duration(1+random.ExpFloat64()*3600) * time.Second)
+			events = append(events, Event{
+				SessionID:   id,
+				Timestamp:   at,
+				Description: activity,
+				Result:      results[random.IntN(len(results))],
+				Resource:    fmt.Sprintf("user-%d", random.IntN(20)),
+			})
+		}
+		instances[id] = &ProcessInstance{ID: id, Events: events}
+	}
+	return instances
+}
+
+// benchmarkSizes — число кейсов в логах бенчмарков по размеру лога. С -short выполняются только логи
+// не больше shortBenchmarkCases кейсов.
+var benchmarkSizes = []int{10_000, 100_000, 1_000_000}
+
+const shortBenchmarkCases = 10_000
+
+// forEachBenchmarkSize запускает run отдельным подбенчмарком для каждого размера лога.
+func forEachBenchmarkSize(b *testing.B, run func(b *testing.B, instances map[string]*ProcessInstance)) {
+	for _, cases := range benchmarkSizes {
+		b.Run(fmt.Sprintf("cases=%d", cases), func(b *testing.B) {
+			if testing.Short() && cases > shortBenchmarkCases {
+				b.Skip("большой лог пропускается с -short")
+			}
+			instances := benchmarkInstances(cases)
+			numberActivities(instances)
+			run(b, instances)
+		})
+	}
+}
+
+func benchmarkAnalyzer() *Analyzer {
+	analyzer := NewAnalyzer()
+	analyzer.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return analyzer
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	analyzer := benchmarkAnalyzer()
+	instances := benchmarkInstances(20000)
+	numberActivities(instances)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		analyzer.Analyze(instances)
+	}
+}
+
+func BenchmarkAnalyzeIndexed(b *testing.B) {
+	analyzer := benchmarkAnalyzer()
+	instances := benchmarkInstances(20000)
+	numberActivities(instances)
+	durations := analyzer.tallyCases(instances, tallyDurations|tallySamples).durationIndex()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		analyzer.AnalyzeIndexed(instances, durations)
+	}
+}
+
+func BenchmarkAnalyzeMetric(b *testing.B) {
+	analyzer := benchmarkAnalyzer()
+	instances := benchmarkInstances(20000)
+	numberActivities(instances)
+	for _, key := range []string{"Rework", "Anomalously Long Stage"} {
+		b.Run(key, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := analyzer.AnalyzeMetric(instances, nil, key); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAnalyzeCases(b *testing.B) {
+	analyzer := benchmarkAnalyzer()
+	forEachBenchmarkSize(b, func(b *testing.B, instances map[string]*ProcessInstance) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			analyzer.Analyze(instances)
+		}
+	})
+}
+
+// BenchmarkCollector измеряет сборщик каждого семейства метрик отдельно (по первой метрике семейства);
+// индекс длительностей строится заранее, как при анализе набора данных сервером.
+func BenchmarkCollector(b *testing.B) {
+	analyzer := benchmarkAnalyzer()
+	forEachBenchmarkSize(b, func(b *testing.B, instances map[string]*ProcessInstance) {
+		durations := analyzer.tallyCases(instances, tallyDurations|tallySamples).durationIndex()
+		for _, family := range analyzer.families() {
+			key := family.keys[0]
+			// «/» в названии подбенчмарка разделяет уровни в -bench, поэтому заменяется
+			b.Run(strings.ReplaceAll(key, "/", "-"), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := analyzer.AnalyzeMetric(instances, durations, key); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Типы активностей в карте автоматизации.
+const (
+	ActivityManual    = "manual"
+	ActivityAutomated = "automated"
+)
+
+// AutomationMapping размечает активности как ручные или автоматические: активность → manual/automated.
+type AutomationMapping map[string]string
+
+// Validate проверяет, что каждая активность размечена допустимым типом.
+func (m AutomationMapping) Validate() error {
+	for activity, kind := range m {
+		if kind != ActivityManual && kind != ActivityAutomated {
+			return fmt.Errorf("неизвестный тип активности %s: %q (ожидается %s или %s)", activity, kind, ActivityManual, ActivityAutomated)
+		}
+	}
+	return nil
+}
+
+// AutomationSummary — уровень автоматизации процесса по размеченным активностям.
+type AutomationSummary struct {
+	AutomationRate     float64  `json:"automation_rate"`     // доля автоматических событий среди размеченных, %
+	AverageCaseRate    float64  `json:"average_case_rate"`   // средний уровень автоматизации кейса, %
+	ManualTime         float64  `json:"manual_time"`         // сек в ручных активностях
+	AutomatedTime      float64  `json:"automated_time"`      // сек в автоматических активностях
+	ManualTimeShare    float64  `json:"manual_time_share"`   // доля ручного времени от размеченного, %
+	UntaggedActivities []string `json:"untagged_activities"` // активности лога без разметки
+}
+
+// CaseAutomation — уровень автоматизации одного кейса.
+type CaseAutomation struct {
+	CaseID          string  `json:"case_id"`
+	AutomationRate  float64 `json:"automation_rate"` // %
+	ManualEvents    int     `json:"manual_events"`
+	AutomatedEvents int     `json:"automated_events"`
+	ManualTime      float64 `json:"manual_time"` // сек
+}
+
+// SetAutomation задаёт разметку активностей на ручные и автоматические.
+func (a *Analyzer) SetAutomation(mapping AutomationMapping) error {
+	if err := mapping.Validate(); err != nil {
+		return err
+	}
+	a.automation = mapping
+	return nil
+}
+
+// CaseAutomation считает уровень автоматизации каждого кейса, начиная с наименее автоматизированных.
+// Время события — от начала обработки, а если оно неизвестно — от предыдущего события кейса.
+func (a *Analyzer) CaseAutomation(instances map[string]*ProcessInstance) []CaseAutomation {
+	result := make([]CaseAutomation, 0, len(instances))
+	for id, instance := range instances {
+		c := CaseAutomation{CaseID: id}
+		for i, event := range instance.Events {
+			switch a.automation[event.Description] {
+			case ActivityManual:
+				c.ManualEvents++
+				c.ManualTime += event.Timestamp.Sub(eventStart(instance.Events, i)).Seconds()
+			case ActivityAutomated:
+				c.AutomatedEvents++
+			}
+		}
+		if tagged := c.ManualEvents + c.AutomatedEvents; tagged > 0 {
+			c.AutomationRate = math.Round(float64(c.AutomatedEvents)/float64(tagged)*1000) / 10
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].AutomationRate != result[j].AutomationRate {
+			return result[i].AutomationRate < result[j].AutomationRate
+		}
+		return result[i].CaseID < result[j].CaseID
+	})
+	return result
+}
+
+// automationSummary считает уровень автоматизации процесса. Возвращает nil, если разметка не задана.
+func (a *Analyzer) automationSummary(instances map[string]*ProcessInstance) *AutomationSummary {
+	if len(a.automation) == 0 {
+		return nil
+	}
+
+	summary := &AutomationSummary{UntaggedActivities: []string{}}
+	untagged := make(map[string]bool)
+	manual, automated := 0, 0
+	for _, instance := range instances {
+		for i, event := range instance.Events {
+			duration := event.Timestamp.Sub(eventStart(instance.Events, i)).Seconds()
+			switch a.automation[event.Description] {
+			case ActivityManual:
+				manual++
+				summary.ManualTime += duration
+			case ActivityAutomated:
+				automated++
+				summary.AutomatedTime += duration
+			default:
+				untagged[event.Description] = true
+			}
+		}
+	}
+	if manual+automated > 0 {
+		summary.AutomationRate = math.Round(float64(automated)/float64(manual+automated)*1000) / 10
+	}
+	if total := summary.ManualTime + summary.AutomatedTime; total > 0 {
+		summary.ManualTimeShare = math.Round(summary.ManualTime/total*1000) / 10
+	}
+
+	cases := a.CaseAutomation(instances)
+	for _, c := range cases {
+		summary.AverageCaseRate += c.AutomationRate
+	}
+	if len(cases) > 0 {
+		summary.AverageCaseRate = math.Round(summary.AverageCaseRate/float64(len(cases))*10) / 10
+	}
+
+	for activity := range untagged {
+		summary.UntaggedActivities = append(summary.UntaggedActivities, activity)
+	}
+	sort.Strings(summary.UntaggedActivities)
+	return summary
+}
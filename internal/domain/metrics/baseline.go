@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultRegressionTolerance — допустимый относительный рост показателя, после которого он считается регрессией.
+const DefaultRegressionTolerance = 0.05
+
+// ErrUnknownBaseline возвращается при обращении к несохранённому эталону.
+var ErrUnknownBaseline = errors.New("эталон не найден")
+
+// BaselineMetric — значения одной метрики неэффективности в эталоне.
+type BaselineMetric struct {
+	Metric         string  `json:"metric"`
+	Count          int     `json:"count"`
+	WastedDuration float64 `json:"wasted_duration"`     // сек
+	LogLevel       bool    `json:"log_level,omitempty"` // метрика уровня всего лога, не нормируется на кейс
+}
+
+// isLogLevel проверяет, что все вхождения метрики относятся ко всему логу, а не к отдельным кейсам.
+func isLogLevel(metric InefficiencyMetric) bool {
+	for _, occurrence := range metric.Occurrences {
+		if occurrence.InstanceID != "ALL" {
+			return false
+		}
+	}
+	return len(metric.Occurrences) > 0
+}
+
+// Baseline — сохранённый под именем снимок показателей отчёта, с которым сравниваются последующие анализы.
+type Baseline struct {
+	Name            string           `json:"name"`
+	Workspace       string           `json:"workspace,omitempty"` // рабочая область, которой принадлежит эталон
+	CreatedAt       time.Time        `json:"created_at"`
+	Cases           int              `json:"cases"`
+	AverageDuration float64          `json:"average_duration"` // сек
+	MedianDuration  float64          `json:"median_duration"`  // сек
+	FirstPassYield  float64          `json:"first_pass_yield"` // %
+	Metrics         []BaselineMetric `json:"metrics"`
+}
+
+// NewBaseline сохраняет показатели отчёта как эталон с именем name.
+func NewBaseline(name string, report *MetricsReport, createdAt time.Time) *Baseline {
+	baseline := &Baseline{
+		Name:            name,
+		CreatedAt:       createdAt,
+		Cases:           report.TotalProcessInstances,
+		AverageDuration: report.AverageProcessDuration,
+		MedianDuration:  report.MedianProcessDuration,
+		FirstPassYield:  report.FirstPassYield.Yield,
+		Metrics:         make([]BaselineMetric, 0, len(report.Metrics)),
+	}
+	for _, metric := range report.Metrics {
+		baseline.Metrics = append(baseline.Metrics, BaselineMetric{
+			Metric:         metric.Definition.Name,
+			Count:          metric.Count,
+			WastedDuration: metric.TotalWastedDuration,
+			LogLevel:       isLogLevel(metric),
+		})
+	}
+	return baseline
+}
+
+// BaselineMetricDelta — изменение метрики относительно эталона. Значения нормированы на кейс,
+// чтобы сравнивать логи разного объёма (кроме метрик уровня всего лога).
+type BaselineMetricDelta struct {
+	Metric         string  `json:"metric"`
+	BaselineRate   float64 `json:"baseline_rate"`   // вхождений на кейс в эталоне
+	CurrentRate    float64 `json:"current_rate"`    // вхождений на кейс сейчас
+	RateChange     float64 `json:"rate_change"`     // вхождений на кейс
+	BaselineWasted float64 `json:"baseline_wasted"` // сек на кейс в эталоне
+	CurrentWasted  float64 `json:"current_wasted"`  // сек на кейс сейчас
+	WastedChange   float64 `json:"wasted_change"`   // сек на кейс
+	Regression     bool    `json:"regression"`
+}
+
+// BaselineComparison — сравнение текущего отчёта с эталоном: изменения считаются как «сейчас» минус «эталон».
+type BaselineComparison struct {
+	Baseline              string                `json:"baseline"`
+	BaselineCreatedAt     time.Time             `json:"baseline_created_at"`
+	Tolerance             float64               `json:"tolerance"`
+	BaselineCases         int                   `json:"baseline_cases"`
+	Cases                 int                   `json:"cases"`
+	DurationChange        float64               `json:"duration_change"`         // изменение средней длительности, сек
+	DurationChangePercent float64               `json:"duration_change_percent"` // изменение средней длительности, %
+	DurationRegression    bool                  `json:"duration_regression"`
+	FirstPassYieldChange  float64               `json:"first_pass_yield_change"` // п.п.
+	FirstPassYieldDropped bool                  `json:"first_pass_yield_dropped"`
+	Metrics               []BaselineMetricDelta `json:"metrics"`
+	Regressions           int                   `json:"regressions"` // количество показателей с регрессией
+}
+
+// regressed проверяет, что показатель вырос больше чем на долю tolerance от эталонного значения.
+func regressed(baseline, current, tolerance float64) bool {
+	return current > baseline && current > baseline*(1+tolerance)
+}
+
+// perCase нормирует значение на количество кейсов. Метрики уровня лога не нормируются.
+func perCase(value float64, cases int, logLevel bool) float64 {
+	if logLevel {
+		return value
+	}
+	if cases == 0 {
+		return 0
+	}
+	return value / float64(cases)
+}
+
+// CompareWithBaseline сравнивает отчёт с эталоном и отмечает показатели, ухудшившиеся больше чем на tolerance.
+func CompareWithBaseline(baseline *Baseline, report *MetricsReport, tolerance float64) *BaselineComparison {
+	cases := report.TotalProcessInstances
+	comparison := &BaselineComparison{
+		Baseline:             baseline.Name,
+		BaselineCreatedAt:    baseline.CreatedAt,
+		Tolerance:            tolerance,
+		BaselineCases:        baseline.Cases,
+		Cases:                cases,
+		DurationChange:       report.AverageProcessDuration - baseline.AverageDuration,
+		DurationRegression:   regressed(baseline.AverageDuration, report.AverageProcessDuration, tolerance),
+		FirstPassYieldChange: math.Round((report.FirstPassYield.Yield-baseline.FirstPassYield)*10) / 10,
+		// Выход с первого прохода — показатель «больше — лучше»: регрессия означает падение
+		FirstPassYieldDropped: regressed(100-baseline.FirstPassYield, 100-report.FirstPassYield.Yield, tolerance),
+		Metrics:               []BaselineMetricDelta{},
+	}
+	if baseline.AverageDuration > 0 {
+		comparison.DurationChangePercent = math.Round(comparison.DurationChange/baseline.AverageDuration*1000) / 10
+	}
+
+	deltas := make(map[string]*BaselineMetricDelta)
+	delta := func(name string) *BaselineMetricDelta {
+		if deltas[name] == nil {
+			deltas[name] = &BaselineMetricDelta{Metric: name}
+		}
+		return deltas[name]
+	}
+	for _, metric := range baseline.Metrics {
+		d := delta(metric.Metric)
+		d.BaselineRate = perCase(float64(metric.Count), baseline.Cases, metric.LogLevel)
+		d.BaselineWasted = perCase(metric.WastedDuration, baseline.Cases, metric.LogLevel)
+	}
+	for _, metric := range report.Metrics {
+		d := delta(metric.Definition.Name)
+		logLevel := isLogLevel(metric)
+		d.CurrentRate = perCase(float64(metric.Count), cases, logLevel)
+		d.CurrentWasted = perCase(metric.TotalWastedDuration, cases, logLevel)
+	}
+
+	for _, d := range deltas {
+		d.RateChange = d.CurrentRate - d.BaselineRate
+		d.WastedChange = d.CurrentWasted - d.BaselineWasted
+		d.Regression = regressed(d.BaselineRate, d.CurrentRate, tolerance) || regressed(d.BaselineWasted, d.CurrentWasted, tolerance)
+		if d.Regression {
+			comparison.Regressions++
+		}
+		comparison.Metrics = append(comparison.Metrics, *d)
+	}
+	if comparison.DurationRegression {
+		comparison.Regressions++
+	}
+	if comparison.FirstPassYieldDropped {
+		comparison.Regressions++
+	}
+	sort.Slice(comparison.Metrics, func(i, j int) bool {
+		mi, mj := comparison.Metrics[i], comparison.Metrics[j]
+		if mi.Regression != mj.Regression {
+			return mi.Regression
+		}
+		if mi.WastedChange != mj.WastedChange {
+			return mi.WastedChange > mj.WastedChange
+		}
+		return mi.Metric < mj.Metric
+	})
+	return comparison
+}
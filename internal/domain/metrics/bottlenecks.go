@@ -0,0 +1,54 @@
+package metrics
+
+import "sort"
+
+// ActivityBottleneck — суммарное ожидание перед активностью (время перехода в неё из предыдущей).
+type ActivityBottleneck struct {
+	Activity      string  `json:"activity"`
+	TotalWait     float64 `json:"total_wait"`     // суммарное ожидание, сек
+	AvgWait       float64 `json:"avg_wait"`       // среднее ожидание на переход, сек
+	Transitions   int     `json:"transitions"`    // количество переходов в активность
+	AffectedCases int     `json:"affected_cases"` // количество кейсов, проходящих через активность
+}
+
+// RankBottlenecks агрегирует время ожидания по целевой активности перехода
+// и возвращает активности в порядке убывания суммарного ожидания.
+func RankBottlenecks(instances map[string]*ProcessInstance) []ActivityBottleneck {
+	byActivity := make(map[string]*ActivityBottleneck)
+	for _, instance := range instances {
+		seen := make(map[string]bool)
+		for i := 1; i < len(instance.Events); i++ {
+			wait := instance.Events[i].Timestamp.Sub(instance.Events[i-1].Timestamp).Seconds()
+			if wait < 0 {
+				continue
+			}
+
+			activity := instance.Events[i].Description
+			b := byActivity[activity]
+			if b == nil {
+				b = &ActivityBottleneck{Activity: activity}
+				byActivity[activity] = b
+			}
+			b.TotalWait += wait
+			b.Transitions++
+			if !seen[activity] {
+				seen[activity] = true
+				b.AffectedCases++
+			}
+		}
+	}
+
+	ranking := make([]ActivityBottleneck, 0, len(byActivity))
+	for _, b := range byActivity {
+		b.AvgWait = b.TotalWait / float64(b.Transitions)
+		ranking = append(ranking, *b)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].TotalWait != ranking[j].TotalWait {
+			return ranking[i].TotalWait > ranking[j].TotalWait
+		}
+		return ranking[i].Activity < ranking[j].Activity
+	})
+
+	return ranking
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Calendar — рабочий календарь для расчёта длительности в рабочем времени.
+type Calendar struct {
+	// WorkDays — рабочие дни недели (0 — воскресенье, 1 — понедельник, …). По умолчанию пн–пт.
+	WorkDays []time.Weekday `json:"work_days,omitempty"`
+	// WorkStart и WorkEnd — начало и конец рабочего дня в формате "15:04". По умолчанию 09:00–18:00.
+	WorkStart string `json:"work_start,omitempty"`
+	WorkEnd   string `json:"work_end,omitempty"`
+	// Holidays — нерабочие даты в формате "2006-01-02".
+	Holidays []string `json:"holidays,omitempty"`
+	// Timezone — часовой пояс календаря (имя IANA). По умолчанию UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// businessCalendar — разобранный Calendar, готовый к вычислениям.
+type businessCalendar struct {
+	workDays   [7]bool
+	start, end clock // начало и конец рабочего дня по местным часам
+	holidays   map[string]bool
+	location   *time.Location
+}
+
+// clock — время суток по часам, без привязки к дате.
+type clock struct {
+	hour, minute int
+}
+
+// on возвращает момент времени c в день day (по часам часового пояса day). Граница строится
+// по часам, а не смещением от полуночи, чтобы в дни перехода на летнее время не сдвигаться на час.
+func (c clock) on(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.hour, c.minute, 0, 0, day.Location())
+}
+
+// minutes возвращает число минут от начала суток.
+func (c clock) minutes() int {
+	return c.hour*60 + c.minute
+}
+
+func (c Calendar) compile() (*businessCalendar, error) {
+	bc := &businessCalendar{holidays: make(map[string]bool), location: time.UTC}
+
+	workDays := c.WorkDays
+	if len(workDays) == 0 {
+		workDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	for _, day := range workDays {
+		if day < time.Sunday || day > time.Saturday {
+			return nil, fmt.Errorf("некорректный день недели: %d", day)
+		}
+		bc.workDays[day] = true
+	}
+
+	var err error
+	if bc.start, err = parseClock(c.WorkStart, clock{hour: 9}); err != nil {
+		return nil, err
+	}
+	if bc.end, err = parseClock(c.WorkEnd, clock{hour: 18}); err != nil {
+		return nil, err
+	}
+	if bc.end.minutes() <= bc.start.minutes() {
+		return nil, errors.New("конец рабочего дня должен быть позже начала")
+	}
+
+	for _, holiday := range c.Holidays {
+		date, err := time.Parse(time.DateOnly, holiday)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная дата праздника %q: %w", holiday, err)
+		}
+		bc.holidays[date.Format(time.DateOnly)] = true
+	}
+
+	if c.Timezone != "" {
+		if bc.location, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("неизвестный часовой пояс %q: %w", c.Timezone, err)
+		}
+	}
+	return bc, nil
+}
+
+// Validate проверяет корректность календаря.
+func (c Calendar) Validate() error {
+	_, err := c.compile()
+	return err
+}
+
+func parseClock(value string, fallback clock) (clock, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return clock{}, fmt.Errorf("некорректное время %q, ожидается формат ЧЧ:ММ", value)
+	}
+	return clock{hour: t.Hour(), minute: t.Minute()}, nil
+}
+
+// duration возвращает рабочее время между from и to.
+func (bc *businessCalendar) duration(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	from, to = from.In(bc.location), to.In(bc.location)
+
+	var total time.Duration
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, bc.location)
+	for !day.After(to) {
+		if bc.workDays[day.Weekday()] && !bc.holidays[day.Format(time.DateOnly)] {
+			start, end := bc.start.on(day), bc.end.on(day)
+			if from.After(start) {
+				start = from
+			}
+			if to.Before(end) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+// SetCalendar задаёт рабочий календарь; nil отключает расчёт рабочего времени.
+func (a *Analyzer) SetCalendar(calendar *Calendar) error {
+	if calendar == nil {
+		a.calendar = nil
+		return nil
+	}
+	bc, err := calendar.compile()
+	if err != nil {
+		return err
+	}
+	a.calendar = bc
+	return nil
+}
+
+// StageBusinessDuration — средняя длительность перехода в календарном и рабочем времени.
+type StageBusinessDuration struct {
+	From                string  `json:"from"`
+	To                  string  `json:"to"`
+	Count               int     `json:"count"`
+	AvgDuration         float64 `json:"avg_duration"`          // сек, календарное время
+	AvgBusinessDuration float64 `json:"avg_business_duration"` // сек, рабочее время
+}
+
+// BusinessDurations содержит длительности кейсов и этапов с учётом рабочего календаря.
+type BusinessDurations struct {
+	AverageCaseDuration  float64                 `json:"average_case_duration"` // сек, рабочее время
+	MedianCaseDuration   float64                 `json:"median_case_duration"`  // сек, рабочее время
+	AverageStageDuration float64                 `json:"average_stage_duration"`
+	Stages               []StageBusinessDuration `json:"stages"`
+}
+
+// businessDurations считает длительности в рабочем времени; nil, если календарь не задан.
+func (a *Analyzer) businessDurations(instances map[string]*ProcessInstance) *BusinessDurations {
+	if a.calendar == nil {
+		return nil
+	}
+
+	result := &BusinessDurations{Stages: []StageBusinessDuration{}}
+	byStage := make(map[[2]string]*StageBusinessDuration)
+	var caseDurations []float64
+	var stageSum float64
+	var stageCount int
+
+	for _, instance := range instances {
+		events := instance.Events
+		if len(events) < 2 {
+			continue
+		}
+		caseDurations = append(caseDurations,
+			a.calendar.duration(events[0].Timestamp, events[len(events)-1].Timestamp).Seconds())
+
+		for i := 1; i < len(events); i++ {
+			prev, curr := events[i-1], events[i]
+			business := a.calendar.duration(prev.Timestamp, curr.Timestamp).Seconds()
+			key := [2]string{prev.Description, curr.Description}
+			stage := byStage[key]
+			if stage == nil {
+				stage = &StageBusinessDuration{From: key[0], To: key[1]}
+				byStage[key] = stage
+			}
+			stage.Count++
+			stage.AvgDuration += curr.Timestamp.Sub(prev.Timestamp).Seconds()
+			stage.AvgBusinessDuration += business
+			stageSum += business
+			stageCount++
+		}
+	}
+
+	if len(caseDurations) > 0 {
+		sort.Float64s(caseDurations)
+		var sum float64
+		for _, d := range caseDurations {
+			sum += d
+		}
+		result.AverageCaseDuration = sum / float64(len(caseDurations))
+		mid := len(caseDurations) / 2
+		if len(caseDurations)%2 == 0 {
+			result.MedianCaseDuration = (caseDurations[mid-1] + caseDurations[mid]) / 2
+		} else {
+			result.MedianCaseDuration = caseDurations[mid]
+		}
+	}
+	if stageCount > 0 {
+		result.AverageStageDuration = stageSum / float64(stageCount)
+	}
+
+	for _, stage := range byStage {
+		stage.AvgDuration /= float64(stage.Count)
+		stage.AvgBusinessDuration /= float64(stage.Count)
+		result.Stages = append(result.Stages, *stage)
+	}
+	sort.Slice(result.Stages, func(i, j int) bool {
+		a, b := result.Stages[i], result.Stages[j]
+		if a.AvgBusinessDuration != b.AvgBusinessDuration {
+			return a.AvgBusinessDuration > b.AvgBusinessDuration
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	return result
+}
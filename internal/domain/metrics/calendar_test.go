@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCalendarDurationDST проверяет рабочее время в дни перехода на летнее (31.03.2024) и зимнее
+// (27.10.2024) время в Europe/Berlin: рабочий день 09:00–18:00 остаётся девятичасовым.
+func TestCalendarDurationDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("нет базы часовых поясов: %v", err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, berlin)
+	}
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+
+	tests := []struct {
+		name     string
+		workDays []time.Weekday
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"сутки перехода на летнее время", everyDay, at(time.March, 31, 0, 0), at(time.April, 1, 0, 0), 9 * time.Hour},
+		{"сутки перехода на зимнее время", everyDay, at(time.October, 27, 0, 0), at(time.October, 28, 0, 0), 9 * time.Hour},
+		{"часть дня перехода на летнее время", everyDay, at(time.March, 31, 9, 30), at(time.March, 31, 18, 30), 8*time.Hour + 30*time.Minute},
+		{"часть дня перехода на зимнее время", everyDay, at(time.October, 27, 8, 0), at(time.October, 27, 17, 15), 8*time.Hour + 15*time.Minute},
+		{"через переход на летнее время", everyDay, at(time.March, 30, 8, 30), at(time.March, 31, 18, 30), 18 * time.Hour},
+		{"через переход на зимнее время", everyDay, at(time.October, 26, 10, 0), at(time.October, 27, 10, 0), 9 * time.Hour},
+		{"время события в UTC", everyDay, time.Date(2024, time.March, 31, 7, 30, 0, 0, time.UTC), time.Date(2024, time.March, 31, 15, 0, 0, 0, time.UTC), 7*time.Hour + 30*time.Minute},
+		{"выходные с переходом на летнее время", nil, at(time.March, 29, 0, 0), at(time.April, 2, 0, 0), 18 * time.Hour},
+		{"выходные с переходом на зимнее время", nil, at(time.October, 25, 0, 0), at(time.October, 29, 0, 0), 18 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := Calendar{WorkDays: tt.workDays, Timezone: "Europe/Berlin"}.compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bc.duration(tt.from, tt.to); got != tt.want {
+				t.Errorf("рабочее время %v, ожидается %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnknownCase возвращается при запросе несуществующего кейса.
+var ErrUnknownCase = errors.New("кейс не найден")
+
+// CaseEvent — событие кейса в ответе API.
+type CaseEvent struct {
+	Activity   string            `json:"activity"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Start      *time.Time        `json:"start,omitempty"`
+	Resource   string            `json:"resource,omitempty"`
+	Result     string            `json:"result,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// CaseFinding — вхождение метрики неэффективности в кейсе.
+type CaseFinding struct {
+	Metric     string           `json:"metric"` // ключ метрики
+	Name       string           `json:"name"`
+	Occurrence MetricOccurrence `json:"occurrence"`
+}
+
+// CaseDetail — полная трасса кейса и все найденные в нём неэффективности.
+type CaseDetail struct {
+	CaseID         string        `json:"case_id"`
+	Duration       float64       `json:"duration"`        // сек
+	WastedDuration float64       `json:"wasted_duration"` // сек, сумма по вхождениям
+	Events         []CaseEvent   `json:"events"`
+	Findings       []CaseFinding `json:"findings"`
+}
+
+// CaseDetail возвращает события кейса id и вхождения метрик, относящиеся к нему.
+func (r *MetricsReport) CaseDetail(instances map[string]*ProcessInstance, id string) (*CaseDetail, error) {
+	instance, ok := instances[id]
+	if !ok {
+		return nil, ErrUnknownCase
+	}
+
+	detail := &CaseDetail{CaseID: id, Events: make([]CaseEvent, len(instance.Events)), Findings: []CaseFinding{}}
+	for i, event := range instance.Events {
+		detail.Events[i] = CaseEvent{
+			Activity:   event.Description,
+			Timestamp:  event.Timestamp,
+			Resource:   event.Resource,
+			Result:     event.Result,
+			Attributes: event.Attributes,
+		}
+		if !event.Start.IsZero() {
+			start := event.Start
+			detail.Events[i].Start = &start
+		}
+	}
+	if n := len(instance.Events); n > 1 {
+		detail.Duration = instance.Events[n-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+	}
+
+	for _, metric := range r.Metrics {
+		for _, occurrence := range metric.Occurrences {
+			if occurrence.InstanceID != id {
+				continue
+			}
+			detail.Findings = append(detail.Findings, CaseFinding{
+				Metric:     metric.Key,
+				Name:       metric.Definition.Name,
+				Occurrence: occurrence,
+			})
+			detail.WastedDuration += occurrence.WastedDurationSeconds
+		}
+	}
+	return detail, nil
+}
+
+// Критерии ранжирования кейсов.
+const (
+	RankByWastedDuration = "wasted_duration"
+	RankByRework         = "rework"
+	RankByDuration       = "duration"
+)
+
+// CaseRanking — показатели кейса для поиска худших.
+type CaseRanking struct {
+	CaseID         string  `json:"case_id"`
+	Duration       float64 `json:"duration"`        // сек
+	WastedDuration float64 `json:"wasted_duration"` // сек, сумма по вхождениям метрик
+	ReworkCount    int     `json:"rework_count"`    // количество повторных выполнений активностей
+	Findings       int     `json:"findings"`        // количество вхождений метрик
+}
+
+// WorstCases ранжирует кейсы по критерию by и возвращает не более limit худших.
+func (r *MetricsReport) WorstCases(instances map[string]*ProcessInstance, by string, limit int) ([]CaseRanking, error) {
+	if by != RankByWastedDuration && by != RankByRework && by != RankByDuration {
+		return nil, fmt.Errorf("неизвестный критерий ранжирования: %s", by)
+	}
+
+	rankings := make(map[string]*CaseRanking, len(instances))
+	for id, instance := range instances {
+		ranking := &CaseRanking{CaseID: id}
+		if n := len(instance.Events); n > 1 {
+			ranking.Duration = instance.Events[n-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+		}
+		seen := make(map[string]bool, len(instance.Events))
+		for _, event := range instance.Events {
+			if seen[event.Description] {
+				ranking.ReworkCount++
+			}
+			seen[event.Description] = true
+		}
+		rankings[id] = ranking
+	}
+	for _, metric := range r.Metrics {
+		for _, occurrence := range metric.Occurrences {
+			if ranking, ok := rankings[occurrence.InstanceID]; ok {
+				ranking.WastedDuration += occurrence.WastedDurationSeconds
+				ranking.Findings++
+			}
+		}
+	}
+
+	key := func(c *CaseRanking) float64 {
+		switch by {
+		case RankByRework:
+			return float64(c.ReworkCount)
+		case RankByDuration:
+			return c.Duration
+		default:
+			return c.WastedDuration
+		}
+	}
+	result := make([]CaseRanking, 0, len(rankings))
+	sorted := make([]*CaseRanking, 0, len(rankings))
+	for _, ranking := range rankings {
+		sorted = append(sorted, ranking)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if ki, kj := key(sorted[i]), key(sorted[j]); ki != kj {
+			return ki > kj
+		}
+		return sorted[i].CaseID < sorted[j].CaseID
+	})
+	for _, ranking := range sorted {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, *ranking)
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import "fmt"
+
+// MetricCollector — расширение анализатора пользовательской метрикой неэффективности.
+//
+// Пример:
+//
+//	type nightWork struct{}
+//
+//	func (nightWork) Key() string { return "Night Work" }
+//	func (nightWork) Definition() MetricDefinition {
+//		return MetricDefinition{Name: "Работа ночью", Category: "Длительность", ...}
+//	}
+//	func (nightWork) Collect(instances map[string]*ProcessInstance) []MetricOccurrence { ... }
+//
+//	analyzer.RegisterCollector(nightWork{})
+type MetricCollector interface {
+	// Key возвращает уникальный ключ метрики (используется в отчёте и при настройке порогов).
+	Key() string
+	// Definition возвращает описание метрики.
+	Definition() MetricDefinition
+	// Collect находит вхождения метрики в экземплярах процесса.
+	Collect(instances map[string]*ProcessInstance) []MetricOccurrence
+}
+
+// RegisterCollector добавляет пользовательскую метрику в анализатор.
+func (a *Analyzer) RegisterCollector(collector MetricCollector) error {
+	key := collector.Key()
+	if key == "" {
+		return fmt.Errorf("ключ метрики не может быть пустым")
+	}
+	if _, exists := a.definitions[key]; exists {
+		return fmt.Errorf("метрика %s уже зарегистрирована", key)
+	}
+	a.definitions[key] = collector.Definition()
+	a.collectors = append(a.collectors, collector)
+	return nil
+}
+
+// customOccurrences собирает вхождения пользовательской метрики collector.
+func customOccurrences(collector MetricCollector, instances map[string]*ProcessInstance) []rawOccurrence {
+	occurrences := collector.Collect(instances)
+	results := make([]rawOccurrence, 0, len(occurrences))
+	key := collector.Key()
+	for _, occurrence := range occurrences {
+		results = append(results, rawOccurrence{
+			metricType: key,
+			occurrence: occurrence,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Period — интервал времени [From, To). Отсутствующая граница означает отсутствие ограничения.
+type Period struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// contains проверяет, что момент t попадает в период.
+func (p Period) contains(t time.Time) bool {
+	if p.From != nil && t.Before(*p.From) {
+		return false
+	}
+	if p.To != nil && !t.Before(*p.To) {
+		return false
+	}
+	return true
+}
+
+// PeriodSummary — основные показатели процесса за период.
+type PeriodSummary struct {
+	Period          Period  `json:"period"`
+	Cases           int     `json:"cases"`
+	AverageDuration float64 `json:"average_duration"` // сек
+	MedianDuration  float64 `json:"median_duration"`  // сек
+	ReworkRate      float64 `json:"rework_rate"`      // доля кейсов с переделками, %
+	CompletionRate  float64 `json:"completion_rate"`  // доля завершённых кейсов, %
+}
+
+// MetricChange — изменение метрики неэффективности между периодами.
+type MetricChange struct {
+	Metric       string  `json:"metric"`
+	BeforeCount  int     `json:"before_count"`
+	AfterCount   int     `json:"after_count"`
+	CountChange  int     `json:"count_change"`
+	BeforeValue  float64 `json:"before_value"`
+	AfterValue   float64 `json:"after_value"`
+	ValueChange  float64 `json:"value_change"`
+	BeforeWasted float64 `json:"before_wasted"` // сек
+	AfterWasted  float64 `json:"after_wasted"`  // сек
+	WastedChange float64 `json:"wasted_change"` // сек
+}
+
+// PeriodComparison — сравнение двух периодов: изменения считаются как «после» минус «до».
+type PeriodComparison struct {
+	Before                PeriodSummary  `json:"before"`
+	After                 PeriodSummary  `json:"after"`
+	DurationChange        float64        `json:"duration_change"`         // изменение средней длительности, сек
+	DurationChangePercent float64        `json:"duration_change_percent"` // изменение средней длительности, %
+	ReworkRateChange      float64        `json:"rework_rate_change"`      // п.п.
+	CompletionRateChange  float64        `json:"completion_rate_change"`  // п.п.
+	Metrics               []MetricChange `json:"metrics"`
+}
+
+// instancesInPeriod отбирает экземпляры, начавшиеся в периоде.
+func instancesInPeriod(instances map[string]*ProcessInstance, period Period) map[string]*ProcessInstance {
+	selected := make(map[string]*ProcessInstance)
+	for id, instance := range instances {
+		if len(instance.Events) > 0 && period.contains(instance.Events[0].Timestamp) {
+			selected[id] = instance
+		}
+	}
+	return selected
+}
+
+// ComparePeriods делит кейсы по времени начала на два периода и сравнивает их отчёты.
+func (a *Analyzer) ComparePeriods(instances map[string]*ProcessInstance, before, after Period) *PeriodComparison {
+	return a.compare(instancesInPeriod(instances, before), instancesInPeriod(instances, after), before, after)
+}
+
+// CompareLogs сравнивает отчёты двух логов (например, до и после улучшения процесса) целиком.
+func (a *Analyzer) CompareLogs(beforeInstances, afterInstances map[string]*ProcessInstance) *PeriodComparison {
+	return a.compare(beforeInstances, afterInstances, Period{}, Period{})
+}
+
+func (a *Analyzer) compare(beforeInstances, afterInstances map[string]*ProcessInstance, before, after Period) *PeriodComparison {
+	beforeReport := a.Analyze(beforeInstances)
+	afterReport := a.Analyze(afterInstances)
+
+	comparison := &PeriodComparison{
+		Before:  a.summarizePeriod(before, beforeInstances, beforeReport),
+		After:   a.summarizePeriod(after, afterInstances, afterReport),
+		Metrics: []MetricChange{},
+	}
+	comparison.DurationChange = comparison.After.AverageDuration - comparison.Before.AverageDuration
+	if comparison.Before.AverageDuration > 0 {
+		comparison.DurationChangePercent = math.Round(comparison.DurationChange/comparison.Before.AverageDuration*1000) / 10
+	}
+	comparison.ReworkRateChange = comparison.After.ReworkRate - comparison.Before.ReworkRate
+	comparison.CompletionRateChange = comparison.After.CompletionRate - comparison.Before.CompletionRate
+
+	afterMetrics := make(map[string]InefficiencyMetric, len(afterReport.Metrics))
+	for _, metric := range afterReport.Metrics {
+		afterMetrics[metric.Definition.Name] = metric
+	}
+	for _, bm := range beforeReport.Metrics {
+		am := afterMetrics[bm.Definition.Name]
+		comparison.Metrics = append(comparison.Metrics, MetricChange{
+			Metric:       bm.Definition.Name,
+			BeforeCount:  bm.Count,
+			AfterCount:   am.Count,
+			CountChange:  am.Count - bm.Count,
+			BeforeValue:  bm.TotalValue,
+			AfterValue:   am.TotalValue,
+			ValueChange:  am.TotalValue - bm.TotalValue,
+			BeforeWasted: bm.TotalWastedDuration,
+			AfterWasted:  am.TotalWastedDuration,
+			WastedChange: am.TotalWastedDuration - bm.TotalWastedDuration,
+		})
+	}
+	sort.Slice(comparison.Metrics, func(i, j int) bool {
+		ci, cj := comparison.Metrics[i], comparison.Metrics[j]
+		if ci.CountChange != cj.CountChange {
+			return ci.CountChange > cj.CountChange
+		}
+		return ci.Metric < cj.Metric
+	})
+
+	return comparison
+}
+
+func (a *Analyzer) summarizePeriod(period Period, instances map[string]*ProcessInstance, report *MetricsReport) PeriodSummary {
+	summary := PeriodSummary{
+		Period:          period,
+		Cases:           len(instances),
+		AverageDuration: report.AverageProcessDuration,
+		MedianDuration:  report.MedianProcessDuration,
+	}
+	if len(instances) == 0 {
+		return summary
+	}
+
+	var reworked, completed int
+	for _, instance := range instances {
+		if a.IsCompleted(instance) {
+			completed++
+		}
+		if hasRework(InstanceVariant(instance)) {
+			reworked++
+		}
+	}
+	summary.ReworkRate = math.Round(float64(reworked)/float64(len(instances))*1000) / 10
+	summary.CompletionRate = math.Round(float64(completed)/float64(len(instances))*1000) / 10
+	return summary
+}
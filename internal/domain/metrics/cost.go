@@ -0,0 +1,55 @@
+package metrics
+
+import "fmt"
+
+// CostModel задаёт стоимость часа работы для расчёта финансового эффекта потерь времени.
+// Ставка ресурса имеет приоритет над ставкой активности, ставка активности — над общей.
+type CostModel struct {
+	HourlyCost    float64            `json:"hourly_cost"`
+	ActivityCosts map[string]float64 `json:"activity_costs,omitempty"` // активность → стоимость часа
+	ResourceCosts map[string]float64 `json:"resource_costs,omitempty"` // ресурс → стоимость часа
+	Currency      string             `json:"currency,omitempty"`
+}
+
+// Validate проверяет, что все ставки неотрицательны.
+func (m CostModel) Validate() error {
+	if m.HourlyCost < 0 {
+		return fmt.Errorf("стоимость часа не может быть отрицательной")
+	}
+	for activity, cost := range m.ActivityCosts {
+		if cost < 0 {
+			return fmt.Errorf("стоимость часа активности %s не может быть отрицательной", activity)
+		}
+	}
+	for resource, cost := range m.ResourceCosts {
+		if cost < 0 {
+			return fmt.Errorf("стоимость часа ресурса %s не может быть отрицательной", resource)
+		}
+	}
+	return nil
+}
+
+// hourlyRate возвращает ставку для вхождения метрики.
+func (m CostModel) hourlyRate(occurrence MetricOccurrence) float64 {
+	if rate, ok := m.ResourceCosts[occurrence.Resource]; ok && occurrence.Resource != "" {
+		return rate
+	}
+	if rate, ok := m.ActivityCosts[occurrence.Activity]; ok && occurrence.Activity != "" {
+		return rate
+	}
+	return m.HourlyCost
+}
+
+// Cost переводит потерянное время вхождения в деньги.
+func (m CostModel) Cost(occurrence MetricOccurrence) float64 {
+	return occurrence.WastedDurationSeconds / 3600 * m.hourlyRate(occurrence)
+}
+
+// SetCostModel задаёт модель затрат для расчёта TotalWastedCost.
+func (a *Analyzer) SetCostModel(model CostModel) error {
+	if err := model.Validate(); err != nil {
+		return err
+	}
+	a.costModel = model
+	return nil
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// DefaultHistogramBins — количество интервалов гистограммы по умолчанию.
+const DefaultHistogramBins = 20
+
+// Percentiles — процентили длительности, сек.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// HistogramBucket — интервал гистограммы [From, To).
+type HistogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// VariantDurations — распределение длительности кейсов одного варианта (последовательности активностей).
+type VariantDurations struct {
+	Variant     []string    `json:"variant"`
+	Count       int         `json:"count"`
+	Mean        float64     `json:"mean"`
+	Percentiles Percentiles `json:"percentiles"`
+}
+
+// DurationDistribution — распределение длительности кейсов для построения графиков.
+type DurationDistribution struct {
+	Count       int                `json:"count"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	Percentiles Percentiles        `json:"percentiles"`
+	Histogram   []HistogramBucket  `json:"histogram"`
+	Variants    []VariantDurations `json:"variants"` // по убыванию частоты
+}
+
+// CaseDurationDistribution строит гистограмму и процентили длительности кейсов, в том числе по вариантам.
+// Кейсы из одного события не учитываются.
+func CaseDurationDistribution(instances map[string]*ProcessInstance, bins int) DurationDistribution {
+	if bins <= 0 {
+		bins = DefaultHistogramBins
+	}
+	result := DurationDistribution{Histogram: []HistogramBucket{}, Variants: []VariantDurations{}}
+
+	numberActivities(instances)
+	var durations []float64
+	var byVariant [][]float64
+	variants := newPathIndex()
+	for _, instance := range instances {
+		if len(instance.Events) < 2 {
+			continue
+		}
+		duration := instance.Events[len(instance.Events)-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+		durations = append(durations, duration)
+
+		number := variants.of(instance.Events)
+		if number == len(byVariant) {
+			byVariant = append(byVariant, nil)
+		}
+		byVariant[number] = append(byVariant[number], duration)
+	}
+	if len(durations) == 0 {
+		return result
+	}
+
+	sort.Float64s(durations)
+	result.Count = len(durations)
+	result.Min = durations[0]
+	result.Max = durations[len(durations)-1]
+	result.Mean = mean(durations)
+	result.Percentiles = percentiles(durations)
+	result.Histogram = histogram(durations, bins)
+
+	for number, values := range byVariant {
+		sort.Float64s(values)
+		result.Variants = append(result.Variants, VariantDurations{
+			Variant:     variants.names[number],
+			Count:       len(values),
+			Mean:        mean(values),
+			Percentiles: percentiles(values),
+		})
+	}
+	sort.Slice(result.Variants, func(i, j int) bool {
+		if result.Variants[i].Count != result.Variants[j].Count {
+			return result.Variants[i].Count > result.Variants[j].Count
+		}
+		return slices.Compare(result.Variants[i].Variant, result.Variants[j].Variant) < 0
+	})
+
+	return result
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile возвращает процентиль p (0–100) отсортированной выборки с линейной интерполяцией.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// DurationPercentiles возвращает процентили выборки длительностей (порядок значений не важен).
+func DurationPercentiles(values []float64) Percentiles {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return percentiles(sorted)
+}
+
+func percentiles(sorted []float64) Percentiles {
+	return Percentiles{
+		P50: percentile(sorted, 50),
+		P75: percentile(sorted, 75),
+		P90: percentile(sorted, 90),
+		P95: percentile(sorted, 95),
+		P99: percentile(sorted, 99),
+	}
+}
+
+// histogram делит диапазон отсортированной выборки на bins равных интервалов.
+func histogram(sorted []float64, bins int) []HistogramBucket {
+	low, high := sorted[0], sorted[len(sorted)-1]
+	if high == low {
+		return []HistogramBucket{{From: low, To: high, Count: len(sorted)}}
+	}
+
+	width := (high - low) / float64(bins)
+	buckets := make([]HistogramBucket, bins)
+	for i := range buckets {
+		buckets[i].From = low + width*float64(i)
+		buckets[i].To = low + width*float64(i+1)
+	}
+	for _, v := range sorted {
+		i := int((v - low) / width)
+		if i >= bins {
+			// Максимальное значение попадает в последний интервал
+			i = bins - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+)
+
+// DurationIndex — длительности этапов и кейсов лога, отсортированные по возрастанию: по каждому переходу
+// (from→to), по целевой активности этапа и по кейсам. Индекс строится один раз после загрузки событий
+// и передаётся в AnalyzeIndexed, так что пороги аномалий и процентили не требуют повторного сбора
+// и сортировки длительностей. Каждое значение помнит номер своего кейса: индекс части лога, в которой
+// кейсы оставлены целиком, получается из индекса всего лога без повторной сортировки (см. Subset).
+//
+// Заполняется через AddStage и AddCase, после чего вызывается Sort; отсортированный индекс не изменяется
+// и безопасен для одновременного чтения.
+type DurationIndex struct {
+	transitions map[[2]ActivityID]*durationSeries
+	activities  map[ActivityID]*durationSeries
+	cases       durationSeries
+}
+
+// durationSeries — выборка длительностей: values по возрастанию и номера кейсов значений.
+type durationSeries struct {
+	samples []durationSample
+	values  []float64
+}
+
+type durationSample struct {
+	value    float64
+	instance int32
+}
+
+// NewDurationIndex создаёт пустой индекс длительностей.
+func NewDurationIndex() *DurationIndex {
+	return &DurationIndex{
+		transitions: make(map[[2]ActivityID]*durationSeries),
+		activities:  make(map[ActivityID]*durationSeries),
+	}
+}
+
+// AddStage добавляет длительность этапа from→to (сек) кейса instance. Этапы с нулевым временем
+// или обратным порядком событий не добавляются, как и при проходе анализатора по кейсам.
+func (x *DurationIndex) AddStage(instance int32, from, to ActivityID, seconds float64) {
+	sample := durationSample{value: seconds, instance: instance}
+	transition := x.transitions[[2]ActivityID{from, to}]
+	if transition == nil {
+		transition = &durationSeries{}
+		x.transitions[[2]ActivityID{from, to}] = transition
+	}
+	transition.samples = append(transition.samples, sample)
+	activity := x.activities[to]
+	if activity == nil {
+		activity = &durationSeries{}
+		x.activities[to] = activity
+	}
+	activity.samples = append(activity.samples, sample)
+}
+
+// AddCase добавляет длительность кейса instance из двух и более событий (сек).
+func (x *DurationIndex) AddCase(instance int32, seconds float64) {
+	x.cases.samples = append(x.cases.samples, durationSample{value: seconds, instance: instance})
+}
+
+// Sort упорядочивает выборки индекса; вызывается после заполнения.
+func (x *DurationIndex) Sort() {
+	x.each(func(series *durationSeries) {
+		slices.SortFunc(series.samples, func(a, b durationSample) int { return cmp.Compare(a.value, b.value) })
+		series.fill()
+	})
+}
+
+// Subset возвращает индекс кейсов, для которых renumber возвращает новый номер; остальные кейсы
+// отбрасываются. События оставленных кейсов должны совпадать с событиями в исходном индексе.
+func (x *DurationIndex) Subset(renumber func(instance int32) (int32, bool)) *DurationIndex {
+	numbers := make(map[int32]int32)
+	dropped := make(map[int32]bool)
+	keep := func(instance int32) (int32, bool) {
+		if number, ok := numbers[instance]; ok {
+			return number, true
+		}
+		if dropped[instance] {
+			return 0, false
+		}
+		number, ok := renumber(instance)
+		if ok {
+			numbers[instance] = number
+		} else {
+			dropped[instance] = true
+		}
+		return number, ok
+	}
+	subset := func(series *durationSeries) *durationSeries {
+		kept := &durationSeries{}
+		for _, sample := range series.samples {
+			if number, ok := keep(sample.instance); ok {
+				kept.samples = append(kept.samples, durationSample{value: sample.value, instance: number})
+			}
+		}
+		kept.fill()
+		return kept
+	}
+
+	result := NewDurationIndex()
+	for key, series := range x.transitions {
+		if kept := subset(series); len(kept.values) > 0 {
+			result.transitions[key] = kept
+		}
+	}
+	for activity, series := range x.activities {
+		if kept := subset(series); len(kept.values) > 0 {
+			result.activities[activity] = kept
+		}
+	}
+	result.cases = *subset(&x.cases)
+	return result
+}
+
+// each вызывает fn для каждой выборки индекса.
+func (x *DurationIndex) each(fn func(series *durationSeries)) {
+	for _, series := range x.transitions {
+		fn(series)
+	}
+	for _, series := range x.activities {
+		fn(series)
+	}
+	fn(&x.cases)
+}
+
+// fill заполняет values по упорядоченным samples.
+func (s *durationSeries) fill() {
+	s.values = make([]float64, len(s.samples))
+	for i, sample := range s.samples {
+		s.values[i] = sample.value
+	}
+}
+
+// durationIndex строит индекс по длительностям, собранным при проходе по кейсам. Номера кейсов
+// в таком индексе не сохраняются: он используется только в текущем анализе.
+func (t *caseTally) durationIndex() *DurationIndex {
+	index := NewDurationIndex()
+	series := func(values []float64) *durationSeries {
+		s := &durationSeries{values: values}
+		slices.Sort(s.values)
+		return s
+	}
+	for key, values := range t.byTransition {
+		index.transitions[key] = series(values)
+	}
+	for activity, values := range t.byActivity {
+		index.activities[activity] = series(values)
+	}
+	index.cases = *series(t.caseDurations)
+	return index
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// LeadTimeEfficiency — отношение времени обработки (touch time) к полному времени прохождения (lead time).
+type LeadTimeEfficiency struct {
+	TouchTime             float64              `json:"touch_time"`              // сек обработки по всем кейсам
+	LeadTime              float64              `json:"lead_time"`               // сек прохождения по всем кейсам
+	Efficiency            float64              `json:"efficiency"`              // touch/lead по всему логу, %
+	AverageCaseEfficiency float64              `json:"average_case_efficiency"` // средняя эффективность кейса, %
+	Activities            []ActivityEfficiency `json:"activities"`
+}
+
+// ActivityEfficiency — эффективность активности: обработка относительно времени от предыдущего события.
+type ActivityEfficiency struct {
+	Activity   string  `json:"activity"`
+	Count      int     `json:"count"`
+	TouchTime  float64 `json:"touch_time"` // сек
+	LeadTime   float64 `json:"lead_time"`  // сек
+	Efficiency float64 `json:"efficiency"` // %
+}
+
+// CaseEfficiency — эффективность одного кейса.
+type CaseEfficiency struct {
+	CaseID     string  `json:"case_id"`
+	TouchTime  float64 `json:"touch_time"` // сек
+	LeadTime   float64 `json:"lead_time"`  // сек
+	Efficiency float64 `json:"efficiency"` // %
+}
+
+// efficiencyRatio возвращает долю touch от lead в процентах, не больше 100.
+func efficiencyRatio(touch, lead float64) float64 {
+	if lead <= 0 {
+		return 0
+	}
+	return math.Round(math.Min(touch/lead, 1)*1000) / 10
+}
+
+// hasProcessingTimes проверяет, что в логе известно время обработки хотя бы одного события.
+func hasProcessingTimes(instances map[string]*ProcessInstance) bool {
+	for _, instance := range instances {
+		for _, event := range instance.Events {
+			if eventProcessing(event) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CaseEfficiencies считает эффективность каждого кейса, начиная с наименее эффективных.
+// Lead time кейса — от начала первой активности до завершения последней.
+// Возвращает пустой список, если в логе нет времени обработки.
+func CaseEfficiencies(instances map[string]*ProcessInstance) []CaseEfficiency {
+	result := make([]CaseEfficiency, 0, len(instances))
+	if !hasProcessingTimes(instances) {
+		return result
+	}
+	for id, instance := range instances {
+		if len(instance.Events) == 0 {
+			continue
+		}
+		c := CaseEfficiency{CaseID: id}
+		first := instance.Events[0].Timestamp
+		for _, event := range instance.Events {
+			c.TouchTime += eventProcessing(event)
+			if !event.Start.IsZero() && event.Start.Before(first) {
+				first = event.Start
+			}
+		}
+		c.LeadTime = instance.Events[len(instance.Events)-1].Timestamp.Sub(first).Seconds()
+		c.Efficiency = efficiencyRatio(c.TouchTime, c.LeadTime)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Efficiency != result[j].Efficiency {
+			return result[i].Efficiency < result[j].Efficiency
+		}
+		return result[i].CaseID < result[j].CaseID
+	})
+	return result
+}
+
+// leadTimeEfficiency считает эффективность процесса и активностей.
+// Возвращает nil, если в логе нет времени начала или длительности обработки.
+func leadTimeEfficiency(instances map[string]*ProcessInstance) *LeadTimeEfficiency {
+	if !hasProcessingTimes(instances) {
+		return nil
+	}
+
+	report := &LeadTimeEfficiency{Activities: []ActivityEfficiency{}}
+	byActivity := make(map[string]*ActivityEfficiency)
+	for _, instance := range instances {
+		for i, event := range instance.Events {
+			touch := eventProcessing(event)
+			lead := touch
+			if i > 0 {
+				lead = math.Max(event.Timestamp.Sub(instance.Events[i-1].Timestamp).Seconds(), touch)
+			}
+			acc := byActivity[event.Description]
+			if acc == nil {
+				acc = &ActivityEfficiency{Activity: event.Description}
+				byActivity[event.Description] = acc
+			}
+			acc.Count++
+			acc.TouchTime += touch
+			acc.LeadTime += lead
+		}
+	}
+	for _, acc := range byActivity {
+		acc.Efficiency = efficiencyRatio(acc.TouchTime, acc.LeadTime)
+		report.Activities = append(report.Activities, *acc)
+	}
+	sort.Slice(report.Activities, func(i, j int) bool {
+		if report.Activities[i].Efficiency != report.Activities[j].Efficiency {
+			return report.Activities[i].Efficiency < report.Activities[j].Efficiency
+		}
+		return report.Activities[i].Activity < report.Activities[j].Activity
+	})
+
+	cases := CaseEfficiencies(instances)
+	for _, c := range cases {
+		report.TouchTime += c.TouchTime
+		report.LeadTime += c.LeadTime
+		report.AverageCaseEfficiency += c.Efficiency
+	}
+	report.Efficiency = efficiencyRatio(report.TouchTime, report.LeadTime)
+	if len(cases) > 0 {
+		report.AverageCaseEfficiency = math.Round(report.AverageCaseEfficiency/float64(len(cases))*10) / 10
+	}
+	return report
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrorSemantics задаёт, какие значения результата события считаются ошибкой.
+// Сравнение значений не зависит от регистра. Без настроек ошибкой считается "error".
+type ErrorSemantics struct {
+	Values  []string `json:"values,omitempty"`  // например, ["error", "fail", "rejected", "отказ"]
+	Pattern string   `json:"pattern,omitempty"` // регулярное выражение для результата
+}
+
+// ErrorMatcher проверяет результаты событий по ErrorSemantics.
+type ErrorMatcher struct {
+	values  map[string]bool
+	pattern *regexp.Regexp
+}
+
+// DefaultErrorMatcher считает ошибкой только результат "error".
+var DefaultErrorMatcher = &ErrorMatcher{values: map[string]bool{"error": true}}
+
+// NewErrorMatcher компилирует правила распознавания ошибок.
+func NewErrorMatcher(semantics ErrorSemantics) (*ErrorMatcher, error) {
+	if len(semantics.Values) == 0 && semantics.Pattern == "" {
+		return DefaultErrorMatcher, nil
+	}
+
+	matcher := &ErrorMatcher{values: make(map[string]bool, len(semantics.Values))}
+	for _, value := range semantics.Values {
+		matcher.values[normalizeResult(value)] = true
+	}
+	if semantics.Pattern != "" {
+		pattern, err := regexp.Compile(semantics.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное регулярное выражение ошибки: %w", err)
+		}
+		matcher.pattern = pattern
+	}
+	return matcher, nil
+}
+
+func normalizeResult(result string) string {
+	return strings.ToLower(strings.TrimSpace(result))
+}
+
+// IsError проверяет, что результат события означает ошибку.
+func (m *ErrorMatcher) IsError(result string) bool {
+	if result == "" {
+		return false
+	}
+	if m.values[normalizeResult(result)] {
+		return true
+	}
+	return m.pattern != nil && m.pattern.MatchString(result)
+}
+
+// HasError проверяет, что среди событий есть ошибочное.
+func (m *ErrorMatcher) HasError(events []Event) bool {
+	for _, event := range events {
+		if m.IsError(event.Result) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetErrorSemantics задаёт, какие результаты событий считаются ошибкой.
+func (a *Analyzer) SetErrorSemantics(semantics ErrorSemantics) error {
+	matcher, err := NewErrorMatcher(semantics)
+	if err != nil {
+		return err
+	}
+	a.errors = matcher
+	return nil
+}
+
+// ActivityErrorRate — доля ошибочных выполнений активности.
+type ActivityErrorRate struct {
+	Activity   string  `json:"activity"`
+	Executions int     `json:"executions"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"` // %
+}
+
+// activityErrorRates считает долю ошибок по активностям; nil, если в логе нет результатов событий.
+func (a *Analyzer) activityErrorRates(instances map[string]*ProcessInstance) []ActivityErrorRate {
+	byActivity := make(map[string]*ActivityErrorRate)
+	hasResults := false
+	for _, instance := range instances {
+		for _, event := range instance.Events {
+			if event.Result != "" {
+				hasResults = true
+			}
+			rate := byActivity[event.Description]
+			if rate == nil {
+				rate = &ActivityErrorRate{Activity: event.Description}
+				byActivity[event.Description] = rate
+			}
+			rate.Executions++
+			if a.errors.IsError(event.Result) {
+				rate.Errors++
+			}
+		}
+	}
+	if !hasResults {
+		return nil
+	}
+
+	rates := make([]ActivityErrorRate, 0, len(byActivity))
+	for _, rate := range byActivity {
+		rate.ErrorRate = math.Round(float64(rate.Errors)/float64(rate.Executions)*1000) / 10
+		rates = append(rates, *rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].ErrorRate != rates[j].ErrorRate {
+			return rates[i].ErrorRate > rates[j].ErrorRate
+		}
+		if rates[i].Errors != rates[j].Errors {
+			return rates[i].Errors > rates[j].Errors
+		}
+		return rates[i].Activity < rates[j].Activity
+	})
+	return rates
+}
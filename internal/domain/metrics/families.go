@@ -0,0 +1,138 @@
+package metrics
+
+import "math"
+
+// metricFamily — метрики, вхождения которых находит один сборщик. parts — части прохода по кейсам,
+// нужные сборщику; если collect не задан, вхождения метрик находятся при самом проходе (caseTally.results).
+type metricFamily struct {
+	keys    []string
+	parts   tallyParts
+	collect func(tally *caseTally, durations *DurationIndex, shards []map[string]*ProcessInstance, instances map[string]*ProcessInstance) []rawOccurrence
+}
+
+// families возвращает семейства метрик анализатора в порядке их сборки в отчёте:
+// встроенные метрики, затем пользовательские (каждая — отдельное семейство).
+func (a *Analyzer) families() []metricFamily {
+	families := []metricFamily{
+		{keys: []string{"Self-Loop", "Return to Previous Stage", "Ping-Pong", "Return to Start", "Rework"}, parts: tallyLoops},
+		{keys: []string{"Manual/Unlogged Stage"}, parts: tallyManual},
+		{
+			keys:  []string{"Anomalously Long Stage", "Anomalously Long Case", "Increasing Stage Duration Trend", "Increasing Process Instance Duration Trend"},
+			parts: tallyDurations,
+			collect: func(tally *caseTally, durations *DurationIndex, shards []map[string]*ProcessInstance, _ map[string]*ProcessInstance) []rawOccurrence {
+				return a.collectDurationMetrics(tally, durations, shards)
+			},
+		},
+		{keys: []string{"High Process Variability"}, parts: tallyCounts, collect: tallyCollector(a.collectComplexityMetrics)},
+		{keys: []string{"Low Process Completion Rate"}, parts: tallyCounts, collect: tallyCollector(a.collectCompletionMetrics)},
+		{keys: []string{"High Error Rate"}, parts: tallyCounts, collect: tallyCollector(a.collectErrorMetrics)},
+		{keys: []string{"SLA Breach"}, collect: instanceCollector(a.collectSLAMetrics)},
+		{keys: []string{"Stuck Case"}, collect: instanceCollector(a.collectStuckCaseMetrics)},
+	}
+	for _, collector := range a.collectors {
+		families = append(families, metricFamily{
+			keys: []string{collector.Key()},
+			collect: instanceCollector(func(instances map[string]*ProcessInstance) []rawOccurrence {
+				return customOccurrences(collector, instances)
+			}),
+		})
+	}
+	return families
+}
+
+// tallyCollector приводит сборщик по итогам прохода по кейсам к виду metricFamily.collect.
+func tallyCollector(collect func(tally *caseTally) []rawOccurrence) func(*caseTally, *DurationIndex, []map[string]*ProcessInstance, map[string]*ProcessInstance) []rawOccurrence {
+	return func(tally *caseTally, _ *DurationIndex, _ []map[string]*ProcessInstance, _ map[string]*ProcessInstance) []rawOccurrence {
+		return collect(tally)
+	}
+}
+
+// instanceCollector приводит сборщик по экземплярам процесса к виду metricFamily.collect.
+func instanceCollector(collect func(instances map[string]*ProcessInstance) []rawOccurrence) func(*caseTally, *DurationIndex, []map[string]*ProcessInstance, map[string]*ProcessInstance) []rawOccurrence {
+	return func(_ *caseTally, _ *DurationIndex, _ []map[string]*ProcessInstance, instances map[string]*ProcessInstance) []rawOccurrence {
+		return collect(instances)
+	}
+}
+
+// AnalyzeMetric рассчитывает только семейство метрик, в которое входит метрика key (например, все метрики
+// зацикливания), не строя полный отчёт: проход по кейсам собирает лишь нужные семейству данные.
+// durations — индекс длительностей тех же экземпляров (nil — длительности собираются при проходе).
+// Оценка критичности и место в списке приоритетов считаются относительно всех метрик отчёта,
+// поэтому у возвращаемых метрик они не заполнены.
+func (a *Analyzer) AnalyzeMetric(instances map[string]*ProcessInstance, durations *DurationIndex, key string) ([]InefficiencyMetric, error) {
+	for _, family := range a.families() {
+		for _, familyKey := range family.keys {
+			if familyKey == key {
+				return a.analyzeFamily(instances, durations, family), nil
+			}
+		}
+	}
+	return nil, ErrUnknownMetric
+}
+
+// analyzeFamily собирает вхождения метрик семейства family и агрегирует их.
+func (a *Analyzer) analyzeFamily(instances map[string]*ProcessInstance, durations *DurationIndex, family metricFamily) []InefficiencyMetric {
+	numberActivities(instances)
+	shards := shardInstances(instances)
+	parts := family.parts
+	if parts&tallyDurations != 0 && durations == nil {
+		parts |= tallySamples
+	}
+	var tally *caseTally
+	if parts != 0 {
+		tally = a.tallyShards(shards, parts)
+		if parts&tallySamples != 0 {
+			durations = tally.durationIndex()
+		}
+	}
+
+	if family.collect == nil {
+		result := a.aggregate(tally.results, family.keys)
+		putRawOccurrences(tally.results)
+		return result
+	}
+	return a.aggregate(family.collect(tally, durations, shards, instances), family.keys)
+}
+
+// aggregate сводит вхождения rawMetrics в метрики с ключами keys (вхождения других метрик пропускаются):
+// считает итоги и стоимость потерь, упорядочивает вхождения и отмечает превышение порога.
+func (a *Analyzer) aggregate(rawMetrics []rawOccurrence, keys []string) []InefficiencyMetric {
+	// Вхождения каждой метрики копируются в отчёт один раз, в срез заранее известной длины
+	counts := make(map[string]int, len(keys))
+	for i := range rawMetrics {
+		counts[rawMetrics[i].metricType]++
+	}
+	aggregated := make(map[string]*InefficiencyMetric, len(keys))
+	for _, key := range keys {
+		aggregated[key] = &InefficiencyMetric{
+			Key:         key,
+			Definition:  a.definitions[key],
+			Occurrences: make([]MetricOccurrence, 0, counts[key]),
+		}
+	}
+
+	for i := range rawMetrics {
+		raw := &rawMetrics[i]
+		if metric, exists := aggregated[raw.metricType]; exists {
+			raw.occurrence.WastedCost = a.costModel.Cost(raw.occurrence)
+			metric.Occurrences = append(metric.Occurrences, raw.occurrence)
+			metric.TotalValue += raw.occurrence.Value
+			metric.TotalWastedDuration += raw.occurrence.WastedDurationSeconds
+			metric.TotalWastedCost += raw.occurrence.WastedCost
+			metric.Count++
+			if raw.occurrence.Value > metric.Definition.Threshold {
+				metric.Exceeded = true // Устанавливаем флаг, если хотя бы одно вхождение превышает порог
+			}
+		}
+	}
+
+	result := make([]InefficiencyMetric, 0, len(keys))
+	for _, key := range keys {
+		metric := aggregated[key]
+		sortOccurrences(metric.Occurrences)
+		metric.TotalValue = math.Round(metric.TotalValue*10) / 10
+		metric.TotalWastedCost = math.Round(metric.TotalWastedCost*100) / 100
+		result = append(result, *metric)
+	}
+	return result
+}
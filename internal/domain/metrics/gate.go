@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MetricLimit — допустимое число вхождений метрики: условие вида "Rework>100" нарушено,
+// если вхождений метрики Rework больше 100.
+type MetricLimit struct {
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"` // >, >=, < или <=
+	Value    float64 `json:"value"`
+}
+
+// limitOperators — операторы условий; двухсимвольные проверяются раньше односимвольных.
+var limitOperators = []string{">=", "<=", ">", "<"}
+
+// ParseMetricLimits разбирает условия через запятую, например "Rework>100,Self-Loop>0".
+func ParseMetricLimits(spec string) ([]MetricLimit, error) {
+	var limits []MetricLimit
+	for _, condition := range strings.Split(spec, ",") {
+		condition = strings.TrimSpace(condition)
+		if condition == "" {
+			continue
+		}
+		limit, ok := MetricLimit{}, false
+		for _, operator := range limitOperators {
+			if key, value, found := strings.Cut(condition, operator); found {
+				limit = MetricLimit{Metric: strings.TrimSpace(key), Operator: operator}
+				number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || limit.Metric == "" {
+					return nil, fmt.Errorf("некорректное условие %q: ожидается метрика>число", condition)
+				}
+				limit.Value, ok = number, true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("некорректное условие %q: ожидается метрика>число", condition)
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// Violated сообщает, что число вхождений count нарушает условие.
+func (l MetricLimit) Violated(count int) bool {
+	value := float64(count)
+	switch l.Operator {
+	case ">=":
+		return value >= l.Value
+	case "<=":
+		return value <= l.Value
+	case "<":
+		return value < l.Value
+	default:
+		return value > l.Value
+	}
+}
+
+// LimitViolation — нарушенное условие и фактическое число вхождений метрики.
+type LimitViolation struct {
+	Limit MetricLimit `json:"limit"`
+	Count int         `json:"count"`
+}
+
+// CheckLimits возвращает нарушенные условия в порядке их перечисления. Условие на метрику,
+// которой нет в отчёте, считается ошибкой: иначе опечатка в ключе незаметно отключила бы проверку.
+func (r *MetricsReport) CheckLimits(limits []MetricLimit) ([]LimitViolation, error) {
+	counts := make(map[string]int, len(r.Metrics))
+	keys := make([]string, 0, len(r.Metrics))
+	for _, metric := range r.Metrics {
+		counts[metric.Key] = metric.Count
+		keys = append(keys, metric.Key)
+	}
+	var violations []LimitViolation
+	for _, limit := range limits {
+		count, ok := counts[limit.Metric]
+		if !ok {
+			return nil, fmt.Errorf("неизвестная метрика %q: доступны %s", limit.Metric, strings.Join(keys, ", "))
+		}
+		if limit.Violated(count) {
+			violations = append(violations, LimitViolation{Limit: limit, Count: count})
+		}
+	}
+	return violations, nil
+}
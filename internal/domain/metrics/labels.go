@@ -0,0 +1,66 @@
+package metrics
+
+import "strings"
+
+// ActivityID — номер активности в справочнике Labels. Нулевой номер означает, что активность
+// не занесена в справочник.
+type ActivityID int32
+
+// Labels — справочник названий активностей. Каждое название хранится в одном экземпляре
+// и получает номер, поэтому события одной активности не держат собственные копии строки,
+// а анализатор сравнивает активности по номерам. Справочник не безопасен для одновременной записи.
+type Labels struct {
+	ids   map[string]ActivityID
+	names []string
+}
+
+// NewLabels создаёт пустой справочник.
+func NewLabels() *Labels {
+	return &Labels{ids: make(map[string]ActivityID), names: []string{""}}
+}
+
+// Intern возвращает номер активности name и название в том экземпляре, который хранит справочник.
+// Новое название копируется, чтобы не удерживать в памяти строку, из которой оно вырезано
+// (например, целую строку CSV).
+func (l *Labels) Intern(name string) (ActivityID, string) {
+	if id, ok := l.ids[name]; ok {
+		return id, l.names[id]
+	}
+	name = strings.Clone(name)
+	id := ActivityID(len(l.names))
+	l.ids[name] = id
+	l.names = append(l.names, name)
+	return id, name
+}
+
+// Name возвращает название активности с номером id.
+func (l *Labels) Name(id ActivityID) string {
+	return l.names[id]
+}
+
+// Len возвращает число активностей в справочнике.
+func (l *Labels) Len() int {
+	return len(l.names) - 1
+}
+
+// numberActivities нумерует активности, если среди экземпляров есть собранные без справочника
+// (с нулевым номером активности — например, созданные вызывающим вручную): тогда номера
+// по новому справочнику получают события всех экземпляров. Иначе ничего не меняется.
+func numberActivities(instances map[string]*ProcessInstance) {
+	numbered := true
+	for _, instance := range instances {
+		if len(instance.Events) > 0 && instance.Events[0].Activity == 0 {
+			numbered = false
+			break
+		}
+	}
+	if numbered {
+		return
+	}
+	labels := NewLabels()
+	for _, instance := range instances {
+		for i := range instance.Events {
+			instance.Events[i].Activity, _ = labels.Intern(instance.Events[i].Description)
+		}
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,8 +13,12 @@ import (
 type Event struct {
     SessionID   string
     Timestamp   time.Time
+    Start       time.Time // время начала обработки (нулевое, если неизвестно)
     Description string
+    Activity    ActivityID // номер активности в справочнике Labels
     Result      string
+    Resource    string
+    Attributes  map[string]string
 }
 
 // ProcessInstance представляет последовательность событий для одного экземпляра процесса.
@@ -38,16 +42,23 @@ type MetricOccurrence struct {
 	Value               float64 // Значение метрики
 	WastedDurationSeconds float64 // Потерянное время в секундах ("финансовый эффект")
 	Details             string  // Краткая детализация (опционально)
+	Activity            string  // Активность, к которой относится вхождение (опционально)
+	Resource            string  // Ресурс, к которому относится вхождение (опционально)
+	WastedCost          float64 // Стоимость потерянного времени по модели затрат
 }
 
 // InefficiencyMetric содержит агрегированный результат по метрике.
 type InefficiencyMetric struct {
+    Key         string           `json:"key"` // Ключ метрики (используется в настройках порогов и API вхождений)
     Definition  MetricDefinition `json:"definition"`
     Occurrences []MetricOccurrence `json:"occurrences"`
     TotalValue            float64 `json:"total_value"`             // Агрегированное значение
 	TotalWastedDuration   float64 `json:"total_wasted_duration"`   // Общее потерянное время в секундах
+	TotalWastedCost       float64 `json:"total_wasted_cost"`       // Стоимость потерянного времени
     Count       int `json:"count"`     // Количество вхождений
     Exceeded    bool `json:"exceeded"`    // Превышен ли порог
+    Severity    float64 `json:"severity"` // Оценка критичности от 0 до 100
+    Priority    int `json:"priority"`     // Место в списке приоритетов (1 — исправлять первым)
 }
 
 // DurationMetricsResult содержит агрегированные метрики длительности и их вхождения.
@@ -85,12 +96,30 @@ type MetricsReport struct {
 	StageDurationIQR       float64         `json:"stage_duration_iqr"`
 	AnomalousStageCount    int             `json:"anomalous_stage_count"`
 	StageDurationTrendSlope float64        `json:"stage_duration_trend_slope"`
+	Bottlenecks            []ActivityBottleneck `json:"bottlenecks"` // активности с наибольшим суммарным ожиданием
+	TimeDecomposition      TimeDecomposition    `json:"time_decomposition"` // ожидание и обработка по переходам
+	BusinessDurations      *BusinessDurations   `json:"business_durations,omitempty"` // длительности в рабочем времени (если задан календарь)
+	Resources              *ResourceReport      `json:"resources,omitempty"` // загрузка ресурсов (если в логе есть столбец ресурса)
+	ErrorRates             []ActivityErrorRate  `json:"error_rates,omitempty"` // доля ошибок по активностям (если в логе есть результаты)
+	FirstPassYield         FirstPassYield       `json:"first_pass_yield"` // доля кейсов, завершённых без переделок и ошибок
+	Automation             *AutomationSummary   `json:"automation,omitempty"` // уровень автоматизации (если задана разметка активностей)
+	LeadTimeEfficiency     *LeadTimeEfficiency  `json:"lead_time_efficiency,omitempty"` // touch time / lead time (если известно время обработки)
+	CostCurrency           string               `json:"cost_currency,omitempty"` // валюта total_wasted_cost
 	Metrics                []InefficiencyMetric `json:"metrics"`
 }
 
 // Analyzer — основной компонент для вычисления метрик.
 type Analyzer struct {
     definitions map[string]MetricDefinition
+    collectors  []MetricCollector
+    sla         SLA
+    calendar    *businessCalendar
+    costModel   CostModel
+    outlierMethod string
+    endActivities map[string]bool
+    errors        *ErrorMatcher
+    automation    AutomationMapping
+    topK          int // сколько самых частых активностей и путей попадает в отчёт
     Logger      *slog.Logger
 }
 
@@ -99,9 +128,37 @@ func NewAnalyzer() *Analyzer {
     return &Analyzer{
         Logger: slog.Default(),
         definitions: initMetricDefinitions(),
+        outlierMethod: OutlierIQR,
+        topK: defaultTopK,
+        errors: DefaultErrorMatcher,
     }
 }
 
+// Definitions возвращает копию справочника определений метрик с текущими порогами.
+func (a *Analyzer) Definitions() map[string]MetricDefinition {
+	definitions := make(map[string]MetricDefinition, len(a.definitions))
+	for key, def := range a.definitions {
+		definitions[key] = def
+	}
+	return definitions
+}
+
+// SetThreshold переопределяет пороговое значение метрики.
+func (a *Analyzer) SetThreshold(metricType string, threshold float64) error {
+	def, ok := a.definitions[metricType]
+	if !ok {
+		return fmt.Errorf("неизвестная метрика: %s", metricType)
+	}
+	def.Threshold = threshold
+	a.definitions[metricType] = def
+	return nil
+}
+
+// threshold возвращает текущее пороговое значение метрики.
+func (a *Analyzer) threshold(metricType string) float64 {
+	return a.definitions[metricType].Threshold
+}
+
 // initMetricDefinitions инициализирует справочник определений метрик.
 func initMetricDefinitions() map[string]MetricDefinition {
     return map[string]MetricDefinition{
@@ -143,21 +200,28 @@ func initMetricDefinitions() map[string]MetricDefinition {
         "Anomalously Long Stage": {
             Name:        "Аномально долгий этап",
             Category:    "Длительность",
-            Calculation: "Выявление выбросов длительности этапов через межквартильный размах (IQR > Q3 + 1.5*IQR)",
+            Calculation: "Выявление выбросов длительности этапов отдельно для каждого перехода (по умолчанию IQR: > Q3 + 1.5*IQR; также MAD, z-оценка логарифма, isolation forest)",
             Impact:      "Узкие места или проблемы производительности на конкретных этапах.",
             Threshold:   0.0, // Рассчитывается динамически
         },
+        "Anomalously Long Case": {
+            Name:        "Аномально долгий кейс",
+            Category:    "Длительность",
+            Calculation: "Выявление выбросов длительности кейсов тем же методом, что и для этапов",
+            Impact:      "Отдельные кейсы застревают — недовольные клиенты и скрытые исключения процесса.",
+            Threshold:   0.0, // Рассчитывается динамически
+        },
         "Increasing Stage Duration Trend": {
             Name:        "Рост длительности этапа",
             Category:    "Длительность",
-            Calculation: "Линейная регрессия длительности этапов во времени (положительный наклон)",
+            Calculation: "Линейная регрессия средней длительности этапов по часам, дням или неделям (положительный наклон)",
             Impact:      "Деградация производительности или рост сложности задач со временем.",
             Threshold:   0.0,
         },
         "Increasing Process Instance Duration Trend": {
             Name:        "Рост длительности экземпляра",
             Category:    "Длительность",
-            Calculation: "Линейная регрессия длительности экземпляров во времени",
+            Calculation: "Линейная регрессия средней длительности экземпляров по времени начала (часы, дни или недели)",
             Impact:      "Общее ухудшение производительности процесса со временем.",
             Threshold:   0.0,
         },
@@ -178,46 +242,66 @@ func initMetricDefinitions() map[string]MetricDefinition {
         "Low Process Completion Rate": {
             Name:        "Низкий процент завершения",
             Category:    "Завершённость",
-            Calculation: "Доля экземпляров, завершивших полный цикл (начало→конец)",
+            Calculation: "Доля экземпляров, завершивших полный цикл (начало→конец или заданные завершающие активности)",
             Impact:      "Процесс прерывается или не доходит до конца, что снижает эффективность.",
             Threshold:   100.0,
         },
         "High Error Rate": {
             Name:        "Высокий процент ошибок",
             Category:    "Качество",
-            Calculation: "Сравнение количества ошибок с успешными экземплярами",
+            Calculation: "Сравнение количества экземпляров с ошибками (результаты задаются в настройках) с успешными",
             Impact:      "Нестабильность процесса, превышение ошибок над успешными выполнениями.",
             Threshold:   0.0,
         },
+        "Stuck Case": {
+            Name:        "Застрявший кейс",
+            Category:    "Завершённость",
+            Calculation: "Кейс не дошёл до завершающей активности, и с последнего события прошло больше порога (сек) до конца лога",
+            Impact:      "Заявки зависли и не обрабатываются — клиент ждёт, работа потеряна.",
+            Threshold:   DefaultStuckCaseAge.Seconds(),
+        },
+        "SLA Breach": {
+            Name:        "Нарушение SLA",
+            Category:    "Длительность",
+            Calculation: "Превышение заданных лимитов длительности кейса, переходов и активностей (сек)",
+            Impact:      "Нарушены обязательства перед клиентом — штрафы, потеря лояльности.",
+            Threshold:   0.0,
+        },
     }
 }
 
 // Analyze выполняет анализ экземпляров процесса.
 func (a *Analyzer) Analyze(instances map[string]*ProcessInstance) *MetricsReport {
+	return a.AnalyzeIndexed(instances, nil)
+}
+
+// AnalyzeIndexed выполняет анализ экземпляров процесса, беря длительности этапов и кейсов из индекса
+// durations, построенного по тем же экземплярам (nil — длительности собираются при проходе по кейсам).
+func (a *Analyzer) AnalyzeIndexed(instances map[string]*ProcessInstance, durations *DurationIndex) *MetricsReport {
 	report := &MetricsReport{}
 
+	// Кейсы обрабатываются частями параллельно, каждый кейс — за один проход по событиям
+	numberActivities(instances)
+	shards := shardInstances(instances)
+	parts := tallyAll
+	if durations == nil {
+		parts |= tallySamples
+	}
+	tally := a.tallyShards(shards, parts)
+	if durations == nil {
+		durations = tally.durationIndex()
+	}
+
 	// 1. Общее количество экземпляров процессов
-	report.TotalProcessInstances = len(instances)
+	report.TotalProcessInstances = tally.instances
 
 	// 2. Общее количество событий
-	var totalEvents int
-	for _, instance := range instances {
-		totalEvents += len(instance.Events)
-	}
-	report.TotalEvents = totalEvents
+	report.TotalEvents = tally.events
 
 	// 3. Средняя и медианная продолжительность процесса
-	var processDurations []float64
-	for _, instance := range instances {
-		if len(instance.Events) > 1 {
-			start := instance.Events[0].Timestamp
-			end := instance.Events[len(instance.Events)-1].Timestamp
-			processDurations = append(processDurations, end.Sub(start).Seconds())
-		}
-	}
+	processDurations := durations.cases.values
 
 	if len(processDurations) > 0 {
-		sort.Float64s(processDurations)
 		var sumDuration float64
 		for _, d := range processDurations {
 			sumDuration += d
@@ -236,445 +320,360 @@ func (a *Analyzer) Analyze(instances map[string]*ProcessInstance) *MetricsReport
 	}
 
 	// 4. Наиболее частые действия
-	activityCounts := make(map[string]int)
-	for _, instance := range instances {
-		for _, event := range instance.Events {
-			activityCounts[event.Description]++
-		}
-	}
+	report.MostFrequentActivities = topActivities(tally.activityCounts, tally.activityNames, a.topK)
 
-	var sortedActivities []ActivityCount
-	for activity, count := range activityCounts {
-		sortedActivities = append(sortedActivities, ActivityCount{Activity: activity, Count: count})
-	}
-	sort.Slice(sortedActivities, func(i, j int) bool {
-		return sortedActivities[i].Count > sortedActivities[j].Count
-	})
+	// 5. Наиболее частые пути
+	report.MostFrequentPaths = topPaths(tally.pathCounts, tally.paths, a.topK)
 
-	if len(sortedActivities) > 5 {
-		report.MostFrequentActivities = sortedActivities[:5]
-	} else {
-		report.MostFrequentActivities = sortedActivities
-	}
+	// 6. Узкие места по суммарному времени ожидания
+	report.Bottlenecks = RankBottlenecks(instances)
 
-	// 5. Наиболее частые пути
-	pathCounts := make(map[string]int)
-	pathMap := make(map[string][]string)
-	for _, instance := range instances {
-		if len(instance.Events) > 0 {
-			path := make([]string, len(instance.Events))
-			for i, event := range instance.Events {
-				path[i] = event.Description
-			}
-			pathKey := fmt.Sprintf("%v", path)
-			pathCounts[pathKey]++
-			pathMap[pathKey] = path
-		}
-	}
+	// 7. Разложение времени переходов на ожидание и обработку
+	report.TimeDecomposition = DecomposeTransitions(instances)
 
-	var sortedPaths []PathCount
-	for pathKey, count := range pathCounts {
-		sortedPaths = append(sortedPaths, PathCount{Path: pathMap[pathKey], Count: count})
-	}
-	sort.Slice(sortedPaths, func(i, j int) bool {
-		return sortedPaths[i].Count > sortedPaths[j].Count
-	})
+	// 8. Длительности в рабочем времени по календарю
+	report.BusinessDurations = a.businessDurations(instances)
+	report.CostCurrency = a.costModel.Currency
 
-	if len(sortedPaths) > 5 {
-		report.MostFrequentPaths = sortedPaths[:5]
-	} else {
-		report.MostFrequentPaths = sortedPaths
-	}
+	// 9. Загрузка ресурсов
+	report.Resources = ResourceUtilizations(instances)
 
-	// Собираем все вхождения метрик
-	rawMetrics := []struct {
-		metricType string
-		occurrence MetricOccurrence
-	}{}
+	// 10. Доля ошибок по активностям
+	report.ErrorRates = a.activityErrorRates(instances)
 
-	// Вызываем функции расчёта метрик
-	rawMetrics = append(rawMetrics, a.collectLoopingMetrics(instances)...)
-	rawMetrics = append(rawMetrics, a.collectDurationMetrics(instances)...)
-	rawMetrics = append(rawMetrics, a.collectManualStageMetrics(instances)...)
-	rawMetrics = append(rawMetrics, a.collectComplexityMetrics(instances)...)
-	rawMetrics = append(rawMetrics, a.collectCompletionMetrics(instances)...)
-	rawMetrics = append(rawMetrics, a.collectErrorMetrics(instances)...)
+	// 11. Выход с первого прохода
+	report.FirstPassYield = a.firstPassYield(instances)
 
-	// Агрегируем по типам метрик
-	aggregated := make(map[string]*InefficiencyMetric)
+	// 12. Уровень автоматизации по разметке активностей
+	report.Automation = a.automationSummary(instances)
 
-	// Сначала инициализируем все метрики с нулевыми значениями
-	for key, def := range a.definitions {
-		aggregated[key] = &InefficiencyMetric{
-			Definition:  def,
-			Occurrences: []MetricOccurrence{},
-			Count:       0,
-			Exceeded:    false,
-		}
-	}
+	// 13. Эффективность по времени обработки
+	report.LeadTimeEfficiency = leadTimeEfficiency(instances)
 
-	// Теперь заполняем найденные вхождения
-	for _, raw := range rawMetrics {
-		if metric, exists := aggregated[raw.metricType]; exists {
-			metric.Occurrences = append(metric.Occurrences, raw.occurrence)
-			metric.TotalValue += raw.occurrence.Value
-			metric.TotalWastedDuration += raw.occurrence.WastedDurationSeconds
-			metric.Count++
-			if raw.occurrence.Value > metric.Definition.Threshold {
-				metric.Exceeded = true // Устанавливаем флаг, если хотя бы одно вхождение превышает порог
-			}
+	// Собираем все вхождения метрик: вхождения покейсовых метрик найдены при проходе по кейсам,
+	// остальным семействам метрик нужны итоги по всему логу
+	rawMetrics := tally.results
+	var keys []string
+	for _, family := range a.families() {
+		keys = append(keys, family.keys...)
+		if family.collect != nil {
+			rawMetrics = append(rawMetrics, family.collect(tally, durations, shards, instances)...)
 		}
 	}
+	report.Metrics = a.aggregate(rawMetrics, keys)
+	putRawOccurrences(rawMetrics)
 
-	// Преобразуем в слайс
-	for _, metric := range aggregated {
-		metric.TotalValue = math.Round(metric.TotalValue*10) / 10
-		report.Metrics = append(report.Metrics, *metric)
-	}
+	// Сортируем метрики по критичности
+	prioritize(report.Metrics)
 
 	return report
 }
 
-// collectLoopingMetrics собирает вхождения метрик зацикливания.
-func (a *Analyzer) collectLoopingMetrics(instances map[string]*ProcessInstance) []struct {
-    metricType string
-    occurrence MetricOccurrence
-} {
-    var results []struct {
-        metricType string
-        occurrence MetricOccurrence
+// collectLoopingMetrics добавляет к results вхождения метрик зацикливания в экземпляре.
+func (a *Analyzer) collectLoopingMetrics(instance *ProcessInstance, results []rawOccurrence) []rawOccurrence {
+    if len(instance.Events) < 2 {
+        return results
     }
 
-    for _, instance := range instances {
-        if len(instance.Events) < 2 {
-            continue
-        }
-
-        // Self-loop
-		for i := 1; i < len(instance.Events); i++ {
-			if instance.Events[i].Description == instance.Events[i-1].Description {
-				results = append(results, struct {
-					metricType string
-					occurrence MetricOccurrence
-				}{
-					metricType: "Self-Loop",
-					occurrence: MetricOccurrence{
-						InstanceID:          instance.ID,
-						Value:               1.0,
-						WastedDurationSeconds: instance.Events[i].Timestamp.Sub(instance.Events[i-1].Timestamp).Seconds(),
-						Details:             fmt.Sprintf("Шаг %d: '%s'", i, instance.Events[i].Description),
-					},
-				})
-			}
+    // Self-loop
+	for i := 1; i < len(instance.Events); i++ {
+		if instance.Events[i].Activity == instance.Events[i-1].Activity {
+			results = append(results, rawOccurrence{
+				metricType: "Self-Loop",
+				occurrence: MetricOccurrence{
+					InstanceID:          instance.ID,
+					Value:               1.0,
+					WastedDurationSeconds: instance.Events[i].Timestamp.Sub(instance.Events[i-1].Timestamp).Seconds(),
+					Details:             fmt.Sprintf("Шаг %d: '%s'", i, instance.Events[i].Description),
+					Activity:            instance.Events[i].Description,
+					Resource:            instance.Events[i].Resource,
+				},
+			})
 		}
+	}
 
-        // Return to Previous Stage
-		for i := 2; i < len(instance.Events); i++ {
-			if instance.Events[i].Description == instance.Events[i-2].Description {
-				results = append(results, struct {
-					metricType string
-					occurrence MetricOccurrence
-				}{
-					metricType: "Return to Previous Stage",
-					occurrence: MetricOccurrence{
-						InstanceID:          instance.ID,
-						Value:               1.0,
-						WastedDurationSeconds: instance.Events[i].Timestamp.Sub(instance.Events[i-2].Timestamp).Seconds(),
-						Details:             fmt.Sprintf("Шаг %d: '%s'", i, instance.Events[i].Description),
-					},
-				})
-			}
+    // Return to Previous Stage
+	for i := 2; i < len(instance.Events); i++ {
+		if instance.Events[i].Activity == instance.Events[i-2].Activity {
+			results = append(results, rawOccurrence{
+				metricType: "Return to Previous Stage",
+				occurrence: MetricOccurrence{
+					InstanceID:          instance.ID,
+					Value:               1.0,
+					WastedDurationSeconds: instance.Events[i].Timestamp.Sub(instance.Events[i-2].Timestamp).Seconds(),
+					Details:             fmt.Sprintf("Шаг %d: '%s'", i, instance.Events[i].Description),
+					Activity:            instance.Events[i].Description,
+					Resource:            instance.Events[i].Resource,
+				},
+			})
 		}
+	}
 
-        // Ping-pong
-        for i := 3; i < len(instance.Events); i++ {
-            if instance.Events[i].Description == instance.Events[i-2].Description &&
-                instance.Events[i-1].Description == instance.Events[i-3].Description {
-                results = append(results, struct {
-                    metricType string
-                    occurrence MetricOccurrence
-                }{
-                    metricType: "Ping-Pong",
-                    occurrence: MetricOccurrence{
-						InstanceID:          instance.ID,
-						Value:               1.0,
-						WastedDurationSeconds: instance.Events[i-1].Timestamp.Sub(instance.Events[i-3].Timestamp).Seconds(),
-						Details:             fmt.Sprintf("Шаг %d: '%s' ↔ '%s'", i, instance.Events[i-1].Description, instance.Events[i].Description),
-					},
-                })
-            }
+    // Ping-pong
+    for i := 3; i < len(instance.Events); i++ {
+        if instance.Events[i].Activity == instance.Events[i-2].Activity &&
+            instance.Events[i-1].Activity == instance.Events[i-3].Activity {
+            results = append(results, rawOccurrence{
+                metricType: "Ping-Pong",
+                occurrence: MetricOccurrence{
+					InstanceID:          instance.ID,
+					Value:               1.0,
+					WastedDurationSeconds: instance.Events[i-1].Timestamp.Sub(instance.Events[i-3].Timestamp).Seconds(),
+					Details:             fmt.Sprintf("Шаг %d: '%s' ↔ '%s'", i, instance.Events[i-1].Description, instance.Events[i].Description),
+					Activity:            instance.Events[i].Description,
+					Resource:            instance.Events[i].Resource,
+				},
+            })
         }
+    }
 
-        // Return to Start
-        if len(instance.Events) > 1 {
-            firstEvent := instance.Events[0]
-            for i := 1; i < len(instance.Events); i++ {
-                if instance.Events[i].Description == firstEvent.Description {
-                    results = append(results, struct {
-                        metricType string
-                        occurrence MetricOccurrence
-                    }{
-                        metricType: "Return to Start",
-                        occurrence: MetricOccurrence{
-                            InstanceID: instance.ID,
-                            Value:      1.0,
-                            Details:    fmt.Sprintf("Шаг %d: возврат к '%s'", i, instance.Events[i].Description),
-                        },
-                    })
-                }
+    // Return to Start
+    if len(instance.Events) > 1 {
+        firstEvent := instance.Events[0]
+        for i := 1; i < len(instance.Events); i++ {
+            if instance.Events[i].Activity == firstEvent.Activity {
+                results = append(results, rawOccurrence{
+                    metricType: "Return to Start",
+                    occurrence: MetricOccurrence{
+                        InstanceID: instance.ID,
+                        Value:      1.0,
+                        Details:    fmt.Sprintf("Шаг %d: возврат к '%s'", i, instance.Events[i].Description),
+                    },
+                })
             }
         }
+    }
 
-        // Rework
-		eventIndices := make(map[string][]int)
-		for i, event := range instance.Events {
-			eventIndices[event.Description] = append(eventIndices[event.Description], i)
-		}
-
-		for desc, indices := range eventIndices {
-			if len(indices) > 1 {
-				var wastedDuration float64
-				// Суммируем длительность всех переделанных этапов, кроме последнего
-				for i := 0; i < len(indices)-1; i++ {
-					currentIndex := indices[i]
-					// Убедимся, что следующий эвент существует, чтобы посчитать длительность
-					if currentIndex+1 < len(instance.Events) {
-						wastedDuration += instance.Events[currentIndex+1].Timestamp.Sub(instance.Events[currentIndex].Timestamp).Seconds()
-					}
-				}
-
-				results = append(results, struct {
-					metricType string
-					occurrence MetricOccurrence
-				}{
-					metricType: "Rework",
-					occurrence: MetricOccurrence{
-						InstanceID:          instance.ID,
-						Value:               float64(len(indices) - 1),
-						WastedDurationSeconds: wastedDuration,
-						Details:             fmt.Sprintf("Этап '%s' повторён %d раз", desc, len(indices)),
-					},
-				})
-			}
+    // Rework
+	repeats := countRepeats(instance.Events)
+	for _, repeat := range repeats.byOrder {
+		if repeat.count > 1 {
+			desc := instance.Events[repeat.first].Description
+			results = append(results, rawOccurrence{
+				metricType: "Rework",
+				occurrence: MetricOccurrence{
+					InstanceID:          instance.ID,
+					Value:               float64(repeat.count - 1),
+					WastedDurationSeconds: repeat.wasted,
+					Details:             fmt.Sprintf("Этап '%s' повторён %d раз", desc, repeat.count),
+					Activity:            desc,
+					Resource:            instance.Events[repeat.last].Resource,
+				},
+			})
 		}
-    }
+	}
+	repeats.release()
 
     return results
 }
 
-// collectDurationMetrics собирает метрики длительности.
-func (a *Analyzer) collectDurationMetrics(instances map[string]*ProcessInstance) []struct {
-    metricType string
-    occurrence MetricOccurrence
-} {
-    var results []struct {
-        metricType string
-        occurrence MetricOccurrence
-    }
-    var durations []float64
+// activityRepeats — повторы активностей одного кейса для метрики Rework. Счётчики берутся из пула
+// и возвращаются в него после обработки кейса (release), чтобы не создавать их для каждого кейса.
+type activityRepeats struct {
+	byActivity map[ActivityID]int // активность → номер в byOrder
+	byOrder    []activityRepeat   // в порядке первого появления активности
+}
 
-    // Собираем длительности всех операций
-    for _, instance := range instances {
-        if len(instance.Events) < 2 {
-            // Пропускаем экземпляры с менее чем двумя событиями, так как длительность не может быть рассчитана.
-            a.Logger.Warn("Экземпляр имеет менее двух событий, длительность не может быть рассчитана", "instance_id", instance.ID)
-            continue
-        }
-        for i := 0; i < len(instance.Events)-1; i++ {
-            event1 := instance.Events[i]
-            event2 := instance.Events[i+1]
+type activityRepeat struct {
+	first, last int     // индексы первого и последнего события активности
+	count       int     // число событий активности
+	wasted      float64 // длительность этапов, начатых событиями активности, кроме последнего (сек)
+}
 
-            if event1.Timestamp.IsZero() || event2.Timestamp.IsZero() {
-                a.Logger.Warn("Обнаружена нулевая временная метка, пропуск расчета длительности", "instance_id", instance.ID, "event_index_1", i, "event_index_2", i+1)
-                continue
-            }
+var activityRepeatsPool = sync.Pool{
+	New: func() any {
+		return &activityRepeats{byActivity: make(map[ActivityID]int)}
+	},
+}
 
-            if event2.Timestamp.Before(event1.Timestamp) {
-                a.Logger.Warn("Некорректный порядок временных меток", "instance_id", instance.ID, "event_index_1", i, "timestamp_1", event1.Timestamp, "event_index_2", i+1, "timestamp_2", event2.Timestamp)
-                continue
-            }
+// countRepeats подсчитывает повторы активностей в событиях кейса.
+func countRepeats(events []Event) *activityRepeats {
+	repeats := activityRepeatsPool.Get().(*activityRepeats)
+	for i := range events {
+		number, seen := repeats.byActivity[events[i].Activity]
+		if !seen {
+			repeats.byActivity[events[i].Activity] = len(repeats.byOrder)
+			repeats.byOrder = append(repeats.byOrder, activityRepeat{first: i, last: i, count: 1})
+			continue
+		}
+		repeat := &repeats.byOrder[number]
+		// Этап, начатый предыдущим событием активности, переделывается
+		repeat.wasted += events[repeat.last+1].Timestamp.Sub(events[repeat.last].Timestamp).Seconds()
+		repeat.last = i
+		repeat.count++
+	}
+	return repeats
+}
 
-            duration := event2.Timestamp.Sub(event1.Timestamp)
-            durations = append(durations, duration.Seconds())
-        }
-    }
+// maxPooledRepeats — наибольшее число активностей кейса, счётчики которого возвращаются в пул:
+// очистка разросшейся карты дороже новой для следующих, обычно коротких кейсов.
+const maxPooledRepeats = 256
+
+// release очищает счётчики и возвращает их в пул.
+func (r *activityRepeats) release() {
+	if len(r.byOrder) > maxPooledRepeats {
+		return
+	}
+	clear(r.byActivity)
+	r.byOrder = r.byOrder[:0]
+	activityRepeatsPool.Put(r)
+}
+
+// collectDurationMetrics собирает метрики длительности по итогам прохода по кейсам. Пороги аномалий
+// известны только по всему логу, поэтому аномальные этапы и кейсы ищутся вторым, лёгким проходом
+// по частям shards — параллельно, без повторного сбора длительностей. Длительности этапов и кейсов
+// берутся из индекса durations.
+func (a *Analyzer) collectDurationMetrics(tally *caseTally, durations *DurationIndex, shards []map[string]*ProcessInstance) []rawOccurrence {
+    var results []rawOccurrence
 
-    if len(durations) == 0 {
+    if len(tally.stagePoints) == 0 {
         a.Logger.Warn("Нет доступных длительностей для расчета метрик")
         return results
     }
 
-    if len(durations) < 4 {
-        a.Logger.Warn("Недостаточно длительностей для расчета IQR", "count", len(durations))
+    if len(tally.stagePoints) < 4 {
+        a.Logger.Warn("Недостаточно длительностей для расчета IQR", "count", len(tally.stagePoints))
         // В этом случае мы можем решить, возвращать ли частичные результаты или нет.
         // Пока что, мы просто продолжим, но без расчетов, требующих 4+ длительностей.
     }
 
-    // Вычисляем среднее и стандартное отклонение
-    var sum float64
-    for _, d := range durations {
-        sum += d
+    // Аномально длинные этапы: каждый переход сравнивается с собственным эталоном,
+    // аномально долгие кейсы — с медианой длительности кейсов
+    baselineOf := a.stageBaselines(durations)
+    isLongCase := a.outlierDetector(durations.cases.values)
+    var medianCase float64
+    if isLongCase != nil {
+        medianCase = percentile(durations.cases.values, 50)
+    }
+    parts := make([][]rawOccurrence, len(shards))
+    forEachShard(shards, func(i int, shard map[string]*ProcessInstance) {
+        parts[i] = collectDurationAnomalies(shard, baselineOf, isLongCase, medianCase)
+    })
+    size := 0
+    for _, part := range parts {
+        size += len(part)
+    }
+    results = make([]rawOccurrence, 0, size+2) // и вхождения двух метрик трендов
+    for _, part := range parts {
+        results = append(results, part...)
     }
-    avgDuration := sum / float64(len(durations))
-
-    // Расчет аномалий возможен только при наличии достаточного количества данных
-	if len(durations) >= 4 {
-		// Сортируем и вычисляем IQR
-		sort.Float64s(durations)
-		q1Index := int(math.Round(float64(len(durations)-1) * 0.25))
-		q3Index := int(math.Round(float64(len(durations)-1) * 0.75))
-		q1 := durations[q1Index]
-		q3 := durations[q3Index]
-		iqr := q3 - q1
-		outlierThreshold := q3 + 1.5*iqr
-
-		// Аномально длинные этапы
-		for _, instance := range instances {
-			for i := 0; i < len(instance.Events)-1; i++ {
-				duration := instance.Events[i+1].Timestamp.Sub(instance.Events[i].Timestamp)
-				if duration.Seconds() > outlierThreshold {
-					results = append(results, struct {
-						metricType string
-						occurrence MetricOccurrence
-					}{
-						metricType: "Anomalously Long Stage",
-						occurrence: MetricOccurrence{
-							InstanceID: instance.ID,
-							Value:      duration.Seconds(),
-							Details:    fmt.Sprintf("Этап '%s': %.2f сек (avg: %.2f сек)", instance.Events[i].Description, duration.Seconds(), avgDuration),
-						},
-					})
-				}
-			}
-		}
-	}
 
-    // Тренд длительности этапов
-    slope, _ := calculateLinearRegression(durations)
-	angleRadians := math.Atan(slope)
-	angleDegrees := angleRadians * 180 / math.Pi
-	if angleDegrees > 5.0 {
-        results = append(results, struct {
-            metricType string
-            occurrence MetricOccurrence
-        }{
+    // Тренд длительности этапов: этапы упорядочены по времени завершения и усреднены по интервалам
+    if slope, unit, ok := timeOrderedTrend(tally.stagePoints); ok && slope > a.threshold("Increasing Stage Duration Trend") {
+        results = append(results, rawOccurrence{
             metricType: "Increasing Stage Duration Trend",
             occurrence: MetricOccurrence{
                 InstanceID: "ALL",
                 Value:      slope,
-                Details:    fmt.Sprintf("Наклон: %.4f сек/операцию", slope),
+                Details:    fmt.Sprintf("Наклон: %.4f сек/%s", slope, unit),
+            },
+        })
+    }
+
+    // Тренд длительности экземпляров: экземпляры упорядочены по времени начала
+    if instanceSlope, unit, ok := timeOrderedTrend(tally.instancePoints); ok && instanceSlope > a.threshold("Increasing Process Instance Duration Trend") {
+        results = append(results, rawOccurrence{
+            metricType: "Increasing Process Instance Duration Trend",
+            occurrence: MetricOccurrence{
+                InstanceID: "ALL",
+                Value:      instanceSlope,
+                Details:    fmt.Sprintf("Наклон: %.4f сек/%s", instanceSlope, unit),
             },
         })
     }
 
-    // Тренд длительности экземпляров
-    var instanceDurations []float64
+    return results
+}
+
+// collectDurationAnomalies находит аномально длинные этапы (по эталонам baselineOf) и аномально долгие
+// кейсы (по детектору isLongCase, если он задан). Потери кейса отсчитываются от медианы medianCase.
+func collectDurationAnomalies(instances map[string]*ProcessInstance, baselineOf func(from, to ActivityID) *stageBaseline, isLongCase func(float64) bool, medianCase float64) []rawOccurrence {
+    var results []rawOccurrence
     for _, instance := range instances {
-        if len(instance.Events) > 1 {
-            instanceDurations = append(instanceDurations, instance.Events[len(instance.Events)-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds())
+        for i := 0; i < len(instance.Events)-1; i++ {
+            from, to := instance.Events[i], instance.Events[i+1]
+            baseline := baselineOf(from.Activity, to.Activity)
+            if baseline == nil {
+                continue
+            }
+            duration := to.Timestamp.Sub(from.Timestamp)
+            if baseline.isOutlier(duration.Seconds()) {
+                results = append(results, rawOccurrence{
+                    metricType: "Anomalously Long Stage",
+                    occurrence: MetricOccurrence{
+                        InstanceID:            instance.ID,
+                        Value:                 duration.Seconds(),
+                        WastedDurationSeconds: duration.Seconds() - baseline.average,
+                        Details: fmt.Sprintf("Этап '%s' → '%s': %.2f сек (avg (%s): %.2f сек)",
+                            from.Description, to.Description, duration.Seconds(), baseline.scope, baseline.average),
+                        Activity: from.Description,
+                        Resource: from.Resource,
+                    },
+                })
+            }
         }
-    }
 
-    if len(instanceDurations) > 1 {
-        instanceSlope, _ := calculateLinearRegression(instanceDurations)
-        if instanceSlope > 0 {
-            results = append(results, struct {
-                metricType string
-                occurrence MetricOccurrence
-            }{
-                metricType: "Increasing Process Instance Duration Trend",
+        if isLongCase == nil || len(instance.Events) < 2 {
+            continue
+        }
+        caseDuration := instance.Events[len(instance.Events)-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+        if isLongCase(caseDuration) {
+            results = append(results, rawOccurrence{
+                metricType: "Anomalously Long Case",
                 occurrence: MetricOccurrence{
-                    InstanceID: "ALL",
-                    Value:      instanceSlope,
-                    Details:    fmt.Sprintf("Наклон: %.4f сек/экземпляр", instanceSlope),
+                    InstanceID:            instance.ID,
+                    Value:                 caseDuration,
+                    WastedDurationSeconds: caseDuration - medianCase,
+                    Details:               fmt.Sprintf("Кейс: %.2f сек (медиана: %.2f сек)", caseDuration, medianCase),
                 },
             })
         }
     }
-
     return results
 }
 
-// collectManualStageMetrics собирает метрики ручных этапов.
-func (a *Analyzer) collectManualStageMetrics(instances map[string]*ProcessInstance) []struct {
-    metricType string
-    occurrence MetricOccurrence
-} {
-    var results []struct {
-        metricType string
-        occurrence MetricOccurrence
-    }
-
-    for _, instance := range instances {
-        if len(instance.Events) < 2 {
-            continue
-        }
-
-        totalInstanceDuration := instance.Events[len(instance.Events)-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+// collectManualStageMetrics добавляет к results вхождения метрики ручных этапов в экземпляре
+// длительностью totalInstanceDuration секунд.
+func (a *Analyzer) collectManualStageMetrics(instance *ProcessInstance, totalInstanceDuration float64, results []rawOccurrence) []rawOccurrence {
+    for i := 0; i < len(instance.Events)-1; i++ {
+        stageDuration := instance.Events[i+1].Timestamp.Sub(instance.Events[i].Timestamp).Seconds()
+        percentage := (stageDuration / totalInstanceDuration) * 100
 
-        for i := 0; i < len(instance.Events)-1; i++ {
-            stageDuration := instance.Events[i+1].Timestamp.Sub(instance.Events[i].Timestamp).Seconds()
-            percentage := (stageDuration / totalInstanceDuration) * 100
-            
-            if totalInstanceDuration > 0 && percentage > 80.0 {
-                results = append(results, struct {
-                    metricType string
-                    occurrence MetricOccurrence
-                }{
-                    metricType: "Manual/Unlogged Stage",
-                    occurrence: MetricOccurrence{
-                        InstanceID: instance.ID,
-                        Value:      math.Round(percentage*10) / 10,
-                        Details:    fmt.Sprintf("Этап '%s': %.1f%% времени (%.2f сек)", instance.Events[i].Description, percentage, stageDuration),
-                    },
-                })
-            }
+        if totalInstanceDuration > 0 && percentage > a.threshold("Manual/Unlogged Stage") {
+            results = append(results, rawOccurrence{
+                metricType: "Manual/Unlogged Stage",
+                occurrence: MetricOccurrence{
+                    InstanceID: instance.ID,
+                    Value:      math.Round(percentage*10) / 10,
+                    Details:    fmt.Sprintf("Этап '%s': %.1f%% времени (%.2f сек)", instance.Events[i].Description, percentage, stageDuration),
+                },
+            })
         }
     }
 
     return results
 }
 
-// collectComplexityMetrics собирает метрики сложности процесса.
-func (a *Analyzer) collectComplexityMetrics(instances map[string]*ProcessInstance) []struct {
-    metricType string
-    occurrence MetricOccurrence
-} {
-    var results []struct {
-        metricType string
-        occurrence MetricOccurrence
-    }
+// collectComplexityMetrics собирает метрики сложности процесса по вариантам, найденным при проходе по кейсам.
+func (a *Analyzer) collectComplexityMetrics(tally *caseTally) []rawOccurrence {
+    var results []rawOccurrence
 
-    uniquePaths := make(map[string]struct{})
-    totalInstances := len(instances)
+    uniquePaths := tally.paths.len()
+    totalInstances := tally.instances
 
     if totalInstances == 0 {
         return results
     }
 
-    for _, instance := range instances {
-        path := ""
-        for _, event := range instance.Events {
-            path += event.Description + "→"
-        }
-        if len(path) > 0 {
-            path = path[:len(path)-3] // Удаляем последний "→"
-        }
-        uniquePaths[path] = struct{}{}
-    }
-
-    variability := float64(len(uniquePaths)) / float64(totalInstances) * 100
+    variability := float64(uniquePaths) / float64(totalInstances) * 100
 
-    if variability > 80.0 {
-        results = append(results, struct {
-            metricType string
-            occurrence MetricOccurrence
-        }{
+    if variability > a.threshold("High Process Variability") {
+        results = append(results, rawOccurrence{
             metricType: "High Process Variability",
             occurrence: MetricOccurrence{
                 InstanceID: "ALL",
                 Value:      math.Round(variability*10) / 10,
-                Details:    fmt.Sprintf("%d уникальных путей из %d экземпляров", len(uniquePaths), totalInstances),
+                Details:    fmt.Sprintf("%d уникальных путей из %d экземпляров", uniquePaths, totalInstances),
             },
         })
     }
@@ -682,42 +681,21 @@ func (a *Analyzer) collectComplexityMetrics(instances map[string]*ProcessInstanc
     return results
 }
 
-// collectCompletionMetrics собирает метрики завершённости процесса.
-func (a *Analyzer) collectCompletionMetrics(instances map[string]*ProcessInstance) []struct {
-    metricType string
-    occurrence MetricOccurrence
-} {
-    var results []struct {
-        metricType string
-        occurrence MetricOccurrence
-    }
+// collectCompletionMetrics собирает метрики завершённости процесса по итогам прохода по кейсам.
+func (a *Analyzer) collectCompletionMetrics(tally *caseTally) []rawOccurrence {
+    var results []rawOccurrence
 
-    completedInstances := 0
-    totalInstances := len(instances)
+    completedInstances := tally.completed
+    totalInstances := tally.instances
 
     if totalInstances == 0 {
         return results
     }
 
-    for _, instance := range instances {
-        if len(instance.Events) < 2 {
-            continue
-        }
-
-        if len(instance.Events) > 1 &&
-		strings.Contains(strings.ToLower(instance.Events[0].Description), "начало") &&
-		strings.Contains(strings.ToLower(instance.Events[len(instance.Events)-1].Description), "конец") {
-            completedInstances++
-        }
-    }
-
     completionRate := float64(completedInstances) / float64(totalInstances) * 100
 
-    if completionRate < 100.0 {
-        results = append(results, struct {
-            metricType string
-            occurrence MetricOccurrence
-        }{
+    if completionRate < a.threshold("Low Process Completion Rate") {
+        results = append(results, rawOccurrence{
             metricType: "Low Process Completion Rate",
             occurrence: MetricOccurrence{
                 InstanceID: "ALL",
@@ -730,39 +708,22 @@ func (a *Analyzer) collectCompletionMetrics(instances map[string]*ProcessInstanc
     return results
 }
 
-// collectErrorMetrics собирает метрики ошибок.
-func (a *Analyzer) collectErrorMetrics(instances map[string]*ProcessInstance) []struct {
-	metricType string
-	occurrence MetricOccurrence
-} {
-	var results []struct {
-		metricType string
-		occurrence MetricOccurrence
-	}
+// isCompletedInstance проверяет, что экземпляр прошёл полный цикл: от начала до конца.
+func isCompletedInstance(instance *ProcessInstance) bool {
+	return len(instance.Events) > 1 &&
+		strings.Contains(strings.ToLower(instance.Events[0].Description), "начало") &&
+		strings.Contains(strings.ToLower(instance.Events[len(instance.Events)-1].Description), "конец")
+}
 
-	errorInstances := 0
-	successInstances := 0
+// collectErrorMetrics собирает метрики ошибок по итогам прохода по кейсам.
+func (a *Analyzer) collectErrorMetrics(tally *caseTally) []rawOccurrence {
+	var results []rawOccurrence
 
-	for _, instance := range instances {
-		hasError := false
-		for _, event := range instance.Events {
-			if event.Result == "error" {
-				hasError = true
-				break
-			}
-		}
-		if hasError {
-			errorInstances++
-		} else {
-			successInstances++
-		}
-	}
+	errorInstances := tally.failed
+	successInstances := tally.instances - tally.failed
 
 	if errorInstances > successInstances {
-		results = append(results, struct {
-			metricType string
-			occurrence MetricOccurrence
-		}{
+		results = append(results, rawOccurrence{
 			metricType: "High Error Rate",
 			occurrence: MetricOccurrence{
 				InstanceID: "ALL",
@@ -776,18 +737,17 @@ func (a *Analyzer) collectErrorMetrics(instances map[string]*ProcessInstance) []
 }
 
 // calculateLinearRegression вычисляет линейную регрессию.
-func calculateLinearRegression(data []float64) (slope, intercept float64) {
+func calculateLinearRegression(xs, ys []float64) (slope, intercept float64) {
     var sumX, sumY, sumXY, sumX2 float64
-    for i, y := range data {
-        x := float64(i)
-        sumX += x
-        sumY += y
-        sumXY += x * y
-        sumX2 += x * x
+    for i := range xs {
+        sumX += xs[i]
+        sumY += ys[i]
+        sumXY += xs[i] * ys[i]
+        sumX2 += xs[i] * xs[i]
     }
 
-    n := float64(len(data))
-    if n == 0 {
+    n := float64(len(xs))
+    if n == 0 || n*sumX2 == sumX*sumX {
         return 0, 0
     }
     slope = (n*sumXY - sumX*sumY) / (n*sumX2 - sumX*sumX)
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// DefaultTopOccurrences — количество вхождений каждой метрики в отчёте по умолчанию.
+const DefaultTopOccurrences = 10
+
+// ErrUnknownMetric возвращается при запросе несуществующей метрики или её вхождений.
+var ErrUnknownMetric = errors.New("неизвестная метрика")
+
+// OccurrencePage — страница вхождений одной метрики.
+type OccurrencePage struct {
+	Metric      string             `json:"metric"`
+	Total       int                `json:"total"`
+	Offset      int                `json:"offset"`
+	Limit       int                `json:"limit"`
+	Occurrences []MetricOccurrence `json:"occurrences"`
+}
+
+// rawOccurrence — вхождение метрики metricType, найденное сборщиком, до агрегирования в отчёт.
+type rawOccurrence struct {
+	metricType string
+	occurrence MetricOccurrence
+}
+
+// rawOccurrenceBuffers переиспользует буферы вхождений, найденных при проходе по кейсам: анализ
+// повторяется при каждой смене фильтра или порогов, и буфер прошлого анализа уже нужной ёмкости.
+// Буферы, не взятые до следующей сборки мусора, освобождаются вместе с пулом.
+var rawOccurrenceBuffers = sync.Pool{
+	New: func() any {
+		return new([]rawOccurrence)
+	},
+}
+
+// getRawOccurrences возвращает пустой буфер вхождений из пула.
+func getRawOccurrences() []rawOccurrence {
+	return (*rawOccurrenceBuffers.Get().(*[]rawOccurrence))[:0]
+}
+
+// putRawOccurrences возвращает буфер в пул; вхождения в буфере обнуляются, чтобы не удерживать их строки.
+func putRawOccurrences(buffer []rawOccurrence) {
+	if cap(buffer) == 0 {
+		return
+	}
+	clear(buffer)
+	buffer = buffer[:0]
+	rawOccurrenceBuffers.Put(&buffer)
+}
+
+// sortOccurrences упорядочивает вхождения: сначала с наибольшими потерями времени, затем по значению.
+func sortOccurrences(occurrences []MetricOccurrence) {
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		a, b := &occurrences[i], &occurrences[j]
+		if a.WastedDurationSeconds != b.WastedDurationSeconds {
+			return a.WastedDurationSeconds > b.WastedDurationSeconds
+		}
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		if a.InstanceID != b.InstanceID {
+			return a.InstanceID < b.InstanceID
+		}
+		return a.Details < b.Details
+	})
+}
+
+// TruncateOccurrences оставляет в отчёте не более top самых значимых вхождений каждой метрики.
+// Агрегаты (Count, TotalValue и т.д.) по-прежнему учитывают все вхождения.
+func (r *MetricsReport) TruncateOccurrences(top int) {
+	for i := range r.Metrics {
+		if len(r.Metrics[i].Occurrences) > top {
+			r.Metrics[i].Occurrences = r.Metrics[i].Occurrences[:top]
+		}
+	}
+}
+
+// Metric возвращает метрику отчёта с ключом key.
+func (r *MetricsReport) Metric(key string) (*InefficiencyMetric, error) {
+	for i := range r.Metrics {
+		if r.Metrics[i].Key == key {
+			return &r.Metrics[i], nil
+		}
+	}
+	return nil, ErrUnknownMetric
+}
+
+// OccurrencePage возвращает страницу вхождений метрики.
+func (m *InefficiencyMetric) OccurrencePage(offset, limit int) *OccurrencePage {
+	page := &OccurrencePage{Metric: m.Key, Total: len(m.Occurrences), Offset: offset, Limit: limit}
+	start := min(offset, len(m.Occurrences))
+	end := min(start+limit, len(m.Occurrences))
+	page.Occurrences = m.Occurrences[start:end]
+	return page
+}
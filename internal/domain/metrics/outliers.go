@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Методы поиска аномально долгих этапов и кейсов.
+const (
+	OutlierIQR             = "iqr"              // Q3 + 1.5·IQR
+	OutlierMAD             = "mad"              // модифицированная z-оценка по медианному абсолютному отклонению > 3.5
+	OutlierLogZScore       = "log_zscore"       // z-оценка логарифма длительности > 3
+	OutlierIsolationForest = "isolation_forest" // оценка аномальности isolation forest > 0.6
+)
+
+const (
+	// minOutlierSamples — минимальный размер выборки для поиска выбросов.
+	minOutlierSamples = 4
+
+	madThreshold    = 3.5
+	zScoreThreshold = 3.0
+
+	forestTrees          = 100
+	forestSampleSize     = 256
+	forestScoreThreshold = 0.6
+	forestSeed           = 1
+)
+
+// SetOutlierMethod выбирает метод поиска аномальных длительностей. Пустая строка означает IQR.
+func (a *Analyzer) SetOutlierMethod(method string) error {
+	switch method {
+	case "":
+		method = OutlierIQR
+	case OutlierIQR, OutlierMAD, OutlierLogZScore, OutlierIsolationForest:
+	default:
+		return fmt.Errorf("неизвестный метод поиска выбросов: %s", method)
+	}
+	a.outlierMethod = method
+	return nil
+}
+
+// outlierDetector обучается на упорядоченной по возрастанию выборке длительностей и возвращает
+// проверку «аномально долгое значение». Возвращает nil, если выборка слишком мала
+// или в ней нет разброса, по которому можно судить о выбросах.
+func (a *Analyzer) outlierDetector(sorted []float64) func(float64) bool {
+	if len(sorted) < minOutlierSamples {
+		return nil
+	}
+	switch a.outlierMethod {
+	case OutlierMAD:
+		return madDetector(sorted)
+	case OutlierLogZScore:
+		return logZScoreDetector(sorted)
+	case OutlierIsolationForest:
+		return isolationForestDetector(sorted)
+	default:
+		return iqrDetector(sorted)
+	}
+}
+
+func iqrDetector(sorted []float64) func(float64) bool {
+	q1 := sorted[int(math.Round(float64(len(sorted)-1)*0.25))]
+	q3 := sorted[int(math.Round(float64(len(sorted)-1)*0.75))]
+	threshold := q3 + 1.5*(q3-q1)
+	return func(v float64) bool { return v > threshold }
+}
+
+func madDetector(sorted []float64) func(float64) bool {
+	med := percentile(sorted, 50)
+	deviations := make([]float64, len(sorted))
+	for i, v := range sorted {
+		deviations[i] = math.Abs(v - med)
+	}
+	sort.Float64s(deviations)
+	mad := percentile(deviations, 50)
+	if mad == 0 {
+		// Больше половины значений совпадают с медианой: модифицированная z-оценка считается
+		// по среднему абсолютному отклонению (0.7979 = 1/1.2533); если и оно нулевое, выбросов нет
+		meanAD := mean(deviations)
+		if meanAD == 0 {
+			return nil
+		}
+		return func(v float64) bool { return 0.7979*(v-med)/meanAD > madThreshold }
+	}
+	return func(v float64) bool { return 0.6745*(v-med)/mad > madThreshold }
+}
+
+func logZScoreDetector(sorted []float64) func(float64) bool {
+	logs := make([]float64, len(sorted))
+	for i, v := range sorted {
+		logs[i] = math.Log1p(math.Max(v, 0))
+	}
+	m := mean(logs)
+	std := calculateStandardDeviation(logs, m)
+	if std == 0 {
+		return func(v float64) bool { return math.Log1p(math.Max(v, 0)) > m }
+	}
+	return func(v float64) bool { return (math.Log1p(math.Max(v, 0))-m)/std > zScoreThreshold }
+}
+
+// isolationForestDetector строит одномерный isolation forest: аномальные значения изолируются
+// случайными разбиениями быстрее остальных. Аномально долгими считаются значения выше медианы
+// с оценкой больше forestScoreThreshold.
+func isolationForestDetector(sorted []float64) func(float64) bool {
+	rng := rand.New(rand.NewSource(forestSeed))
+	sampleSize := forestSampleSize
+	if len(sorted) < sampleSize {
+		sampleSize = len(sorted)
+	}
+
+	trees := make([]*isolationNode, forestTrees)
+	heightLimit := int(math.Ceil(math.Log2(float64(sampleSize))))
+	for i := range trees {
+		sample := make([]float64, sampleSize)
+		for j := range sample {
+			sample[j] = sorted[rng.Intn(len(sorted))]
+		}
+		trees[i] = buildIsolationTree(sample, 0, heightLimit, rng)
+	}
+
+	norm := averagePathLength(sampleSize)
+	med := percentile(sorted, 50)
+	return func(v float64) bool {
+		if v <= med || norm == 0 {
+			return false
+		}
+		var total float64
+		for _, tree := range trees {
+			total += tree.pathLength(v, 0)
+		}
+		score := math.Pow(2, -total/float64(len(trees))/norm)
+		return score > forestScoreThreshold
+	}
+}
+
+type isolationNode struct {
+	split       float64
+	size        int
+	left, right *isolationNode
+}
+
+func buildIsolationTree(sample []float64, depth, limit int, rng *rand.Rand) *isolationNode {
+	low, high := sample[0], sample[0]
+	for _, v := range sample {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	if depth >= limit || len(sample) <= 1 || low == high {
+		return &isolationNode{size: len(sample)}
+	}
+
+	split := low + rng.Float64()*(high-low)
+	var left, right []float64
+	for _, v := range sample {
+		if v < split {
+			left = append(left, v)
+		} else {
+			right = append(right, v)
+		}
+	}
+	return &isolationNode{
+		split: split,
+		left:  buildIsolationTree(left, depth+1, limit, rng),
+		right: buildIsolationTree(right, depth+1, limit, rng),
+	}
+}
+
+func (n *isolationNode) pathLength(v float64, depth int) float64 {
+	if n.left == nil {
+		return float64(depth) + averagePathLength(n.size)
+	}
+	if v < n.split {
+		return n.left.pathLength(v, depth+1)
+	}
+	return n.right.pathLength(v, depth+1)
+}
+
+// averagePathLength — средняя длина безуспешного поиска в двоичном дереве из n элементов.
+func averagePathLength(n int) float64 {
+	if n <= 1 {
+		return 0
+	}
+	if n == 2 {
+		return 1
+	}
+	harmonic := math.Log(float64(n-1)) + 0.5772156649
+	return 2*harmonic - 2*float64(n-1)/float64(n)
+}
+
+// stageBaseline — эталон длительности этапа: проверка на выброс и средняя длительность.
+type stageBaseline struct {
+	isOutlier func(float64) bool
+	average   float64
+	scope     string // по какой выборке построен эталон
+}
+
+// stageBaselines строит эталоны длительности для каждого перехода (from→to), а для переходов
+// с малым числом наблюдений — для целевой активности. Так естественно долгие этапы
+// сравниваются только с собой, а не с быстрыми. Длительности этапов берутся из индекса
+// длительностей (см. DurationIndex) уже отсортированными.
+func (a *Analyzer) stageBaselines(durations *DurationIndex) func(from, to ActivityID) *stageBaseline {
+	baseline := func(series *durationSeries, scope string) *stageBaseline {
+		isOutlier := a.outlierDetector(series.values)
+		if isOutlier == nil {
+			return nil
+		}
+		return &stageBaseline{isOutlier: isOutlier, average: mean(series.values), scope: scope}
+	}
+
+	transitions := make(map[[2]ActivityID]*stageBaseline, len(durations.transitions))
+	for key, series := range durations.transitions {
+		if b := baseline(series, "переход"); b != nil {
+			transitions[key] = b
+		}
+	}
+	activities := make(map[ActivityID]*stageBaseline, len(durations.activities))
+	for activity, series := range durations.activities {
+		if b := baseline(series, "активность"); b != nil {
+			activities[activity] = b
+		}
+	}
+
+	return func(from, to ActivityID) *stageBaseline {
+		if b, ok := transitions[[2]ActivityID{from, to}]; ok {
+			return b
+		}
+		return activities[to]
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestMADDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   []float64
+		outliers []float64 // без outliers и normal проверки быть не должно
+		normal   []float64
+	}{
+		{
+			name:   "постоянная выборка",
+			sample: []float64{60, 60, 60, 60, 60, 60},
+		},
+		{
+			name:     "MAD = 0, один выброс",
+			sample:   []float64{60, 60, 60, 60, 60, 60, 60, 61, 3600},
+			outliers: []float64{3600},
+			normal:   []float64{60, 61},
+		},
+		{
+			name:     "MAD > 0",
+			sample:   []float64{50, 55, 60, 62, 65, 70, 75, 600},
+			outliers: []float64{600},
+			normal:   []float64{50, 60, 75, 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := append([]float64(nil), tt.sample...)
+			sort.Float64s(sorted)
+			a := &Analyzer{}
+			if err := a.SetOutlierMethod(OutlierMAD); err != nil {
+				t.Fatal(err)
+			}
+			isOutlier := a.outlierDetector(sorted)
+			if tt.outliers == nil && tt.normal == nil {
+				if isOutlier != nil {
+					t.Fatal("для выборки без разброса возвращена проверка")
+				}
+				return
+			}
+			if isOutlier == nil {
+				t.Fatal("проверка не построена")
+			}
+			for _, v := range tt.outliers {
+				if !isOutlier(v) {
+					t.Errorf("%v не считается выбросом", v)
+				}
+			}
+			for _, v := range tt.normal {
+				if isOutlier(v) {
+					t.Errorf("%v считается выбросом", v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minShardInstances — наименьшее число кейсов в части: на небольших логах запуск горутин
+// обходится дороже самого анализа.
+const minShardInstances = 1000
+
+// shardInstances делит экземпляры на части для параллельного анализа: не больше runtime.GOMAXPROCS(0)
+// частей и не меньше minShardInstances кейсов в каждой. Небольшой лог остаётся одной частью.
+func shardInstances(instances map[string]*ProcessInstance) []map[string]*ProcessInstance {
+	n := min(runtime.GOMAXPROCS(0), max(1, len(instances)/minShardInstances))
+	if n == 1 {
+		return []map[string]*ProcessInstance{instances}
+	}
+	shards := make([]map[string]*ProcessInstance, n)
+	for i := range shards {
+		shards[i] = make(map[string]*ProcessInstance, len(instances)/n+1)
+	}
+	i := 0
+	for id, instance := range instances {
+		shards[i%n][id] = instance
+		i++
+	}
+	return shards
+}
+
+// forEachShard вызывает fn для каждой части в отдельной горутине и возвращается, когда обработаны
+// все части. fn получает номер части, чтобы записать результат в свою ячейку без блокировок.
+func forEachShard(shards []map[string]*ProcessInstance, fn func(i int, shard map[string]*ProcessInstance)) {
+	if len(shards) == 1 {
+		fn(0, shards[0])
+		return
+	}
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, shard)
+		}()
+	}
+	wg.Wait()
+}
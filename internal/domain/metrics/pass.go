@@ -0,0 +1,190 @@
+package metrics
+
+import "slices"
+
+// caseTally — итог одного прохода по кейсам части лога: вхождения метрик, которые определяются
+// самим кейсом (в буфере из пула, см. getRawOccurrences), и данные, по которым затем считаются
+// сводка и метрики всего лога.
+type caseTally struct {
+	results        []rawOccurrence
+	instances      int
+	events         int
+	completed      int // кейсов, дошедших до завершения
+	failed         int // кейсов с ошибочным событием
+	activityCounts map[ActivityID]int
+	activityNames  map[ActivityID]string
+	paths          *pathIndex
+	pathCounts     []int        // номер варианта в paths → число кейсов
+	caseDurations  []float64    // длительности кейсов из двух и более событий
+	instancePoints []timedValue // длительности кейсов на момент их начала
+	stagePoints    []timedValue // длительности этапов на момент их завершения
+	byTransition   map[[2]ActivityID][]float64
+	byActivity     map[ActivityID][]float64
+}
+
+// tallyParts — части прохода по кейсам: полный отчёт собирает все части, отдельная метрика
+// (см. AnalyzeMetric) — только нужные ей.
+type tallyParts uint8
+
+const (
+	tallyCounts    tallyParts = 1 << iota // события, активности, варианты, завершённые и ошибочные кейсы
+	tallyDurations                        // моменты длительностей этапов и кейсов для трендов
+	tallySamples                          // сами длительности по переходам и кейсам (если нет индекса длительностей)
+	tallyLoops                            // вхождения метрик зацикливания
+	tallyManual                           // вхождения метрики ручных этапов
+
+	tallyAll = tallyCounts | tallyDurations | tallyLoops | tallyManual
+)
+
+// tallyCases проходит по каждому кейсу один раз: в одном цикле по событиям ищутся зацикливания
+// и ручные этапы, собираются длительности этапов и кейсов, варианты и признаки завершения и ошибок.
+// Собираются только части parts. Активности сравниваются и группируются по номерам (см. Labels).
+func (a *Analyzer) tallyCases(instances map[string]*ProcessInstance, parts tallyParts) *caseTally {
+	tally := &caseTally{
+		instances:      len(instances),
+		activityCounts: make(map[ActivityID]int),
+		activityNames:  make(map[ActivityID]string),
+		paths:          newPathIndex(),
+		byTransition:   make(map[[2]ActivityID][]float64),
+		byActivity:     make(map[ActivityID][]float64),
+	}
+	if parts&(tallyLoops|tallyManual) != 0 {
+		tally.results = getRawOccurrences()
+	}
+	if parts&tallyDurations != 0 {
+		// Моменты длительностей собираются по каждому кейсу и этапу: ёмкость известна заранее
+		stages := 0
+		for _, instance := range instances {
+			stages += max(len(instance.Events)-1, 0)
+		}
+		tally.instancePoints = make([]timedValue, 0, len(instances))
+		tally.stagePoints = make([]timedValue, 0, stages)
+		if parts&tallySamples != 0 {
+			tally.caseDurations = make([]float64, 0, len(instances))
+		}
+	}
+	for _, instance := range instances {
+		events := instance.Events
+		if parts&tallyCounts != 0 {
+			tally.events += len(events)
+			if a.errors.HasError(events) {
+				tally.failed++
+			}
+
+			for _, event := range events {
+				if tally.activityCounts[event.Activity] == 0 {
+					tally.activityNames[event.Activity] = event.Description
+				}
+				tally.activityCounts[event.Activity]++
+			}
+			tally.countPath(tally.paths.of(events), 1)
+		}
+
+		if len(events) < 2 {
+			// Пропускаем экземпляры с менее чем двумя событиями, так как длительность не может быть рассчитана.
+			if parts&tallyDurations != 0 {
+				a.Logger.Warn("Экземпляр имеет менее двух событий, длительность не может быть рассчитана", "instance_id", instance.ID)
+			}
+			continue
+		}
+		if parts&tallyCounts != 0 && a.IsCompleted(instance) {
+			tally.completed++
+		}
+		caseDuration := events[len(events)-1].Timestamp.Sub(events[0].Timestamp).Seconds()
+		if parts&tallyDurations != 0 {
+			a.tallyDurations(tally, instance, caseDuration, parts&tallySamples != 0)
+		}
+
+		if parts&tallyLoops != 0 {
+			tally.results = a.collectLoopingMetrics(instance, tally.results)
+		}
+		if parts&tallyManual != 0 {
+			tally.results = a.collectManualStageMetrics(instance, caseDuration, tally.results)
+		}
+	}
+	return tally
+}
+
+// tallyDurations заносит в итог длительности кейса instance (caseDuration сек) и его этапов;
+// сами длительности по переходам и кейсам заносятся, если задан samples.
+func (a *Analyzer) tallyDurations(tally *caseTally, instance *ProcessInstance, caseDuration float64, samples bool) {
+	events := instance.Events
+	if samples {
+		tally.caseDurations = append(tally.caseDurations, caseDuration)
+	}
+	tally.instancePoints = append(tally.instancePoints, timedValue{at: events[0].Timestamp, value: caseDuration})
+
+	for i := 1; i < len(events); i++ {
+		prev, curr := events[i-1], events[i]
+		if prev.Timestamp.IsZero() || curr.Timestamp.IsZero() {
+			a.Logger.Warn("Обнаружена нулевая временная метка, пропуск расчета длительности", "instance_id", instance.ID, "event_index_1", i-1, "event_index_2", i)
+			continue
+		}
+		if curr.Timestamp.Before(prev.Timestamp) {
+			a.Logger.Warn("Некорректный порядок временных меток", "instance_id", instance.ID, "event_index_1", i-1, "timestamp_1", prev.Timestamp, "event_index_2", i, "timestamp_2", curr.Timestamp)
+			continue
+		}
+		duration := curr.Timestamp.Sub(prev.Timestamp).Seconds()
+		tally.stagePoints = append(tally.stagePoints, timedValue{at: curr.Timestamp, value: duration})
+		if samples {
+			transition := [2]ActivityID{prev.Activity, curr.Activity}
+			tally.byTransition[transition] = append(tally.byTransition[transition], duration)
+			tally.byActivity[curr.Activity] = append(tally.byActivity[curr.Activity], duration)
+		}
+	}
+}
+
+// tallyShards выполняет tallyCases для каждой части параллельно и объединяет итоги частей.
+func (a *Analyzer) tallyShards(shards []map[string]*ProcessInstance, parts tallyParts) *caseTally {
+	tallies := make([]*caseTally, len(shards))
+	forEachShard(shards, func(i int, shard map[string]*ProcessInstance) {
+		tallies[i] = a.tallyCases(shard, parts)
+	})
+	total := tallies[0]
+	var results, caseDurations, instancePoints, stagePoints int
+	for _, tally := range tallies {
+		results += len(tally.results)
+		caseDurations += len(tally.caseDurations)
+		instancePoints += len(tally.instancePoints)
+		stagePoints += len(tally.stagePoints)
+	}
+	total.results = slices.Grow(total.results, results-len(total.results))
+	total.caseDurations = slices.Grow(total.caseDurations, caseDurations-len(total.caseDurations))
+	total.instancePoints = slices.Grow(total.instancePoints, instancePoints-len(total.instancePoints))
+	total.stagePoints = slices.Grow(total.stagePoints, stagePoints-len(total.stagePoints))
+	for _, tally := range tallies[1:] {
+		total.results = append(total.results, tally.results...)
+		putRawOccurrences(tally.results)
+		total.instances += tally.instances
+		total.events += tally.events
+		total.completed += tally.completed
+		total.failed += tally.failed
+		for activity, count := range tally.activityCounts {
+			if total.activityCounts[activity] == 0 {
+				total.activityNames[activity] = tally.activityNames[activity]
+			}
+			total.activityCounts[activity] += count
+		}
+		for number, count := range tally.pathCounts {
+			total.countPath(total.paths.merge(tally.paths, number), count)
+		}
+		total.caseDurations = append(total.caseDurations, tally.caseDurations...)
+		total.instancePoints = append(total.instancePoints, tally.instancePoints...)
+		total.stagePoints = append(total.stagePoints, tally.stagePoints...)
+		for transition, durations := range tally.byTransition {
+			total.byTransition[transition] = append(total.byTransition[transition], durations...)
+		}
+		for activity, durations := range tally.byActivity {
+			total.byActivity[activity] = append(total.byActivity[activity], durations...)
+		}
+	}
+	return total
+}
+
+// countPath прибавляет count кейсов к варианту с номером number.
+func (t *caseTally) countPath(number, count int) {
+	if number == len(t.pathCounts) {
+		t.pathCounts = append(t.pathCounts, 0)
+	}
+	t.pathCounts[number] += count
+}
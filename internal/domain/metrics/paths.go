@@ -0,0 +1,82 @@
+package metrics
+
+import "slices"
+
+// Параметры 64-битного хеша FNV-1a.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashPath возвращает хеш FNV-1a последовательности номеров активностей.
+func hashPath(activities []ActivityID) uint64 {
+	h := uint64(fnvOffset64)
+	for _, activity := range activities {
+		for shift := 0; shift < 32; shift += 8 {
+			h ^= uint64(byte(uint32(activity) >> shift))
+			h *= fnvPrime64
+		}
+	}
+	return h
+}
+
+// pathIndex нумерует варианты процесса (последовательности активностей кейсов). Варианты ищутся
+// по хешу номеров активностей, так что для кейса не строится строка пути; варианты с совпавшим хешем
+// различаются сравнением последовательностей. Названия активностей варианта сохраняются один раз,
+// при его первом появлении. Индекс не безопасен для одновременной записи.
+type pathIndex struct {
+	byHash     map[uint64][]int
+	activities [][]ActivityID
+	names      [][]string
+	scratch    []ActivityID
+}
+
+func newPathIndex() *pathIndex {
+	return &pathIndex{byHash: make(map[uint64][]int)}
+}
+
+// of возвращает номер варианта кейса с событиями events. События должны быть пронумерованы (см. Labels).
+func (p *pathIndex) of(events []Event) int {
+	p.scratch = p.scratch[:0]
+	for i := range events {
+		p.scratch = append(p.scratch, events[i].Activity)
+	}
+	number, added := p.find(p.scratch)
+	if added {
+		names := make([]string, len(events))
+		for i := range events {
+			names[i] = events[i].Description
+		}
+		p.names = append(p.names, names)
+	}
+	return number
+}
+
+// merge добавляет вариант number индекса other и возвращает его номер в p.
+func (p *pathIndex) merge(other *pathIndex, number int) int {
+	merged, added := p.find(other.activities[number])
+	if added {
+		p.names = append(p.names, other.names[number])
+	}
+	return merged
+}
+
+// find возвращает номер варианта activities; added сообщает, что вариант добавлен в индекс
+// (тогда вызывающий дописывает его названия в names).
+func (p *pathIndex) find(activities []ActivityID) (number int, added bool) {
+	hash := hashPath(activities)
+	for _, candidate := range p.byHash[hash] {
+		if slices.Equal(p.activities[candidate], activities) {
+			return candidate, false
+		}
+	}
+	number = len(p.activities)
+	p.byHash[hash] = append(p.byHash[hash], number)
+	p.activities = append(p.activities, slices.Clone(activities))
+	return number, true
+}
+
+// len возвращает число различных вариантов.
+func (p *pathIndex) len() int {
+	return len(p.activities)
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ResourceUtilization — загрузка одного ресурса (исполнителя).
+type ResourceUtilization struct {
+	Resource    string  `json:"resource"`
+	Events      int     `json:"events"`
+	Cases       int     `json:"cases"`
+	ActiveTime  float64 `json:"active_time"` // сек, объединение интервалов работы
+	IdleTime    float64 `json:"idle_time"`   // сек, от первого до последнего события за вычетом активного времени
+	Utilization float64 `json:"utilization"` // доля активного времени, %
+}
+
+// ResourceReport — загрузка ресурсов и неравномерность распределения работы.
+type ResourceReport struct {
+	Resources []ResourceUtilization `json:"resources"` // по убыванию количества событий
+	// WorkloadGini — коэффициент Джини количества событий по ресурсам: 0 — работа распределена
+	// поровну, близко к 1 — почти вся работа у одного ресурса.
+	WorkloadGini float64 `json:"workload_gini"`
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// ResourceUtilizations считает загрузку ресурсов. Интервал работы события — от начала обработки,
+// а если оно неизвестно — от предыдущего события кейса. Возвращает nil, если в логе нет ресурсов.
+func ResourceUtilizations(instances map[string]*ProcessInstance) *ResourceReport {
+	type accumulator struct {
+		events    int
+		cases     map[string]struct{}
+		intervals []interval
+		first     time.Time
+		last      time.Time
+	}
+	byResource := make(map[string]*accumulator)
+
+	for id, instance := range instances {
+		for i, event := range instance.Events {
+			if event.Resource == "" {
+				continue
+			}
+			acc := byResource[event.Resource]
+			if acc == nil {
+				acc = &accumulator{cases: make(map[string]struct{}), first: event.Timestamp, last: event.Timestamp}
+				byResource[event.Resource] = acc
+			}
+			acc.events++
+			acc.cases[id] = struct{}{}
+
+			start := eventStart(instance.Events, i)
+			if start.Before(event.Timestamp) {
+				acc.intervals = append(acc.intervals, interval{start: start, end: event.Timestamp})
+			}
+			if start.Before(acc.first) {
+				acc.first = start
+			}
+			if event.Timestamp.After(acc.last) {
+				acc.last = event.Timestamp
+			}
+		}
+	}
+	if len(byResource) == 0 {
+		return nil
+	}
+
+	report := &ResourceReport{Resources: make([]ResourceUtilization, 0, len(byResource))}
+	workloads := make([]float64, 0, len(byResource))
+	for resource, acc := range byResource {
+		active := unionDuration(acc.intervals).Seconds()
+		span := acc.last.Sub(acc.first).Seconds()
+		utilization := ResourceUtilization{
+			Resource:   resource,
+			Events:     acc.events,
+			Cases:      len(acc.cases),
+			ActiveTime: active,
+			IdleTime:   math.Max(span-active, 0),
+		}
+		if span > 0 {
+			utilization.Utilization = math.Round(active/span*1000) / 10
+		}
+		report.Resources = append(report.Resources, utilization)
+		workloads = append(workloads, float64(acc.events))
+	}
+	sort.Slice(report.Resources, func(i, j int) bool {
+		if report.Resources[i].Events != report.Resources[j].Events {
+			return report.Resources[i].Events > report.Resources[j].Events
+		}
+		return report.Resources[i].Resource < report.Resources[j].Resource
+	})
+	report.WorkloadGini = math.Round(gini(workloads)*1000) / 1000
+
+	return report
+}
+
+// eventStart возвращает начало работы над событием i: начало обработки,
+// а если оно неизвестно — время предыдущего события кейса.
+func eventStart(events []Event, i int) time.Time {
+	event := events[i]
+	if !event.Start.IsZero() && event.Start.Before(event.Timestamp) {
+		return event.Start
+	}
+	if i > 0 && events[i-1].Timestamp.Before(event.Timestamp) {
+		return events[i-1].Timestamp
+	}
+	return event.Timestamp
+}
+
+// unionDuration возвращает суммарную длительность объединения интервалов.
+func unionDuration(intervals []interval) time.Duration {
+	if len(intervals) == 0 {
+		return 0
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+
+	var total time.Duration
+	current := intervals[0]
+	for _, next := range intervals[1:] {
+		if next.start.After(current.end) {
+			total += current.end.Sub(current.start)
+			current = next
+			continue
+		}
+		if next.end.After(current.end) {
+			current.end = next.end
+		}
+	}
+	return total + current.end.Sub(current.start)
+}
+
+// gini вычисляет коэффициент Джини неотрицательных значений.
+func gini(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var weighted, total float64
+	for i, v := range sorted {
+		weighted += float64(i+1) * v
+		total += v
+	}
+	if total == 0 {
+		return 0
+	}
+	n := float64(len(sorted))
+	return (2*weighted)/(n*total) - (n+1)/n
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+)
+
+// MissingSegment — значение сегмента для кейсов без атрибута сегментации.
+const MissingSegment = "(нет значения)"
+
+// ErrUnknownAttribute возвращается, если атрибут сегментации не встречается ни в одном кейсе.
+var ErrUnknownAttribute = errors.New("атрибут не найден в логе")
+
+// Segment — отчёт по кейсам с одним значением атрибута.
+type Segment struct {
+	Value  string         `json:"value"`
+	Cases  int            `json:"cases"`
+	Report *MetricsReport `json:"report"`
+}
+
+// SegmentedReport — отчёт по метрикам, разбитый по значениям атрибута кейса.
+type SegmentedReport struct {
+	Attribute string    `json:"attribute"`
+	Segments  []Segment `json:"segments"`
+}
+
+// caseAttribute возвращает значение атрибута кейса — первое непустое значение в его событиях.
+func caseAttribute(instance *ProcessInstance, attribute string) (string, bool) {
+	for _, event := range instance.Events {
+		if value := event.Attributes[attribute]; value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// AnalyzeSegments строит отчёт по метрикам отдельно для каждого значения атрибута кейса,
+// начиная с самых крупных сегментов. Кейсы без атрибута попадают в сегмент MissingSegment.
+func (a *Analyzer) AnalyzeSegments(instances map[string]*ProcessInstance, attribute string) (*SegmentedReport, error) {
+	segments := make(map[string]map[string]*ProcessInstance)
+	found := false
+	for id, instance := range instances {
+		value, ok := caseAttribute(instance, attribute)
+		if ok {
+			found = true
+		} else {
+			value = MissingSegment
+		}
+		if segments[value] == nil {
+			segments[value] = make(map[string]*ProcessInstance)
+		}
+		segments[value][id] = instance
+	}
+	if !found {
+		return nil, ErrUnknownAttribute
+	}
+
+	result := &SegmentedReport{Attribute: attribute, Segments: make([]Segment, 0, len(segments))}
+	for value, segment := range segments {
+		result.Segments = append(result.Segments, Segment{Value: value, Cases: len(segment), Report: a.Analyze(segment)})
+	}
+	sort.Slice(result.Segments, func(i, j int) bool {
+		if result.Segments[i].Cases != result.Segments[j].Cases {
+			return result.Segments[i].Cases > result.Segments[j].Cases
+		}
+		return result.Segments[i].Value < result.Segments[j].Value
+	})
+	return result, nil
+}
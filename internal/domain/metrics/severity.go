@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// Веса составляющих оценки критичности метрики.
+const (
+	severityCountWeight  = 0.3
+	severityWastedWeight = 0.4
+	severityCostWeight   = 0.3
+)
+
+// prioritize вычисляет для каждой метрики оценку критичности от 0 до 100 — взвешенную сумму
+// количества вхождений, потерянного времени и стоимости, нормированных на максимум по всем метрикам, —
+// и сортирует метрики по убыванию критичности. Если стоимость не рассчитывалась, её вес не учитывается.
+func prioritize(metrics []InefficiencyMetric) {
+	var maxCount, maxWasted, maxCost float64
+	for _, metric := range metrics {
+		maxCount = math.Max(maxCount, float64(metric.Count))
+		maxWasted = math.Max(maxWasted, metric.TotalWastedDuration)
+		maxCost = math.Max(maxCost, metric.TotalWastedCost)
+	}
+
+	for i := range metrics {
+		metric := &metrics[i]
+		var score, weights float64
+		if maxCount > 0 {
+			score += severityCountWeight * float64(metric.Count) / maxCount
+			weights += severityCountWeight
+		}
+		if maxWasted > 0 {
+			score += severityWastedWeight * math.Max(metric.TotalWastedDuration, 0) / maxWasted
+			weights += severityWastedWeight
+		}
+		if maxCost > 0 {
+			score += severityCostWeight * math.Max(metric.TotalWastedCost, 0) / maxCost
+			weights += severityCostWeight
+		}
+		if weights > 0 {
+			metric.Severity = math.Round(score/weights*1000) / 10
+		}
+	}
+
+	sort.SliceStable(metrics, func(i, j int) bool {
+		if metrics[i].Severity != metrics[j].Severity {
+			return metrics[i].Severity > metrics[j].Severity
+		}
+		if metrics[i].Exceeded != metrics[j].Exceeded {
+			return metrics[i].Exceeded
+		}
+		return metrics[i].Definition.Name < metrics[j].Definition.Name
+	})
+	for i := range metrics {
+		metrics[i].Priority = i + 1
+	}
+}
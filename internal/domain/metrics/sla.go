@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SLARule ограничивает длительность перехода From→To или выполнения активности Activity.
+type SLARule struct {
+	Name               string  `json:"name,omitempty"`
+	From               string  `json:"from,omitempty"`
+	To                 string  `json:"to,omitempty"`
+	Activity           string  `json:"activity,omitempty"`
+	MaxDurationSeconds float64 `json:"max_duration_seconds"`
+}
+
+// SLA — соглашение об уровне обслуживания: предельная длительность кейса и отдельных этапов.
+type SLA struct {
+	MaxCaseDurationSeconds float64   `json:"max_case_duration_seconds,omitempty"` // 0 — без ограничения
+	Rules                  []SLARule `json:"rules,omitempty"`
+}
+
+// Validate проверяет корректность правил SLA.
+func (s SLA) Validate() error {
+	if s.MaxCaseDurationSeconds < 0 {
+		return errors.New("максимальная длительность кейса не может быть отрицательной")
+	}
+	for i, rule := range s.Rules {
+		if rule.MaxDurationSeconds <= 0 {
+			return fmt.Errorf("правило SLA %d: max_duration_seconds должно быть положительным", i+1)
+		}
+		transition := rule.From != "" || rule.To != ""
+		if transition == (rule.Activity != "") {
+			return fmt.Errorf("правило SLA %d: укажите либо from и to, либо activity", i+1)
+		}
+		if transition && (rule.From == "" || rule.To == "") {
+			return fmt.Errorf("правило SLA %d: для перехода нужны и from, и to", i+1)
+		}
+	}
+	return nil
+}
+
+// label возвращает название правила для детализации нарушения.
+func (r SLARule) label() string {
+	switch {
+	case r.Name != "":
+		return r.Name
+	case r.Activity != "":
+		return fmt.Sprintf("активность %s", r.Activity)
+	default:
+		return fmt.Sprintf("переход %s → %s", r.From, r.To)
+	}
+}
+
+// SetSLA задаёт SLA, нарушения которого попадают в метрику "SLA Breach".
+func (a *Analyzer) SetSLA(sla SLA) error {
+	if err := sla.Validate(); err != nil {
+		return err
+	}
+	a.sla = sla
+	return nil
+}
+
+// activityDuration возвращает длительность выполнения события: от начала обработки,
+// а если оно неизвестно — от завершения предыдущего события кейса.
+func activityDuration(events []Event, i int) (time.Duration, bool) {
+	event := events[i]
+	if !event.Start.IsZero() && !event.Start.After(event.Timestamp) {
+		return event.Timestamp.Sub(event.Start), true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return event.Timestamp.Sub(events[i-1].Timestamp), true
+}
+
+// collectSLAMetrics собирает нарушения SLA. Значение вхождения — превышение лимита в секундах.
+func (a *Analyzer) collectSLAMetrics(instances map[string]*ProcessInstance) []rawOccurrence {
+	var results []rawOccurrence
+
+	addBreach := func(instanceID string, event *Event, actual, limit float64, details string) {
+		occurrence := MetricOccurrence{
+			InstanceID:            instanceID,
+			Value:                 actual - limit,
+			WastedDurationSeconds: actual - limit,
+			Details: fmt.Sprintf("%s: %.0f сек при лимите %.0f сек (превышение %.0f сек)",
+				details, actual, limit, actual-limit),
+		}
+		if event != nil {
+			occurrence.Activity = event.Description
+			occurrence.Resource = event.Resource
+		}
+		results = append(results, rawOccurrence{
+			metricType: "SLA Breach",
+			occurrence: occurrence,
+		})
+	}
+
+	for id, instance := range instances {
+		events := instance.Events
+		if len(events) == 0 {
+			continue
+		}
+
+		if limit := a.sla.MaxCaseDurationSeconds; limit > 0 {
+			start := events[0].Timestamp
+			if !events[0].Start.IsZero() && events[0].Start.Before(start) {
+				start = events[0].Start
+			}
+			if actual := events[len(events)-1].Timestamp.Sub(start).Seconds(); actual > limit {
+				addBreach(id, nil, actual, limit, "длительность кейса")
+			}
+		}
+
+		for _, rule := range a.sla.Rules {
+			for i := range events {
+				var duration time.Duration
+				switch {
+				case rule.Activity != "":
+					if events[i].Description != rule.Activity {
+						continue
+					}
+					d, ok := activityDuration(events, i)
+					if !ok {
+						continue
+					}
+					duration = d
+				default:
+					if i == 0 || events[i-1].Description != rule.From || events[i].Description != rule.To {
+						continue
+					}
+					duration = events[i].Timestamp.Sub(events[i-1].Timestamp)
+				}
+				if actual := duration.Seconds(); actual > rule.MaxDurationSeconds {
+					addBreach(id, &events[i], actual, rule.MaxDurationSeconds, rule.label())
+				}
+			}
+		}
+	}
+
+	return results
+}
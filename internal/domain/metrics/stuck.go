@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultStuckCaseAge — возраст последнего события, после которого незавершённый кейс считается застрявшим.
+const DefaultStuckCaseAge = 7 * 24 * time.Hour
+
+// StuckCase — незавершённый кейс, по которому давно не было событий.
+type StuckCase struct {
+	CaseID     string    `json:"case_id"`
+	StuckAt    string    `json:"stuck_at"` // последняя выполненная активность
+	LastEvent  time.Time `json:"last_event"`
+	AgeSeconds float64   `json:"age_seconds"` // время с последнего события до конца лога
+}
+
+// SetEndActivities задаёт активности, которыми завершается процесс. Без них завершённым
+// считается кейс, начинающийся с «начало» и заканчивающийся «конец».
+func (a *Analyzer) SetEndActivities(activities []string) {
+	a.endActivities = make(map[string]bool, len(activities))
+	for _, activity := range activities {
+		a.endActivities[activity] = true
+	}
+}
+
+// IsCompleted проверяет, что экземпляр дошёл до конца процесса (см. SetEndActivities).
+func (a *Analyzer) IsCompleted(instance *ProcessInstance) bool {
+	if len(a.endActivities) == 0 {
+		return isCompletedInstance(instance)
+	}
+	for _, event := range instance.Events {
+		if a.endActivities[event.Description] {
+			return true
+		}
+	}
+	return false
+}
+
+// isEndActivity проверяет, что активность завершает процесс.
+func (a *Analyzer) isEndActivity(activity string) bool {
+	if len(a.endActivities) == 0 {
+		return strings.Contains(strings.ToLower(activity), "конец")
+	}
+	return a.endActivities[activity]
+}
+
+// StuckCases находит кейсы, не дошедшие до завершающей активности, последнее событие которых
+// старше maxAge относительно последнего события лога. Кейсы отсортированы по убыванию возраста.
+func (a *Analyzer) StuckCases(instances map[string]*ProcessInstance, maxAge time.Duration) []StuckCase {
+	var logEnd time.Time
+	for _, instance := range instances {
+		if n := len(instance.Events); n > 0 && instance.Events[n-1].Timestamp.After(logEnd) {
+			logEnd = instance.Events[n-1].Timestamp
+		}
+	}
+
+	stuck := []StuckCase{}
+	for id, instance := range instances {
+		n := len(instance.Events)
+		if n == 0 {
+			continue
+		}
+		reachedEnd := false
+		for _, event := range instance.Events {
+			if a.isEndActivity(event.Description) {
+				reachedEnd = true
+				break
+			}
+		}
+		last := instance.Events[n-1]
+		if age := logEnd.Sub(last.Timestamp); !reachedEnd && age > maxAge {
+			stuck = append(stuck, StuckCase{
+				CaseID:     id,
+				StuckAt:    last.Description,
+				LastEvent:  last.Timestamp,
+				AgeSeconds: age.Seconds(),
+			})
+		}
+	}
+
+	sort.Slice(stuck, func(i, j int) bool {
+		if stuck[i].AgeSeconds != stuck[j].AgeSeconds {
+			return stuck[i].AgeSeconds > stuck[j].AgeSeconds
+		}
+		return stuck[i].CaseID < stuck[j].CaseID
+	})
+	return stuck
+}
+
+// collectStuckCaseMetrics собирает застрявшие кейсы. Порог метрики — допустимый возраст в секундах.
+func (a *Analyzer) collectStuckCaseMetrics(instances map[string]*ProcessInstance) []rawOccurrence {
+	maxAge := a.threshold("Stuck Case")
+	stuckCases := a.StuckCases(instances, time.Duration(maxAge*float64(time.Second)))
+	results := make([]rawOccurrence, 0, len(stuckCases))
+	for _, stuck := range stuckCases {
+		results = append(results, rawOccurrence{
+			metricType: "Stuck Case",
+			occurrence: MetricOccurrence{
+				InstanceID:            stuck.CaseID,
+				Value:                 stuck.AgeSeconds,
+				WastedDurationSeconds: stuck.AgeSeconds - maxAge,
+				Details:               fmt.Sprintf("Застрял на '%s': нет событий %.0f сек", stuck.StuckAt, stuck.AgeSeconds),
+				Activity:              stuck.StuckAt,
+			},
+		})
+	}
+
+	return results
+}
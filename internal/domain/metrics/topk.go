@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+// defaultTopK — сколько самых частых активностей и путей попадает в отчёт, если число не задано.
+const defaultTopK = 5
+
+// SetTopK задаёт, сколько самых частых активностей и путей попадает в отчёт. 0 означает значение по умолчанию (5).
+func (a *Analyzer) SetTopK(k int) error {
+	if k < 0 {
+		return errors.New("число самых частых активностей и путей не может быть отрицательным")
+	}
+	if k == 0 {
+		k = defaultTopK
+	}
+	a.topK = k
+	return nil
+}
+
+// topSelector отбирает k первых элементов в порядке before, не сортируя все элементы. Отобранные
+// хранятся в куче, на вершине которой — последний из них; новый элемент вытесняет его, если идёт раньше.
+// Отбор из n элементов занимает O(n log k) времени и O(k) памяти, что важно при миллионах вариантов.
+type topSelector[T any] struct {
+	k      int
+	before func(a, b T) bool
+	items  []T
+}
+
+func newTopSelector[T any](k int, before func(a, b T) bool) *topSelector[T] {
+	return &topSelector[T]{k: k, before: before}
+}
+
+// offer предлагает элемент для отбора.
+func (s *topSelector[T]) offer(item T) {
+	if len(s.items) < s.k {
+		s.items = append(s.items, item)
+		s.up(len(s.items) - 1)
+		return
+	}
+	if s.k > 0 && s.before(item, s.items[0]) {
+		s.items[0] = item
+		s.down(0)
+	}
+}
+
+// sorted возвращает отобранные элементы в порядке before (nil — не отобрано ни одного).
+func (s *topSelector[T]) sorted() []T {
+	sort.Slice(s.items, func(i, j int) bool { return s.before(s.items[i], s.items[j]) })
+	return s.items
+}
+
+// later сообщает, что элемент i идёт после элемента j и потому ближе к вершине кучи.
+func (s *topSelector[T]) later(i, j int) bool {
+	return s.before(s.items[j], s.items[i])
+}
+
+func (s *topSelector[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !s.later(i, parent) {
+			return
+		}
+		s.items[i], s.items[parent] = s.items[parent], s.items[i]
+		i = parent
+	}
+}
+
+func (s *topSelector[T]) down(i int) {
+	for {
+		top := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(s.items) && s.later(child, top) {
+				top = child
+			}
+		}
+		if top == i {
+			return
+		}
+		s.items[i], s.items[top] = s.items[top], s.items[i]
+		i = top
+	}
+}
+
+// topActivities возвращает k самых частых активностей; при равной частоте — по названию.
+func topActivities(counts map[ActivityID]int, names map[ActivityID]string, k int) []ActivityCount {
+	selector := newTopSelector(k, func(a, b ActivityCount) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Activity < b.Activity
+	})
+	for activity, count := range counts {
+		selector.offer(ActivityCount{Activity: names[activity], Count: count})
+	}
+	return selector.sorted()
+}
+
+// topPaths возвращает k самых частых непустых путей (вариантов paths с числом кейсов counts);
+// при равной частоте — в порядке названий активностей.
+func topPaths(counts []int, paths *pathIndex, k int) []PathCount {
+	selector := newTopSelector(k, func(a, b PathCount) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return slices.Compare(a.Path, b.Path) < 0
+	})
+	for number, count := range counts {
+		if path := paths.names[number]; len(path) > 0 {
+			selector.offer(PathCount{Path: path, Count: count})
+		}
+	}
+	return selector.sorted()
+}
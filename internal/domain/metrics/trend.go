@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// timedValue — значение, привязанное ко времени (например, длительность этапа на момент его завершения).
+type timedValue struct {
+	at    time.Time
+	value float64
+}
+
+// trendGranularity выбирает размер интервала агрегации по охвату данных во времени.
+func trendGranularity(span time.Duration) (time.Duration, string) {
+	switch {
+	case span <= 48*time.Hour:
+		return time.Hour, "час"
+	case span <= 60*24*time.Hour:
+		return 24 * time.Hour, "день"
+	default:
+		return 7 * 24 * time.Hour, "неделю"
+	}
+}
+
+// timeOrderedTrend упорядочивает значения по времени, усредняет их по часам, дням или неделям
+// и возвращает наклон линейной регрессии средних по номеру интервала.
+// ok = false, если данных меньше чем на два интервала.
+func timeOrderedTrend(points []timedValue) (slope float64, unit string, ok bool) {
+	if len(points) < 2 {
+		return 0, "", false
+	}
+	sorted := make([]timedValue, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].at.Before(sorted[j].at) })
+
+	first := sorted[0].at
+	bucket, unit := trendGranularity(sorted[len(sorted)-1].at.Sub(first))
+
+	var xs, ys []float64
+	var sum float64
+	var count int
+	current := int64(-1)
+	flush := func() {
+		if count > 0 {
+			xs = append(xs, float64(current))
+			ys = append(ys, sum/float64(count))
+		}
+	}
+	for _, p := range sorted {
+		index := int64(p.at.Sub(first) / bucket)
+		if index != current {
+			flush()
+			current, sum, count = index, 0, 0
+		}
+		sum += p.value
+		count++
+	}
+	flush()
+
+	if len(xs) < 2 {
+		return 0, unit, false
+	}
+	slope, _ = calculateLinearRegression(xs, ys)
+	return slope, unit, true
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+)
+
+// VariantMetrics — показатели кейсов одного варианта (одинаковой последовательности активностей).
+type VariantMetrics struct {
+	ID              string   `json:"id"`
+	Variant         []string `json:"variant"`
+	Cases           int      `json:"cases"`
+	Share           float64  `json:"share"`            // доля кейсов лога, %
+	AverageDuration float64  `json:"average_duration"` // сек
+	ReworkRate      float64  `json:"rework_rate"`      // доля кейсов с переделками, %
+	ErrorRate       float64  `json:"error_rate"`       // доля кейсов с ошибками, %
+	WastedDuration  float64  `json:"wasted_duration"`  // сек, сумма по вхождениям метрик
+	AverageWasted   float64  `json:"average_wasted"`   // сек на кейс
+}
+
+// VariantID возвращает короткий устойчивый идентификатор варианта.
+func VariantID(activities []string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(activities, "\x00")))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// InstanceVariant возвращает последовательность активностей экземпляра.
+func InstanceVariant(instance *ProcessInstance) []string {
+	activities := make([]string, len(instance.Events))
+	for i, event := range instance.Events {
+		activities[i] = event.Description
+	}
+	return activities
+}
+
+// VariantMetrics считает показатели по вариантам процесса, от самых частых к редким.
+// Потерянное время берётся из вхождений метрик отчёта report.
+func (a *Analyzer) VariantMetrics(instances map[string]*ProcessInstance, report *MetricsReport) []VariantMetrics {
+	wasted := make(map[string]float64)
+	for _, metric := range report.Metrics {
+		for _, occurrence := range metric.Occurrences {
+			wasted[occurrence.InstanceID] += occurrence.WastedDurationSeconds
+		}
+	}
+
+	// Варианты группируются по номерам активностей; идентификатор и признак переделок
+	// считаются один раз на вариант, а не для каждого кейса
+	type accumulator struct {
+		metrics              VariantMetrics
+		durationSum          float64
+		rework               bool
+		reworked, withErrors int
+	}
+	numberActivities(instances)
+	variants := newPathIndex()
+	var byVariant []*accumulator
+	for id, instance := range instances {
+		if len(instance.Events) == 0 {
+			continue
+		}
+		number := variants.of(instance.Events)
+		if number == len(byVariant) {
+			activities := variants.names[number]
+			byVariant = append(byVariant, &accumulator{
+				metrics: VariantMetrics{ID: VariantID(activities), Variant: activities},
+				rework:  hasRework(activities),
+			})
+		}
+		acc := byVariant[number]
+		acc.metrics.Cases++
+		acc.durationSum += instance.Events[len(instance.Events)-1].Timestamp.Sub(instance.Events[0].Timestamp).Seconds()
+		acc.metrics.WastedDuration += wasted[id]
+		if acc.rework {
+			acc.reworked++
+		}
+		if a.errors.HasError(instance.Events) {
+			acc.withErrors++
+		}
+	}
+
+	result := make([]VariantMetrics, 0, len(byVariant))
+	for _, acc := range byVariant {
+		v := acc.metrics
+		cases := float64(v.Cases)
+		v.Share = math.Round(cases/float64(len(instances))*1000) / 10
+		v.AverageDuration = acc.durationSum / cases
+		v.ReworkRate = math.Round(float64(acc.reworked)/cases*1000) / 10
+		v.ErrorRate = math.Round(float64(acc.withErrors)/cases*1000) / 10
+		v.AverageWasted = v.WastedDuration / cases
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cases != result[j].Cases {
+			return result[i].Cases > result[j].Cases
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// hasRework проверяет, что какая-либо активность выполнялась повторно.
+func hasRework(activities []string) bool {
+	seen := make(map[string]bool, len(activities))
+	for _, activity := range activities {
+		if seen[activity] {
+			return true
+		}
+		seen[activity] = true
+	}
+	return false
+}
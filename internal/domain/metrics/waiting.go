@@ -0,0 +1,96 @@
+package metrics
+
+import "sort"
+
+// TransitionTime — разложение времени перехода на ожидание и обработку.
+type TransitionTime struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Count           int     `json:"count"`
+	AvgWaiting      float64 `json:"avg_waiting"`      // среднее ожидание до начала обработки To, сек
+	AvgProcessing   float64 `json:"avg_processing"`   // средняя обработка To, сек
+	TotalWaiting    float64 `json:"total_waiting"`    // суммарное ожидание, сек
+	TotalProcessing float64 `json:"total_processing"` // суммарная обработка, сек
+}
+
+// TimeDecomposition содержит разложение времени процесса по переходам.
+type TimeDecomposition struct {
+	// ProcessingTimeAvailable — в логе есть время начала обработки (столбец start или lifecycle).
+	// Без этих данных всё время перехода считается ожиданием.
+	ProcessingTimeAvailable bool             `json:"processing_time_available"`
+	TotalWaiting            float64          `json:"total_waiting"`
+	TotalProcessing         float64          `json:"total_processing"`
+	Transitions             []TransitionTime `json:"transitions"`
+}
+
+// transitionSplit разделяет переход prev→curr на ожидание (от завершения prev до начала curr)
+// и обработку curr. Если начало обработки curr неизвестно, всё время считается ожиданием.
+func transitionSplit(prev, curr Event) (waiting, processing float64) {
+	total := curr.Timestamp.Sub(prev.Timestamp).Seconds()
+	if curr.Start.IsZero() || !curr.Start.After(prev.Timestamp) || curr.Start.After(curr.Timestamp) {
+		return total, 0
+	}
+	waiting = curr.Start.Sub(prev.Timestamp).Seconds()
+	return waiting, total - waiting
+}
+
+// eventProcessing возвращает время обработки события (0, если начало неизвестно).
+func eventProcessing(event Event) float64 {
+	if event.Start.IsZero() || event.Start.After(event.Timestamp) {
+		return 0
+	}
+	return event.Timestamp.Sub(event.Start).Seconds()
+}
+
+// DecomposeTransitions раскладывает время каждого перехода на ожидание и обработку.
+func DecomposeTransitions(instances map[string]*ProcessInstance) TimeDecomposition {
+	result := TimeDecomposition{Transitions: []TransitionTime{}}
+	byTransition := make(map[[2]string]*TransitionTime)
+
+	for _, instance := range instances {
+		for i, event := range instance.Events {
+			if !event.Start.IsZero() {
+				result.ProcessingTimeAvailable = true
+			}
+			if i == 0 {
+				continue
+			}
+
+			prev := instance.Events[i-1]
+			if event.Timestamp.Before(prev.Timestamp) {
+				continue
+			}
+			waiting, processing := transitionSplit(prev, event)
+
+			key := [2]string{prev.Description, event.Description}
+			t := byTransition[key]
+			if t == nil {
+				t = &TransitionTime{From: key[0], To: key[1]}
+				byTransition[key] = t
+			}
+			t.Count++
+			t.TotalWaiting += waiting
+			t.TotalProcessing += processing
+			result.TotalWaiting += waiting
+			result.TotalProcessing += processing
+		}
+	}
+
+	for _, t := range byTransition {
+		t.AvgWaiting = t.TotalWaiting / float64(t.Count)
+		t.AvgProcessing = t.TotalProcessing / float64(t.Count)
+		result.Transitions = append(result.Transitions, *t)
+	}
+	sort.Slice(result.Transitions, func(i, j int) bool {
+		a, b := result.Transitions[i], result.Transitions[j]
+		if a.TotalWaiting != b.TotalWaiting {
+			return a.TotalWaiting > b.TotalWaiting
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	return result
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import "math"
+
+// FirstPassYield — доля завершённых кейсов, прошедших процесс с первого раза:
+// без повторов активностей (переделок и возвратов) и без ошибок.
+type FirstPassYield struct {
+	CompletedCases int     `json:"completed_cases"`
+	FirstPassCases int     `json:"first_pass_cases"`
+	Yield          float64 `json:"yield"` // %
+}
+
+// firstPassYield считает долю кейсов, завершённых с первого прохода.
+func (a *Analyzer) firstPassYield(instances map[string]*ProcessInstance) FirstPassYield {
+	var result FirstPassYield
+	for _, instance := range instances {
+		if len(instance.Events) == 0 || !a.IsCompleted(instance) {
+			continue
+		}
+		result.CompletedCases++
+		if !hasRework(InstanceVariant(instance)) && !a.errors.HasError(instance.Events) {
+			result.FirstPassCases++
+		}
+	}
+	if result.CompletedCases > 0 {
+		result.Yield = math.Round(float64(result.FirstPassCases)/float64(result.CompletedCases)*1000) / 10
+	}
+	return result
+}
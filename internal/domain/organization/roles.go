@@ -0,0 +1,205 @@
+package organization
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// DefaultRoleSimilarity — порог косинусного сходства профилей, при котором ресурсы объединяются в одну роль.
+const DefaultRoleSimilarity = 0.7
+
+// ErrNoResources возвращается, если в логе нет данных о ресурсах (исполнителях).
+var ErrNoResources = errors.New("в логе нет столбца ресурса (исполнителя)")
+
+// ActivityShare — доля активности в работе роли.
+type ActivityShare struct {
+	Activity string  `json:"activity"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"` // доля от всех событий роли, %
+}
+
+// Role — группа ресурсов со схожим профилем выполняемых активностей.
+type Role struct {
+	Name       string          `json:"name"`
+	Resources  []string        `json:"resources"`
+	Activities []ActivityShare `json:"activities"`
+	Events     int             `json:"events"`      // количество событий, выполненных ролью
+	Cases      int             `json:"cases"`       // количество кейсов, в которых участвовала роль
+	EventShare float64         `json:"event_share"` // доля от всех событий лога, %
+}
+
+// Handover — передача работы между ролями внутри кейса.
+type Handover struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// RoleReport содержит найденные роли и передачи работы между ними.
+type RoleReport struct {
+	Roles     []Role     `json:"roles"`
+	Handovers []Handover `json:"handovers"`
+}
+
+type cluster struct {
+	resources []string
+	profile   map[string]float64
+}
+
+// MineRoles кластеризует ресурсы по профилям выполняемых активностей (агломеративно,
+// по косинусному сходству центроидов) и считает нагрузку ролей и передачи работы между ними.
+func MineRoles(instances []metrics.ProcessInstance, similarity float64) (*RoleReport, error) {
+	profiles := make(map[string]map[string]float64)
+	totalEvents := 0
+	for _, instance := range instances {
+		for _, event := range instance.Events {
+			if event.Resource == "" {
+				continue
+			}
+			if profiles[event.Resource] == nil {
+				profiles[event.Resource] = make(map[string]float64)
+			}
+			profiles[event.Resource][event.Description]++
+			totalEvents++
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, ErrNoResources
+	}
+
+	resources := make([]string, 0, len(profiles))
+	for resource := range profiles {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	clusters := make([]*cluster, len(resources))
+	for i, resource := range resources {
+		clusters[i] = &cluster{resources: []string{resource}, profile: copyProfile(profiles[resource])}
+	}
+
+	for {
+		bestI, bestJ, bestSimilarity := -1, -1, similarity
+		for i := 0; i < len(clusters); i++ {
+			for j := i + 1; j < len(clusters); j++ {
+				if s := cosine(clusters[i].profile, clusters[j].profile); s >= bestSimilarity {
+					bestI, bestJ, bestSimilarity = i, j, s
+				}
+			}
+		}
+		if bestI < 0 {
+			break
+		}
+		merged := clusters[bestI]
+		merged.resources = append(merged.resources, clusters[bestJ].resources...)
+		for activity, count := range clusters[bestJ].profile {
+			merged.profile[activity] += count
+		}
+		clusters = append(clusters[:bestJ], clusters[bestJ+1:]...)
+	}
+
+	// Крупные роли идут первыми
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return profileTotal(clusters[i].profile) > profileTotal(clusters[j].profile)
+	})
+
+	report := &RoleReport{Roles: make([]Role, len(clusters)), Handovers: []Handover{}}
+	roleOf := make(map[string]string)
+	for i, c := range clusters {
+		sort.Strings(c.resources)
+		role := Role{Resources: c.resources, Events: int(profileTotal(c.profile))}
+		for activity, count := range c.profile {
+			role.Activities = append(role.Activities, ActivityShare{
+				Activity: activity,
+				Count:    int(count),
+				Share:    math.Round(count/float64(role.Events)*1000) / 10,
+			})
+		}
+		sort.Slice(role.Activities, func(a, b int) bool {
+			if role.Activities[a].Count != role.Activities[b].Count {
+				return role.Activities[a].Count > role.Activities[b].Count
+			}
+			return role.Activities[a].Activity < role.Activities[b].Activity
+		})
+		role.Name = fmt.Sprintf("Роль %d: %s", i+1, role.Activities[0].Activity)
+		role.EventShare = math.Round(float64(role.Events)/float64(totalEvents)*1000) / 10
+		for _, resource := range c.resources {
+			roleOf[resource] = role.Name
+		}
+		report.Roles[i] = role
+	}
+
+	roleIndex := make(map[string]int, len(report.Roles))
+	for i, role := range report.Roles {
+		roleIndex[role.Name] = i
+	}
+	handovers := make(map[[2]string]int)
+	for _, instance := range instances {
+		seen := make(map[string]bool)
+		prevRole := ""
+		for _, event := range instance.Events {
+			role, ok := roleOf[event.Resource]
+			if !ok {
+				continue
+			}
+			if !seen[role] {
+				seen[role] = true
+				report.Roles[roleIndex[role]].Cases++
+			}
+			if prevRole != "" && prevRole != role {
+				handovers[[2]string{prevRole, role}]++
+			}
+			prevRole = role
+		}
+	}
+	for pair, count := range handovers {
+		report.Handovers = append(report.Handovers, Handover{From: pair[0], To: pair[1], Count: count})
+	}
+	sort.Slice(report.Handovers, func(i, j int) bool {
+		if report.Handovers[i].Count != report.Handovers[j].Count {
+			return report.Handovers[i].Count > report.Handovers[j].Count
+		}
+		if report.Handovers[i].From != report.Handovers[j].From {
+			return report.Handovers[i].From < report.Handovers[j].From
+		}
+		return report.Handovers[i].To < report.Handovers[j].To
+	})
+
+	return report, nil
+}
+
+func copyProfile(profile map[string]float64) map[string]float64 {
+	c := make(map[string]float64, len(profile))
+	for k, v := range profile {
+		c[k] = v
+	}
+	return c
+}
+
+func profileTotal(profile map[string]float64) float64 {
+	var total float64
+	for _, v := range profile {
+		total += v
+	}
+	return total
+}
+
+// cosine вычисляет косинусное сходство двух профилей активностей.
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for k, v := range a {
+		dot += v * b[k]
+		normA += v * v
+	}
+	for _, v := range b {
+		normB += v * v
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unique"
+
+	"process-mining/internal/domain/metrics"
+	"process-mining/internal/infrastructure"
+)
+
+// parsedLogVersion — версия формата записей кэша разбора. Меняется вместе с форматом или правилами
+// разбора лога, чтобы записи прежней версии не использовались (версия входит в ключ записи).
+const parsedLogVersion = 1
+
+// SetParseCache задаёт кэш разбора логов (nil — без кэша). После разбора файла в пустой построитель
+// его события сохраняются в кэш, а при следующем построении по файлу с тем же содержимым
+// и теми же названиями столбцов загружаются из кэша без разбора CSV.
+func (gb *GraphBuilder) SetParseCache(cache *infrastructure.ParseCache) {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	gb.cache = cache
+}
+
+// parsedLog — события построителя в формате кэша разбора: столбцы eventStore со словарями значений,
+// активностей и часовых поясов, незавершённые активности и итоги чтения лога.
+type parsedLog struct {
+	Rows       int      // прочитано записей лога
+	Warnings   []string // предупреждения, выданные при чтении
+	Activities []string // названия активностей справочника начиная с номера 1
+	CaseIDs    []string
+	CaseRows   [][]int32
+	CaseIndex  []int32
+	Activity   []metrics.ActivityID
+	Timestamp  []int64
+	Start      []int64
+	EndZone    []uint16
+	StartZone  []uint16
+	Result     []int32
+	Resource   []int32
+	EventID    []int32
+	Values     []string
+	Zones      []parsedZone // часовые пояса начиная с номера 1
+	// Атрибуты хранятся по столбцам: номер атрибута → номер значения в AttributeValues у каждой строки
+	// (0 — у события нет атрибута); nil — ни у одного события нет атрибутов
+	AttributeNames  []string
+	AttributeValues []string
+	Attributes      [][]int32
+	PendingStarts   map[string]int32
+}
+
+// parsedZone — часовой пояс времени событий (см. zoneKey).
+type parsedZone struct {
+	Local  bool
+	Name   string
+	Offset int
+}
+
+// parseCacheKey возвращает ключ записи кэша для лога filePath; пустой ключ — кэш не используется:
+// он не задан, лог читается из стандартного ввода или события лога добавляются к уже загруженным.
+func (gb *GraphBuilder) parseCacheKey(filePath string) (string, error) {
+	if gb.cache == nil || filePath == infrastructure.StdinPath || gb.events.rows() > 0 {
+		return "", nil
+	}
+	columns, err := json.Marshal(gb.columns)
+	if err != nil {
+		return "", err
+	}
+	return gb.cache.Key(filePath, fmt.Sprintf("%d %s", parsedLogVersion, columns))
+}
+
+// loadParsed загружает из кэша события лога с ключом key, дополняя state итогами чтения; touched
+// получает все кейсы лога. Возвращает false, если записи нет или её не удалось прочитать
+// (тогда в state добавляется предупреждение, и лог разбирается заново).
+func (gb *GraphBuilder) loadParsed(key string, state *BuildProgress, touched map[string]struct{}) bool {
+	var parsed parsedLog
+	loaded, err := gb.cache.Load(key, func(r io.Reader) error {
+		if err := gob.NewDecoder(r).Decode(&parsed); err != nil {
+			return err
+		}
+		return parsed.validate()
+	})
+	if err != nil {
+		state.Warnings = append(state.Warnings, err.Error())
+	}
+	if !loaded {
+		return false
+	}
+	gb.events = parsed.eventStore()
+	gb.pendingStarts = parsed.PendingStarts
+	if gb.pendingStarts == nil {
+		gb.pendingStarts = make(map[string]int32)
+	}
+	for _, id := range gb.events.caseIDs {
+		touched[id] = struct{}{}
+	}
+	state.RowsRead = parsed.Rows
+	state.Warnings = append(state.Warnings, parsed.Warnings...)
+	return true
+}
+
+// storeParsed сохраняет события построителя в кэш под ключом key вместе с итогами чтения лога.
+func (gb *GraphBuilder) storeParsed(key string, rows int, warnings []string) error {
+	return gb.cache.Store(key, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(newParsedLog(gb.events, gb.pendingStarts, rows, warnings))
+	})
+}
+
+func newParsedLog(s *eventStore, pendingStarts map[string]int32, rows int, warnings []string) *parsedLog {
+	parsed := &parsedLog{
+		Rows:          rows,
+		Warnings:      warnings,
+		Activities:    make([]string, s.labels.Len()),
+		CaseIDs:       s.caseIDs,
+		CaseRows:      s.caseRows,
+		CaseIndex:     s.caseIndex,
+		Activity:      s.activity,
+		Timestamp:     s.timestamp,
+		Start:         s.start,
+		EndZone:       s.endZone,
+		StartZone:     s.startZone,
+		Result:        s.result,
+		Resource:      s.resource,
+		EventID:       s.eventID,
+		Values:        s.values.values,
+		Zones:         make([]parsedZone, len(s.zones)-1),
+		PendingStarts: pendingStarts,
+	}
+	for i := range parsed.Activities {
+		parsed.Activities[i] = s.labels.Name(metrics.ActivityID(i + 1))
+	}
+	for key, zone := range s.zoneNumbers {
+		parsed.Zones[zone-1] = parsedZone{Local: key.local, Name: key.name, Offset: key.offset}
+	}
+	if s.attributes == nil {
+		return parsed
+	}
+
+	names := make(map[string]int)
+	values := map[string]int32{"": 0}
+	parsed.AttributeValues = []string{""}
+	for row, attributes := range s.attributes {
+		for name, value := range attributes {
+			column, ok := names[name]
+			if !ok {
+				column = len(parsed.AttributeNames)
+				names[name] = column
+				parsed.AttributeNames = append(parsed.AttributeNames, name)
+				parsed.Attributes = append(parsed.Attributes, make([]int32, s.rows()))
+			}
+			number, ok := values[value]
+			if !ok {
+				number = int32(len(parsed.AttributeValues))
+				values[value] = number
+				parsed.AttributeValues = append(parsed.AttributeValues, value)
+			}
+			parsed.Attributes[column][row] = number
+		}
+	}
+	return parsed
+}
+
+// validate проверяет, что столбцы записи согласованы между собой.
+func (p *parsedLog) validate() error {
+	rows := len(p.CaseIndex)
+	for _, n := range []int{len(p.Activity), len(p.Timestamp), len(p.Start), len(p.EndZone), len(p.StartZone), len(p.Result), len(p.Resource), len(p.EventID)} {
+		if n != rows {
+			return errors.New("длины столбцов событий не совпадают")
+		}
+	}
+	if len(p.CaseRows) != len(p.CaseIDs) || len(p.Attributes) != len(p.AttributeNames) || len(p.Values) == 0 {
+		return errors.New("повреждены словари событий")
+	}
+	for _, column := range p.Attributes {
+		if len(column) != rows {
+			return errors.New("длины столбцов атрибутов не совпадают")
+		}
+	}
+	return nil
+}
+
+// eventStore восстанавливает хранилище событий по записи кэша.
+func (p *parsedLog) eventStore() *eventStore {
+	s := newEventStore()
+	for _, name := range p.Activities {
+		s.labels.Intern(name)
+	}
+	s.caseIDs = p.CaseIDs
+	s.caseNumbers = make(map[string]int32, len(p.CaseIDs))
+	for c, id := range p.CaseIDs {
+		s.caseNumbers[id] = int32(c)
+	}
+	s.caseRows = p.CaseRows
+	s.caseIndex = p.CaseIndex
+	s.activity = p.Activity
+	s.timestamp = p.Timestamp
+	s.start = p.Start
+	s.endZone = p.EndZone
+	s.startZone = p.StartZone
+	s.result = p.Result
+	s.resource = p.Resource
+	s.eventID = p.EventID
+	s.values = valueDict{numbers: make(map[string]int32, len(p.Values)), values: p.Values}
+	for n, value := range p.Values {
+		s.values.numbers[value] = int32(n)
+	}
+	for _, zone := range p.Zones {
+		key := zoneKey{local: zone.Local, name: zone.Name, offset: zone.Offset}
+		location := time.Local
+		if !key.local {
+			location = time.FixedZone(key.name, key.offset)
+		}
+		s.zoneNumbers[key] = uint16(len(s.zones))
+		s.zones = append(s.zones, location)
+	}
+	if p.Attributes == nil {
+		return s
+	}
+
+	// Названия и значения атрибутов заменяются общими экземплярами, как при разборе (см. GraphBuilder.intern)
+	names := make([]string, len(p.AttributeNames))
+	for i, name := range p.AttributeNames {
+		names[i] = unique.Make(name).Value()
+	}
+	values := make([]string, len(p.AttributeValues))
+	for i, value := range p.AttributeValues {
+		values[i] = unique.Make(value).Value()
+	}
+	s.attributes = make([]map[string]string, len(p.CaseIndex))
+	for row := range s.attributes {
+		for column, numbers := range p.Attributes {
+			if number := numbers[row]; number != 0 {
+				if s.attributes[row] == nil {
+					s.attributes[row] = make(map[string]string, len(names))
+				}
+				s.attributes[row][names[column]] = values[number]
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+// writeTestLog записывает в каталог dir CSV-лог из cases кейсов, события которых перемежаются между
+// кейсами: активности с жизненным циклом start/complete, время в разных форматах и часовых поясах,
+// ресурсы, результаты и атрибут region у части кейсов. У последнего кейса остаётся начатая, но не
+// завершённая активность. Возвращает путь к файлу.
+func writeTestLog(t *testing.T, dir string, cases int) string {
+	t.Helper()
+	activities := []string{"Регистрация", "Проверка", "Согласование", "Доработка", "Оплата", "Закрытие"}
+	formats := []string{time.RFC3339, "2006-01-02 15:04:05", time.RFC3339}
+	zone := time.FixedZone("", 3*60*60)
+	random := rand.New(rand.NewPCG(3, 4))
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	path := filepath.Join(dir, "log.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	fmt.Fprintln(writer, "case_id,timestamp,activity,lifecycle,resource,result,region")
+	lengths := make([]int, cases)
+	for c := range lengths {
+		lengths[c] = 2 + random.IntN(6)
+	}
+	for step := 0; step < 8; step++ {
+		for c, length := range lengths {
+			if step >= length {
+				continue
+			}
+			activity := activities[(c+step*step)%len(activities)]
+			at := start.Add(time.Duration(c)*time.Hour + time.Duration(step*37)*time.Minute)
+			if c%2 == 1 {
+				at = at.In(zone)
+			}
+			region := ""
+			if c%3 != 0 {
+				region = fmt.Sprintf("регион-%d", c%4)
+			}
+			row := func(at time.Time, lifecycle string) {
+				fmt.Fprintf(writer, "case-%d,%s,%s,%s,user-%d,%s,%s\n", c, at.Format(formats[(c+step)%len(formats)]), activity, lifecycle, (c+step)%5, []string{"success", "error"}[step%2], region)
+			}
+			switch {
+			case c == cases-1 && step == length-1:
+				row(at, "start")
+			case c%4 == 0:
+				row(at, "start")
+				row(at.Add(10*time.Minute), "complete")
+			default:
+				row(at, "complete")
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// buildTestGraph строит граф по логу path в новом построителе, настроенном configure (если задана).
+func buildTestGraph(t *testing.T, path string, configure func(gb *GraphBuilder)) (*GraphBuilder, BuildProgress) {
+	t.Helper()
+	gb := NewGraphBuilder(infrastructure.NewCSVReader())
+	if configure != nil {
+		configure(gb)
+	}
+	var last BuildProgress
+	if err := gb.BuildGraphWithProgress(path, func(progress BuildProgress) { last = progress }); err != nil {
+		t.Fatal(err)
+	}
+	return gb, last
+}
+
+// assertSameBuild проверяет, что got содержит те же события (по кейсам, в порядке событий кейса),
+// незавершённые активности и граф, что и want.
+func assertSameBuild(t *testing.T, want, got *GraphBuilder) {
+	t.Helper()
+	wantCases, gotCases := eventsByCase(want.Events()), eventsByCase(got.Events())
+	if len(gotCases) != len(wantCases) {
+		t.Fatalf("%d кейсов, ожидается %d", len(gotCases), len(wantCases))
+	}
+	for id, wantEvents := range wantCases {
+		gotEvents := gotCases[id]
+		if len(gotEvents) != len(wantEvents) {
+			t.Fatalf("кейс %s: %d событий, ожидается %d", id, len(gotEvents), len(wantEvents))
+		}
+		for i := range wantEvents {
+			if !sameEvent(wantEvents[i], gotEvents[i]) {
+				t.Fatalf("кейс %s, событие %d: %+v, ожидается %+v", id, i, gotEvents[i], wantEvents[i])
+			}
+		}
+	}
+	if len(got.pendingStarts) != len(want.pendingStarts) {
+		t.Errorf("%d незавершённых активностей, ожидается %d", len(got.pendingStarts), len(want.pendingStarts))
+	}
+	for key, row := range want.pendingStarts {
+		gotRow, ok := got.pendingStarts[key]
+		if !ok || !sameEvent(got.events.event(gotRow), want.events.event(row)) {
+			t.Errorf("незавершённая активность %q не совпадает", key)
+		}
+	}
+	// Порядок узлов и переходов графа не определён
+	wantGraph, gotGraph := sortedGraph(want.GetGraph()), sortedGraph(got.GetGraph())
+	if !reflect.DeepEqual(gotGraph, wantGraph) {
+		wantJSON, _ := json.Marshal(wantGraph)
+		gotJSON, _ := json.Marshal(gotGraph)
+		t.Errorf("граф %s, ожидается %s", gotJSON, wantJSON)
+	}
+}
+
+// eventsByCase группирует события по кейсам, сохраняя порядок событий кейса.
+func eventsByCase(events []Event) map[string][]Event {
+	cases := make(map[string][]Event)
+	for _, event := range events {
+		cases[event.SessionID] = append(cases[event.SessionID], event)
+	}
+	return cases
+}
+
+// sameEvent сравнивает события; время сравнивается вместе со смещением часового пояса, пустые атрибуты
+// равны отсутствующим.
+func sameEvent(a, b Event) bool {
+	sameTime := func(x, y time.Time) bool {
+		_, xOffset := x.Zone()
+		_, yOffset := y.Zone()
+		return x.Equal(y) && xOffset == yOffset
+	}
+	return a.ID == b.ID && a.SessionID == b.SessionID && a.Desc == b.Desc && a.Activity == b.Activity &&
+		a.Result == b.Result && a.Resource == b.Resource && sameTime(a.Timestamp, b.Timestamp) &&
+		sameTime(a.Start, b.Start) && maps.Equal(a.Attributes, b.Attributes)
+}
+
+// cacheEntries возвращает число записей в каталоге кэша разбора dir.
+func cacheEntries(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := filepath.Glob(filepath.Join(dir, "*.pmc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestParseCacheHitMatchesFreshParse(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestLog(t, dir, 200)
+	cacheDir := filepath.Join(dir, "cache")
+	cache, err := infrastructure.OpenParseCache(cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCache := func(gb *GraphBuilder) { gb.SetParseCache(cache) }
+
+	fresh, freshProgress := buildTestGraph(t, path, nil)
+	if len(fresh.pendingStarts) == 0 {
+		t.Fatal("в логе нет незавершённых активностей")
+	}
+	if _, _ = buildTestGraph(t, path, withCache); cacheEntries(t, cacheDir) != 1 {
+		t.Fatalf("после разбора в кэше %d записей, ожидается 1", cacheEntries(t, cacheDir))
+	}
+
+	// При попадании в кэш лог не разбирается, и читатель CSV не сообщает о ходе чтения
+	read := false
+	cached, cachedProgress := buildTestGraph(t, path, func(gb *GraphBuilder) {
+		gb.SetParseCache(cache)
+		gb.CSVReader().SetProgress(func(infrastructure.ReadProgress) { read = true })
+	})
+	if read {
+		t.Fatal("лог разобран заново вместо загрузки из кэша")
+	}
+	assertSameBuild(t, fresh, cached)
+	if cachedProgress.RowsRead != freshProgress.RowsRead || !reflect.DeepEqual(cachedProgress.Warnings, freshProgress.Warnings) {
+		t.Errorf("итог построения из кэша %+v, ожидается %+v", cachedProgress, freshProgress)
+	}
+}
+
+func TestParseCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestLog(t, dir, 20)
+	cache, err := infrastructure.OpenParseCache(filepath.Join(dir, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(path string, columns ColumnNames) string {
+		t.Helper()
+		gb := NewGraphBuilder(infrastructure.NewCSVReader())
+		gb.SetParseCache(cache)
+		gb.SetColumns(columns)
+		key, err := gb.parseCacheKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	base := key(path, nil)
+	if base == "" {
+		t.Fatal("ключ кэша не вычислен")
+	}
+
+	copied := filepath.Join(dir, "copy.csv")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(copied, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if key(copied, nil) != base {
+		t.Error("ключ зависит от пути, а не от содержимого лога")
+	}
+
+	if err := os.WriteFile(copied, append(data, "case-0,2024-02-01T00:00:00Z,Оплата,complete,user-1,success,\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if key(copied, nil) == base {
+		t.Error("ключ не изменился после изменения лога")
+	}
+	if key(path, ColumnNames{"resource": "user"}) == base {
+		t.Error("ключ не зависит от названий столбцов")
+	}
+
+	// Кэш не используется при дозагрузке событий в непустой построитель
+	gb, _ := buildTestGraph(t, path, nil)
+	gb.SetParseCache(cache)
+	if key, err := gb.parseCacheKey(path); err != nil || key != "" {
+		t.Errorf("для непустого построителя ключ %q (ошибка %v), ожидается пустой", key, err)
+	}
+}
@@ -0,0 +1,159 @@
+package prediction
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// maxOutcomeFactors — количество признаков, сильнее всего повлиявших на прогноз, в ответе.
+const maxOutcomeFactors = 5
+
+// ErrNoOutcomeVariety возвращается, если в логе нет кейсов одного из исходов.
+var ErrNoOutcomeVariety = errors.New("для обучения нужны и успешные кейсы, и кейсы с ошибками")
+
+// OutcomeFactor — признак префикса и его вклад в прогноз (логарифм отношения правдоподобий).
+// Положительный вес увеличивает вероятность ошибки.
+type OutcomeFactor struct {
+	Feature string  `json:"feature"`
+	Weight  float64 `json:"weight"`
+}
+
+// OutcomePrediction — прогноз исхода незавершённого кейса.
+type OutcomePrediction struct {
+	ErrorProbability float64         `json:"error_probability"`
+	BaseErrorRate    float64         `json:"base_error_rate"` // доля кейсов с ошибкой в логе
+	Factors          []OutcomeFactor `json:"factors"`
+	TrainingCases    int             `json:"training_cases"`
+}
+
+// OutcomeModel — наивный байесовский классификатор исхода кейса по признакам префикса:
+// выполненные активности, последняя активность, ресурсы, атрибуты и длина префикса.
+type OutcomeModel struct {
+	prefixes   [2]int // количество обучающих префиксов: [успех, ошибка]
+	features   map[string]*[2]int
+	cases      int
+	errorCases int
+	errors     *metrics.ErrorMatcher
+}
+
+// TrainOutcome обучает классификатор на префиксах кейсов лога. Кейс считается ошибочным,
+// если хотя бы одно его событие завершилось ошибкой по правилам matcher; префиксы после ошибки
+// не используются, так как исход по ним уже известен.
+func TrainOutcome(instances []metrics.ProcessInstance, matcher *metrics.ErrorMatcher) (*OutcomeModel, error) {
+	model := &OutcomeModel{features: make(map[string]*[2]int), errors: matcher}
+	for _, instance := range instances {
+		if len(instance.Events) == 0 {
+			continue
+		}
+		label := 0
+		if matcher.HasError(instance.Events) {
+			label = 1
+			model.errorCases++
+		}
+		model.cases++
+
+		steps := make([]Step, 0, len(instance.Events))
+		for _, event := range instance.Events {
+			if matcher.IsError(event.Result) {
+				break
+			}
+			steps = append(steps, Step{
+				Activity:   event.Description,
+				Timestamp:  event.Timestamp,
+				Resource:   event.Resource,
+				Attributes: event.Attributes,
+			})
+			model.prefixes[label]++
+			for feature := range prefixFeatures(steps) {
+				counts := model.features[feature]
+				if counts == nil {
+					counts = &[2]int{}
+					model.features[feature] = counts
+				}
+				counts[label]++
+			}
+		}
+	}
+	if model.errorCases == 0 || model.errorCases == model.cases {
+		return nil, ErrNoOutcomeVariety
+	}
+	return model, nil
+}
+
+// Predict оценивает вероятность того, что кейс завершится ошибкой.
+func (m *OutcomeModel) Predict(steps []Step) (*OutcomePrediction, error) {
+	if len(steps) == 0 {
+		return nil, ErrEmptyPrefix
+	}
+	result := &OutcomePrediction{
+		BaseErrorRate: float64(m.errorCases) / float64(m.cases),
+		Factors:       []OutcomeFactor{},
+		TrainingCases: m.cases,
+	}
+	for _, step := range steps {
+		if m.errors.IsError(step.Result) {
+			result.ErrorProbability = 1
+			return result, nil
+		}
+	}
+
+	// Логарифм отношения апостериорных шансов «ошибка» / «успех» со сглаживанием Лапласа
+	logOdds := math.Log(float64(m.prefixes[1]+1) / float64(m.prefixes[0]+1))
+	for feature := range prefixFeatures(steps) {
+		counts, ok := m.features[feature]
+		if !ok {
+			continue
+		}
+		weight := math.Log(float64(counts[1]+1)/float64(m.prefixes[1]+2)) -
+			math.Log(float64(counts[0]+1)/float64(m.prefixes[0]+2))
+		logOdds += weight
+		result.Factors = append(result.Factors, OutcomeFactor{Feature: feature, Weight: math.Round(weight*1000) / 1000})
+	}
+	result.ErrorProbability = 1 / (1 + math.Exp(-logOdds))
+
+	sort.Slice(result.Factors, func(i, j int) bool {
+		wi, wj := math.Abs(result.Factors[i].Weight), math.Abs(result.Factors[j].Weight)
+		if wi != wj {
+			return wi > wj
+		}
+		return result.Factors[i].Feature < result.Factors[j].Feature
+	})
+	if len(result.Factors) > maxOutcomeFactors {
+		result.Factors = result.Factors[:maxOutcomeFactors]
+	}
+	return result, nil
+}
+
+// prefixFeatures возвращает множество бинарных признаков префикса.
+func prefixFeatures(steps []Step) map[string]struct{} {
+	features := make(map[string]struct{})
+	for _, step := range steps {
+		features["activity="+step.Activity] = struct{}{}
+		if step.Resource != "" {
+			features["resource="+step.Resource] = struct{}{}
+		}
+		for attribute, value := range step.Attributes {
+			if value != "" {
+				features[attribute+"="+value] = struct{}{}
+			}
+		}
+	}
+	features["last_activity="+steps[len(steps)-1].Activity] = struct{}{}
+	features["length="+lengthBucket(len(steps))] = struct{}{}
+	return features
+}
+
+func lengthBucket(length int) string {
+	switch {
+	case length <= 3:
+		return strconv.Itoa(length)
+	case length <= 5:
+		return "4-5"
+	default:
+		return "6+"
+	}
+}
@@ -0,0 +1,156 @@
+package prediction
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// prefixWindows — длины суффиксов префикса, по которым ищется похожее состояние,
+// от самого точного к самому общему. 0 означает весь префикс.
+var prefixWindows = []int{0, 3, 2, 1}
+
+var (
+	// ErrNoTrainingData возвращается, если в логе нет кейсов для обучения.
+	ErrNoTrainingData = errors.New("в логе нет завершённых кейсов для обучения модели")
+	// ErrEmptyPrefix возвращается, если у кейса нет ни одного события.
+	ErrEmptyPrefix = errors.New("для прогноза нужно хотя бы одно событие кейса")
+)
+
+// Step — событие незавершённого кейса.
+type Step struct {
+	Activity   string            `json:"activity"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Resource   string            `json:"resource,omitempty"`
+	Result     string            `json:"result,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Prediction — прогноз оставшегося времени кейса.
+type Prediction struct {
+	ElapsedSeconds         float64   `json:"elapsed_seconds"`
+	RemainingSeconds       float64   `json:"remaining_seconds"`        // среднее по похожим кейсам
+	MedianRemainingSeconds float64   `json:"median_remaining_seconds"` // медиана по похожим кейсам
+	ExpectedCompletion     time.Time `json:"expected_completion"`
+	Basis                  string    `json:"basis"`   // по какому состоянию сделан прогноз
+	Support                int       `json:"support"` // количество наблюдений в этом состоянии
+}
+
+// RemainingTimeModel — аннотированная система переходов: для каждого состояния (префикса
+// последовательности активностей) хранит оставшееся время обученных кейсов.
+type RemainingTimeModel struct {
+	states map[string][]float64
+	global []float64
+}
+
+// TrainRemainingTime обучает модель на завершённых кейсах лога (completed сообщает, что кейс завершён):
+// у незавершённого кейса оставшееся время после последнего события неизвестно.
+func TrainRemainingTime(instances []metrics.ProcessInstance, completed func(*metrics.ProcessInstance) bool) (*RemainingTimeModel, error) {
+	model := &RemainingTimeModel{states: make(map[string][]float64)}
+	for n := range instances {
+		instance := &instances[n]
+		if len(instance.Events) == 0 || !completed(instance) {
+			continue
+		}
+		end := instance.Events[len(instance.Events)-1].Timestamp
+		activities := make([]string, len(instance.Events))
+		for i, event := range instance.Events {
+			activities[i] = event.Description
+		}
+		keys := make([]string, 0, len(prefixWindows))
+		for i, event := range instance.Events {
+			remaining := end.Sub(event.Timestamp).Seconds()
+			// Окна не короче префикса дают одно и то же состояние: событие учитывается в нём один раз
+			keys = keys[:0]
+			for _, window := range prefixWindows {
+				if key := stateKey(activities[:i+1], window); !slices.Contains(keys, key) {
+					keys = append(keys, key)
+					model.states[key] = append(model.states[key], remaining)
+				}
+			}
+			model.global = append(model.global, remaining)
+		}
+	}
+	if len(model.global) == 0 {
+		return nil, ErrNoTrainingData
+	}
+
+	for _, values := range model.states {
+		sort.Float64s(values)
+	}
+	sort.Float64s(model.global)
+	return model, nil
+}
+
+// Predict оценивает оставшееся время кейса по его событиям. Используется самое точное состояние,
+// встречавшееся при обучении: весь префикс, затем последние три, две и одна активность.
+func (m *RemainingTimeModel) Predict(steps []Step) (*Prediction, error) {
+	if len(steps) == 0 {
+		return nil, ErrEmptyPrefix
+	}
+	sorted := make([]Step, len(steps))
+	copy(sorted, steps)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	activities := make([]string, len(sorted))
+	for i, step := range sorted {
+		activities[i] = step.Activity
+	}
+
+	values, basis := m.global, "весь лог"
+	for _, window := range prefixWindows {
+		if observed, ok := m.states[stateKey(activities, window)]; ok {
+			values, basis = observed, basisName(window, len(activities))
+			break
+		}
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	remaining := sum / float64(len(values))
+	last := sorted[len(sorted)-1].Timestamp
+
+	return &Prediction{
+		ElapsedSeconds:         last.Sub(sorted[0].Timestamp).Seconds(),
+		RemainingSeconds:       remaining,
+		MedianRemainingSeconds: median(values),
+		ExpectedCompletion:     last.Add(time.Duration(remaining * float64(time.Second))),
+		Basis:                  basis,
+		Support:                len(values),
+	}, nil
+}
+
+func stateKey(activities []string, window int) string {
+	if window > 0 && len(activities) > window {
+		activities = activities[len(activities)-window:]
+	}
+	if window == 0 {
+		return "full\x00" + strings.Join(activities, "\x00")
+	}
+	return "last\x00" + strings.Join(activities, "\x00")
+}
+
+func basisName(window, length int) string {
+	if window == 0 || window >= length {
+		return "полный префикс"
+	}
+	if window == 1 {
+		return "последняя активность"
+	}
+	return fmt.Sprintf("последние %d активности", window)
+}
+
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
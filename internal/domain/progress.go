@@ -0,0 +1,25 @@
+package domain
+
+// Этапы построения графа.
+const (
+	PhaseReading    = "reading"    // чтение и разбор записей лога
+	PhaseAssembling = "assembling" // построение графа по кейсам
+	PhaseDone       = "done"
+)
+
+// progressInterval — через сколько прочитанных записей сообщается о ходе чтения.
+const progressInterval = 10000
+
+// BuildProgress — состояние построения графа.
+type BuildProgress struct {
+	Phase      string   `json:"phase"`
+	RowsRead   int      `json:"rows_read"`
+	CasesBuilt int      `json:"cases_built"`
+	BytesRead  int64    `json:"bytes_read"`
+	TotalBytes int64    `json:"total_bytes"`
+	Percent    float64  `json:"percent"`  // доля прочитанного файла, %
+	Warnings   []string `json:"warnings"` // некритичные проблемы разбора лога
+}
+
+// ProgressFunc получает состояние построения графа при смене этапа и по ходу чтения.
+type ProgressFunc func(BuildProgress)
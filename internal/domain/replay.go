@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// TokenMove описывает перемещение токена кейса по ребру графа.
+type TokenMove struct {
+	CaseID    string    `json:"case_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"` // момент, когда токен покидает узел From
+	Duration  float64   `json:"duration"`  // время перехода в секундах
+}
+
+// GetReplay возвращает упорядоченные по времени перемещения токенов всех кейсов,
+// включая вход из узла "start" и выход в узел "end".
+func (gb *GraphBuilder) GetReplay() []TokenMove {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+
+	var moves []TokenMove
+	store := gb.events
+	for c, rows := range store.caseRows {
+		if len(rows) == 0 {
+			continue
+		}
+		caseID := store.caseIDs[c]
+
+		moves = append(moves, TokenMove{
+			CaseID:    caseID,
+			From:      "start",
+			To:        store.activityName(rows[0]),
+			Timestamp: store.endTime(rows[0]),
+		})
+
+		for i := 1; i < len(rows); i++ {
+			prev, curr := store.endTime(rows[i-1]), store.endTime(rows[i])
+			moves = append(moves, TokenMove{
+				CaseID:    caseID,
+				From:      store.activityName(rows[i-1]),
+				To:        store.activityName(rows[i]),
+				Timestamp: prev,
+				Duration:  curr.Sub(prev).Seconds(),
+			})
+		}
+
+		last := rows[len(rows)-1]
+		moves = append(moves, TokenMove{
+			CaseID:    caseID,
+			From:      store.activityName(last),
+			To:        "end",
+			Timestamp: store.endTime(last),
+		})
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Timestamp.Before(moves[j].Timestamp)
+	})
+
+	return moves
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+//go:embed templates/report.html
+var reportTemplate string
+
+// HTMLReport — данные отчёта HTML по логу.
+type HTMLReport struct {
+	Title       string
+	GeneratedAt time.Time
+	Graph       *Graph
+	Metrics     *metrics.MetricsReport
+	Durations   metrics.DurationDistribution
+	Variants    []metrics.VariantMetrics // по убыванию частоты
+	Limit       int                      // сколько метрик, вариантов и узких мест показывать в таблицах
+}
+
+// WriteHTMLReport записывает отчёт одним HTML-файлом: стили, скрипт, схема графа и диаграммы
+// (SVG) встроены в него, поэтому файл открывается без сервера и доступа к интернету
+// и его можно отправить по почте.
+func WriteHTMLReport(w io.Writer, report HTMLReport) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"hours":   reportHours,
+		"percent": func(value float64) string { return fmt.Sprintf("%.1f%%", value) },
+		"join":    strings.Join,
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	var metricsWithOccurrences []metrics.InefficiencyMetric
+	for _, metric := range report.Metrics.Metrics {
+		if metric.Count > 0 {
+			metricsWithOccurrences = append(metricsWithOccurrences, metric)
+		}
+	}
+	limit := func(n int) int { return min(report.Limit, n) }
+	return tmpl.Execute(w, map[string]any{
+		"Report":      report,
+		"Metrics":     metricsWithOccurrences,
+		"Variants":    report.Variants[:limit(len(report.Variants))],
+		"Bottlenecks": report.Metrics.Bottlenecks[:limit(len(report.Metrics.Bottlenecks))],
+		"Graph":       template.HTML(graphSVG(report.Graph)),
+		"Waste":       template.HTML(wasteChartSVG(report.Metrics, report.Limit)),
+		"Histogram":   template.HTML(histogramSVG(report.Durations)),
+	})
+}
@@ -0,0 +1,300 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// Размеры элементов схемы графа в отчёте HTML, px.
+const (
+	svgMargin    = 50
+	svgNodeWidth = 160
+	svgNodeHigh  = 44
+	svgColumn    = 250 // шаг между уровнями графа
+	svgRow       = 80  // шаг между узлами одного уровня
+)
+
+// svgNode — узел графа с положением на схеме.
+type svgNode struct {
+	node        *Node
+	rank, order int
+	x, y        float64
+}
+
+// layoutGraph раскладывает узлы по уровням слева направо: уровень узла — кратчайшее число переходов
+// от начального узла, конечный узел — справа от остальных. Внутри уровня узлы упорядочиваются
+// по среднему положению предшественников, чтобы уменьшить число пересечений рёбер.
+func layoutGraph(graph *Graph) (map[string]*svgNode, float64, float64) {
+	nodes := make(map[string]*svgNode, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = &svgNode{node: node, rank: -1}
+	}
+	outgoing := make(map[string][]string)
+	incoming := make(map[string][]string)
+	for _, edge := range graph.Edges {
+		if edge.From != edge.To && nodes[edge.From] != nil && nodes[edge.To] != nil {
+			outgoing[edge.From] = append(outgoing[edge.From], edge.To)
+			incoming[edge.To] = append(incoming[edge.To], edge.From)
+		}
+	}
+
+	var queue []string
+	if start := nodes["start"]; start != nil {
+		start.rank = 0
+		queue = append(queue, "start")
+	} else {
+		for _, node := range graph.Nodes {
+			if len(incoming[node.ID]) == 0 {
+				nodes[node.ID].rank = 0
+				queue = append(queue, node.ID)
+			}
+		}
+	}
+	maxRank := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range outgoing[id] {
+			if nodes[next].rank < 0 && next != "end" {
+				nodes[next].rank = nodes[id].rank + 1
+				maxRank = max(maxRank, nodes[next].rank)
+				queue = append(queue, next)
+			}
+		}
+	}
+	// Недостижимые от начала узлы (например, при фильтрации) — на отдельном уровне перед концом
+	for _, node := range graph.Nodes {
+		if n := nodes[node.ID]; n.rank < 0 && node.ID != "end" {
+			n.rank = maxRank + 1
+		}
+	}
+	for _, n := range nodes {
+		maxRank = max(maxRank, n.rank)
+	}
+	if end := nodes["end"]; end != nil {
+		maxRank++
+		end.rank = maxRank
+	}
+
+	ranks := make([][]*svgNode, maxRank+1)
+	for _, node := range graph.Nodes {
+		n := nodes[node.ID]
+		ranks[n.rank] = append(ranks[n.rank], n)
+	}
+	for _, rank := range ranks {
+		sort.Slice(rank, func(i, j int) bool {
+			if rank[i].node.Count != rank[j].node.Count {
+				return rank[i].node.Count > rank[j].node.Count
+			}
+			return rank[i].node.ID < rank[j].node.ID
+		})
+		for i, n := range rank {
+			n.order = i
+		}
+	}
+	for sweep := 0; sweep < 4; sweep++ {
+		for _, rank := range ranks[1:] {
+			keys := make(map[*svgNode]float64, len(rank))
+			for _, n := range rank {
+				sum, count := 0.0, 0
+				for _, from := range incoming[n.node.ID] {
+					if p := nodes[from]; p.rank < n.rank {
+						sum += float64(p.order)
+						count++
+					}
+				}
+				keys[n] = float64(n.order)
+				if count > 0 {
+					keys[n] = sum / float64(count)
+				}
+			}
+			sort.SliceStable(rank, func(i, j int) bool { return keys[rank[i]] < keys[rank[j]] })
+			for i, n := range rank {
+				n.order = i
+			}
+		}
+	}
+
+	rows := 1
+	for _, rank := range ranks {
+		rows = max(rows, len(rank))
+	}
+	for r, rank := range ranks {
+		offset := float64(rows-len(rank)) * svgRow / 2
+		for _, n := range rank {
+			n.x = svgMargin + float64(r)*svgColumn
+			n.y = svgMargin + offset + float64(n.order)*svgRow
+		}
+	}
+	width := 2*svgMargin + float64(maxRank)*svgColumn + svgNodeWidth
+	height := 2*svgMargin + float64(rows-1)*svgRow + svgNodeHigh + 60 // место для обратных рёбер
+	return nodes, width, height
+}
+
+// graphSVG рисует граф процесса как SVG: ширина ребра пропорциональна числу переходов,
+// обратные переходы (возвраты) рисуются дугами под узлами пунктиром.
+func graphSVG(graph *Graph) string {
+	graph = sortedGraph(graph)
+	nodes, width, height := layoutGraph(graph)
+	maxCount := 1
+	for _, edge := range graph.Edges {
+		maxCount = max(maxCount, edge.Count)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" class="graph">`,
+		width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="7" markerHeight="7" orient="auto-start-reverse">` +
+		`<path d="M0,0 L10,5 L0,10 z" fill="#666"/></marker></defs>`)
+
+	for _, edge := range graph.Edges {
+		from, to := nodes[edge.From], nodes[edge.To]
+		if from == nil || to == nil {
+			continue
+		}
+		var p0, p1, p2, p3 [2]float64
+		dashed := false
+		switch {
+		case from == to:
+			p0 = [2]float64{from.x + svgNodeWidth*0.35, from.y}
+			p1 = [2]float64{from.x + svgNodeWidth*0.35, from.y - 40}
+			p2 = [2]float64{from.x + svgNodeWidth*0.65, from.y - 40}
+			p3 = [2]float64{from.x + svgNodeWidth*0.65, from.y}
+		case to.rank > from.rank:
+			p0 = [2]float64{from.x + svgNodeWidth, from.y + svgNodeHigh/2}
+			p3 = [2]float64{to.x, to.y + svgNodeHigh/2}
+			p1 = [2]float64{p0[0] + 70, p0[1]}
+			p2 = [2]float64{p3[0] - 70, p3[1]}
+		default:
+			// Возврат на свой или предыдущий уровень: дуга под узлами
+			dashed = true
+			p0 = [2]float64{from.x + svgNodeWidth/2, from.y + svgNodeHigh}
+			p3 = [2]float64{to.x + svgNodeWidth/2, to.y + svgNodeHigh}
+			bottom := max(p0[1], p3[1]) + 30 + 10*float64(from.rank-to.rank)
+			p1 = [2]float64{p0[0], bottom}
+			p2 = [2]float64{p3[0], bottom}
+		}
+
+		color := "#888"
+		if edge.HappyPath && edge.Color != "" {
+			color = edge.Color
+		}
+		attrs := fmt.Sprintf(`stroke="%s" stroke-width="%.1f" fill="none"`, xmlEscape(color), 1+5*float64(edge.Count)/float64(maxCount))
+		switch {
+		case edge.Parallel:
+			attrs += ` stroke-dasharray="2,4"`
+		case dashed || edge.Style == "dashed":
+			attrs += ` stroke-dasharray="6,4" marker-end="url(#arrow)"`
+		default:
+			attrs += ` marker-end="url(#arrow)"`
+		}
+		fmt.Fprintf(&b, `<g class="edge"><title>%s → %s: %d, ожидание %s, обработка %s</title>`,
+			xmlEscape(from.node.Label), xmlEscape(to.node.Label), edge.Count,
+			reportHours(edge.AvgWaiting), reportHours(edge.AvgProcessing))
+		fmt.Fprintf(&b, `<path d="M%.1f,%.1f C%.1f,%.1f %.1f,%.1f %.1f,%.1f" %s/>`,
+			p0[0], p0[1], p1[0], p1[1], p2[0], p2[1], p3[0], p3[1], attrs)
+		// Середина кривой Безье: (p0 + 3p1 + 3p2 + p3) / 8
+		mx := (p0[0] + 3*p1[0] + 3*p2[0] + p3[0]) / 8
+		my := (p0[1] + 3*p1[1] + 3*p2[1] + p3[1]) / 8
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="edge-label">%d</text></g>`, mx, my-3, edge.Count)
+	}
+
+	for _, node := range graph.Nodes {
+		n := nodes[node.ID]
+		color := node.Color
+		if color == "" {
+			color = "#add8e6"
+		}
+		stroke := 1.0
+		if node.HappyPath {
+			stroke = 2.5
+		}
+		fmt.Fprintf(&b, `<g class="node"><title>%s: %d</title>`, xmlEscape(node.Label), node.Count)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%d" rx="6" fill="%s" stroke="#555" stroke-width="%.1f"/>`,
+			n.x, n.y, svgNodeWidth, svgNodeHigh, xmlEscape(color), stroke)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="node-label">%s</text>`, n.x+svgNodeWidth/2, n.y+19, xmlEscape(truncateLabel(node.Label, 22)))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="node-count">%d</text></g>`, n.x+svgNodeWidth/2, n.y+35, node.Count)
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// truncateLabel укорачивает подпись до limit символов, чтобы она помещалась в узел.
+func truncateLabel(label string, limit int) string {
+	runes := []rune(label)
+	if len(runes) <= limit {
+		return label
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// reportHours форматирует длительность в секундах как часы.
+func reportHours(seconds float64) string {
+	return fmt.Sprintf("%.1f ч", seconds/3600)
+}
+
+// wasteChartSVG рисует горизонтальную диаграмму потерь времени по метрикам с вхождениями (не больше limit).
+func wasteChartSVG(report *metrics.MetricsReport, limit int) string {
+	var selected []metrics.InefficiencyMetric
+	maxWasted := 0.0
+	for _, metric := range report.Metrics {
+		if metric.Count == 0 || len(selected) == limit {
+			continue
+		}
+		selected = append(selected, metric)
+		maxWasted = max(maxWasted, metric.TotalWastedDuration)
+	}
+	if len(selected) == 0 {
+		return ""
+	}
+	const labelWidth, barWidth, row = 260.0, 420.0, 26.0
+	height := float64(len(selected))*row + 10
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" class="chart">`,
+		labelWidth+barWidth+90, height, labelWidth+barWidth+90, height)
+	for i, metric := range selected {
+		y := 5 + float64(i)*row
+		width := 0.0
+		if maxWasted > 0 {
+			width = barWidth * metric.TotalWastedDuration / maxWasted
+		}
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" class="bar-label">%s</text>`, labelWidth-8, y+15, xmlEscape(truncateLabel(metric.Definition.Name, 34)))
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.1f" width="%.1f" height="18" class="bar"><title>%s: %d вхождений</title></rect>`,
+			labelWidth, y+2, max(width, 1), xmlEscape(metric.Definition.Name), metric.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="bar-value">%s</text>`, labelWidth+max(width, 1)+6, y+15, reportHours(metric.TotalWastedDuration))
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// histogramSVG рисует гистограмму длительности кейсов.
+func histogramSVG(distribution metrics.DurationDistribution) string {
+	if len(distribution.Histogram) == 0 {
+		return ""
+	}
+	const width, height, bottom = 700.0, 220.0, 40.0
+	maxCount := 1
+	for _, bucket := range distribution.Histogram {
+		maxCount = max(maxCount, bucket.Count)
+	}
+	step := width / float64(len(distribution.Histogram))
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" class="chart">`,
+		width, height+bottom, width, height+bottom)
+	for i, bucket := range distribution.Histogram {
+		barHeight := height * float64(bucket.Count) / float64(maxCount)
+		x := float64(i) * step
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" class="bar"><title>%s – %s: %d кейсов</title></rect>`,
+			x+1, height-barHeight, step-2, barHeight, reportHours(bucket.From), reportHours(bucket.To), bucket.Count)
+		// Подписываем каждую вторую границу, чтобы подписи не накладывались
+		if i%2 == 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" class="axis-label">%s</text>`, x, height+16, reportHours(bucket.From))
+		}
+	}
+	fmt.Fprintf(&b, `<line x1="0" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#999"/>`, height, width, height)
+	b.WriteString("</svg>")
+	return b.String()
+}
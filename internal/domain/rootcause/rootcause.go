@@ -0,0 +1,175 @@
+package rootcause
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"process-mining/internal/domain/metrics"
+)
+
+const (
+	// DefaultSignificance — уровень значимости, ниже которого фактор считается причиной.
+	DefaultSignificance = 0.05
+	// DefaultMinCases — минимальное число затронутых кейсов со значением атрибута.
+	DefaultMinCases = 2
+
+	resourceAttribute = "resource"
+)
+
+// ErrNoAttributes возвращается, если в логе нет ни ресурсов, ни атрибутов событий.
+var ErrNoAttributes = errors.New("в логе нет атрибутов кейсов для анализа причин")
+
+// Factor — значение атрибута, непропорционально часто встречающееся среди затронутых метрикой кейсов.
+type Factor struct {
+	Attribute     string  `json:"attribute"`
+	Value         string  `json:"value"`
+	AffectedCases int     `json:"affected_cases"` // затронутые кейсы с этим значением
+	TotalCases    int     `json:"total_cases"`    // все кейсы с этим значением
+	AffectedShare float64 `json:"affected_share"` // доля значения среди затронутых кейсов, %
+	BaselineShare float64 `json:"baseline_share"` // доля значения среди всех кейсов, %
+	Lift          float64 `json:"lift"`           // во сколько раз значение чаще среди затронутых
+	PValue        float64 `json:"p_value"`
+}
+
+// MetricCauses — вероятные причины одной метрики неэффективности.
+type MetricCauses struct {
+	Metric        string   `json:"metric"`
+	AffectedCases int      `json:"affected_cases"`
+	Factors       []Factor `json:"factors"`
+}
+
+// Report содержит вероятные причины всех метрик, у которых есть вхождения в конкретных кейсах.
+type Report struct {
+	TotalCases int            `json:"total_cases"`
+	Metrics    []MetricCauses `json:"metrics"`
+}
+
+// Analyze сопоставляет затронутые метриками кейсы с атрибутами кейсов (ресурсы и атрибуты событий)
+// и находит значения, доля затронутых кейсов у которых значимо выше, чем у остальных
+// (односторонний z-тест разности долей).
+func Analyze(report *metrics.MetricsReport, instances map[string]*metrics.ProcessInstance, significance float64, minCases int) (*Report, error) {
+	caseValues, withValue := caseAttributes(instances)
+	if len(withValue) == 0 {
+		return nil, ErrNoAttributes
+	}
+
+	total := len(instances)
+	result := &Report{TotalCases: total, Metrics: []MetricCauses{}}
+	for _, metric := range report.Metrics {
+		affected := make(map[string]bool)
+		for _, occurrence := range metric.Occurrences {
+			if _, ok := instances[occurrence.InstanceID]; ok {
+				affected[occurrence.InstanceID] = true
+			}
+		}
+		// Метрики уровня всего лога и метрики, затронувшие все кейсы, не различают значения атрибутов
+		if len(affected) == 0 || len(affected) == total {
+			continue
+		}
+
+		counts := make(map[attributeValue]int)
+		for id := range affected {
+			for value := range caseValues[id] {
+				counts[value]++
+			}
+		}
+
+		causes := MetricCauses{Metric: metric.Definition.Name, AffectedCases: len(affected), Factors: []Factor{}}
+		for value, affectedWith := range counts {
+			if affectedWith < minCases {
+				continue
+			}
+			totalWith := withValue[value]
+			pValue := proportionTest(affectedWith, totalWith, len(affected)-affectedWith, total-totalWith)
+			if pValue >= significance {
+				continue
+			}
+			affectedShare := float64(affectedWith) / float64(len(affected))
+			baselineShare := float64(totalWith) / float64(total)
+			causes.Factors = append(causes.Factors, Factor{
+				Attribute:     value.attribute,
+				Value:         value.value,
+				AffectedCases: affectedWith,
+				TotalCases:    totalWith,
+				AffectedShare: math.Round(affectedShare*1000) / 10,
+				BaselineShare: math.Round(baselineShare*1000) / 10,
+				Lift:          math.Round(affectedShare/baselineShare*100) / 100,
+				PValue:        pValue,
+			})
+		}
+		if len(causes.Factors) == 0 {
+			continue
+		}
+		sort.Slice(causes.Factors, func(i, j int) bool {
+			a, b := causes.Factors[i], causes.Factors[j]
+			if a.PValue != b.PValue {
+				return a.PValue < b.PValue
+			}
+			if a.Lift != b.Lift {
+				return a.Lift > b.Lift
+			}
+			if a.Attribute != b.Attribute {
+				return a.Attribute < b.Attribute
+			}
+			return a.Value < b.Value
+		})
+		result.Metrics = append(result.Metrics, causes)
+	}
+
+	sort.Slice(result.Metrics, func(i, j int) bool {
+		if result.Metrics[i].AffectedCases != result.Metrics[j].AffectedCases {
+			return result.Metrics[i].AffectedCases > result.Metrics[j].AffectedCases
+		}
+		return result.Metrics[i].Metric < result.Metrics[j].Metric
+	})
+	return result, nil
+}
+
+type attributeValue struct {
+	attribute, value string
+}
+
+// caseAttributes собирает значения атрибутов каждого кейса: значение принадлежит кейсу,
+// если встречается хотя бы в одном его событии.
+func caseAttributes(instances map[string]*metrics.ProcessInstance) (map[string]map[attributeValue]bool, map[attributeValue]int) {
+	caseValues := make(map[string]map[attributeValue]bool, len(instances))
+	withValue := make(map[attributeValue]int)
+	for id, instance := range instances {
+		values := make(map[attributeValue]bool)
+		for _, event := range instance.Events {
+			if event.Resource != "" {
+				values[attributeValue{resourceAttribute, event.Resource}] = true
+			}
+			for attribute, value := range event.Attributes {
+				if value != "" {
+					values[attributeValue{attribute, value}] = true
+				}
+			}
+		}
+		for value := range values {
+			withValue[value]++
+		}
+		caseValues[id] = values
+	}
+	return caseValues, withValue
+}
+
+// proportionTest проверяет, что доля затронутых кейсов среди кейсов со значением
+// (affectedWith из totalWith) больше доли среди остальных (affectedWithout из totalWithout).
+// Возвращает одностороннее p-значение.
+func proportionTest(affectedWith, totalWith, affectedWithout, totalWithout int) float64 {
+	if totalWithout == 0 {
+		// Значение есть во всех кейсах — сравнивать не с чем
+		return 1
+	}
+	p1 := float64(affectedWith) / float64(totalWith)
+	p2 := float64(affectedWithout) / float64(totalWithout)
+	pooled := float64(affectedWith+affectedWithout) / float64(totalWith+totalWithout)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(totalWith) + 1/float64(totalWithout)))
+	if se == 0 {
+		return 1
+	}
+	z := (p1 - p2) / se
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+
+	"process-mining/internal/infrastructure"
+)
+
+// SampleLog записывает в w заголовок лога filePath и все записи кейсов, попавших в выборку
+// (примерно доля fraction от числа кейсов), читая файл потоком. Кейс попадает в выборку целиком
+// или не попадает совсем, а выбор зависит только от идентификатора кейса и seed: повторный запуск
+// с тем же seed даёт ту же выборку, а выборка с большей долей включает выборку с меньшей.
+// Возвращает число кейсов в выборке и во всём логе.
+func SampleLog(csvReader *infrastructure.CSVReader, filePath string, w io.Writer, columns ColumnNames, fraction float64, seed int64) (sampled, total int, err error) {
+	if fraction <= 0 || fraction > 1 {
+		return 0, 0, errors.New("доля выборки должна быть больше 0 и не больше 1")
+	}
+	writer := csv.NewWriter(w)
+	cases := make(map[string]bool) // кейс → попал в выборку
+	mapping := defaultColumnMapping()
+
+	err = csvReader.ReadAndProcessWithHeader(filePath, func(header []string) error {
+		mapping = DetectColumnsWith(header, columns)
+		return writer.Write(header)
+	}, func(record []string) error {
+		if len(record) < mapping.minRecordLength() {
+			return fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", mapping.minRecordLength(), record)
+		}
+		caseID := record[mapping.CaseID]
+		keep, seen := cases[caseID]
+		if !seen {
+			keep = sampleKey(caseID, seed) < fraction
+			cases[caseID] = keep
+			if keep {
+				sampled++
+			}
+		}
+		if !keep {
+			return nil
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	writer.Flush()
+	return sampled, len(cases), writer.Error()
+}
+
+// sampleKey отображает идентификатор кейса в число из [0, 1), равномерно распределённое по кейсам
+// и одинаковое для одного seed.
+func sampleKey(caseID string, seed int64) float64 {
+	hash := fnv.New64a()
+	binary.Write(hash, binary.LittleEndian, seed)
+	hash.Write([]byte(caseID))
+	// Перемешивание splitmix64: у FNV старшие биты коротких похожих строк распределены неравномерно
+	x := hash.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
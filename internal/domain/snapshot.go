@@ -0,0 +1,35 @@
+package domain
+
+// Events возвращает копии событий всех кейсов, в порядке событий внутри кейса.
+// Вместе с LoadEvents позволяет сохранить данные построителя и восстановить их без повторного разбора лога.
+func (gb *GraphBuilder) Events() []Event {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	return gb.events.events()
+}
+
+// events возвращает копии событий всех кейсов, в порядке событий внутри кейса.
+func (s *eventStore) events() []Event {
+	events := make([]Event, 0, s.rows())
+	for _, rows := range s.caseRows {
+		for _, row := range rows {
+			events = append(events, s.event(row))
+		}
+	}
+	return events
+}
+
+// LoadEvents заменяет данные построителя событиями events и строит по ним граф.
+func (gb *GraphBuilder) LoadEvents(events []Event) {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+
+	gb.events = newEventStore()
+	gb.durations = nil
+	gb.pendingStarts = make(map[string]int32)
+	for i := range events {
+		gb.intern(&events[i])
+		gb.events.append(&events[i])
+	}
+	gb.finalizeGraph()
+}
@@ -0,0 +1,270 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unique"
+)
+
+// spillBuckets — число файлов, по которым распределяются выгруженные кейсы: при возврате в память
+// файлы читаются по одному, так что одновременно читается около 1/spillBuckets выгруженных событий.
+const spillBuckets = 64
+
+// Оценка памяти, которую занимает событие в построителе во время чтения лога: строка в столбцах
+// eventStore, номер строки в списке событий кейса и запас ёмкости столбцов; отдельно — на каждый атрибут, байт.
+const (
+	eventFootprint     = 100
+	attributeFootprint = 64
+)
+
+// eventSpill — события кейсов, выгруженные на диск при превышении бюджета памяти построения.
+// Кейс всегда попадает в один и тот же файл (по хешу идентификатора), а события в файле идут
+// в порядке выгрузки, поэтому при возврате в память порядок событий кейса сохраняется.
+type eventSpill struct {
+	dir     string
+	seed    maphash.Seed
+	files   [spillBuckets]*os.File
+	writers [spillBuckets]*bufio.Writer
+	events  [spillBuckets]int // число событий в каждом файле
+	cases   int               // выгруженных кейсов (кейс, выгружавшийся несколько раз, учитывается каждый раз)
+	rounds  int               // сколько раз выгружались кейсы
+	buf     []byte
+}
+
+// SetMemoryLimit задаёт бюджет памяти (байт) под события, прочитанные при следующих построениях графа
+// по файлу; 0 — без ограничения. При превышении бюджета события прочитанных кейсов выгружаются
+// во временные файлы и возвращаются в память в компактном виде по окончании чтения.
+func (gb *GraphBuilder) SetMemoryLimit(limit int64) {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	gb.memoryLimit = limit
+}
+
+// trackMemory учитывает событие в бюджете памяти построения и при превышении бюджета
+// выгружает на диск кейсы touched, прочитанные при этом построении.
+func (gb *GraphBuilder) trackMemory(event *Event, touched map[string]struct{}) error {
+	if gb.memoryLimit <= 0 {
+		return nil
+	}
+	gb.ingested += eventFootprint + int64(len(event.Attributes))*attributeFootprint
+	if gb.ingested <= gb.memoryLimit {
+		return nil
+	}
+	return gb.spillSessions(touched)
+}
+
+// spillSessions выгружает на диск события кейсов touched и убирает кейсы из хранилища. Кейсы с начатыми,
+// но не завершёнными активностями остаются в памяти: их события ещё изменит событие complete.
+func (gb *GraphBuilder) spillSessions(touched map[string]struct{}) error {
+	if gb.spill == nil {
+		dir, err := os.MkdirTemp("", "process-mining-spill-*")
+		if err != nil {
+			return fmt.Errorf("ошибка создания каталога для выгрузки событий: %v", err)
+		}
+		gb.spill = &eventSpill{dir: dir, seed: maphash.MakeSeed()}
+	}
+	pending := make(map[string]struct{}, len(gb.pendingStarts))
+	for _, row := range gb.pendingStarts {
+		pending[gb.events.caseIDs[gb.events.caseIndex[row]]] = struct{}{}
+	}
+	spilled := make(map[string]struct{})
+	for sessionID := range touched {
+		c, ok := gb.events.caseOf(sessionID)
+		if _, started := pending[sessionID]; !ok || started {
+			continue
+		}
+		if err := gb.spill.write(gb.events, c); err != nil {
+			return err
+		}
+		spilled[sessionID] = struct{}{}
+	}
+	moved := gb.events.removeCases(spilled)
+	for key, row := range gb.pendingStarts {
+		gb.pendingStarts[key] = moved[row]
+	}
+	gb.spill.rounds++
+	gb.ingested = 0
+	return nil
+}
+
+// restoreSpilled возвращает выгруженные события в хранилище и удаляет временные файлы. Выгруженные
+// события кейса ставятся перед его событиями, прочитанными после последней выгрузки.
+func (gb *GraphBuilder) restoreSpilled() error {
+	spill := gb.spill
+	if spill == nil {
+		return nil
+	}
+	gb.spill = nil
+	defer spill.close()
+	for bucket, file := range spill.files {
+		if file == nil {
+			continue
+		}
+		events, err := spill.read(bucket)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			gb.intern(&events[i])
+		}
+		// События одного кейса идут в файле в порядке выгрузки: устойчивая сортировка его сохраняет
+		slices.SortStableFunc(events, func(a, b Event) int { return strings.Compare(a.SessionID, b.SessionID) })
+		for i := 0; i < len(events); {
+			j := i + 1
+			for j < len(events) && events[j].SessionID == events[i].SessionID {
+				j++
+			}
+			gb.events.prepend(events[i].SessionID, events[i:j])
+			i = j
+		}
+	}
+	return nil
+}
+
+// bucket возвращает номер файла, в который выгружаются события кейса.
+func (s *eventSpill) bucket(sessionID string) int {
+	return int(maphash.String(s.seed, sessionID) % spillBuckets)
+}
+
+// write дописывает события кейса c хранилища events в файл кейса.
+func (s *eventSpill) write(events *eventStore, c int32) error {
+	rows := events.caseRows[c]
+	bucket := s.bucket(events.caseIDs[c])
+	if s.files[bucket] == nil {
+		file, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("bucket-%02d", bucket)))
+		if err != nil {
+			return fmt.Errorf("ошибка создания файла выгрузки событий: %v", err)
+		}
+		s.files[bucket] = file
+		s.writers[bucket] = bufio.NewWriter(file)
+	}
+	for _, row := range rows {
+		event := events.event(row)
+		buf, err := appendSpilledEvent(s.buf[:0], &event)
+		if err != nil {
+			return err
+		}
+		s.buf = buf
+		if _, err := s.writers[bucket].Write(buf); err != nil {
+			return fmt.Errorf("ошибка выгрузки событий на диск: %v", err)
+		}
+	}
+	s.events[bucket] += len(rows)
+	s.cases++
+	return nil
+}
+
+// read читает все события файла bucket в порядке их выгрузки.
+func (s *eventSpill) read(bucket int) ([]Event, error) {
+	if err := s.writers[bucket].Flush(); err != nil {
+		return nil, fmt.Errorf("ошибка выгрузки событий на диск: %v", err)
+	}
+	file := s.files[bucket]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("ошибка чтения выгруженных событий: %v", err)
+	}
+	r := bufio.NewReader(file)
+	events := make([]Event, s.events[bucket])
+	for i := range events {
+		if err := readSpilledEvent(r, &events[i], &s.buf); err != nil {
+			return nil, fmt.Errorf("ошибка чтения выгруженных событий: %v", err)
+		}
+	}
+	return events, nil
+}
+
+// close закрывает и удаляет временные файлы.
+func (s *eventSpill) close() {
+	for _, file := range s.files {
+		if file != nil {
+			file.Close()
+		}
+	}
+	os.RemoveAll(s.dir)
+}
+
+// appendSpilledEvent дописывает к buf событие в формате файла выгрузки: строки с длиной в начале,
+// время в формате time.Time.MarshalBinary, атрибуты — числом пар и парами «название, значение».
+// Номер активности не сохраняется: при возврате он берётся из справочника построителя.
+func appendSpilledEvent(buf []byte, event *Event) ([]byte, error) {
+	for _, value := range []string{event.ID, event.SessionID, event.Desc, event.Result, event.Resource} {
+		buf = appendSpilledString(buf, value)
+	}
+	for _, t := range []time.Time{event.Timestamp, event.Start} {
+		data, err := t.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка выгрузки событий на диск: %v", err)
+		}
+		buf = appendSpilledString(buf, string(data))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(event.Attributes)))
+	for name, value := range event.Attributes {
+		buf = appendSpilledString(buf, name)
+		buf = appendSpilledString(buf, value)
+	}
+	return buf, nil
+}
+
+func appendSpilledString(buf []byte, value string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// readSpilledEvent читает событие, записанное appendSpilledEvent; buf — буфер для чтения полей.
+// Названия атрибутов заменяются общими экземплярами, остальные строки — при intern.
+func readSpilledEvent(r *bufio.Reader, event *Event, buf *[]byte) error {
+	for _, field := range []*string{&event.ID, &event.SessionID, &event.Desc, &event.Result, &event.Resource} {
+		if err := readSpilledBytes(r, buf); err != nil {
+			return err
+		}
+		*field = string(*buf)
+	}
+	for _, field := range []*time.Time{&event.Timestamp, &event.Start} {
+		if err := readSpilledBytes(r, buf); err != nil {
+			return err
+		}
+		if err := field.UnmarshalBinary(*buf); err != nil {
+			return err
+		}
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		event.Attributes = make(map[string]string, count)
+	}
+	for ; count > 0; count-- {
+		if err := readSpilledBytes(r, buf); err != nil {
+			return err
+		}
+		name := unique.Make(string(*buf)).Value()
+		if err := readSpilledBytes(r, buf); err != nil {
+			return err
+		}
+		event.Attributes[name] = string(*buf)
+	}
+	return nil
+}
+
+// readSpilledBytes читает в buf строку, записанную appendSpilledString.
+func readSpilledBytes(r *bufio.Reader, buf *[]byte) error {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if uint64(cap(*buf)) < n {
+		*buf = make([]byte, n)
+	}
+	*buf = (*buf)[:n]
+	_, err = io.ReadFull(r, *buf)
+	return err
+}
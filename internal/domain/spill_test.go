@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpillMatchesInMemoryBuild(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), 300)
+	want, _ := buildTestGraph(t, path, nil)
+
+	for _, limit := range []int64{1, 20 * eventFootprint, 200 * eventFootprint} {
+		t.Run(fmt.Sprintf("limit=%d", limit), func(t *testing.T) {
+			// Временные файлы выгрузки создаются в отдельном каталоге, чтобы проверить их удаление
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			got, progress := buildTestGraph(t, path, func(gb *GraphBuilder) { gb.SetMemoryLimit(limit) })
+
+			spilled := false
+			for _, warning := range progress.Warnings {
+				spilled = spilled || strings.Contains(warning, "выгружались на диск")
+			}
+			if !spilled {
+				t.Fatalf("события не выгружались на диск, предупреждения: %q", progress.Warnings)
+			}
+			assertSameBuild(t, want, got)
+
+			entries, err := os.ReadDir(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("после построения остались временные файлы выгрузки: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+// SplitPeriods — периоды, по которым можно разделить лог.
+var SplitPeriods = []string{"day", "week", "month", "year"}
+
+// MaxSplitParts — наибольшее число частей при разделении лога: каждая часть — открытый файл.
+const MaxSplitParts = 1000
+
+// SplitNoValue — часть для кейсов, у которых не задан атрибут разделения.
+const SplitNoValue = "none"
+
+// periodKey возвращает название периода, в который попадает момент t.
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case "day":
+		return t.Format(time.DateOnly)
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006")
+	}
+}
+
+// SplitLog делит лог filePath на части по периоду period (см. SplitPeriods), в который начался кейс,
+// или, если period пуст, по значению столбца attribute (первому непустому значению у кейса; кейсы
+// без значения попадают в часть SplitNoValue). Кейс целиком попадает в одну часть. Для каждой части
+// вызывается create, записи копируются в неё без изменений вместе с заголовком. Файл читается потоком
+// дважды. Возвращает число кейсов в каждой части.
+func SplitLog(csvReader *infrastructure.CSVReader, filePath string, columns ColumnNames, period, attribute string, create func(part string) (io.Writer, error)) (map[string]int, error) {
+	if (period == "") == (attribute == "") {
+		return nil, errors.New("задайте либо период, либо атрибут разделения")
+	}
+	if period != "" && !slices.Contains(SplitPeriods, period) {
+		return nil, fmt.Errorf("неизвестный период %q: поддерживаются %s", period, strings.Join(SplitPeriods, ", "))
+	}
+	var header []string
+	mapping := defaultColumnMapping()
+	column := -1
+	starts := make(map[string]time.Time)
+	parts := make(map[string]string) // кейс → часть
+
+	err := csvReader.ReadAndProcessWithHeader(filePath, func(h []string) error {
+		header = h
+		mapping = DetectColumnsWith(header, columns)
+		if attribute == "" {
+			return nil
+		}
+		for i, name := range header {
+			if normalizeColumn(name) == normalizeColumn(attribute) {
+				column = i
+				break
+			}
+		}
+		if column < 0 {
+			return fmt.Errorf("в логе нет столбца %q", attribute)
+		}
+		return nil
+	}, func(record []string) error {
+		if len(record) < mapping.minRecordLength() {
+			return fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", mapping.minRecordLength(), record)
+		}
+		caseID := record[mapping.CaseID]
+		if attribute != "" {
+			if _, ok := parts[caseID]; !ok || parts[caseID] == SplitNoValue {
+				parts[caseID] = SplitNoValue
+				if value := strings.TrimSpace(mapping.field(record, column)); value != "" {
+					parts[caseID] = value
+				}
+			}
+			return nil
+		}
+		timestamp, err := parseTime(record[mapping.Timestamp])
+		if err != nil {
+			return err
+		}
+		if start, ok := starts[caseID]; !ok || timestamp.Before(start) {
+			starts[caseID] = timestamp
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for caseID, start := range starts {
+		parts[caseID] = periodKey(start, period)
+	}
+
+	counts := make(map[string]int)
+	for _, part := range parts {
+		counts[part]++
+	}
+	if len(counts) > MaxSplitParts {
+		return nil, fmt.Errorf("лог делится на %d частей, допускается не больше %d", len(counts), MaxSplitParts)
+	}
+
+	writers := make(map[string]*csv.Writer, len(counts))
+	err = csvReader.ReadAndProcessWithHeader(filePath, nil, func(record []string) error {
+		part := parts[record[mapping.CaseID]]
+		writer, ok := writers[part]
+		if !ok {
+			w, err := create(part)
+			if err != nil {
+				return err
+			}
+			writer = csv.NewWriter(w)
+			writers[part] = writer
+			if err := writer.Write(header); err != nil {
+				return err
+			}
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, writer := range writers {
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
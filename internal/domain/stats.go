@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+	"process-mining/internal/infrastructure"
+)
+
+// LogStats — краткая сводка лога, рассчитываемая за один проход по файлу без построения графа.
+type LogStats struct {
+	Cases       int                 `json:"cases"`
+	Events      int                 `json:"events"` // выполнений активностей: начало и завершение считаются одним событием
+	Rows        int                 `json:"rows"`   // записей файла, включая переходы жизненного цикла
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Activities  int                 `json:"activities"`
+	Variants    int                 `json:"variants"`
+	Durations   metrics.Percentiles `json:"durations"`    // длительность кейсов, сек
+	MaxDuration float64             `json:"max_duration"` // сек
+}
+
+// caseStats — то, что нужно хранить о кейсе при потоковом подсчёте: границы времени и хеш варианта.
+type caseStats struct {
+	first, last time.Time
+	variant     uint64
+}
+
+// FNV-1a для хеширования последовательности активностей кейса без её хранения.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+func hashActivity(hash uint64, activity string) uint64 {
+	for i := 0; i < len(activity); i++ {
+		hash = (hash ^ uint64(activity[i])) * fnvPrime
+	}
+	// Нулевой байт после активности, чтобы последовательности "ab", "c" и "a", "bc" различались
+	return hash * fnvPrime
+}
+
+// ComputeLogStats считает число кейсов, событий, активностей и вариантов, период лога и процентили
+// длительности кейсов, читая файл потоком. События жизненного цикла учитываются так же, как
+// при построении графа: начало и завершение активности образуют одно событие.
+func ComputeLogStats(csvReader *infrastructure.CSVReader, filePath string, columns ColumnNames) (*LogStats, error) {
+	stats := &LogStats{}
+	cases := make(map[string]*caseStats)
+	activities := make(map[string]bool)
+	started := make(map[string]bool) // начатые, но не завершённые активности: кейс + активность
+	mapping := defaultColumnMapping()
+
+	err := csvReader.ReadAndProcessWithHeader(filePath, func(header []string) error {
+		mapping = DetectColumnsWith(header, columns)
+		return nil
+	}, func(record []string) error {
+		if len(record) < mapping.minRecordLength() {
+			return fmt.Errorf("ошибка: запись содержит меньше %d столбцов: %v", mapping.minRecordLength(), record)
+		}
+		timestamp, err := parseTime(record[mapping.Timestamp])
+		if err != nil {
+			return err
+		}
+		stats.Rows++
+		caseID, activity := record[mapping.CaseID], record[mapping.Activity]
+		key := caseID + "\x00" + activity
+		execution := true
+		switch strings.ToLower(mapping.field(record, mapping.Lifecycle)) {
+		case "start":
+			started[key] = true
+		case "complete":
+			if started[key] {
+				delete(started, key)
+				execution = false
+			}
+		case "schedule", "assign", "reassign", "suspend", "resume", "withdraw", "ate_abort", "pi_abort", "autoskip", "manualskip":
+			return nil
+		}
+
+		c := cases[caseID]
+		if c == nil {
+			c = &caseStats{first: timestamp, last: timestamp, variant: fnvOffset}
+			cases[caseID] = c
+		}
+		if timestamp.Before(c.first) {
+			c.first = timestamp
+		}
+		if timestamp.After(c.last) {
+			c.last = timestamp
+		}
+		if stats.From.IsZero() || timestamp.Before(stats.From) {
+			stats.From = timestamp
+		}
+		if timestamp.After(stats.To) {
+			stats.To = timestamp
+		}
+		if execution {
+			stats.Events++
+			activities[activity] = true
+			c.variant = hashActivity(c.variant, activity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make(map[uint64]bool)
+	durations := make([]float64, 0, len(cases))
+	for _, c := range cases {
+		variants[c.variant] = true
+		duration := c.last.Sub(c.first).Seconds()
+		durations = append(durations, duration)
+		stats.MaxDuration = max(stats.MaxDuration, duration)
+	}
+	stats.Cases = len(cases)
+	stats.Activities = len(activities)
+	stats.Variants = len(variants)
+	stats.Durations = metrics.DurationPercentiles(durations)
+	return stats, nil
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// subprocessNodePrefix отличает идентификаторы узлов-подпроцессов от идентификаторов активностей.
+const subprocessNodePrefix = "subprocess:"
+
+// SubprocessGrouping описывает разбиение активностей на подпроцессы.
+type SubprocessGrouping struct {
+	Separator string            `json:"separator"` // разделитель префикса: "Оплата: Выставить счёт" → "Оплата"
+	Mapping   map[string]string `json:"mapping"`   // явное соответствие активность → подпроцесс (приоритетнее префикса)
+}
+
+// SubprocessOf возвращает подпроцесс активности или пустую строку, если активность ни к чему не относится.
+func (g *SubprocessGrouping) SubprocessOf(activity string) string {
+	if name, ok := g.Mapping[activity]; ok {
+		return name
+	}
+	if g.Separator != "" {
+		if idx := strings.Index(activity, g.Separator); idx > 0 {
+			return strings.TrimSpace(activity[:idx])
+		}
+	}
+	return ""
+}
+
+// BuildSubprocessGraph строит двухуровневый граф: активности каждого подпроцесса сворачиваются
+// в один узел, кроме подпроцессов из expanded, которые разворачиваются до отдельных активностей.
+func (gb *GraphBuilder) BuildSubprocessGraph(grouping *SubprocessGrouping, expanded []string) *Graph {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+
+	expandedSet := make(map[string]bool, len(expanded))
+	for _, name := range expanded {
+		expandedSet[name] = true
+	}
+
+	children := make(map[string]map[string]struct{})
+	nodeKey := func(activity string) string {
+		name := grouping.SubprocessOf(activity)
+		if name == "" || expandedSet[name] {
+			return activity
+		}
+		if children[name] == nil {
+			children[name] = make(map[string]struct{})
+		}
+		children[name][activity] = struct{}{}
+		return subprocessNodePrefix + name
+	}
+	collapse := func(key string) bool {
+		return strings.HasPrefix(key, subprocessNodePrefix)
+	}
+
+	graph := newGraphAssembler(nodeKey, collapse).assemble(gb.events)
+
+	for _, node := range graph.Nodes {
+		if !strings.HasPrefix(node.ID, subprocessNodePrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(node.ID, subprocessNodePrefix)
+		node.Label = name
+		node.Subprocess = true
+		if !node.HappyPath {
+			node.Color = "purple"
+		}
+		for activity := range children[name] {
+			node.Children = append(node.Children, activity)
+		}
+		sort.Strings(node.Children)
+	}
+
+	return graph
+}
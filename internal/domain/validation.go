@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+const (
+	// DefaultValidationRows — сколько строк лога проверяется, если не указано иное.
+	DefaultValidationRows = 1000
+	// MaxValidationRows — наибольшее число строк, проверяемых за один запрос.
+	MaxValidationRows = 100000
+	// maxValidationIssues — сколько ошибок и предупреждений каждого вида попадает в результат.
+	maxValidationIssues = 20
+)
+
+// ValidationIssue — проблема, найденная при проверке лога.
+type ValidationIssue struct {
+	Line    int    `json:"line,omitempty"` // номер строки файла (не задан для проблем файла в целом)
+	Message string `json:"message"`
+}
+
+// LogValidation — результат проверки первых строк лога перед загрузкой.
+type LogValidation struct {
+	// Valid — ошибок нет: проверенные строки будут разобраны при построении графа
+	Valid            bool              `json:"valid"`
+	RowsChecked      int               `json:"rows_checked"`
+	Complete         bool              `json:"complete"` // файл проверен целиком
+	HeaderRecognized bool              `json:"header_recognized"`
+	Columns          map[string]string `json:"columns"` // поле события → столбец файла
+	Cases            int               `json:"cases"`   // кейсов в проверенных строках
+	ErrorCount       int               `json:"error_count"`
+	WarningCount     int               `json:"warning_count"`
+	Errors           []ValidationIssue `json:"errors"`   // первые ошибки: с ними построение графа не пройдёт
+	Warnings         []ValidationIssue `json:"warnings"` // первые предупреждения: граф построится, но может быть искажён
+}
+
+func (v *LogValidation) addError(line int, format string, args ...any) {
+	if v.ErrorCount++; len(v.Errors) < maxValidationIssues {
+		v.Errors = append(v.Errors, ValidationIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *LogValidation) addWarning(line int, format string, args ...any) {
+	if v.WarningCount++; len(v.Warnings) < maxValidationIssues {
+		v.Warnings = append(v.Warnings, ValidationIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// ValidateLog проверяет заголовок и первые rows строк CSV-лога из src теми же правилами, что и построение
+// графа: число столбцов, разбор времени и длительностей, а также порядок событий внутри кейса
+// (события используются в порядке файла, поэтому время события не должно быть раньше предыдущего).
+// Названия столбцов columns (если заданы) учитываются так же, как при построении графа.
+func ValidateLog(csvReader *infrastructure.CSVReader, src io.Reader, rows int, columns ColumnNames) (*LogValidation, error) {
+	validation := &LogValidation{Columns: map[string]string{}, Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+	var header []string
+	mapping := defaultColumnMapping()
+	lastEvent := map[string]time.Time{} // кейс → время последнего события
+	unordered := map[string]bool{}      // кейсы, о нарушении порядка в которых уже сообщено
+
+	complete, err := csvReader.ReadSample(src, rows, func(fields []string) error {
+		header = fields
+		mapping = DetectColumnsWith(header, columns)
+		validation.HeaderRecognized = mapping.Recognized
+		if !mapping.Recognized {
+			validation.addWarning(1, "заголовок не распознан: используется порядок столбцов ID сессии, время, описание, первая строка не считается событием")
+		}
+		for field, index := range map[string]int{
+			"case": mapping.CaseID, "timestamp": mapping.Timestamp, "activity": mapping.Activity,
+			"result": mapping.Result, "resource": mapping.Resource, "lifecycle": mapping.Lifecycle,
+			"start": mapping.Start, "processing": mapping.Processing,
+		} {
+			if index >= 0 && index < len(header) {
+				validation.Columns[field] = strings.TrimSpace(strings.TrimPrefix(header[index], "\ufeff"))
+			}
+		}
+		if len(header) < mapping.minRecordLength() {
+			validation.addError(1, "в файле %d столбцов, нужно не меньше %d: ID кейса, время и активность", len(header), mapping.minRecordLength())
+		}
+		return nil
+	}, func(record []string, line int, err error) error {
+		validation.RowsChecked++
+		if errors.Is(err, csv.ErrFieldCount) {
+			validation.addError(line, "строка содержит %d столбцов вместо %d", len(record), len(header))
+			return nil
+		}
+		if err != nil {
+			validation.addError(line, "ошибка разбора CSV: %v", err)
+			return nil
+		}
+		if len(record) < mapping.minRecordLength() {
+			return nil // Об этом уже сообщено при разборе заголовка
+		}
+
+		caseID, activity := record[mapping.CaseID], record[mapping.Activity]
+		if strings.TrimSpace(caseID) == "" {
+			validation.addWarning(line, "пустой ID кейса")
+		}
+		if strings.TrimSpace(activity) == "" {
+			validation.addWarning(line, "пустое название активности")
+		}
+		timestamp, err := parseTime(record[mapping.Timestamp])
+		if err != nil {
+			validation.addError(line, "%v", err)
+			return nil
+		}
+		if value := mapping.field(record, mapping.Start); mapping.Start >= 0 && value != "" {
+			start, err := parseTime(value)
+			if err != nil {
+				validation.addError(line, "%v", err)
+			} else if start.After(timestamp) {
+				validation.addWarning(line, "время начала %s позже времени события %s", value, record[mapping.Timestamp])
+			}
+		}
+		if value := mapping.field(record, mapping.Processing); mapping.Processing >= 0 && value != "" {
+			if _, err := parseProcessingTime(value); err != nil {
+				validation.addError(line, "%v", err)
+			}
+		}
+
+		if last, ok := lastEvent[caseID]; ok && timestamp.Before(last) && !unordered[caseID] {
+			unordered[caseID] = true
+			validation.addWarning(line, "время события кейса %s раньше предыдущего события (%s < %s): события используются в порядке файла",
+				caseID, timestamp.Format(time.RFC3339), last.Format(time.RFC3339))
+		}
+		if last, ok := lastEvent[caseID]; !ok || timestamp.After(last) {
+			lastEvent[caseID] = timestamp
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	validation.Complete = complete
+	validation.Cases = len(lastEvent)
+	if header == nil {
+		validation.addError(0, "файл пуст")
+	} else if validation.RowsChecked == 0 {
+		validation.addError(0, "в файле нет событий")
+	}
+	validation.Valid = validation.ErrorCount == 0
+	return validation, nil
+}
@@ -0,0 +1,248 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// chunkSize — примерный размер части файла, которую разбирает одна рабочая горутина, байт.
+const chunkSize = 1 << 20
+
+// csvChunk — часть файла из целых записей и результат её разбора.
+type csvChunk[T any] struct {
+	data   []byte
+	line   int   // строк файла до начала части
+	end    int64 // смещение конца части в файле
+	rows   int   // разобрано записей
+	result T
+	err    error
+	done   chan struct{} // закрывается по окончании разбора
+}
+
+// ReadChunks читает файл (путь StdinPath — стандартный ввод) частями по границам записей и разбирает
+// части параллельно, в GOMAXPROCS рабочих горутинах. Заголовок передаётся в headerFunc (если она задана)
+// до начала разбора частей. parse вызывается в рабочей горутине с записями части; merge — в горутине
+// вызывающего, с результатами частей в порядке их следования в файле и смещением конца части.
+// Если в части встретилась ошибка CSV или parse вернул ошибку, merge получает результат, разобранный
+// до ошибки, после чего чтение прекращается с этой ошибкой (номера строк в ошибках CSV — номера строк файла).
+func ReadChunks[T any](r *CSVReader, filePath string, headerFunc func([]string) error, parse func(records [][]string) (T, error), merge func(result T, offset int64) error) error {
+	file := os.Stdin
+	if filePath != StdinPath {
+		var err error
+		if file, err = os.Open(filePath); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	state := ReadProgress{Path: filePath}
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		state.TotalBytes = info.Size()
+	}
+
+	// Заголовок разбирается до запуска рабочих горутин: по нему parse определяет столбцы
+	data, eof, err := readUntilRecord(file, nil)
+	if err != nil {
+		return err
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	fields := 0
+	if err == nil {
+		fields = len(header)
+		if headerFunc != nil {
+			if err := headerFunc(header); err != nil {
+				return err
+			}
+		}
+	}
+	headerEnd := int(reader.InputOffset())
+	split := &chunkSplitter{src: file, carry: data[headerEnd:], eof: eof, offset: int64(headerEnd), line: bytes.Count(data[:headerEnd], []byte("\n"))}
+
+	workers := runtime.GOMAXPROCS(0)
+	work := make(chan *csvChunk[T], workers)
+	ordered := make(chan *csvChunk[T], 2*workers)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(ordered)
+		defer close(work)
+		for {
+			chunk := &csvChunk[T]{done: make(chan struct{})}
+			chunk.data, chunk.line, chunk.end, chunk.err = split.next()
+			if chunk.err != nil || len(chunk.data) == 0 {
+				close(chunk.done)
+				if chunk.err != nil {
+					select {
+					case ordered <- chunk:
+					case <-stop:
+					}
+				}
+				return
+			}
+			select {
+			case ordered <- chunk:
+			case <-stop:
+				return
+			}
+			work <- chunk
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range work {
+				chunk.parse(fields, parse)
+				close(chunk.done)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(stop)
+
+	for chunk := range ordered {
+		<-chunk.done
+		if chunk.rows > 0 || chunk.err == nil {
+			if err := merge(chunk.result, chunk.end); err != nil {
+				return err
+			}
+		}
+		if chunk.err != nil {
+			return chunk.err
+		}
+		state.Rows += chunk.rows
+		state.BytesRead = chunk.end
+		if r.progress != nil {
+			r.progress(state)
+		}
+	}
+	if r.progress != nil {
+		state.Done = true
+		r.progress(state)
+	}
+	return nil
+}
+
+// parse разбирает записи части (fields — число полей записи по заголовку, 0 — не проверяется) и передаёт их в parse.
+func (c *csvChunk[T]) parse(fields int, parse func(records [][]string) (T, error)) {
+	reader := csv.NewReader(bytes.NewReader(c.data))
+	reader.FieldsPerRecord = fields
+	var records [][]string
+	var readErr error
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				// Строки считаются от начала части, а не файла
+				parseErr.StartLine += c.line
+				parseErr.Line += c.line
+			}
+			readErr = err
+			break
+		}
+		records = append(records, record)
+	}
+	c.rows = len(records)
+	c.result, c.err = parse(records)
+	if c.err == nil {
+		c.err = readErr
+	}
+	c.data = nil
+}
+
+// chunkSplitter делит поток на части из целых записей.
+type chunkSplitter struct {
+	src    io.Reader
+	carry  []byte // прочитанное начало следующей части
+	eof    bool
+	offset int64 // смещение начала следующей части
+	line   int   // строк до начала следующей части
+}
+
+// next возвращает следующую часть, число строк до неё и смещение её конца; пустая часть — поток прочитан.
+func (s *chunkSplitter) next() (data []byte, line int, end int64, err error) {
+	if s.eof {
+		data, s.carry = s.carry, nil
+	} else {
+		if data, s.eof, err = readUntilRecord(s.src, s.carry); err != nil {
+			return nil, 0, 0, err
+		}
+		cut := len(data)
+		if !s.eof {
+			cut = recordBoundary(data, false)
+		}
+		// Начало следующей части копируется, чтобы часть не удерживала буфер после разбора
+		data, s.carry = data[:cut], bytes.Clone(data[cut:])
+	}
+	line = s.line
+	s.line += bytes.Count(data, []byte("\n"))
+	s.offset += int64(len(data))
+	return data, line, s.offset, nil
+}
+
+// readUntilRecord дочитывает к prefix из src около chunkSize байт и, если нужно, дальше — пока в данных
+// не найдётся конец записи или не кончится поток (eof).
+func readUntilRecord(src io.Reader, prefix []byte) (data []byte, eof bool, err error) {
+	data = make([]byte, len(prefix), len(prefix)+chunkSize)
+	copy(data, prefix)
+	for {
+		n, err := io.ReadFull(src, data[len(data):cap(data)])
+		data = data[:len(data)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return data, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if recordBoundary(data, true) > 0 {
+			return data, false, nil
+		}
+		data = slices.Grow(data, chunkSize)
+	}
+}
+
+// recordBoundary возвращает позицию после первого (first) или последнего перевода строки в data,
+// завершающего запись, то есть стоящего вне кавычек; -1 — такого нет. data начинается с начала записи.
+func recordBoundary(data []byte, first bool) int {
+	boundary := -1
+	for i := 0; i < len(data); {
+		rest := data[i:]
+		quote := bytes.IndexByte(rest, '"')
+		unquoted := rest
+		if quote >= 0 {
+			unquoted = rest[:quote]
+		}
+		if first {
+			if n := bytes.IndexByte(unquoted, '\n'); n >= 0 {
+				return i + n + 1
+			}
+		} else if n := bytes.LastIndexByte(unquoted, '\n'); n >= 0 {
+			boundary = i + n + 1
+		}
+		if quote < 0 {
+			break
+		}
+		// Закрывающая кавычка; удвоенная кавычка внутри поля закрывает и тут же открывает поле снова
+		closing := bytes.IndexByte(rest[quote+1:], '"')
+		if closing < 0 {
+			break
+		}
+		i += quote + 1 + closing + 1
+	}
+	return boundary
+}
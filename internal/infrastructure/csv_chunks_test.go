@@ -0,0 +1,177 @@
+package infrastructure
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeChunksLog записывает CSV-лог из rows записей на несколько частей chunkSize. В каждой записи
+// есть поле в кавычках с переводами строк, запятыми и удвоенными кавычками; длина записей разная,
+// так что границы частей приходятся и на переводы строк внутри полей. invalid > 0 — номер записи с лишним полем.
+func writeChunksLog(t *testing.T, rows, invalid int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	fmt.Fprintln(writer, "case_id,timestamp,activity,comment")
+	for i := 1; i <= rows; i++ {
+		fmt.Fprintf(writer, "case-%d,2024-01-01T00:00:%02dZ,Проверка %d,\"строка %d\nс \"\"кавычками\"\", запятой%s\nи третьей строкой\"", i/7, i%60, i%10, i, strings.Repeat(".", i*i%89))
+		if i == invalid {
+			writer.WriteString(",лишнее")
+		}
+		writer.WriteString("\n")
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readSequential читает лог построчно через ReadAndProcessWithOffsets.
+func readSequential(t *testing.T, path string) (header []string, records [][]string, offsets map[int64]bool, err error) {
+	t.Helper()
+	offsets = make(map[int64]bool)
+	err = NewCSVReader().ReadAndProcessWithOffsets(path, func(h []string) error {
+		header = h
+		return nil
+	}, func(record []string, offset int64) error {
+		records = append(records, record)
+		offsets[offset] = true
+		return nil
+	})
+	return header, records, offsets, err
+}
+
+func TestReadChunksMatchesSequential(t *testing.T) {
+	// Несколько рабочих горутин и на одном процессоре: части могут разбираться не по порядку
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	path := writeChunksLog(t, 40_000, 0)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() < 3*chunkSize {
+		t.Fatalf("лог %d байт меньше трёх частей", info.Size())
+	}
+	wantHeader, want, recordEnds, err := readSequential(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var header []string
+	var got [][]string
+	var ends []int64
+	var progress []ReadProgress
+	reader := NewCSVReader()
+	reader.SetProgress(func(p ReadProgress) { progress = append(progress, p) })
+	err = ReadChunks(reader, path, func(h []string) error {
+		header = h
+		return nil
+	}, func(records [][]string) ([][]string, error) {
+		return records, nil
+	}, func(records [][]string, offset int64) error {
+		got = append(got, records...)
+		ends = append(ends, offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(header, wantHeader) {
+		t.Errorf("заголовок %q, ожидается %q", header, wantHeader)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("прочитано %d записей, ожидается %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("запись %d: %q, ожидается %q", i, got[i], want[i])
+		}
+	}
+
+	if len(ends) < 3 {
+		t.Fatalf("лог разобран в %d частях, ожидается не меньше трёх", len(ends))
+	}
+	for i, end := range ends {
+		if !recordEnds[end] {
+			t.Errorf("часть %d кончается на смещении %d, не совпадающем с концом записи", i, end)
+		}
+		if i > 0 && end <= ends[i-1] {
+			t.Errorf("смещение части %d (%d) не больше предыдущего (%d)", i, end, ends[i-1])
+		}
+	}
+	if last := ends[len(ends)-1]; last != info.Size() {
+		t.Errorf("последняя часть кончается на %d, размер файла %d", last, info.Size())
+	}
+
+	if len(progress) == 0 || !progress[len(progress)-1].Done {
+		t.Fatal("нет сообщения об окончании чтения")
+	}
+	if final := progress[len(progress)-1]; final.Rows != len(want) || final.BytesRead != info.Size() || final.TotalBytes != info.Size() {
+		t.Errorf("итог чтения %+v, ожидается %d записей и %d байт", final, len(want), info.Size())
+	}
+}
+
+func TestReadChunksParseErrorLine(t *testing.T) {
+	path := writeChunksLog(t, 30_000, 25_000)
+	_, _, _, wantErr := readSequential(t, path)
+	var want *csv.ParseError
+	if !errors.As(wantErr, &want) {
+		t.Fatalf("последовательное чтение вернуло %v, ожидается ошибка CSV", wantErr)
+	}
+
+	merged := 0
+	err := ReadChunks(NewCSVReader(), path, nil, func(records [][]string) (int, error) {
+		return len(records), nil
+	}, func(rows int, _ int64) error {
+		merged += rows
+		return nil
+	})
+	var got *csv.ParseError
+	if !errors.As(err, &got) {
+		t.Fatalf("ReadChunks вернул %v, ожидается ошибка CSV", err)
+	}
+	if got.StartLine != want.StartLine || got.Line != want.Line {
+		t.Errorf("ошибка в строках %d–%d, ожидается %d–%d", got.StartLine, got.Line, want.StartLine, want.Line)
+	}
+	if merged != 25_000-1 {
+		t.Errorf("до ошибки передано %d записей, ожидается %d", merged, 25_000-1)
+	}
+}
+
+func TestRecordBoundary(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		first, last int
+	}{
+		{"без перевода строки", "a,b", -1, -1},
+		{"две записи", "a,b\nc,d\n", 4, 8},
+		{"перевод строки в кавычках", "a,\"b\nc\"\nd\n", 8, 10},
+		{"удвоенная кавычка", "a,\"b\"\"\nc\"\n", 10, 10},
+		{"незакрытая кавычка", "a\n\"b\nc\n", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordBoundary([]byte(tt.data), true); got != tt.first {
+				t.Errorf("первая граница %d, ожидается %d", got, tt.first)
+			}
+			if got := recordBoundary([]byte(tt.data), false); got != tt.last {
+				t.Errorf("последняя граница %d, ожидается %d", got, tt.last)
+			}
+		})
+	}
+}
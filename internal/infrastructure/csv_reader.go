@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,7 +10,24 @@ import (
 	"path/filepath"
 )
 
-type CSVReader struct{}
+// ReadProgress — ход чтения CSV-файла.
+type ReadProgress struct {
+	Path       string
+	Rows       int   // прочитано записей без заголовка
+	BytesRead  int64 // прочитано байт
+	TotalBytes int64 // размер файла (0 — неизвестен)
+	Done       bool  // файл прочитан до конца
+}
+
+// StdinPath — путь к логу, означающий чтение из стандартного ввода.
+const StdinPath = "-"
+
+// progressRows — через сколько записей читатель сообщает о ходе чтения.
+const progressRows = 1000
+
+type CSVReader struct {
+	progress func(ReadProgress) // nil — о ходе чтения не сообщается
+}
 type TMPCleaner struct{}
 
 func NewCSVReader() *CSVReader {
@@ -20,18 +38,52 @@ func NewTMPCleaner() *TMPCleaner {
 	return &TMPCleaner{}
 }
 
+// SetProgress задаёт функцию, которой читатель сообщает о ходе чтения файлов: каждые progressRows
+// записей и по окончании файла. Функция вызывается в той же горутине, что и обработка записей.
+func (r *CSVReader) SetProgress(progress func(ReadProgress)) {
+	r.progress = progress
+}
+
 func (r *CSVReader) ReadAndProcess(filePath string, processFunc func([]string) error) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
+	return r.ReadAndProcessWithHeader(filePath, nil, processFunc)
+}
+
+// ReadAndProcessWithHeader передаёт заголовок файла в headerFunc (если она задана),
+// а затем каждую запись — в processFunc.
+func (r *CSVReader) ReadAndProcessWithHeader(filePath string, headerFunc func([]string) error, processFunc func([]string) error) error {
+	return r.ReadAndProcessWithOffsets(filePath, headerFunc, func(record []string, _ int64) error {
+		return processFunc(record)
+	})
+}
+
+// ReadAndProcessWithOffsets работает как ReadAndProcessWithHeader, но вместе с каждой записью
+// передаёт смещение в байтах, до которого прочитан файл (для отображения хода чтения).
+// Путь StdinPath означает стандартный ввод; его можно прочитать только один раз.
+func (r *CSVReader) ReadAndProcessWithOffsets(filePath string, headerFunc func([]string) error, processFunc func(record []string, offset int64) error) error {
+	file := os.Stdin
+	if filePath != StdinPath {
+		var err error
+		if file, err = os.Open(filePath); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	state := ReadProgress{Path: filePath}
+	// Размер известен только для обычного файла: у канала его нет
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		state.TotalBytes = info.Size()
 	}
-	defer file.Close()
 
 	reader := csv.NewReader(file)
-	_, err = reader.Read() // without file header
+	header, err := reader.Read()
 	if err != nil && err != io.EOF {
 		return err
 	}
+	if headerFunc != nil && err == nil {
+		if err := headerFunc(header); err != nil {
+			return err
+		}
+	}
 
 	for {
 		record, err := reader.Read()
@@ -42,33 +94,74 @@ func (r *CSVReader) ReadAndProcess(filePath string, processFunc func([]string) e
 			return err
 		}
 
-		if err := processFunc(record); err != nil {
+		if err := processFunc(record, reader.InputOffset()); err != nil {
 			return err
 		}
+		if state.Rows++; r.progress != nil && state.Rows%progressRows == 0 {
+			state.BytesRead = reader.InputOffset()
+			r.progress(state)
+		}
 	}
 
+	if r.progress != nil {
+		state.BytesRead, state.Done = reader.InputOffset(), true
+		r.progress(state)
+	}
 	return nil
 }
 
-func (c *TMPCleaner) ClearTempFiles() error {
-	// Указываем путь к директории /tmp
-	dir := "/tmp"
+// ReadSample читает из src заголовок и не больше limit записей. В отличие от ReadAndProcessWithHeader
+// чтение не прерывается на записи с неверным числом столбцов: она передаётся в processFunc вместе
+// с ошибкой; line — номер строки, с которой начинается запись. complete сообщает, что src прочитан до конца.
+func (r *CSVReader) ReadSample(src io.Reader, limit int, headerFunc func([]string) error, processFunc func(record []string, line int, err error) error) (complete bool, err error) {
+	reader := csv.NewReader(src)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := headerFunc(header); err != nil {
+		return false, err
+	}
+
+	for rows := 0; ; rows++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return true, nil
+		}
+		if rows == limit {
+			return false, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && !errors.Is(err, csv.ErrFieldCount) {
+			// После ошибки кавычек границы записей не определены, продолжать чтение нельзя
+			return false, processFunc(nil, parseErr.StartLine, err)
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return false, err
+		}
+		line, _ := reader.FieldPos(0)
+		if procErr := processFunc(record, line, err); procErr != nil {
+			return false, procErr
+		}
+	}
+}
 
-	// Читаем содержимое директории
-	files, err := os.ReadDir(dir)
+// ClearUploadedFiles удаляет из временной директории только файлы загруженных логов (uploaded-*.csv).
+func (c *TMPCleaner) ClearUploadedFiles() error {
+	files, err := filepath.Glob(filepath.Join(os.TempDir(), "uploaded-*.csv"))
 	if err != nil {
-		return fmt.Errorf("ошибка чтения директории %s: %v", dir, err)
+		return fmt.Errorf("ошибка поиска временных файлов: %v", err)
 	}
 
-	// Удаляем все файлы в директории
-	for _, file := range files {
-		filePath := filepath.Join(dir, file.Name())
+	for _, filePath := range files {
 		if err := os.Remove(filePath); err != nil {
 			log.Printf("Ошибка удаления файла %s: %v", filePath, err)
 		} else {
 			log.Printf("Файл удален: %s", filePath)
 		}
 	}
-
 	return nil
 }
@@ -0,0 +1,188 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	datasetsBucket = []byte("datasets") // идентификатор → описание набора данных
+	eventsBucket   = []byte("events")   // идентификатор → события набора данных
+	reportsBucket  = []byte("reports")  // идентификатор + ключ настроек → рассчитанный отчёт
+	sharesBucket   = []byte("shares")   // токен → снимок графа и отчёта для публичной ссылки
+	auditBucket    = []byte("audit")    // порядковый номер → запись журнала аудита
+)
+
+// DatasetStore хранит наборы данных и рассчитанные по ним отчёты во встроенной базе bbolt.
+// Хранилище работает с уже сериализованными значениями, формат которых определяет сервисный слой.
+type DatasetStore struct {
+	db *bolt.DB
+}
+
+// OpenDatasetStore открывает (или создаёт) файл базы по пути path.
+func OpenDatasetStore(path string) (*DatasetStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("ошибка создания каталога базы %s: %v", dir, err)
+		}
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{datasetsBucket, eventsBucket, reportsBucket, sharesBucket, auditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка инициализации базы %s: %v", path, err)
+	}
+	return &DatasetStore{db: db}, nil
+}
+
+// Close закрывает базу.
+func (s *DatasetStore) Close() error {
+	return s.db.Close()
+}
+
+// PutDataset сохраняет описание и события набора данных. Ранее рассчитанные отчёты набора удаляются.
+func (s *DatasetStore) PutDataset(id string, meta, events []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(datasetsBucket).Put([]byte(id), meta); err != nil {
+			return err
+		}
+		if err := tx.Bucket(eventsBucket).Put([]byte(id), events); err != nil {
+			return err
+		}
+		return deleteReports(tx, id)
+	})
+}
+
+// PutMeta обновляет описание набора данных, не затрагивая события и отчёты.
+func (s *DatasetStore) PutMeta(id string, meta []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(datasetsBucket).Put([]byte(id), meta)
+	})
+}
+
+// Datasets возвращает описания всех сохранённых наборов данных: идентификатор → описание.
+func (s *DatasetStore) Datasets() (map[string][]byte, error) {
+	datasets := make(map[string][]byte)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(datasetsBucket).ForEach(func(k, v []byte) error {
+			datasets[string(k)] = bytes.Clone(v)
+			return nil
+		})
+	})
+	return datasets, err
+}
+
+// Events возвращает события набора данных (nil, если набор не сохранён).
+func (s *DatasetStore) Events(id string) ([]byte, error) {
+	var events []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		events = bytes.Clone(tx.Bucket(eventsBucket).Get([]byte(id)))
+		return nil
+	})
+	return events, err
+}
+
+// DeleteDataset удаляет набор данных вместе с событиями и отчётами.
+func (s *DatasetStore) DeleteDataset(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(datasetsBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(eventsBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return deleteReports(tx, id)
+	})
+}
+
+// PutReport сохраняет отчёт набора данных, рассчитанный при настройках с ключом key.
+func (s *DatasetStore) PutReport(id, key string, report []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(reportsBucket).Put(reportKey(id, key), report)
+	})
+}
+
+// Report возвращает сохранённый отчёт набора данных (nil, если отчёт не рассчитывался).
+func (s *DatasetStore) Report(id, key string) ([]byte, error) {
+	var report []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		report = bytes.Clone(tx.Bucket(reportsBucket).Get(reportKey(id, key)))
+		return nil
+	})
+	return report, err
+}
+
+// PutShare сохраняет снимок публичной ссылки token.
+func (s *DatasetStore) PutShare(token string, snapshot []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sharesBucket).Put([]byte(token), snapshot)
+	})
+}
+
+// Share возвращает снимок публичной ссылки (nil, если ссылки нет).
+func (s *DatasetStore) Share(token string) ([]byte, error) {
+	var snapshot []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		snapshot = bytes.Clone(tx.Bucket(sharesBucket).Get([]byte(token)))
+		return nil
+	})
+	return snapshot, err
+}
+
+// AppendAudit добавляет запись в конец журнала аудита.
+func (s *DatasetStore) AppendAudit(entry []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(auditBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(binary.BigEndian.AppendUint64(nil, seq), entry)
+	})
+}
+
+// AuditEntries передаёт записи журнала аудита в visit, начиная с последней, пока visit возвращает true.
+// Запись действительна только внутри visit.
+func (s *DatasetStore) AuditEntries(visit func(entry []byte) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(auditBucket).Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			if !visit(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func reportKey(id, key string) []byte {
+	return []byte(id + "\x00" + key)
+}
+
+// deleteReports удаляет все отчёты набора данных id.
+func deleteReports(tx *bolt.Tx, id string) error {
+	prefix := []byte(id + "\x00")
+	cursor := tx.Bucket(reportsBucket).Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Seek(prefix) {
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// telegramAPI — адрес Bot API Telegram.
+const telegramAPI = "https://api.telegram.org"
+
+// TelegramSender отправляет сообщения в чат Telegram от имени бота.
+type TelegramSender struct {
+	client *http.Client
+	url    string
+	chatID string
+}
+
+func NewTelegramSender(token, chatID string) *TelegramSender {
+	return &TelegramSender{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    telegramAPI + "/bot" + token + "/sendMessage",
+		chatID: chatID,
+	}
+}
+
+// Send отправляет текстовое сообщение в чат.
+func (s *TelegramSender) Send(text string) error {
+	return postMessage(s.client, s.url, "Telegram", map[string]any{
+		"chat_id":                  s.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+}
+
+// SlackSender отправляет сообщения в канал Slack через входящий вебхук.
+type SlackSender struct {
+	client *http.Client
+	url    string
+}
+
+func NewSlackSender(webhookURL string) *SlackSender {
+	return &SlackSender{client: &http.Client{Timeout: 10 * time.Second}, url: webhookURL}
+}
+
+// Send отправляет текстовое сообщение в канал вебхука.
+func (s *SlackSender) Send(text string) error {
+	return postMessage(s.client, s.url, "Slack", map[string]any{"text": text})
+}
+
+// postMessage отправляет сообщение методом POST. Адрес в ошибку не попадает: он содержит токен бота
+// или секрет вебхука.
+func postMessage(client *http.Client, url, service string, message map[string]any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	response, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("ошибка отправки сообщения в %s", service)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s ответил %s", service, response.Status)
+	}
+	return nil
+}
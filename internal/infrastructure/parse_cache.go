@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parseCacheExt — расширение файлов кэша разбора.
+const parseCacheExt = ".pmc"
+
+// ParseCache хранит разобранные логи в каталоге на диске: каждый лог — отдельный файл, сжатый flate,
+// с именем по ключу (хешу содержимого лога и настроек разбора). Формат данных определяет вызывающий.
+// Если файлы кэша превышают бюджет, удаляются давно не использованные. Безопасен для одновременного использования.
+type ParseCache struct {
+	dir   string
+	limit int64 // бюджет размера файлов кэша, байт (0 — без ограничения)
+	mu    sync.Mutex
+}
+
+// OpenParseCache открывает (или создаёт) каталог кэша разбора dir с бюджетом limit байт (0 — без ограничения).
+func OpenParseCache(dir string, limit int64) (*ParseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога кэша разбора %s: %v", dir, err)
+	}
+	return &ParseCache{dir: dir, limit: limit}, nil
+}
+
+// Key возвращает ключ кэша для файла filePath: хеш SHA-256 его содержимого и настроек разбора settings.
+func (c *ParseCache) Key(filePath, settings string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("ошибка чтения %s: %v", filePath, err)
+	}
+	hash.Write([]byte{0})
+	hash.Write([]byte(settings))
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Load передаёт в decode распакованное содержимое записи key. Возвращает false, если записи нет.
+// Запись, которую не удалось прочитать, удаляется из кэша.
+func (c *ParseCache) Load(key string, decode func(r io.Reader) error) (bool, error) {
+	path := c.path(key)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка чтения кэша разбора: %v", err)
+	}
+	defer file.Close()
+	reader := flate.NewReader(file)
+	defer reader.Close()
+	if err := decode(reader); err != nil {
+		os.Remove(path)
+		return false, fmt.Errorf("ошибка чтения кэша разбора: %v", err)
+	}
+	// Время изменения отмечает последнее использование записи (см. evict)
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return true, nil
+}
+
+// Store сохраняет в запись key данные, которые encode пишет в w. Запись появляется в кэше целиком:
+// данные пишутся во временный файл, который затем переименовывается.
+func (c *ParseCache) Store(key string, encode func(w io.Writer) error) error {
+	// Каталог мог быть удалён после открытия кэша (например, при очистке временных файлов)
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка записи кэша разбора: %v", err)
+	}
+	file, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("ошибка записи кэша разбора: %v", err)
+	}
+	defer os.Remove(file.Name())
+	writer, _ := flate.NewWriter(file, flate.BestSpeed)
+	err = encode(writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка записи кэша разбора: %v", err)
+	}
+	return c.evict()
+}
+
+// path возвращает путь к файлу записи key.
+func (c *ParseCache) path(key string) string {
+	return filepath.Join(c.dir, key+parseCacheExt)
+}
+
+// evict удаляет давно не использованные записи, пока размер кэша превышает бюджет.
+func (c *ParseCache) evict() error {
+	if c.limit <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения каталога кэша разбора: %v", err)
+	}
+	var files []fs.FileInfo
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), parseCacheExt) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			files = append(files, info)
+			total += info.Size()
+		}
+	}
+	slices.SortFunc(files, func(a, b fs.FileInfo) int { return a.ModTime().Compare(b.ModTime()) })
+	// Последняя запись остаётся, даже если одна превышает бюджет
+	for i := 0; i < len(files)-1 && total > c.limit; i++ {
+		if err := os.Remove(filepath.Join(c.dir, files[i].Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("ошибка удаления записи кэша разбора: %v", err)
+		}
+		total -= files[i].Size()
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPSender отправляет письма через SMTP-сервер. Если сервер поддерживает STARTTLS,
+// соединение шифруется; пароль передаётся только по зашифрованному соединению.
+type SMTPSender struct {
+	addr     string
+	host     string
+	user     string
+	password string
+	from     string
+}
+
+func NewSMTPSender(host string, port int, user, password, from string) *SMTPSender {
+	return &SMTPSender{
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		user:     user,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send отправляет текстовое письмо subject получателям to.
+func (s *SMTPSender) Send(to []string, subject, body string) error {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+	if err := smtp.SendMail(s.addr, auth, s.from, to, message.Bytes()); err != nil {
+		return fmt.Errorf("ошибка отправки письма через %s: %v", s.addr, err)
+	}
+	return nil
+}
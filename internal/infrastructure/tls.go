@@ -0,0 +1,34 @@
+package infrastructure
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewTLSConfig возвращает настройки TLS из файлов сертификата и ключа или, если они не заданы,
+// с автоматическим получением сертификатов Let's Encrypt для доменов autocertDomains (сертификаты
+// хранятся в cacheDir). Если не задано ни то, ни другое, возвращает nil — сервер работает по HTTP.
+func NewTLSConfig(certFile, keyFile string, autocertDomains []string, cacheDir string) (*tls.Config, error) {
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки сертификата: %v", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+	}
+	if len(autocertDomains) == 0 {
+		return nil, nil
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(autocertDomains...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+	// Подтверждение домена проходит по протоколу TLS-ALPN-01 на том же порту, отдельный порт 80 не нужен
+	config := manager.TLSConfig()
+	config.MinVersion = tls.VersionTLS12
+	return config, nil
+}
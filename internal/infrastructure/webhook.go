@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookAttempts — число попыток доставки уведомления.
+const webhookAttempts = 3
+
+// WebhookSender отправляет JSON-уведомления на адреса вебхуков.
+type WebhookSender struct {
+	client *http.Client
+}
+
+func NewWebhookSender() *WebhookSender {
+	return &WebhookSender{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send отправляет payload методом POST. Если задан secret, тело подписывается HMAC-SHA256
+// в заголовке X-Signature-256 ("sha256=<hex>"), чтобы получатель мог проверить отправителя.
+// При сетевой ошибке или ответе 5xx отправка повторяется.
+func (s *WebhookSender) Send(url, secret string, payload []byte) error {
+	var err error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		var retry bool
+		if retry, err = s.send(url, secret, payload); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// send выполняет одну попытку отправки; retry сообщает, имеет ли смысл повторить её.
+func (s *WebhookSender) send(url, secret string, payload []byte) (retry bool, err error) {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		request.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return response.StatusCode >= 500, fmt.Errorf("вебхук %s ответил %s", url, response.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,78 @@
+package presentation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"process-mining/internal/service"
+)
+
+const (
+	// defaultAuditLimit и maxAuditLimit — число записей журнала аудита в ответе по умолчанию и наибольшее.
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// audit записывает в журнал аудита действие пользователя запроса r с набором данных datasetID.
+func (h *GraphHandler) audit(r *http.Request, action, datasetID, details string) {
+	entry := service.AuditEntry{
+		Action:    action,
+		DatasetID: datasetID,
+		Details:   details,
+		RequestID: requestID(r),
+		ClientIP:  clientIP(r),
+	}
+	if identity, ok := r.Context().Value(identityKey{}).(*Identity); ok {
+		entry.User, entry.Workspace = identity.User, identity.Workspace
+	}
+	h.graphService.Audit(entry)
+}
+
+// AuditLog возвращает записи журнала аудита, начиная с последней (GET). Параметры user, action, dataset,
+// from и to (RFC 3339 или ГГГГ-ММ-ДД, to не включается) отбирают записи, limit — их число.
+func (h *GraphHandler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	query := service.AuditQuery{
+		User:      params.Get("user"),
+		Action:    params.Get("action"),
+		DatasetID: params.Get("dataset"),
+		Limit:     defaultAuditLimit,
+	}
+	var err error
+	if param := params.Get("from"); param != "" {
+		if query.From, err = parseTimeParam(param); err != nil {
+			http.Error(w, "Некорректный параметр from: ожидается RFC 3339 или ГГГГ-ММ-ДД", http.StatusBadRequest)
+			return
+		}
+	}
+	if param := params.Get("to"); param != "" {
+		if query.To, err = parseTimeParam(param); err != nil {
+			http.Error(w, "Некорректный параметр to: ожидается RFC 3339 или ГГГГ-ММ-ДД", http.StatusBadRequest)
+			return
+		}
+	}
+	if param := params.Get("limit"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > maxAuditLimit {
+			http.Error(w, fmt.Sprintf("Некорректный параметр limit: ожидается число от 1 до %d", maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		query.Limit = value
+	}
+
+	entries, err := h.graphService.AuditLog(query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка чтения журнала аудита: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+	}
+}
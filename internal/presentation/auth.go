@@ -0,0 +1,272 @@
+package presentation
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"process-mining/internal/service"
+)
+
+// errInvalidToken возвращается, если JWT не прошёл проверку.
+var errInvalidToken = errors.New("недействительный токен")
+
+// Role — роль пользователя. Роли упорядочены: каждая следующая включает права предыдущей.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"  // Просмотр графа, метрик и отчётов
+	RoleAnalyst Role = "analyst" // Загрузка логов и изменение настроек анализа
+	RoleAdmin   Role = "admin"   // Удаление и очистка наборов данных, доступ ко всем рабочим областям
+)
+
+// level возвращает уровень прав роли; неизвестная роль прав не имеет.
+func (r Role) level() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleAnalyst:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Identity — аутентифицированный пользователь.
+type Identity struct {
+	User      string
+	Role      Role
+	Workspace string // Рабочая область пользователя или команды
+}
+
+// Scope возвращает область видимости наборов данных пользователя.
+func (id *Identity) Scope() service.Scope {
+	return service.Scope{Workspace: id.Workspace, All: id.Role == RoleAdmin}
+}
+
+// identityKey — ключ пользователя в контексте запроса.
+type identityKey struct{}
+
+// requestScope возвращает область видимости наборов данных пользователя запроса.
+func requestScope(r *http.Request) service.Scope {
+	return contextScope(r.Context())
+}
+
+// contextScope возвращает область видимости наборов данных пользователя из контекста.
+// Без аутентификации доступны все наборы.
+func contextScope(ctx context.Context) service.Scope {
+	if id, ok := ctx.Value(identityKey{}).(*Identity); ok {
+		return id.Scope()
+	}
+	return service.Scope{All: true}
+}
+
+// sharedSettings — маршруты настроек анализа, общих для всех рабочих областей (пороги, SLA, календарь,
+// затраты, подпроцессы, эталонная модель и др.): их изменение затрагивает все команды.
+var sharedSettings = map[string]bool{
+	"/metrics/definitions": true,
+	"/subprocesses":        true,
+	"/conformance/model":   true,
+	"/automation":          true,
+	"/errors":              true,
+	"/sla":                 true,
+	"/calendar":            true,
+	"/costs":               true,
+}
+
+// requiredRole возвращает роль, необходимую для запроса к маршруту pattern: удаление, очистка,
+// перечитывание и изменение общих настроек и журнал аудита доступны администраторам, изменения — аналитикам,
+// чтение и прогнозы — всем пользователям.
+func requiredRole(r *http.Request, pattern string) Role {
+	readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case r.Method == http.MethodDelete || pattern == "/clear" || pattern == "/config/reload" || pattern == "/audit":
+		return RoleAdmin
+	case sharedSettings[pattern] && !readOnly:
+		return RoleAdmin
+	case readOnly || strings.HasPrefix(pattern, "/predict/"):
+		return RoleViewer
+	}
+	return RoleAnalyst
+}
+
+// Authenticator проверяет доступ к API по ключу или JWT. Учётные данные передаются в заголовке
+// Authorization: Bearer <ключ или JWT>, в заголовке X-API-Key или в параметре api_key
+// (для EventSource и ссылок на скачивание, которые не могут задать заголовок).
+type Authenticator struct {
+	keys      []apiKey
+	jwtSecret []byte
+}
+
+// apiKey — API-ключ и пользователь, которому он выдан.
+type apiKey struct {
+	key      []byte
+	identity Identity
+}
+
+// NewAuthenticator создаёт проверку доступа по списку API-ключей и секрету JWT (HS256).
+// Ключ задаётся в виде "ключ" (администратор) или "ключ:пользователь:роль[:рабочая область]".
+// Пустые ключи и секрет отключают соответствующий способ входа.
+func NewAuthenticator(keys []string, jwtSecret string) (*Authenticator, error) {
+	a := &Authenticator{}
+	for _, entry := range keys {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		key := apiKey{key: []byte(parts[0]), identity: Identity{User: "admin", Role: RoleAdmin}}
+		switch len(parts) {
+		case 1:
+		case 3, 4:
+			key.identity = Identity{User: parts[1], Role: Role(parts[2])}
+			if len(parts) == 4 {
+				key.identity.Workspace = parts[3]
+			}
+			if key.identity.Role.level() == 0 {
+				return nil, fmt.Errorf("неизвестная роль %q у пользователя %s", parts[2], parts[1])
+			}
+		default:
+			return nil, fmt.Errorf("ожидается ключ или ключ:пользователь:роль[:рабочая область], получено %d полей", len(parts))
+		}
+		a.keys = append(a.keys, key)
+	}
+	if jwtSecret != "" {
+		a.jwtSecret = []byte(jwtSecret)
+	}
+	return a, nil
+}
+
+// Enabled сообщает, настроен ли хотя бы один способ входа.
+func (a *Authenticator) Enabled() bool {
+	return len(a.keys) > 0 || a.jwtSecret != nil
+}
+
+// Wrap требует аутентификации для всех маршрутов mux, кроме маршрутов с шаблонами public
+// (например, "/" для статических файлов интерфейса; шаблоны API указываются без префикса версии), и проверяет роль пользователя (см. requiredRole).
+// Пользователь передаётся обработчикам в контексте запроса. Если вход не настроен, mux возвращается без изменений.
+func (a *Authenticator) Wrap(mux *http.ServeMux, public ...string) http.Handler {
+	if !a.Enabled() {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		pattern = routePattern(pattern)
+		for _, p := range public {
+			if pattern == p {
+				mux.ServeHTTP(w, r)
+				return
+			}
+		}
+		identity, ok := a.authenticate(credentials(r))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="process-mining"`)
+			http.Error(w, "Требуется аутентификация", http.StatusUnauthorized)
+			return
+		}
+		if required := requiredRole(r, pattern); identity.Role.level() < required.level() {
+			http.Error(w, fmt.Sprintf("Недостаточно прав: требуется роль %s", required), http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+	})
+}
+
+// credentials извлекает ключ или токен из запроса.
+func credentials(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// authenticate проверяет, что token — известный API-ключ или действительный JWT, и возвращает пользователя.
+func (a *Authenticator) authenticate(token string) (*Identity, bool) {
+	if token == "" {
+		return nil, false
+	}
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(token), key.key) == 1 {
+			identity := key.identity
+			return &identity, true
+		}
+	}
+	if a.jwtSecret != nil {
+		identity, err := a.verifyJWT(token, time.Now())
+		return identity, err == nil
+	}
+	return nil, false
+}
+
+// verifyJWT проверяет подпись HS256 и сроки действия (exp, nbf) токена. Пользователь берётся
+// из утверждений sub, role (по умолчанию viewer) и workspace.
+func (a *Authenticator) verifyJWT(token string, now time.Time) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+
+	var claims struct {
+		Subject   string `json:"sub"`
+		Role      Role   `json:"role"`
+		Workspace string `json:"workspace"`
+		ExpiresAt *int64 `json:"exp"`
+		NotBefore *int64 `json:"nbf"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errInvalidToken
+	}
+	if claims.ExpiresAt != nil && now.Unix() >= *claims.ExpiresAt {
+		return nil, errInvalidToken
+	}
+	if claims.NotBefore != nil && now.Unix() < *claims.NotBefore {
+		return nil, errInvalidToken
+	}
+	if claims.Role == "" {
+		claims.Role = RoleViewer
+	}
+	if claims.Role.level() == 0 {
+		return nil, errInvalidToken
+	}
+	return &Identity{User: claims.Subject, Role: claims.Role, Workspace: claims.Workspace}, nil
+}
+
+// decodeSegment декодирует base64url-сегмент JWT в v.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
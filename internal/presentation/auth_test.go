@@ -0,0 +1,228 @@
+package presentation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "secret"
+
+// unsignedJWT кодирует заголовок и утверждения токена без подписи.
+func unsignedJWT(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+	segments := make([]string, 0, 2)
+	for _, v := range []map[string]any{header, claims} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, base64.RawURLEncoding.EncodeToString(data))
+	}
+	return strings.Join(segments, ".")
+}
+
+// hs256Signature возвращает подпись HS256 неподписанной части токена секретом secret.
+func hs256Signature(unsigned, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signJWT возвращает токен с заголовком HS256 и утверждениями claims, подписанный секретом secret.
+func signJWT(t *testing.T, claims map[string]any, secret string) string {
+	t.Helper()
+	unsigned := unsignedJWT(t, map[string]any{"alg": "HS256", "typ": "JWT"}, claims)
+	return unsigned + "." + hs256Signature(unsigned, secret)
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	alice := map[string]any{"sub": "alice", "role": "analyst", "workspace": "A"}
+	valid := signJWT(t, alice, testJWTSecret)
+	signature := valid[strings.LastIndex(valid, ".")+1:]
+	none := unsignedJWT(t, map[string]any{"alg": "none"}, map[string]any{"sub": "alice", "role": "admin"})
+	rs256 := unsignedJWT(t, map[string]any{"alg": "RS256"}, alice)
+	tampered := unsignedJWT(t, map[string]any{"alg": "HS256", "typ": "JWT"}, map[string]any{"sub": "alice", "role": "admin"})
+
+	tests := []struct {
+		name  string
+		token string
+		want  *Identity // nil — токен отклоняется
+	}{
+		{"действительный", valid, &Identity{User: "alice", Role: RoleAnalyst, Workspace: "A"}},
+		{"роль по умолчанию", signJWT(t, map[string]any{"sub": "bob"}, testJWTSecret), &Identity{User: "bob", Role: RoleViewer}},
+		{"в пределах срока", signJWT(t, map[string]any{"sub": "bob", "exp": now.Unix() + 1, "nbf": now.Unix()}, testJWTSecret), &Identity{User: "bob", Role: RoleViewer}},
+		{"срок истёк", signJWT(t, map[string]any{"sub": "bob", "exp": now.Unix() - 1}, testJWTSecret), nil},
+		{"срок истекает сейчас", signJWT(t, map[string]any{"sub": "bob", "exp": now.Unix()}, testJWTSecret), nil},
+		{"ещё не действует", signJWT(t, map[string]any{"sub": "bob", "nbf": now.Unix() + 60}, testJWTSecret), nil},
+		{"alg none без подписи", none + ".", nil},
+		{"alg none с подписью HS256", none + "." + hs256Signature(none, testJWTSecret), nil},
+		{"RS256", rs256 + "." + hs256Signature(rs256, testJWTSecret), nil},
+		{"чужой секрет", signJWT(t, alice, "other"), nil},
+		{"подпись другого токена", tampered + "." + signature, nil},
+		{"подпись не base64url", tampered + ".!!", nil},
+		{"неизвестная роль", signJWT(t, map[string]any{"sub": "bob", "role": "root"}, testJWTSecret), nil},
+		{"не JSON", "e30.bm90LWpzb24." + hs256Signature("e30.bm90LWpzb24", testJWTSecret), nil},
+		{"два сегмента", "e30.e30", nil},
+		{"пустой токен", "", nil},
+	}
+
+	a, err := NewAuthenticator(nil, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := a.verifyJWT(tt.token, now)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("токен принят: %+v", *identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("токен отклонён: %v", err)
+			}
+			if *identity != *tt.want {
+				t.Errorf("пользователь %+v, ожидается %+v", *identity, *tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    *Identity // nil — ключ пропускается
+		wantErr bool
+	}{
+		{"только ключ", "k1", &Identity{User: "admin", Role: RoleAdmin}, false},
+		{"пользователь и роль", "k1:alice:viewer", &Identity{User: "alice", Role: RoleViewer}, false},
+		{"рабочая область", " k1:alice:analyst:A ", &Identity{User: "alice", Role: RoleAnalyst, Workspace: "A"}, false},
+		{"пустой ключ", "  ", nil, false},
+		{"два поля", "k1:alice", nil, true},
+		{"пять полей", "k1:alice:analyst:A:B", nil, true},
+		{"неизвестная роль", "k1:alice:root", nil, true},
+		{"пустая роль", "k1:alice:", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAuthenticator([]string{tt.entry}, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ключ %q принят", tt.entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ключ %q отклонён: %v", tt.entry, err)
+			}
+			if tt.want == nil {
+				if a.Enabled() {
+					t.Fatalf("пустой ключ %q включил вход", tt.entry)
+				}
+				return
+			}
+			identity, ok := a.authenticate("k1")
+			if !ok {
+				t.Fatal("ключ k1 не принят")
+			}
+			if *identity != *tt.want {
+				t.Errorf("пользователь %+v, ожидается %+v", *identity, *tt.want)
+			}
+			if _, ok := a.authenticate("k2"); ok {
+				t.Error("принят неизвестный ключ k2")
+			}
+		})
+	}
+}
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method  string
+		pattern string
+		want    Role
+	}{
+		{http.MethodGet, "/graph", RoleViewer},
+		{http.MethodHead, "/graph", RoleViewer},
+		{http.MethodGet, "/metrics", RoleViewer},
+		{http.MethodPost, "/predict/remaining", RoleViewer},
+		{http.MethodPost, "/upload", RoleAnalyst},
+		{http.MethodPost, "/datasets/{id}/events", RoleAnalyst},
+		{http.MethodPost, "/baselines", RoleAnalyst},
+		{http.MethodDelete, "/datasets/{id}", RoleAdmin},
+		{http.MethodDelete, "/baselines/{name}", RoleAdmin},
+		{http.MethodPost, "/clear", RoleAdmin},
+		{http.MethodPost, "/config/reload", RoleAdmin},
+		{http.MethodGet, "/audit", RoleAdmin},
+		{http.MethodGet, "/sla", RoleViewer},
+		{http.MethodPut, "/sla", RoleAdmin},
+		{http.MethodPost, "/calendar", RoleAdmin},
+		{http.MethodPut, "/costs", RoleAdmin},
+		{http.MethodPost, "/subprocesses", RoleAdmin},
+		{http.MethodPost, "/conformance/model", RoleAdmin},
+		{http.MethodPut, "/metrics/definitions", RoleAdmin},
+		{http.MethodPut, "/automation", RoleAdmin},
+		{http.MethodPut, "/errors", RoleAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.pattern, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, APIPrefix+tt.pattern, nil)
+			if got := requiredRole(r, tt.pattern); got != tt.want {
+				t.Errorf("роль %s, ожидается %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorWrap(t *testing.T) {
+	a, err := NewAuthenticator([]string{"viewer-key:vera:viewer:A", "analyst-key:alice:analyst:A"}, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc(APIPrefix+"/upload", func(w http.ResponseWriter, r *http.Request) {
+		if scope := requestScope(r); scope.Workspace != "A" || scope.All {
+			t.Errorf("область видимости %+v, ожидается рабочая область A", scope)
+		}
+	})
+	handler := a.Wrap(mux, "/")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		header string
+		want   int
+	}{
+		{"публичный маршрут", http.MethodGet, "/index.html", "", http.StatusOK},
+		{"без ключа", http.MethodPost, APIPrefix + "/upload", "", http.StatusUnauthorized},
+		{"неизвестный ключ", http.MethodPost, APIPrefix + "/upload", "Bearer other", http.StatusUnauthorized},
+		{"недостаточно прав", http.MethodPost, APIPrefix + "/upload", "Bearer viewer-key", http.StatusForbidden},
+		{"ключ аналитика", http.MethodPost, APIPrefix + "/upload", "Bearer analyst-key", http.StatusOK},
+		{"ключ в параметре", http.MethodPost, APIPrefix + "/upload?api_key=analyst-key", "", http.StatusOK},
+		{"JWT", http.MethodPost, APIPrefix + "/upload", "Bearer " + signJWT(t, map[string]any{"sub": "bob", "role": "analyst", "workspace": "A"}, testJWTSecret), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("код ответа %d, ожидается %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package presentation
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsAllowedHeaders — заголовки запросов, которые может передавать интерфейс с другого адреса.
+var corsAllowedHeaders = []string{"Authorization", "Content-Type", "X-API-Key", RequestIDHeader}
+
+// corsExposedHeaders — заголовки ответов, доступные такому интерфейсу.
+var corsExposedHeaders = []string{"Location", "Retry-After", RequestIDHeader}
+
+// defaultCORSMethods — методы, разрешённые по умолчанию.
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORS добавляет заголовки Cross-Origin Resource Sharing, чтобы интерфейс, размещённый
+// на другом адресе, мог обращаться к API без обратного прокси.
+type CORS struct {
+	origins []string // разрешённые источники; "*" — любой
+	methods string
+}
+
+// NewCORS создаёт обработчик CORS для источников origins (например, https://app.example.com или "*")
+// и методов methods (по умолчанию GET, POST, PUT, PATCH, DELETE). Без источников CORS отключён.
+func NewCORS(origins, methods []string) *CORS {
+	var allowed []string
+	for _, origin := range origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowed = append(allowed, origin)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	normalized := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			normalized = append(normalized, method)
+		}
+	}
+	return &CORS{origins: allowed, methods: strings.Join(normalized, ", ")}
+}
+
+// allowed проверяет, разрешён ли источник запроса.
+func (c *CORS) allowed(origin string) bool {
+	return slices.Contains(c.origins, "*") || slices.Contains(c.origins, origin)
+}
+
+// Wrap добавляет заголовки CORS к ответам на запросы с разрешённых источников и сам отвечает
+// на предварительные запросы (OPTIONS), которые браузер отправляет без ключа доступа.
+// Обработчик nil пропускает запросы без изменений.
+func (c *CORS) Wrap(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,70 @@
+package presentation
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/service"
+)
+
+// parseLogFilter читает параметры фильтра лога: from и to — временное окно (RFC 3339 или ГГГГ-ММ-ДД,
+// to не включается), time_scope — case (кейсы, начавшиеся в окне, по умолчанию) или event (события в окне);
+// include_activities и exclude_activities — активности через запятую, activity_scope — event (убрать
+// события, по умолчанию) или case (отобрать кейсы, проходящие через эти активности); variants —
+// идентификаторы вариантов через запятую (см. /variants), exclude_happy_path=true убирает кейсы самого частого варианта.
+func parseLogFilter(r *http.Request) (domain.LogFilter, error) {
+	query := r.URL.Query()
+	filter := domain.LogFilter{TimeScope: query.Get("time_scope"), ActivityScope: query.Get("activity_scope")}
+	if param := query.Get("include_activities"); param != "" {
+		filter.IncludeActivities = strings.Split(param, ",")
+	}
+	if param := query.Get("exclude_activities"); param != "" {
+		filter.ExcludeActivities = strings.Split(param, ",")
+	}
+	if param := query.Get("variants"); param != "" {
+		filter.Variants = strings.Split(param, ",")
+	}
+	filter.ExcludeHappyPath = query.Get("exclude_happy_path") == "true"
+	if param := query.Get("from"); param != "" {
+		from, err := parseTimeParam(param)
+		if err != nil {
+			return filter, errors.New("Некорректный параметр from")
+		}
+		filter.From = from
+	}
+	if param := query.Get("to"); param != "" {
+		to, err := parseTimeParam(param)
+		if err != nil {
+			return filter, errors.New("Некорректный параметр to")
+		}
+		filter.To = to
+	}
+	return filter, filter.Validate()
+}
+
+// analysisService возвращает сервис набора данных (см. datasetService), ограниченный фильтром лога
+// из параметров запроса (см. parseLogFilter). При ошибке отвечает клиенту и возвращает false.
+func (h *GraphHandler) analysisService(w http.ResponseWriter, r *http.Request) (*service.GraphService, bool) {
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	svc, ok := h.datasetService(w, r)
+	if !ok {
+		return nil, false
+	}
+	filtered, err := svc.Filter(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if !filter.Empty() {
+		params := r.URL.Query()
+		params.Del("api_key")
+		h.audit(r, service.AuditFilter, filtered.DatasetID(), r.URL.Path+"?"+params.Encode())
+	}
+	return filtered, true
+}
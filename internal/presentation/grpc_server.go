@@ -0,0 +1,327 @@
+package presentation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "process-mining/api/processmining"
+	"process-mining/internal/domain"
+	"process-mining/internal/service"
+)
+
+// defaultGraphBatch — количество узлов и рёбер в одной части потока GetGraph по умолчанию.
+const defaultGraphBatch = 1000
+
+// GRPCServer реализует gRPC API поверх того же сервиса, что и HTTP-обработчики.
+type GRPCServer struct {
+	pb.UnimplementedProcessMiningServer
+	graphService *service.GraphService
+}
+
+func NewGRPCServer(graphService *service.GraphService) *GRPCServer {
+	return &GRPCServer{graphService: graphService}
+}
+
+// NewGRPC создаёт gRPC-сервер с проверкой доступа authenticator и зарегистрированным API.
+// Дополнительные параметры options (например, TLS) передаются серверу.
+func NewGRPC(graphService *service.GraphService, authenticator *Authenticator, options ...grpc.ServerOption) *grpc.Server {
+	options = append(options,
+		grpc.UnaryInterceptor(authenticator.unaryInterceptor),
+		grpc.StreamInterceptor(authenticator.streamInterceptor),
+	)
+	server := grpc.NewServer(options...)
+	pb.RegisterProcessMiningServer(server, NewGRPCServer(graphService))
+	return server
+}
+
+// grpcRequiredRole возвращает роль, необходимую для вызова метода: загрузка — аналитикам, остальное — всем.
+func grpcRequiredRole(method string) Role {
+	if method == pb.ProcessMining_Upload_FullMethodName {
+		return RoleAnalyst
+	}
+	return RoleViewer
+}
+
+// authorizeGRPC проверяет ключ или токен из метаданных authorization (Bearer) или x-api-key
+// и возвращает контекст с пользователем.
+func (a *Authenticator) authorizeGRPC(ctx context.Context, method string) (context.Context, error) {
+	if !a.Enabled() {
+		return ctx, nil
+	}
+	var token string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ = strings.CutPrefix(values[0], "Bearer ")
+	} else if values := md.Get("x-api-key"); len(values) > 0 {
+		token = values[0]
+	}
+
+	identity, ok := a.authenticate(strings.TrimSpace(token))
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "требуется аутентификация")
+	}
+	if required := grpcRequiredRole(method); identity.Role.level() < required.level() {
+		return nil, status.Errorf(codes.PermissionDenied, "недостаточно прав: требуется роль %s", required)
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+func (a *Authenticator) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authorizeGRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorizeGRPC(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizedStream подменяет контекст потока контекстом с пользователем.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// datasetService возвращает сервис набора данных id в области видимости пользователя вызова.
+func (s *GRPCServer) datasetService(ctx context.Context, id string) (*service.GraphService, error) {
+	svc, err := s.graphService.Dataset(id, contextScope(ctx))
+	if errors.Is(err, service.ErrUnknownDataset) {
+		return nil, status.Errorf(codes.NotFound, "набор данных %q не найден", id)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return svc, nil
+}
+
+// Upload сохраняет принятый поток во временный файл и запускает построение графа.
+func (s *GRPCServer) Upload(stream pb.ProcessMining_UploadServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "ошибка получения файла: %v", err)
+	}
+	filename := first.GetFilename()
+	if filename == "" {
+		return status.Error(codes.InvalidArgument, "первое сообщение должно содержать имя файла")
+	}
+
+	tempFile, err := os.CreateTemp("", "uploaded-*.csv")
+	if err != nil {
+		log.Printf("Ошибка создания временного файла: %v", err)
+		return status.Error(codes.Internal, "ошибка создания временного файла")
+	}
+	defer tempFile.Close()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.Remove(tempFile.Name())
+			return status.Errorf(codes.Aborted, "ошибка чтения файла: %v", err)
+		}
+		if _, err := tempFile.Write(req.GetChunk()); err != nil {
+			log.Printf("Ошибка записи во временный файл: %v", err)
+			os.Remove(tempFile.Name())
+			return status.Error(codes.Internal, "ошибка записи во временный файл")
+		}
+	}
+
+	job := s.graphService.StartBuildJob(tempFile.Name(), filename, contextScope(stream.Context()).Workspace, "", nil)
+	entry := service.AuditEntry{Action: service.AuditUpload, DatasetID: job.DatasetID, Details: filename}
+	if identity, ok := stream.Context().Value(identityKey{}).(*Identity); ok {
+		entry.User, entry.Workspace = identity.User, identity.Workspace
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			entry.ClientIP = host
+		}
+	}
+	s.graphService.Audit(entry)
+	return stream.SendAndClose(jobMessage(job))
+}
+
+// GetJob возвращает ход и результат задачи построения графа.
+func (s *GRPCServer) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.Job, error) {
+	job, err := s.graphService.GetJob(req.GetId(), contextScope(ctx))
+	if errors.Is(err, service.ErrUnknownJob) {
+		return nil, status.Errorf(codes.NotFound, "задача %q не найдена", req.GetId())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return jobMessage(job), nil
+}
+
+// WatchJob передаёт состояние задачи при каждом изменении, последним — итоговое.
+func (s *GRPCServer) WatchJob(req *pb.GetJobRequest, stream pb.ProcessMining_WatchJobServer) error {
+	scope := contextScope(stream.Context())
+	updates, cancel, err := s.graphService.SubscribeJob(req.GetId(), scope)
+	if errors.Is(err, service.ErrUnknownJob) {
+		return status.Errorf(codes.NotFound, "задача %q не найдена", req.GetId())
+	}
+	defer cancel()
+
+	for {
+		job, err := s.graphService.GetJob(req.GetId(), scope)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(jobMessage(job)); err != nil {
+			return err
+		}
+		if job.Finished() {
+			return nil
+		}
+
+		select {
+		case <-updates:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// GetGraph передаёт граф частями: сначала узлы, затем рёбра, не больше batch_size в части.
+func (s *GRPCServer) GetGraph(req *pb.GetGraphRequest, stream pb.ProcessMining_GetGraphServer) error {
+	svc, err := s.datasetService(stream.Context(), req.GetDataset())
+	if err != nil {
+		return err
+	}
+	graph, err := svc.GetGraphData()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	batch := int(req.GetBatchSize())
+	if batch <= 0 {
+		batch = defaultGraphBatch
+	}
+
+	for start := 0; start < len(graph.Nodes); start += batch {
+		chunk := &pb.GraphChunk{}
+		for _, node := range graph.Nodes[start:min(start+batch, len(graph.Nodes))] {
+			chunk.Nodes = append(chunk.Nodes, nodeMessage(node))
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(graph.Edges); start += batch {
+		chunk := &pb.GraphChunk{}
+		for _, edge := range graph.Edges[start:min(start+batch, len(graph.Edges))] {
+			chunk.Edges = append(chunk.Edges, edgeMessage(edge))
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMetrics возвращает отчёт по метрикам набора данных.
+func (s *GRPCServer) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.MetricsReport, error) {
+	svc, err := s.datasetService(ctx, req.GetDataset())
+	if err != nil {
+		return nil, err
+	}
+	report, err := svc.GetMetricsReport()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	message := &pb.MetricsReport{
+		TotalProcessInstances:  int64(report.TotalProcessInstances),
+		TotalEvents:            int64(report.TotalEvents),
+		AverageProcessDuration: report.AverageProcessDuration,
+		MedianProcessDuration:  report.MedianProcessDuration,
+	}
+	for _, metric := range report.Metrics {
+		message.Metrics = append(message.Metrics, &pb.Metric{
+			Key:                 metric.Key,
+			Name:                metric.Definition.Name,
+			Category:            metric.Definition.Category,
+			Threshold:           metric.Definition.Threshold,
+			Count:               int64(metric.Count),
+			TotalValue:          metric.TotalValue,
+			TotalWastedDuration: metric.TotalWastedDuration,
+			TotalWastedCost:     metric.TotalWastedCost,
+			Exceeded:            metric.Exceeded,
+			Severity:            metric.Severity,
+			Priority:            int64(metric.Priority),
+		})
+	}
+	return message, nil
+}
+
+// jobMessage преобразует задачу сервиса в сообщение gRPC.
+func jobMessage(job service.Job) *pb.Job {
+	message := &pb.Job{
+		Id:         job.ID,
+		DatasetId:  job.DatasetID,
+		Status:     job.Status,
+		Phase:      job.Phase,
+		RowsRead:   int64(job.RowsRead),
+		CasesBuilt: int64(job.CasesBuilt),
+		Percent:    job.Percent,
+		Warnings:   job.Warnings,
+		Error:      job.Error,
+		StartedAt:  timestamppb.New(job.StartedAt),
+	}
+	if job.FinishedAt != nil {
+		message.FinishedAt = timestamppb.New(*job.FinishedAt)
+	}
+	if job.Result != nil {
+		message.Result = &pb.JobResult{
+			Cases: int64(job.Result.Cases),
+			Nodes: int64(job.Result.Nodes),
+			Edges: int64(job.Result.Edges),
+		}
+	}
+	return message
+}
+
+func nodeMessage(node *domain.Node) *pb.Node {
+	return &pb.Node{
+		Id:        node.ID,
+		Label:     node.Label,
+		Count:     int64(node.Count),
+		Total:     int64(node.Total),
+		HappyPath: node.HappyPath,
+	}
+}
+
+func edgeMessage(edge *domain.Edge) *pb.Edge {
+	return &pb.Edge{
+		From:          edge.From,
+		To:            edge.To,
+		Count:         int64(edge.Count),
+		AvgDuration:   edge.AvgDuration,
+		AvgWaiting:    edge.AvgWaiting,
+		AvgProcessing: edge.AvgProcessing,
+		Parallel:      edge.Parallel,
+		HappyPath:     edge.HappyPath,
+	}
+}
@@ -1,162 +1,1750 @@
 package presentation
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"process-mining/internal/domain"
-	"process-mining/internal/infrastructure"
+	"process-mining/internal/domain/metrics"
+	"process-mining/internal/domain/organization"
+	"process-mining/internal/domain/prediction"
+	"process-mining/internal/domain/rootcause"
 	"process-mining/internal/service"
 )
 
+const (
+	// defaultPageLimit и maxPageLimit ограничивают размер страницы постраничных ответов.
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+
+	// maxGraphEdges — наибольшее число переходов в ответе /graph; более крупные графы отдаются
+	// по самым частым переходам до этого предела, остальные доступны постранично.
+	maxGraphEdges = 5000
+
+	// uploadBufferSize — размер буфера записи загружаемого файла во временный файл.
+	uploadBufferSize = 1024 * 1024
+)
+
 type GraphHandler struct {
-	graphService *service.GraphService
+	graphService  *service.GraphService
+	maxUploadSize int64              // наибольший размер загружаемого лога, байт
+	graphBudget   domain.GraphBudget // размер графа /graph, сверх которого граф упрощается (нулевой — не упрощается)
+	uploads       uploadGuard
+	reloadConfig  func() error // перечитывание настроек анализа (nil — не поддерживается)
 }
 
 func NewGraphHandler(graphService *service.GraphService) *GraphHandler {
-	return &GraphHandler{graphService: graphService}
+	return &GraphHandler{graphService: graphService, maxUploadSize: defaultMaxUploadSize}
 }
 
-func (h *GraphHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	log.Println("Начало обработки запроса на загрузку файла")
+// SetMaxUploadSize задаёт наибольший размер загружаемого лога в байтах.
+func (h *GraphHandler) SetMaxUploadSize(size int64) {
+	h.maxUploadSize = size
+}
+
+// SetGraphBudget задаёт наибольшее число узлов и переходов в ответе /graph: больший граф
+// упрощается (см. domain.GraphBuilder.SimplifiedGraph). 0 — без ограничения.
+func (h *GraphHandler) SetGraphBudget(budget domain.GraphBudget) {
+	h.graphBudget = budget
+}
+
+// SetConfigReloader задаёт функцию, перечитывающую настройки анализа для POST /config/reload.
+func (h *GraphHandler) SetConfigReloader(reload func() error) {
+	h.reloadConfig = reload
+}
+
+// datasetService возвращает сервис набора данных из параметра dataset (по умолчанию — текущий набор
+// рабочей области пользователя). Предупреждения анализатора записываются в журнал с идентификатором запроса.
+// Если набор не найден или не загружается из базы, отвечает ошибкой и возвращает false.
+func (h *GraphHandler) datasetService(w http.ResponseWriter, r *http.Request) (*service.GraphService, bool) {
+	id := r.URL.Query().Get("dataset")
+	svc, err := h.graphService.WithLogger(requestLogger(r)).Dataset(id, requestScope(r))
+	if errors.Is(err, service.ErrUnknownDataset) {
+		http.Error(w, fmt.Sprintf("Набор данных %q не найден", id), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return svc, true
+}
 
-	cleaner := infrastructure.NewTMPCleaner()
-	if err := cleaner.ClearTempFiles(); err != nil {
-		log.Printf("Ошибка очистки временных файлов: %v", err)
+// notModified устанавливает заголовок ETag ответа и отвечает 304 Not Modified, если у клиента
+// уже есть актуальная версия (заголовок If-None-Match). Пустой etag не проверяется.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
 	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		// Слабое сравнение: W/"x" и "x" совпадают
+		if candidate = strings.TrimSpace(candidate); strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// UploadFile принимает CSV-лог и запускает построение графа в фоне. Отвечает 202 с задачей,
+// ход и результат которой доступны через /jobs/{id}.
+func (h *GraphHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+	logger.Info("Начало обработки запроса на загрузку файла")
 
 	if r.Method != http.MethodPost {
-		log.Println("Метод не поддерживается")
+		logger.Warn("Метод не поддерживается", "method", r.Method)
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 3*1024*1024*1024) // 3 ГБ
-	file, _, err := r.FormFile("file")
+	// Один клиент загружает не больше одного файла одновременно: место клиента занято до окончания
+	// построения графа по файлу, а не только до окончания передачи
+	client := uploadClient(r)
+	if !h.uploads.acquire(client) {
+		logger.Warn("Загрузка уже выполняется", "client", client)
+		http.Error(w, "Загрузка или построение графа по предыдущему файлу ещё выполняется, дождитесь их окончания", http.StatusTooManyRequests)
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			h.uploads.release(client)
+		}
+	}()
+
+	// Файл из формы пишется сразу во временный файл лога, минуя промежуточный файл разбора формы
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	part, err := uploadPart(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		logger.Warn("Файл превышает допустимый размер", "limit", tooLarge.Limit)
+		http.Error(w, fmt.Sprintf("Файл превышает допустимый размер %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
-		log.Printf("Ошибка получения файла: %v", err)
+		logger.Error("Ошибка получения файла", "error", err)
 		http.Error(w, "Ошибка загрузки файла", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	defer part.Close()
 
 	tempFile, err := os.CreateTemp("", "uploaded-*.csv")
 	if err != nil {
-		log.Printf("Ошибка создания временного файла: %v", err)
+		logger.Error("Ошибка создания временного файла", "error", err)
 		http.Error(w, "Ошибка создания временного файла", http.StatusInternalServerError)
 		return
 	}
 	defer tempFile.Close()
 
-	buf := make([]byte, 1024*1024) // Буфер размером 1 МБ
-	for {
-		n, err := file.Read(buf)
-		if n > 0 {
-			if _, writeErr := tempFile.Write(buf[:n]); writeErr != nil {
-				log.Printf("Ошибка записи во временный файл: %v", writeErr)
-				http.Error(w, "Ошибка записи во временный файл", http.StatusInternalServerError)
-				return
-			}
+	// Файл скрыт за io.Writer: иначе bufio.Writer передал бы копирование os.File.ReadFrom в обход буфера,
+	// и файл записывался бы кусками, которыми читается форма (по несколько КБ)
+	writer := bufio.NewWriterSize(struct{ io.Writer }{tempFile}, uploadBufferSize)
+	_, err = io.Copy(writer, part)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		// Ошибки записи во временный файл — *fs.PathError; остальные возникают при чтении запроса
+		var pathErr *fs.PathError
+		switch {
+		case errors.As(err, &tooLarge):
+			logger.Warn("Файл превышает допустимый размер", "limit", tooLarge.Limit)
+			http.Error(w, fmt.Sprintf("Файл превышает допустимый размер %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		case errors.As(err, &pathErr):
+			logger.Error("Ошибка записи во временный файл", "error", err)
+			http.Error(w, "Ошибка записи во временный файл", http.StatusInternalServerError)
+		default:
+			logger.Error("Ошибка чтения файла", "error", err)
+			http.Error(w, "Ошибка чтения файла", http.StatusBadRequest)
 		}
+		return
+	}
+
+	logger.Info("Файл успешно загружен. Начинается обработка...")
+	job := h.graphService.StartBuildJob(tempFile.Name(), part.FileName(), requestScope(r).Workspace, requestID(r), func() {
+		h.uploads.release(client)
+	})
+	started = true
+	h.audit(r, service.AuditUpload, job.DatasetID, part.FileName())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", APIPrefix+"/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Error("Ошибка сериализации задачи", "error", err)
+	}
+	logger.Info("Обработка завершена успешно")
+}
+
+// uploadPart возвращает поле формы file запроса загрузки (multipart/form-data), пропуская предшествующие поля.
+// Содержимое поля читается из тела запроса по мере чтения части.
+func uploadPart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
 		if err == io.EOF {
-			break
+			return nil, http.ErrMissingFile
 		}
 		if err != nil {
-			log.Printf("Ошибка чтения файла: %v", err)
-			http.Error(w, "Ошибка чтения файла", http.StatusInternalServerError)
-			return
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
 		}
+		part.Close()
 	}
+}
 
-	log.Println("Файл успешно загружен. Начинается обработка...")
-	err = h.graphService.BuildGraphFromCSV(tempFile.Name())
-	if err != nil {
-		log.Printf("Ошибка построения графа: %v", err)
-		http.Error(w, fmt.Sprintf("Ошибка построения графа: %v", err), http.StatusInternalServerError)
+// ServeGraphData возвращает граф процесса. Параметры limit и offset задают страницу переходов,
+// упорядоченных по убыванию частоты (limit не больше 5000, по умолчанию — все переходы в этих пределах);
+// summary=true возвращает только размеры графа.
+func (h *GraphHandler) ServeGraphData(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
 		return
 	}
+	offset, limit := 0, maxGraphEdges
+	if param := r.URL.Query().Get("offset"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 0 {
+			http.Error(w, "Некорректный параметр offset", http.StatusBadRequest)
+			return
+		}
+		offset = value
+	}
+	if param := r.URL.Query().Get("limit"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > maxGraphEdges {
+			http.Error(w, fmt.Sprintf("Некорректный параметр limit: ожидается число от 1 до %d", maxGraphEdges), http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Файл успешно загружен и граф построен"))
-	log.Println("Обработка завершена успешно")
-}
-
-func (h *GraphHandler) ServeGraphData(w http.ResponseWriter, r *http.Request) {
-	graphData, err := h.graphService.GetGraphData()
+	graphData, err := svc.GetGraphData()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// // Логирование данных для отладки
-	// for _, edge := range graphData.Edges {
-	// 	fmt.Printf("Edge: %s -> %s, Style: %s\n", edge.From, edge.To, edge.Style)
-	// }
+	if r.URL.Query().Get("summary") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(graphData.Summary()); err != nil {
+			http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Граф больше бюджета упрощается, чтобы интерфейс мог его отобразить; simplify=false отключает упрощение
+	var simplification *domain.GraphSimplification
+	if r.URL.Query().Get("simplify") != "false" {
+		graphData, simplification = svc.GetSimplifiedGraph(h.graphBudget)
+	}
+	page := graphData.Page(offset, limit)
+	writeGraph(w, r, &page.Graph, page, simplification)
+}
+
+// writeGraph отправляет клиенту граф в формате, понятном фронтенду; узлы и переходы кодируются по одному.
+// Для страницы графа (page не nil) в ответ добавляются общие размеры и границы страницы,
+// для упрощённого графа (simplification не nil) — описание упрощения.
+func writeGraph(w http.ResponseWriter, r *http.Request, graphData *domain.Graph, page *domain.GraphPage, simplification *domain.GraphSimplification) {
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONStream(w)
+	stream.graph(graphData, page, simplification)
+	stream.finish(w, r)
+}
+
+// cytoscapeGraph — граф в формате Cytoscape.js.
+type cytoscapeGraph struct {
+	Nodes      []map[string]*domain.Node `json:"nodes"`
+	Edges      []map[string]*domain.Edge `json:"edges"`
+	TotalNodes *int                      `json:"total_nodes,omitempty"`
+	TotalEdges *int                      `json:"total_edges,omitempty"`
+	Offset     *int                      `json:"offset,omitempty"`
+	Limit      *int                      `json:"limit,omitempty"`
+}
 
-	// Преобразуем данные в формат, понятный фронтенду
-	cytoscapeData := struct {
-		Nodes []map[string]*domain.Node `json:"nodes"`
-		Edges []map[string]*domain.Edge `json:"edges"`
-	}{
+// newCytoscapeGraph преобразует граф (или его страницу page, если она задана) в формат Cytoscape.js.
+func newCytoscapeGraph(graphData *domain.Graph, page *domain.GraphPage) cytoscapeGraph {
+	cytoscapeData := cytoscapeGraph{
 		Nodes: make([]map[string]*domain.Node, len(graphData.Nodes)),
 		Edges: make([]map[string]*domain.Edge, len(graphData.Edges)),
 	}
+	if page != nil {
+		cytoscapeData.TotalNodes = &page.TotalNodes
+		cytoscapeData.TotalEdges = &page.TotalEdges
+		cytoscapeData.Offset = &page.Offset
+		cytoscapeData.Limit = &page.Limit
+	}
 
 	for i, node := range graphData.Nodes {
 		cytoscapeData.Nodes[i] = map[string]*domain.Node{"data": node}
 	}
 
 	for i, edge := range graphData.Edges {
-		edge.Label = fmt.Sprintf("%d\n%.2f sec avg", edge.Count, edge.AvgDuration)
-		cytoscapeData.Edges[i] = map[string]*domain.Edge{"data": edge}
+		cytoscapeData.Edges[i] = map[string]*domain.Edge{"data": labeledEdge(edge)}
+	}
+	return cytoscapeData
+}
+
+// labeledEdge возвращает копию перехода с подписью для фронтенда (граф общий для всех запросов).
+func labeledEdge(edge *domain.Edge) *domain.Edge {
+	labeled := *edge
+	labeled.Label = fmt.Sprintf("%d\n%.2f sec avg", edge.Count, edge.AvgDuration)
+	return &labeled
+}
+
+// Subprocesses возвращает (GET) или задаёт (POST) правила группировки активностей в подпроцессы.
+func (h *GraphHandler) Subprocesses(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(h.graphService.GetSubprocessGrouping()); err != nil {
+			http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		}
+	case http.MethodPost:
+		var grouping domain.SubprocessGrouping
+		if err := json.NewDecoder(r.Body).Decode(&grouping); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное описание подпроцессов: %v", err), http.StatusBadRequest)
+			return
+		}
+		if grouping.Separator == "" && len(grouping.Mapping) == 0 {
+			http.Error(w, "Нужно указать separator или mapping", http.StatusBadRequest)
+			return
+		}
+		h.graphService.SetSubprocessGrouping(&grouping)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Группировка подпроцессов сохранена"))
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// ServeSubprocessGraph возвращает двухуровневый граф. Параметр expand содержит
+// через запятую подпроцессы, которые нужно развернуть до отдельных активностей.
+func (h *GraphHandler) ServeSubprocessGraph(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	var expanded []string
+	if param := r.URL.Query().Get("expand"); param != "" {
+		expanded = strings.Split(param, ",")
+	}
+
+	graphData, err := svc.GetSubprocessGraph(expanded)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeGraph(w, r, graphData, nil, nil)
+}
+
+// ServeReplay возвращает упорядоченные по времени перемещения токенов.
+// Необязательный параметр limit ограничивает количество перемещений в ответе.
+func (h *GraphHandler) ServeReplay(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moves := svc.GetReplay()
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(moves) {
+			moves = moves[:limit]
+		}
 	}
 
-	// Отправляем данные клиенту
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(cytoscapeData); err != nil {
+	if err := json.NewEncoder(w).Encode(moves); err != nil {
 		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
 		return
 	}
 }
 
-func (h *GraphHandler) ClearGraph(w http.ResponseWriter, r *http.Request) {
-	cleaner := infrastructure.NewTMPCleaner()
-	if err := cleaner.ClearTempFiles(); err != nil {
-		log.Printf("Ошибка очистки временных файлов: %v", err)
+// GetJob возвращает ход и результат задачи построения графа {id}, созданной загрузкой файла.
+func (h *GraphHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.graphService.GetJob(r.PathValue("id"), requestScope(r))
+	if errors.Is(err, service.ErrUnknownJob) {
+		http.Error(w, fmt.Sprintf("Задача %q не найдена", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// StreamJob передаёт ход задачи {id} как Server-Sent Events: событие progress при каждом изменении
+// и итоговое событие done или failed. Данные каждого события — состояние задачи в JSON.
+func (h *GraphHandler) StreamJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	scope := requestScope(r)
+	updates, cancel, err := h.graphService.SubscribeJob(id, scope)
+	if errors.Is(err, service.ErrUnknownJob) {
+		http.Error(w, fmt.Sprintf("Задача %q не найдена", id), http.StatusNotFound)
+		return
+	}
+	defer cancel()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Поток живёт дольше обычного таймаута записи сервера
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	for {
+		job, err := h.graphService.GetJob(id, scope)
+		if err != nil {
+			return
+		}
+		event := "progress"
+		if job.Finished() {
+			event = job.Status
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return
+		}
+		flusher.Flush()
+		if job.Finished() {
+			return
+		}
+
+		select {
+		case <-updates:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ListDatasets возвращает описания наборов данных, доступных пользователю.
+func (h *GraphHandler) ListDatasets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.ListDatasets(requestScope(r))); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// datasetRename — тело запроса переименования набора данных.
+type datasetRename struct {
+	Name string `json:"name"`
+}
+
+// Dataset возвращает (GET), переименовывает (PATCH, тело {"name": "..."}) или удаляет (DELETE) набор данных {id}.
+func (h *GraphHandler) Dataset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var dataset service.Dataset
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		dataset, err = h.graphService.GetDataset(id, requestScope(r))
+	case http.MethodPatch:
+		var rename datasetRename
+		if err := json.NewDecoder(r.Body).Decode(&rename); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(rename.Name) == "" {
+			http.Error(w, "Название набора данных не может быть пустым", http.StatusBadRequest)
+			return
+		}
+		dataset, err = h.graphService.RenameDataset(id, strings.TrimSpace(rename.Name), requestScope(r))
+	case http.MethodDelete:
+		err = h.graphService.DeleteDataset(id, requestScope(r))
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, service.ErrUnknownDataset) {
+		http.Error(w, fmt.Sprintf("Набор данных %q не найден", id), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrDefaultDataset) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch r.Method {
+	case http.MethodPatch:
+		h.audit(r, service.AuditRename, id, dataset.Name)
+	case http.MethodDelete:
+		h.audit(r, service.AuditDelete, id, "")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dataset); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// AppendEvents добавляет к набору данных события CSV-лога из поля формы "file" или тела запроса
+// (с заголовком, как при загрузке). Граф обновляется по затронутым кейсам без полного перестроения,
+// поэтому новые события можно досылать небольшими порциями по мере их появления.
+func (h *GraphHandler) AppendEvents(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+	svc, err := h.graphService.Dataset(id, requestScope(r))
+	if errors.Is(err, service.ErrUnknownDataset) {
+		http.Error(w, fmt.Sprintf("Набор данных %q не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Дозагрузка занимает то же место клиента, что и загрузка файла
+	client := uploadClient(r)
+	if !h.uploads.acquire(client) {
+		logger.Warn("Загрузка уже выполняется", "client", client)
+		http.Error(w, "Загрузка или построение графа по предыдущему файлу ещё выполняется, дождитесь их окончания", http.StatusTooManyRequests)
+		return
+	}
+	defer h.uploads.release(client)
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	var events io.Reader = r.Body
+	var tooLarge *http.MaxBytesError
+	file, _, err := r.FormFile("file")
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Файл превышает допустимый размер %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err == nil {
+		defer file.Close()
+		events = file
+	}
+	tempFile, err := os.CreateTemp("", "appended-*.csv")
+	if err != nil {
+		logger.Error("Ошибка создания временного файла", "error", err)
+		http.Error(w, "Ошибка создания временного файла", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+	_, err = io.Copy(tempFile, events)
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Файл превышает допустимый размер %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка записи во временный файл", "error", err)
+		http.Error(w, "Ошибка записи во временный файл", http.StatusInternalServerError)
+		return
+	}
+
+	dataset, err := svc.AppendCSV(tempFile.Name())
+	if err != nil {
+		logger.Error("Ошибка добавления событий", "dataset_id", id, "error", err)
+		http.Error(w, fmt.Sprintf("Ошибка добавления событий: %v", err), http.StatusBadRequest)
+		return
+	}
+	h.audit(r, service.AuditAppend, id, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dataset); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
 	}
+}
 
+// UploadReferenceModel принимает эталонную модель процесса (BPMN 2.0 XML или PNML)
+// в поле формы "model" или в теле запроса.
+func (h *GraphHandler) UploadReferenceModel(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
-	h.graphService.ClearGraph()
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	var model io.Reader = r.Body
+	var tooLarge *http.MaxBytesError
+	file, _, err := r.FormFile("model")
+	if err == nil {
+		defer file.Close()
+		model = file
+	}
+	if !errors.As(err, &tooLarge) {
+		err = h.graphService.SetReferenceModel(model)
+	}
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Файл превышает допустимый размер %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Ошибка загрузки эталонной модели", "error", err)
+		http.Error(w, fmt.Sprintf("Ошибка загрузки эталонной модели: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Граф успешно очищен"))
+	w.Write([]byte("Эталонная модель загружена"))
 }
 
-func (h *GraphHandler) GetMetricsReport(w http.ResponseWriter, r *http.Request) {
-	log.Println("Начало обработки запроса на получение отчета по метрикам")
+// GetConformance возвращает результат проверки соответствия лога эталонной модели.
+// Необязательный параметр limit ограничивает количество отклоняющихся кейсов в ответе.
+func (h *GraphHandler) GetConformance(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	result, err := svc.CheckConformance()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(result.DeviatingCases) {
+			result.DeviatingCases = result.DeviatingCases[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
 
-	metricsReport, err := h.graphService.GetMetricsReport()
+// GetAlignments возвращает оптимальные выравнивания кейсов с эталонной моделью.
+// Параметр deviating=true оставляет только кейсы с отклонениями, limit ограничивает их количество.
+func (h *GraphHandler) GetAlignments(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	result, err := svc.AlignTraces()
 	if err != nil {
-		log.Printf("Ошибка получения отчета по метрикам: %v", err)
-		http.Error(w, fmt.Sprintf("Ошибка получения отчета по метрикам: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if r.URL.Query().Get("deviating") == "true" {
+		deviating := result.Cases[:0]
+		for _, c := range result.Cases {
+			if c.LogMoves+c.ModelMoves > 0 {
+				deviating = append(deviating, c)
+			}
+		}
+		result.Cases = deviating
+	}
+
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(result.Cases) {
+			result.Cases = result.Cases[:limit]
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(metricsReport); err != nil {
-		log.Printf("Ошибка сериализации отчета по метрикам: %v", err)
-		http.Error(w, "Ошибка сериализации отчета по метрикам", http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetRoles возвращает роли, выделенные по профилям активностей ресурсов, их нагрузку и передачи работы.
+// Параметр similarity (0..1) задаёт порог сходства профилей для объединения ресурсов в роль.
+func (h *GraphHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	similarity := organization.DefaultRoleSimilarity
+	if param := r.URL.Query().Get("similarity"); param != "" {
+		value, err := strconv.ParseFloat(param, 64)
+		if err != nil || value < 0 || value > 1 {
+			http.Error(w, "Некорректный параметр similarity: ожидается число от 0 до 1", http.StatusBadRequest)
+			return
+		}
+		similarity = value
+	}
+
+	report, err := svc.MineRoles(similarity)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *GraphHandler) ClearGraph(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.datasetService(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	svc.ClearGraph()
+	h.audit(r, service.AuditClear, svc.DatasetID(), "")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Граф успешно очищен"))
+}
+
+// GetMetricsReport возвращает отчёт по метрикам. По умолчанию для каждой метрики включаются только
+// самые значимые вхождения; параметр occurrences задаёт их количество ("all" — все вхождения).
+// Полный список доступен постранично через /metrics/{name}/occurrences. Параметр segment
+// (например, segment=region) разбивает отчёт по значениям атрибута кейса. Ответ содержит ETag,
+// по которому клиент может не загружать отчёт повторно (If-None-Match → 304).
+func (h *GraphHandler) GetMetricsReport(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	logger := requestLogger(r)
+	logger.Info("Начало обработки запроса на получение отчета по метрикам")
+	if notModified(w, r, svc.ReportETag()) {
+		return
+	}
+
+	top, ok := topOccurrences(w, r)
+	if !ok {
+		return
+	}
+
+	if attribute := r.URL.Query().Get("segment"); attribute != "" {
+		h.getSegmentedMetricsReport(w, r, svc, attribute, top)
+		return
+	}
+
+	metricsReport, err := svc.GetMetricsReport()
+	if err != nil {
+		logger.Error("Ошибка получения отчета по метрикам", "error", err)
+		http.Error(w, fmt.Sprintf("Ошибка получения отчета по метрикам: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if top >= 0 {
+		metricsReport.TruncateOccurrences(top)
+	} else {
+		h.audit(r, service.AuditExport, svc.DatasetID(), "metrics")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONStream(w)
+	stream.metricsReport(metricsReport)
+	if !stream.finish(w, r) {
+		return
+	}
+	// Логирование JSON-ответа перед отправкой (только на уровне debug)
+	if logger.Enabled(r.Context(), slog.LevelDebug) {
+		jsonOutput, _ := json.MarshalIndent(metricsReport, "", "  ")
+		logger.Debug("Отправляемый JSON-отчет по метрикам", "report", string(jsonOutput))
+	}
+	logger.Info("Отчет по метрикам успешно отправлен")
+}
+
+// getSegmentedMetricsReport отвечает отчётами по метрикам для каждого значения атрибута кейса.
+func (h *GraphHandler) getSegmentedMetricsReport(w http.ResponseWriter, r *http.Request, svc *service.GraphService, attribute string, top int) {
+	segmented, err := svc.GetSegmentedMetricsReport(attribute)
+	if errors.Is(err, metrics.ErrUnknownAttribute) {
+		http.Error(w, fmt.Sprintf("Атрибут сегментации не найден в логе: %s", attribute), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка получения отчета по метрикам: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if top >= 0 {
+		for _, segment := range segmented.Segments {
+			segment.Report.TruncateOccurrences(top)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONStream(w)
+	stream.segmentedReport(segmented)
+	stream.finish(w, r)
+}
+
+// topOccurrences разбирает параметр occurrences: число самых значимых вхождений каждой метрики
+// (по умолчанию metrics.DefaultTopOccurrences; "all" — все вхождения, возвращается -1).
+// При некорректном значении отвечает ошибкой 400 и возвращает false.
+func topOccurrences(w http.ResponseWriter, r *http.Request) (int, bool) {
+	switch param := r.URL.Query().Get("occurrences"); param {
+	case "":
+		return metrics.DefaultTopOccurrences, true
+	case "all":
+		return -1, true
+	default:
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 0 {
+			http.Error(w, "Некорректный параметр occurrences", http.StatusBadRequest)
+			return 0, false
+		}
+		return value, true
+	}
+}
+
+// GetMetric возвращает метрику {name} (ключ метрики, например "Self-Loop") с самыми значимыми
+// вхождениями; параметр occurrences — как у /metrics. Если отчёт по метрикам ещё не рассчитан,
+// рассчитывается только семейство метрик {name} (например, все метрики зацикливания), а оценка
+// критичности и приоритет метрики не заполняются.
+func (h *GraphHandler) GetMetric(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	top, ok := topOccurrences(w, r)
+	if !ok {
+		return
+	}
+
+	metric, err := svc.GetMetric(r.PathValue("name"))
+	if errors.Is(err, metrics.ErrUnknownMetric) {
+		http.Error(w, fmt.Sprintf("Метрика %q не найдена", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Метрика общая для всех запросов: усекается копия
+	result := *metric
+	if top < 0 {
+		h.audit(r, service.AuditExport, svc.DatasetID(), "metrics")
+	} else if len(result.Occurrences) > top {
+		result.Occurrences = result.Occurrences[:top]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	stream := newJSONStream(w)
+	stream.metric(&result)
+	stream.finish(w, r)
+}
+
+// GetMetricOccurrences возвращает страницу вхождений метрики {name} (ключ метрики, например "Self-Loop").
+// Параметры offset и limit задают страницу (limit по умолчанию 100, не больше 1000).
+func (h *GraphHandler) GetMetricOccurrences(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	offset, limit := 0, defaultPageLimit
+	if param := r.URL.Query().Get("offset"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 0 {
+			http.Error(w, "Некорректный параметр offset", http.StatusBadRequest)
+			return
+		}
+		offset = value
+	}
+	if param := r.URL.Query().Get("limit"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > maxPageLimit {
+			http.Error(w, fmt.Sprintf("Некорректный параметр limit: ожидается число от 1 до %d", maxPageLimit), http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	page, err := svc.GetMetricOccurrences(r.PathValue("name"), offset, limit)
+	if errors.Is(err, metrics.ErrUnknownMetric) {
+		http.Error(w, fmt.Sprintf("Метрика %q не найдена", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// MetricDefinitions возвращает (GET) определения метрик с порогами или
+// переопределяет пороги (PATCH, тело: {"<ключ метрики>": <порог>, ...}).
+func (h *GraphHandler) MetricDefinitions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var thresholds map[string]float64
+		if err := json.NewDecoder(r.Body).Decode(&thresholds); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetThresholds(thresholds); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetMetricDefinitions()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// SLA возвращает (GET) или заменяет (PUT) действующее SLA.
+func (h *GraphHandler) SLA(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var sla metrics.SLA
+		if err := json.NewDecoder(r.Body).Decode(&sla); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetSLA(sla); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetSLA()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Calendar возвращает (GET), заменяет (PUT) или удаляет (DELETE) рабочий календарь.
+func (h *GraphHandler) Calendar(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var calendar metrics.Calendar
+		if err := json.NewDecoder(r.Body).Decode(&calendar); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetCalendar(&calendar); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		// nil всегда корректен
+		_ = h.graphService.SetCalendar(nil)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetCalendar()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CostModel возвращает (GET) или заменяет (PUT) модель затрат.
+func (h *GraphHandler) CostModel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var model metrics.CostModel
+		if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetCostModel(model); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetCostModel()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetRootCauses возвращает вероятные причины неэффективностей.
+// Необязательные параметры: significance — уровень значимости (по умолчанию 0.05),
+// min_cases — минимальное число затронутых кейсов со значением атрибута.
+func (h *GraphHandler) GetRootCauses(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	significance := rootcause.DefaultSignificance
+	if param := r.URL.Query().Get("significance"); param != "" {
+		value, err := strconv.ParseFloat(param, 64)
+		if err != nil || value <= 0 || value > 1 {
+			http.Error(w, "Некорректный параметр significance: ожидается число от 0 до 1", http.StatusBadRequest)
+			return
+		}
+		significance = value
+	}
+	minCases := rootcause.DefaultMinCases
+	if param := r.URL.Query().Get("min_cases"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 {
+			http.Error(w, "Некорректный параметр min_cases", http.StatusBadRequest)
+			return
+		}
+		minCases = value
+	}
+
+	report, err := svc.GetRootCauses(significance, minCases)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetDurationStats возвращает распределение длительности кейсов.
+// Необязательные параметры: bins — количество интервалов гистограммы, variants — число вариантов в разбивке.
+func (h *GraphHandler) GetDurationStats(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	bins := metrics.DefaultHistogramBins
+	if param := r.URL.Query().Get("bins"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 {
+			http.Error(w, "Некорректный параметр bins", http.StatusBadRequest)
+			return
+		}
+		bins = value
+	}
+
+	distribution := svc.GetDurationDistribution(bins)
+	if param := r.URL.Query().Get("variants"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр variants", http.StatusBadRequest)
+			return
+		}
+		if limit < len(distribution.Variants) {
+			distribution.Variants = distribution.Variants[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(distribution); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ComparePeriods сравнивает два периода лога. Периоды задаются параметром split (граница между
+// «до» и «после») либо явно: before_from, before_to, after_from, after_to. Даты — RFC 3339 или ГГГГ-ММ-ДД.
+func (h *GraphHandler) ComparePeriods(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var before, after metrics.Period
+	if param := query.Get("split"); param != "" {
+		split, err := parseTimeParam(param)
+		if err != nil {
+			http.Error(w, "Некорректный параметр split", http.StatusBadRequest)
+			return
+		}
+		before.To, after.From = &split, &split
+	} else {
+		bounds := []struct {
+			name   string
+			target **time.Time
+		}{
+			{"before_from", &before.From},
+			{"before_to", &before.To},
+			{"after_from", &after.From},
+			{"after_to", &after.To},
+		}
+		for _, bound := range bounds {
+			param := query.Get(bound.name)
+			if param == "" {
+				continue
+			}
+			value, err := parseTimeParam(param)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Некорректный параметр %s", bound.name), http.StatusBadRequest)
+				return
+			}
+			*bound.target = &value
+		}
+		if before.To == nil || after.From == nil {
+			http.Error(w, "Укажите split или границы периодов before_to и after_from", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(svc.ComparePeriods(before, after)); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseTimeParam разбирает время в формате RFC 3339 или дату ГГГГ-ММ-ДД.
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// ListBaselines возвращает эталоны рабочей области пользователя и общие эталоны в порядке создания.
+func (h *GraphHandler) ListBaselines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.ListBaselines(requestScope(r))); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Baseline возвращает (GET), сохраняет из текущего отчёта (PUT) или удаляет (DELETE) эталон {name}
+// рабочей области пользователя.
+func (h *GraphHandler) Baseline(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	scope := requestScope(r)
+	var baseline *metrics.Baseline
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		baseline, err = h.graphService.GetBaseline(name, scope)
+	case http.MethodPut:
+		svc, ok := h.analysisService(w, r)
+		if !ok {
+			return
+		}
+		baseline = svc.SaveBaseline(name, scope)
+	case http.MethodDelete:
+		err = h.graphService.DeleteBaseline(name, scope)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, metrics.ErrUnknownBaseline) {
+		http.Error(w, fmt.Sprintf("Эталон %q не найден", name), http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(baseline); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CompareWithBaseline сравнивает текущий отчёт с эталоном {name}. Необязательный параметр tolerance —
+// допустимый относительный рост показателя (по умолчанию 0.05), после которого он считается регрессией.
+func (h *GraphHandler) CompareWithBaseline(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	tolerance := metrics.DefaultRegressionTolerance
+	if param := r.URL.Query().Get("tolerance"); param != "" {
+		value, err := strconv.ParseFloat(param, 64)
+		if err != nil || value < 0 {
+			http.Error(w, "Некорректный параметр tolerance: ожидается неотрицательное число", http.StatusBadRequest)
+			return
+		}
+		tolerance = value
+	}
+
+	comparison, err := svc.CompareWithBaseline(r.PathValue("name"), tolerance, requestScope(r))
+	if errors.Is(err, metrics.ErrUnknownBaseline) {
+		http.Error(w, fmt.Sprintf("Эталон %q не найден", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(comparison); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// PredictRemainingTime прогнозирует оставшееся время кейса.
+// Тело запроса: {"events": [{"activity": "…", "timestamp": "…"}, …]}.
+func (h *GraphHandler) PredictRemainingTime(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	steps, ok := decodeCaseSteps(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := svc.PredictRemainingTime(steps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// PredictOutcome оценивает вероятность завершения кейса ошибкой.
+// Тело запроса: {"events": [{"activity": "…", "timestamp": "…", "resource": "…", "attributes": {…}}, …]}.
+func (h *GraphHandler) PredictOutcome(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	steps, ok := decodeCaseSteps(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := svc.PredictOutcome(steps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// decodeCaseSteps читает события незавершённого кейса из тела POST-запроса.
+func decodeCaseSteps(w http.ResponseWriter, r *http.Request) ([]prediction.Step, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	var request struct {
+		Events []prediction.Step `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return request.Events, true
+}
+
+// GetCaseDetail возвращает события кейса {id} и все вхождения метрик, относящиеся к нему.
+func (h *GraphHandler) GetCaseDetail(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	detail, err := svc.GetCaseDetail(r.PathValue("id"))
+	if errors.Is(err, metrics.ErrUnknownCase) {
+		http.Error(w, fmt.Sprintf("Кейс %q не найден", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetWorstCases возвращает худшие кейсы. Параметр by — критерий: wasted_duration (по умолчанию),
+// rework или duration; limit — количество кейсов (по умолчанию 10).
+func (h *GraphHandler) GetWorstCases(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = metrics.RankByWastedDuration
+	}
+	limit := 10
+	if param := r.URL.Query().Get("limit"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > maxPageLimit {
+			http.Error(w, fmt.Sprintf("Некорректный параметр limit: ожидается число от 1 до %d", maxPageLimit), http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	cases, err := svc.GetWorstCases(by, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cases); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetVariants возвращает показатели вариантов процесса от самых частых к редким.
+// Необязательные параметры: sort=wasted — сортировка по суммарным потерям времени, limit — количество вариантов.
+func (h *GraphHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	variants := svc.GetVariantMetrics()
+	switch r.URL.Query().Get("sort") {
+	case "", "cases":
+	case "wasted":
+		sort.SliceStable(variants, func(i, j int) bool { return variants[i].WastedDuration > variants[j].WastedDuration })
+	default:
+		http.Error(w, "Некорректный параметр sort: ожидается cases или wasted", http.StatusBadRequest)
+		return
+	}
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(variants) {
+			variants = variants[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(variants); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetStuckCases возвращает незавершённые кейсы, по которым давно нет событий.
+// Необязательный параметр max_age — допустимый возраст последнего события в секундах.
+func (h *GraphHandler) GetStuckCases(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	var maxAge time.Duration
+	if param := r.URL.Query().Get("max_age"); param != "" {
+		seconds, err := strconv.ParseFloat(param, 64)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "Некорректный параметр max_age", http.StatusBadRequest)
+			return
+		}
+		maxAge = time.Duration(seconds * float64(time.Second))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(svc.GetStuckCases(maxAge)); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ErrorSemantics возвращает (GET) или заменяет (PUT) правила распознавания ошибочных результатов.
+func (h *GraphHandler) ErrorSemantics(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var semantics metrics.ErrorSemantics
+		if err := json.NewDecoder(r.Body).Decode(&semantics); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetErrorSemantics(semantics); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetErrorSemantics()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Automation возвращает (GET) или заменяет (PUT) разметку активностей на ручные и автоматические.
+func (h *GraphHandler) Automation(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var mapping metrics.AutomationMapping
+		if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.graphService.SetAutomation(mapping); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphService.GetAutomation()); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetCaseAutomation возвращает уровень автоматизации кейсов, начиная с наименее автоматизированных.
+// Необязательный параметр limit ограничивает количество кейсов.
+func (h *GraphHandler) GetCaseAutomation(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	cases := svc.GetCaseAutomation()
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(cases) {
+			cases = cases[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cases); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetCaseEfficiencies возвращает эффективность кейсов (touch time / lead time), начиная с наименее эффективных.
+// Необязательный параметр limit ограничивает количество кейсов.
+func (h *GraphHandler) GetCaseEfficiencies(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	cases := svc.GetCaseEfficiencies()
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(cases) {
+			cases = cases[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cases); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetBottlenecks возвращает рейтинг узких мест: активности с наибольшим суммарным ожиданием.
+// Необязательный параметр limit ограничивает длину рейтинга.
+func (h *GraphHandler) GetBottlenecks(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	bottlenecks := svc.GetBottlenecks()
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		if limit < len(bottlenecks) {
+			bottlenecks = bottlenecks[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bottlenecks); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		return
+	}
+}
+
+// webhookRequest — тело запроса регистрации вебхука.
+type webhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"` // необязательный ключ подписи HMAC-SHA256
+}
+
+// Webhooks возвращает вебхуки рабочей области пользователя (GET) или регистрирует новый (POST)
+// с телом {"url": "...", "secret": "..."}. На вебхук отправляется JSON-уведомление, когда после
+// анализа порог метрики оказывается превышен.
+func (h *GraphHandler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(h.graphService.ListWebhooks(requestScope(r))); err != nil {
+			http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		}
+	case http.MethodPost:
+		var request webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("Некорректное тело запроса: %v", err), http.StatusBadRequest)
+			return
+		}
+		webhook, err := h.graphService.AddWebhook(strings.TrimSpace(request.URL), request.Secret, requestScope(r).Workspace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(webhook); err != nil {
+			http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// Webhook удаляет вебхук {id} (DELETE).
+func (h *GraphHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+	if err := h.graphService.DeleteWebhook(id, requestScope(r)); errors.Is(err, service.ErrUnknownWebhook) {
+		http.Error(w, fmt.Sprintf("Вебхук %q не найден", id), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shareResponse — описание созданной публичной ссылки.
+type shareResponse struct {
+	Token     string          `json:"token"`
+	URL       string          `json:"url"` // путь ссылки относительно адреса сервера
+	Dataset   service.Dataset `json:"dataset"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// CreateShare сохраняет снимок графа и отчёта по метрикам набора данных (с учётом фильтров)
+// и возвращает токен публичной ссылки /share/{token} (POST).
+func (h *GraphHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	svc, ok := h.analysisService(w, r)
+	if !ok {
+		return
+	}
+	share, err := svc.CreateShare()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, service.AuditShare, share.Dataset.ID, share.Token)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(shareResponse{Token: share.Token, URL: APIPrefix + "/share/" + share.Token, Dataset: share.Dataset, CreatedAt: share.CreatedAt}); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+	}
+}
+
+// GetShare возвращает снимок публичной ссылки {token} (GET): граф в формате Cytoscape.js и отчёт
+// по метрикам. Ссылка доступна без аутентификации.
+func (h *GraphHandler) GetShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	share, err := h.graphService.GetShare(r.PathValue("token"))
+	if errors.Is(err, service.ErrUnknownShare) {
+		http.Error(w, "Ссылка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		Token     string                 `json:"token"`
+		Dataset   service.Dataset        `json:"dataset"`
+		CreatedAt time.Time              `json:"created_at"`
+		Graph     cytoscapeGraph         `json:"graph"`
+		Metrics   *metrics.MetricsReport `json:"metrics"`
+	}{share.Token, share.Dataset, share.CreatedAt, newCytoscapeGraph(share.Graph, nil), share.Metrics}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+	}
+}
+
+// ReloadConfig перечитывает настройки анализа (пороги, SLA, календарь, модель затрат и т.д.) из файла
+// без перезапуска сервера (POST). Загруженные наборы данных сохраняются.
+func (h *GraphHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.reloadConfig == nil {
+		http.Error(w, "Перечитывание настроек не поддерживается", http.StatusNotImplemented)
+		return
+	}
+	if err := h.reloadConfig(); err != nil {
+		requestLogger(r).Error("Ошибка перечитывания настроек анализа", "error", err)
+		http.Error(w, fmt.Sprintf("Ошибка перечитывания настроек анализа: %v", err), http.StatusInternalServerError)
+		return
+	}
+	requestLogger(r).Info("Настройки анализа перечитаны")
+	h.audit(r, service.AuditReload, "", "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ValidateUpload проверяет первые строки CSV-лога (POST, поле формы file, как для /upload) и возвращает
+// вердикт, не загружая файл: число столбцов, разбор времени и порядок событий внутри кейсов.
+// Параметр rows задаёт число проверяемых строк. Файл читается только до последней проверяемой строки.
+func (h *GraphHandler) ValidateUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	rows := domain.DefaultValidationRows
+	if param := r.URL.Query().Get("rows"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > domain.MaxValidationRows {
+			http.Error(w, fmt.Sprintf("Некорректный параметр rows: ожидается число от 1 до %d", domain.MaxValidationRows), http.StatusBadRequest)
+			return
+		}
+		rows = value
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Ожидается файл в поле формы file (multipart/form-data)", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Ожидается файл в поле формы file (multipart/form-data)", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Ошибка чтения формы: %v", err), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		validation, err := h.graphService.ValidateLog(part, rows)
+		part.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Ошибка чтения файла: %v", err), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(validation); err != nil {
+			http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+		}
 		return
 	}
-	// Логирование JSON-ответа перед отправкой
-	jsonOutput, _ := json.MarshalIndent(metricsReport, "", "  ")
-	log.Printf("Отправляемый JSON-отчет по метрикам:\n%s", jsonOutput)
-	log.Println("Отчет по метрикам успешно отправлен")
 }
@@ -0,0 +1,138 @@
+package presentation
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMaxUploadSize — наибольший размер загружаемого лога по умолчанию.
+const defaultMaxUploadSize = 3 * 1024 * 1024 * 1024 // 3 ГБ
+
+// rateLimiterIdle — через сколько простоя счётчик запросов клиента удаляется.
+const rateLimiterIdle = 10 * time.Minute
+
+// clientIP возвращает IP-адрес клиента запроса.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimiter ограничивает число запросов с одного IP-адреса (алгоритм token bucket):
+// в среднем не больше perMinute запросов в минуту с всплесками до burst запросов.
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // запросов в секунду
+	burst     float64
+	buckets   map[string]*rateBucket
+	lastPrune time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter создаёт ограничитель; perMinute <= 0 отключает ограничение.
+// Если burst меньше 1, всплеск равен perMinute.
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = perMinute
+	}
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// allow расходует запрос клиента key. Если лимит исчерпан, возвращает false и время до
+// следующего разрешённого запроса.
+func (l *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > rateLimiterIdle {
+		for k, bucket := range l.buckets {
+			if now.Sub(bucket.last) > rateLimiterIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// Wrap отвечает 429 Too Many Requests (с заголовком Retry-After) на запросы сверх лимита.
+// Ограничитель nil пропускает все запросы.
+func (l *RateLimiter) Wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.allow(clientIP(r), time.Now()); !ok {
+			seconds := int(wait/time.Second) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, "Слишком много запросов, повторите позже", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// uploadGuard не даёт одному клиенту загружать несколько файлов одновременно: клиент занимает место
+// от начала загрузки до окончания построения графа по загруженному файлу.
+type uploadGuard struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// acquire отмечает начало загрузки клиента key; false — у клиента уже идёт загрузка.
+func (g *uploadGuard) acquire(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.active[key] {
+		return false
+	}
+	if g.active == nil {
+		g.active = make(map[string]bool)
+	}
+	g.active[key] = true
+	return true
+}
+
+// release отмечает окончание загрузки клиента key (вместе с построением графа).
+func (g *uploadGuard) release(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.active, key)
+}
+
+// uploadClient возвращает ключ клиента для ограничения загрузок: пользователь, если он
+// аутентифицирован, иначе IP-адрес.
+func uploadClient(r *http.Request) string {
+	if identity, ok := r.Context().Value(identityKey{}).(*Identity); ok && identity.User != "" {
+		return "user:" + identity.User
+	}
+	return "ip:" + clientIP(r)
+}
@@ -0,0 +1,93 @@
+package presentation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса. Идентификатор клиента сохраняется,
+// если он передан, иначе назначается новый; он возвращается в ответе и попадает во все записи журнала.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey — ключ идентификатора запроса в контексте.
+type requestIDKey struct{}
+
+// RequestLogger назначает запросу идентификатор и по завершении записывает в журнал метод, путь,
+// код ответа и длительность обработки.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		started := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		requestLogger(r).Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration_ms", time.Since(started).Milliseconds(),
+		)
+	})
+}
+
+// newRequestID возвращает случайный идентификатор запроса.
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID возвращает идентификатор запроса или пустую строку вне RequestLogger.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger возвращает журнал с идентификатором запроса.
+func requestLogger(r *http.Request) *slog.Logger {
+	if id := requestID(r); id != "" {
+		return slog.With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// statusRecorder запоминает код ответа. Flush и Unwrap сохраняют работу потоковых ответов (SSE)
+// и http.ResponseController.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,187 @@
+package presentation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/domain/metrics"
+)
+
+// streamBufferSize — размер буфера потоковой записи: ответ уходит клиенту частями такого размера.
+const streamBufferSize = 32 << 10
+
+// jsonStream пишет большой JSON-ответ (граф, отчёт по метрикам) по частям: массивы кодируются
+// поэлементно, так что ответ не собирается в памяти целиком, а первые данные уходят клиенту
+// до конца кодирования. Первая ошибка сохраняется, последующие записи пропускаются.
+type jsonStream struct {
+	out *streamOutput
+	w   *bufio.Writer
+	buf bytes.Buffer // закодированное значение
+	enc *json.Encoder
+	err error
+}
+
+// streamOutput отмечает, что клиенту ушли первые данные ответа.
+type streamOutput struct {
+	w       io.Writer
+	written bool
+}
+
+func (o *streamOutput) Write(p []byte) (int, error) {
+	o.written = true
+	return o.w.Write(p)
+}
+
+func newJSONStream(w io.Writer) *jsonStream {
+	s := &jsonStream{out: &streamOutput{w: w}}
+	s.w = bufio.NewWriterSize(s.out, streamBufferSize)
+	s.enc = json.NewEncoder(&s.buf)
+	return s
+}
+
+// raw дописывает готовый фрагмент JSON.
+func (s *jsonStream) raw(text string) {
+	if s.err == nil {
+		_, s.err = s.w.WriteString(text)
+	}
+}
+
+// value дописывает значение v, закодированное encoding/json.
+func (s *jsonStream) value(v any) {
+	if data := s.encode(v); data != nil {
+		_, s.err = s.w.Write(data)
+	}
+}
+
+// encode кодирует v во внутренний буфер; результат действителен до следующего вызова.
+func (s *jsonStream) encode(v any) []byte {
+	if s.err != nil {
+		return nil
+	}
+	s.buf.Reset()
+	if s.err = s.enc.Encode(v); s.err != nil {
+		return nil
+	}
+	// json.Encoder завершает значение переводом строки
+	return bytes.TrimSuffix(s.buf.Bytes(), []byte("\n"))
+}
+
+// array дописывает массив из n элементов; element дописывает i-й элемент.
+func (s *jsonStream) array(n int, element func(i int)) {
+	s.raw("[")
+	for i := 0; i < n && s.err == nil; i++ {
+		if i > 0 {
+			s.raw(",")
+		}
+		element(i)
+	}
+	s.raw("]")
+}
+
+// objectWith дописывает объект head, дополненный последним полем name; field дописывает значение поля.
+// В head поле name должно быть скрыто (пустым полем с omitempty).
+func (s *jsonStream) objectWith(head any, name string, field func()) {
+	data := s.encode(head)
+	if data == nil {
+		return
+	}
+	// Закрывающая скобка объекта переносится за дописанное поле
+	_, s.err = s.w.Write(data[:len(data)-1])
+	if len(data) > 2 {
+		s.raw(",")
+	}
+	s.raw(`"` + name + `":`)
+	field()
+	s.raw("}")
+}
+
+// finish отправляет остаток ответа. Если ответ не удалось закодировать до отправки первых данных,
+// клиент получает ошибку 500; иначе ответ обрывается, а ошибка записывается в журнал.
+func (s *jsonStream) finish(w http.ResponseWriter, r *http.Request) bool {
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.err == nil {
+		return true
+	}
+	requestLogger(r).Error("Ошибка потоковой записи ответа", "error", s.err)
+	if !s.out.written {
+		http.Error(w, "Ошибка сериализации", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// graph дописывает граф (или страницу графа page) в формате Cytoscape.js, как newCytoscapeGraph,
+// и описание упрощения графа simplification, если оно задано.
+func (s *jsonStream) graph(graphData *domain.Graph, page *domain.GraphPage, simplification *domain.GraphSimplification) {
+	s.raw(`{"nodes":`)
+	s.array(len(graphData.Nodes), func(i int) {
+		s.raw(`{"data":`)
+		s.value(graphData.Nodes[i])
+		s.raw("}")
+	})
+	s.raw(`,"edges":`)
+	s.array(len(graphData.Edges), func(i int) {
+		s.raw(`{"data":`)
+		s.value(labeledEdge(graphData.Edges[i]))
+		s.raw("}")
+	})
+	if page != nil {
+		for _, field := range []struct {
+			name  string
+			value int
+		}{{"total_nodes", page.TotalNodes}, {"total_edges", page.TotalEdges}, {"offset", page.Offset}, {"limit", page.Limit}} {
+			s.raw(`,"` + field.name + `":`)
+			s.value(field.value)
+		}
+	}
+	if simplification != nil {
+		s.raw(`,"simplification":`)
+		s.value(simplification)
+	}
+	s.raw("}")
+}
+
+// metricsReport дописывает отчёт по метрикам; метрики и их вхождения кодируются поэлементно.
+func (s *jsonStream) metricsReport(report *metrics.MetricsReport) {
+	if report == nil {
+		s.raw("null")
+		return
+	}
+	s.objectWith(struct {
+		*metrics.MetricsReport
+		Metrics *struct{} `json:"metrics,omitempty"`
+	}{MetricsReport: report}, "metrics", func() {
+		s.array(len(report.Metrics), func(i int) { s.metric(&report.Metrics[i]) })
+	})
+}
+
+// metric дописывает метрику; вхождения кодируются поэлементно и идут последним полем объекта.
+func (s *jsonStream) metric(metric *metrics.InefficiencyMetric) {
+	s.objectWith(struct {
+		*metrics.InefficiencyMetric
+		Occurrences *struct{} `json:"occurrences,omitempty"`
+	}{InefficiencyMetric: metric}, "occurrences", func() {
+		s.array(len(metric.Occurrences), func(i int) { s.value(&metric.Occurrences[i]) })
+	})
+}
+
+// segmentedReport дописывает отчёты по сегментам атрибута кейса.
+func (s *jsonStream) segmentedReport(segmented *metrics.SegmentedReport) {
+	s.objectWith(struct {
+		*metrics.SegmentedReport
+		Segments *struct{} `json:"segments,omitempty"`
+	}{SegmentedReport: segmented}, "segments", func() {
+		s.array(len(segmented.Segments), func(i int) {
+			segment := &segmented.Segments[i]
+			s.objectWith(struct {
+				*metrics.Segment
+				Report *struct{} `json:"report,omitempty"`
+			}{Segment: segment}, "report", func() { s.metricsReport(segment.Report) })
+		})
+	})
+}
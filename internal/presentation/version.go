@@ -0,0 +1,28 @@
+package presentation
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIPrefix — префикс маршрутов текущей версии API. Несовместимые изменения (формат графа,
+// идентификаторы наборов данных) вводятся только в следующей версии (/api/v2), а маршруты /api/v1
+// продолжают работать как прежде.
+const APIPrefix = "/api/v1"
+
+// HandleAPI регистрирует обработчик маршрута API pattern по адресу с префиксом APIPrefix и, для клиентов,
+// написанных до появления версий, по прежнему адресу без префикса. Ответы по прежнему адресу помечаются
+// заголовками Deprecation и Link (rel="successor-version") с адресом в текущей версии.
+func HandleAPI(mux *http.ServeMux, pattern string, handler http.HandlerFunc) {
+	mux.HandleFunc(APIPrefix+pattern, handler)
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+APIPrefix+r.URL.Path+`>; rel="successor-version"`)
+		handler(w, r)
+	})
+}
+
+// routePattern возвращает шаблон маршрута без префикса версии API.
+func routePattern(pattern string) string {
+	return strings.TrimPrefix(pattern, APIPrefix)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// summaryTopMetrics — сколько метрик перечисляется в сводке анализа.
+const summaryTopMetrics = 5
+
+// reportPath — адрес отчёта по метрикам в API (см. presentation.APIPrefix).
+const reportPath = "/api/v1/metrics"
+
+// Notifier получает итоги анализа, в которых порог хотя бы одной метрики превышен впервые.
+// Notify вызывается в отдельной горутине.
+type Notifier interface {
+	Notify(result AnalysisResult) error
+}
+
+// AnalysisResult — итог анализа набора данных, передаваемый в уведомления.
+type AnalysisResult struct {
+	Dataset    Dataset
+	AnalyzedAt time.Time
+	Report     *metrics.MetricsReport
+	// Breached — метрики, порог которых превышен в этом анализе, но не был превышен в предыдущем
+	// анализе лога с тем же именем в той же рабочей области (для первого анализа — все превышенные метрики)
+	Breached []metrics.InefficiencyMetric
+}
+
+// ReportURL возвращает адрес отчёта по метрикам набора данных относительно адреса сервера baseURL
+// (например, https://pm.example.com). Пустой baseURL — адрес без ссылки.
+func (r AnalysisResult) ReportURL(baseURL string) string {
+	if baseURL == "" {
+		return ""
+	}
+	return strings.TrimRight(baseURL, "/") + reportPath + "?dataset=" + url.QueryEscape(r.Dataset.ID)
+}
+
+// Summary возвращает текстовую сводку анализа по метрикам breached: самые критичные метрики,
+// потерянное время и ссылку на отчёт.
+func (r AnalysisResult) Summary(breached []metrics.InefficiencyMetric, baseURL string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Набор данных: %s (%s)\n", r.Dataset.Name, r.Dataset.ID)
+	fmt.Fprintf(&b, "Анализ: %s\n", r.AnalyzedAt.Format("2006-01-02 15:04 MST"))
+	if r.Report != nil {
+		fmt.Fprintf(&b, "Кейсов: %d, событий: %d\n", r.Report.TotalProcessInstances, r.Report.TotalEvents)
+	}
+	b.WriteString("\nПревышены пороги метрик:\n")
+	var wasted float64
+	for i, metric := range breached {
+		wasted += metric.TotalWastedDuration
+		if i >= summaryTopMetrics {
+			continue
+		}
+		fmt.Fprintf(&b, "%d. %s: значение %s при пороге %s, вхождений %d, критичность %.0f, потери %s\n",
+			i+1, metric.Definition.Name, formatValue(metric.TotalValue), formatValue(metric.Definition.Threshold),
+			metric.Count, metric.Severity, formatWasted(metric.TotalWastedDuration))
+	}
+	if len(breached) > summaryTopMetrics {
+		fmt.Fprintf(&b, "… и ещё %d\n", len(breached)-summaryTopMetrics)
+	}
+	fmt.Fprintf(&b, "\nПотерянное время по этим метрикам: %s\n", formatWasted(wasted))
+	if link := r.ReportURL(baseURL); link != "" {
+		fmt.Fprintf(&b, "Отчёт: %s\n", link)
+	}
+	return b.String()
+}
+
+// formatValue форматирует значение метрики без лишних знаков после запятой.
+func formatValue(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// formatWasted форматирует потерянное время в секундах как дни, часы и минуты.
+func formatWasted(seconds float64) string {
+	total := int64(math.Round(seconds / 60))
+	days, hours, minutes := total/(24*60), total/60%24, total%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dд %dч %dм", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dч %dм", hours, minutes)
+	default:
+		return fmt.Sprintf("%dм", minutes)
+	}
+}
+
+// alertState хранит превышенные метрики последнего анализа каждого лога, чтобы уведомлять только
+// о новых превышениях. Лог определяется рабочей областью и именем набора данных: повторная загрузка
+// того же файла (например, ночной выгрузки) сравнивается с предыдущей.
+type alertState struct {
+	mu       sync.Mutex
+	exceeded map[string]map[string]bool // рабочая область и имя набора → ключи превышенных метрик
+}
+
+// alertKey возвращает ключ лога набора данных в alertState.
+func alertKey(dataset Dataset) string {
+	return dataset.Workspace + "\x00" + dataset.Name
+}
+
+// breached запоминает превышенные метрики отчёта лога key и возвращает те, что превышены впервые.
+func (a *alertState) breached(key string, report *metrics.MetricsReport) []metrics.InefficiencyMetric {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.exceeded == nil {
+		a.exceeded = make(map[string]map[string]bool)
+	}
+	previous := a.exceeded[key]
+	current := make(map[string]bool)
+	var breached []metrics.InefficiencyMetric
+	for _, metric := range report.Metrics {
+		if !metric.Exceeded {
+			continue
+		}
+		current[metric.Key] = true
+		if !previous[metric.Key] {
+			breached = append(breached, metric)
+		}
+	}
+	a.exceeded[key] = current
+	return breached
+}
+
+// hasSubscribers проверяет, что об итогах анализа есть кому сообщать; иначе анализ после
+// загрузки не запускается заранее.
+func (s *GraphService) hasSubscribers() bool {
+	s.mu.RLock()
+	notifiers := len(s.notifiers)
+	s.mu.RUnlock()
+	return notifiers > 0 || s.webhooks.count() > 0
+}
+
+// AddNotifier подключает получателя итогов анализа (например, рассылку писем).
+func (s *GraphService) AddNotifier(notifier Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// analyzed вызывается после расчёта нового отчёта набора данных (не из кеша) и рассылает
+// уведомления о метриках, порог которых превышен впервые. Отфильтрованные наборы не учитываются.
+func (s *GraphService) analyzed(entry *datasetEntry, report *metrics.MetricsReport) {
+	if entry == nil || entry.transient {
+		return
+	}
+	s.datasets.mu.Lock()
+	dataset := entry.Dataset
+	s.datasets.mu.Unlock()
+	breached := s.alerts.breached(alertKey(dataset), report)
+	if len(breached) == 0 {
+		return
+	}
+
+	result := AnalysisResult{Dataset: dataset, AnalyzedAt: time.Now().UTC(), Report: report, Breached: breached}
+	go s.webhooks.notify(result)
+	s.mu.RLock()
+	notifiers := append([]Notifier(nil), s.notifiers...)
+	s.mu.RUnlock()
+	for _, notifier := range notifiers {
+		go func() {
+			if err := notifier.Notify(result); err != nil {
+				slog.Error("Ошибка отправки уведомления", "dataset_id", dataset.ID, "error", err)
+			}
+		}()
+	}
+}
+
+// analyzeAfterBuild рассчитывает отчёт только что построенного набора данных, чтобы уведомления
+// о превышениях приходили без обращения к /metrics.
+func (s *GraphService) analyzeAfterBuild(entry *datasetEntry) {
+	if !s.hasSubscribers() {
+		return
+	}
+	svc := &GraphService{serviceState: s.serviceState, dataset: entry, logger: s.logger}
+	svc.report(svc.processInstances())
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Действия, записываемые в журнал аудита.
+const (
+	AuditUpload = "upload" // загрузка лога
+	AuditAppend = "append" // дозагрузка событий в набор данных
+	AuditClear  = "clear"  // очистка набора данных
+	AuditRename = "rename" // переименование набора данных
+	AuditDelete = "delete" // удаление набора данных
+	AuditFilter = "filter" // просмотр набора данных с фильтром
+	AuditExport = "export" // выгрузка полного отчёта по метрикам
+	AuditShare  = "share"  // создание публичной ссылки
+	AuditReload = "reload" // перечитывание настроек анализа
+)
+
+// maxAuditEntries — сколько последних записей журнала аудита хранится в памяти без базы.
+const maxAuditEntries = 10000
+
+// AuditEntry — запись журнала аудита: кто, когда и что сделал с набором данных.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"` // пусто, если аутентификация отключена
+	Workspace string    `json:"workspace,omitempty"`
+	Action    string    `json:"action"`
+	DatasetID string    `json:"dataset_id,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+// AuditQuery отбирает записи журнала аудита; пустые поля не ограничивают выборку.
+type AuditQuery struct {
+	User      string
+	Action    string
+	DatasetID string
+	From, To  time.Time // To не включается
+	Limit     int
+}
+
+func (q AuditQuery) matches(entry AuditEntry) bool {
+	return (q.User == "" || entry.User == q.User) &&
+		(q.Action == "" || entry.Action == q.Action) &&
+		(q.DatasetID == "" || entry.DatasetID == q.DatasetID) &&
+		(q.From.IsZero() || !entry.Time.Before(q.From)) &&
+		(q.To.IsZero() || entry.Time.Before(q.To))
+}
+
+// auditLog хранит журнал аудита в памяти, если база не подключена.
+type auditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// Audit записывает действие в журнал аудита (в базу, если она подключена) и в журнал сервера.
+func (s *GraphService) Audit(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	slog.Info("audit", "action", entry.Action, "user", entry.User, "workspace", entry.Workspace,
+		"dataset_id", entry.DatasetID, "details", entry.Details, "request_id", entry.RequestID)
+	if s.store != nil {
+		data, err := json.Marshal(entry)
+		if err == nil {
+			err = s.store.AppendAudit(data)
+		}
+		if err != nil {
+			slog.Error("Ошибка записи журнала аудита", "error", err)
+		}
+		return
+	}
+
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	if len(s.audit.entries) == maxAuditEntries {
+		s.audit.entries = append(s.audit.entries[:0], s.audit.entries[1:]...)
+	}
+	s.audit.entries = append(s.audit.entries, entry)
+}
+
+// AuditLog возвращает записи журнала аудита, подходящие под запрос, начиная с последней.
+func (s *GraphService) AuditLog(query AuditQuery) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	full := func() bool {
+		return query.Limit > 0 && len(entries) >= query.Limit
+	}
+	if s.store != nil {
+		var decodeErr error
+		err := s.store.AuditEntries(func(data []byte) bool {
+			var entry AuditEntry
+			if decodeErr = json.Unmarshal(data, &entry); decodeErr != nil {
+				return false
+			}
+			if query.matches(entry) {
+				entries = append(entries, entry)
+			}
+			return !full()
+		})
+		if err == nil {
+			err = decodeErr
+		}
+		return entries, err
+	}
+
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	for i := len(s.audit.entries) - 1; i >= 0 && !full(); i-- {
+		if query.matches(s.audit.entries[i]) {
+			entries = append(entries, s.audit.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// DatasetID возвращает идентификатор набора данных, с которым работает сервис.
+func (s *GraphService) DatasetID() string {
+	return s.currentEntry().ID
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/infrastructure"
+)
+
+// DefaultDatasetID — идентификатор набора данных, переданного в NewGraphService.
+const DefaultDatasetID = "default"
+
+// ErrUnknownDataset возвращается при обращении к несуществующему набору данных.
+var ErrUnknownDataset = errors.New("набор данных не найден")
+
+// ErrDefaultDataset возвращается при попытке удалить набор данных по умолчанию.
+var ErrDefaultDataset = errors.New("набор данных по умолчанию нельзя удалить")
+
+// Dataset — описание загруженного лога.
+type Dataset struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Workspace  string    `json:"workspace,omitempty"` // рабочая область пользователя или команды
+	UploadedAt time.Time `json:"uploaded_at"`
+	Rows       int       `json:"rows"`
+	Size       int64     `json:"size"` // байт
+}
+
+// datasetEntry — набор данных с построенным по нему графом. Набор, восстановленный из базы,
+// загружается при первом обращении.
+type datasetEntry struct {
+	Dataset
+
+	once      sync.Once
+	builder   *domain.GraphBuilder
+	err       error
+	transient bool // отфильтрованная копия набора: не сохраняется в базе
+	reports   reportCache
+
+	// Для отфильтрованной копии: исходный набор, в кеше которого хранятся её отчёты, ключ фильтра
+	// и версия данных исходного набора на момент фильтрации
+	parent        *datasetEntry
+	filterKey     string
+	parentVersion uint64
+}
+
+// graphBuilder возвращает построитель графа набора, при необходимости загружая события из store.
+func (e *datasetEntry) graphBuilder(store *infrastructure.DatasetStore) (*domain.GraphBuilder, error) {
+	e.once.Do(func() {
+		if e.builder != nil {
+			return
+		}
+		builder := domain.NewGraphBuilder(infrastructure.NewCSVReader())
+		if store != nil {
+			data, err := store.Events(e.ID)
+			if err != nil {
+				e.err = fmt.Errorf("ошибка чтения набора данных %s: %v", e.ID, err)
+				return
+			}
+			var events []domain.Event
+			if len(data) > 0 {
+				if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&events); err != nil {
+					e.err = fmt.Errorf("ошибка разбора набора данных %s: %v", e.ID, err)
+					return
+				}
+			}
+			builder.LoadEvents(events)
+			log.Printf("Набор данных %s загружен из базы: %d событий", e.ID, len(events))
+		}
+		e.builder = builder
+	})
+	return e.builder, e.err
+}
+
+// Scope ограничивает доступ к наборам данных рабочей областью пользователя или команды.
+// Наборы без рабочей области (например, набор по умолчанию) доступны всем.
+type Scope struct {
+	Workspace string // Рабочая область, в которой создаются новые наборы
+	All       bool   // Доступ к наборам всех рабочих областей (администратор)
+}
+
+// allows сообщает, доступен ли набор данных в области видимости.
+func (sc Scope) allows(dataset Dataset) bool {
+	return sc.allowsWorkspace(dataset.Workspace)
+}
+
+// allowsWorkspace сообщает, доступны ли в области видимости данные рабочей области workspace
+// (пустая — общие данные, доступные всем).
+func (sc Scope) allowsWorkspace(workspace string) bool {
+	return sc.All || workspace == "" || workspace == sc.Workspace
+}
+
+// datasetRegistry хранит наборы данных. Текущий набор рабочей области — последний загруженный
+// в неё, он используется, если набор не указан явно.
+type datasetRegistry struct {
+	mu       sync.Mutex
+	datasets map[string]*datasetEntry
+	current  map[string]string // рабочая область → текущий набор
+}
+
+// add регистрирует набор данных и делает его текущим в его рабочей области.
+func (r *datasetRegistry) add(entry *datasetEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.datasets == nil {
+		r.datasets = make(map[string]*datasetEntry)
+		r.current = make(map[string]string)
+	}
+	r.datasets[entry.ID] = entry
+	r.current[entry.Workspace] = entry.ID
+}
+
+// get возвращает набор данных по идентификатору; пустой идентификатор означает текущий набор
+// рабочей области (или общий текущий набор, если в ней ещё ничего не загружено).
+// Наборы вне области видимости считаются несуществующими.
+func (r *datasetRegistry) get(id string, scope Scope) (*datasetEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == "" {
+		var ok bool
+		if id, ok = r.current[scope.Workspace]; !ok {
+			id = r.current[""]
+		}
+	}
+	entry, ok := r.datasets[id]
+	if !ok || !scope.allows(entry.Dataset) {
+		return nil, ErrUnknownDataset
+	}
+	return entry, nil
+}
+
+// list возвращает описания доступных наборов данных в порядке загрузки.
+func (r *datasetRegistry) list(scope Scope) []Dataset {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	datasets := make([]Dataset, 0, len(r.datasets))
+	for _, entry := range r.datasets {
+		if scope.allows(entry.Dataset) {
+			datasets = append(datasets, entry.Dataset)
+		}
+	}
+	sort.Slice(datasets, func(i, j int) bool { return datasets[i].UploadedAt.Before(datasets[j].UploadedAt) })
+	return datasets
+}
+
+// rename меняет название набора данных и возвращает его новое описание.
+func (r *datasetRegistry) rename(id, name string, scope Scope) (Dataset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.datasets[id]
+	if !ok || !scope.allows(entry.Dataset) {
+		return Dataset{}, ErrUnknownDataset
+	}
+	entry.Name = name
+	return entry.Dataset, nil
+}
+
+// remove удаляет набор данных. Если он был текущим, текущим в его рабочей области становится
+// последний загруженный из оставшихся (набор по умолчанию не удаляется, поэтому общий текущий набор есть всегда).
+func (r *datasetRegistry) remove(id string, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.datasets[id]
+	if !ok || !scope.allows(entry.Dataset) {
+		return ErrUnknownDataset
+	}
+	if id == DefaultDatasetID {
+		return ErrDefaultDataset
+	}
+	delete(r.datasets, id)
+	if r.current[entry.Workspace] == id {
+		var latest *datasetEntry
+		for _, other := range r.datasets {
+			if other.Workspace == entry.Workspace && (latest == nil || other.UploadedAt.After(latest.UploadedAt)) {
+				latest = other
+			}
+		}
+		if latest != nil {
+			r.current[entry.Workspace] = latest.ID
+		} else {
+			delete(r.current, entry.Workspace)
+		}
+	}
+	return nil
+}
+
+// newDatasetEntry создаёт пустой набор данных в рабочей области workspace с собственным построителем графа.
+func newDatasetEntry(id, name, workspace string) *datasetEntry {
+	return &datasetEntry{
+		Dataset: Dataset{ID: id, Name: name, Workspace: workspace, UploadedAt: time.Now().UTC()},
+		builder: domain.NewGraphBuilder(infrastructure.NewCSVReader()),
+	}
+}
+
+// Dataset возвращает сервис, работающий с набором данных id и общими настройками анализа.
+// Пустой id означает текущий набор данных рабочей области. Набор вне области видимости scope
+// считается несуществующим. Набор, сохранённый в базе, загружается при первом обращении.
+func (s *GraphService) Dataset(id string, scope Scope) (*GraphService, error) {
+	entry, err := s.datasets.get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := entry.graphBuilder(s.store); err != nil {
+		return nil, err
+	}
+	return &GraphService{serviceState: s.serviceState, dataset: entry, logger: s.logger}, nil
+}
+
+// ListDatasets возвращает описания наборов данных, доступных в области видимости scope.
+func (s *GraphService) ListDatasets(scope Scope) []Dataset {
+	return s.datasets.list(scope)
+}
+
+// GetDataset возвращает описание набора данных.
+func (s *GraphService) GetDataset(id string, scope Scope) (Dataset, error) {
+	entry, err := s.datasets.get(id, scope)
+	if err != nil {
+		return Dataset{}, err
+	}
+	s.datasets.mu.Lock()
+	defer s.datasets.mu.Unlock()
+	return entry.Dataset, nil
+}
+
+// RenameDataset меняет название набора данных.
+func (s *GraphService) RenameDataset(id, name string, scope Scope) (Dataset, error) {
+	dataset, err := s.datasets.rename(id, name, scope)
+	if err != nil {
+		return Dataset{}, err
+	}
+	if s.store != nil {
+		meta, err := json.Marshal(dataset)
+		if err != nil {
+			return Dataset{}, err
+		}
+		if err := s.store.PutMeta(id, meta); err != nil {
+			return Dataset{}, fmt.Errorf("ошибка сохранения набора данных %s: %v", id, err)
+		}
+	}
+	return dataset, nil
+}
+
+// DeleteDataset удаляет набор данных вместе с сохранёнными в базе событиями и отчётами.
+func (s *GraphService) DeleteDataset(id string, scope Scope) error {
+	if err := s.datasets.remove(id, scope); err != nil {
+		return err
+	}
+	if s.store != nil {
+		if err := s.store.DeleteDataset(id); err != nil {
+			return fmt.Errorf("ошибка удаления набора данных %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// Filter возвращает сервис, работающий с частью набора данных, прошедшей фильтр.
+// Пустой фильтр возвращает тот же сервис.
+func (s *GraphService) Filter(filter domain.LogFilter) (*GraphService, error) {
+	if filter.Empty() {
+		return s, nil
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	entry := s.currentEntry()
+	s.datasets.mu.Lock()
+	dataset := entry.Dataset
+	s.datasets.mu.Unlock()
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	parent, filterKey := entry, string(key)
+	if entry.parent != nil {
+		parent, filterKey = entry.parent, entry.filterKey+filterKey
+	}
+	filtered := &datasetEntry{
+		Dataset:       dataset,
+		builder:       s.builder().Filtered(filter),
+		transient:     true,
+		parent:        parent,
+		filterKey:     filterKey,
+		parentVersion: parent.reports.currentVersion(),
+	}
+	return &GraphService{serviceState: s.serviceState, dataset: filtered, logger: s.logger}, nil
+}
+
+// WriteEventsCSV записывает события набора данных (с учётом фильтра, см. Filter) в CSV-лог.
+func (s *GraphService) WriteEventsCSV(w io.Writer) error {
+	return domain.WriteEventsCSV(w, s.builder().Events())
+}
+
+// builder возвращает построитель графа набора данных, с которым работает сервис.
+// Если набор недоступен, возвращается пустой построитель.
+func (s *GraphService) builder() *domain.GraphBuilder {
+	entry := s.currentEntry()
+	if entry == nil {
+		// Набор по умолчанию регистрируется в NewGraphService, так что текущий набор должен быть всегда
+		s.serviceLogger().Error("Текущий набор данных не найден")
+		return domain.NewGraphBuilder(infrastructure.NewCSVReader())
+	}
+	builder, err := entry.graphBuilder(s.store)
+	if err != nil {
+		s.serviceLogger().Error("Ошибка загрузки набора данных", "dataset_id", entry.ID, "error", err)
+		return domain.NewGraphBuilder(infrastructure.NewCSVReader())
+	}
+	return builder
+}
+
+// currentEntry возвращает набор данных, с которым работает сервис.
+func (s *GraphService) currentEntry() *datasetEntry {
+	if s.dataset != nil {
+		return s.dataset
+	}
+	entry, _ := s.datasets.get("", Scope{})
+	return entry
+}
+
+// SetStore подключает базу наборов данных: сохранённые наборы регистрируются (их события
+// загружаются при первом обращении), а новые загрузки и рассчитанные отчёты сохраняются в базу.
+func (s *GraphService) SetStore(store *infrastructure.DatasetStore) error {
+	metas, err := store.Datasets()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения списка наборов данных: %v", err)
+	}
+	entries := make([]*datasetEntry, 0, len(metas))
+	for id, data := range metas {
+		entry := &datasetEntry{}
+		if err := json.Unmarshal(data, &entry.Dataset); err != nil {
+			return fmt.Errorf("ошибка разбора описания набора данных %s: %v", id, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// Текущим становится последний загруженный набор
+	sort.Slice(entries, func(i, j int) bool { return entries[i].UploadedAt.Before(entries[j].UploadedAt) })
+	s.store = store
+	for _, entry := range entries {
+		s.datasets.add(entry)
+	}
+	return nil
+}
+
+// persist сохраняет набор данных в базу (если она подключена и набор не является отфильтрованной копией).
+func (s *GraphService) persist(entry *datasetEntry) {
+	if s.store == nil || entry.transient {
+		return
+	}
+	builder, err := entry.graphBuilder(s.store)
+	if err != nil {
+		log.Printf("Ошибка сохранения набора данных %s: %v", entry.ID, err)
+		return
+	}
+	s.datasets.mu.Lock()
+	meta, err := json.Marshal(entry.Dataset)
+	s.datasets.mu.Unlock()
+	if err != nil {
+		log.Printf("Ошибка сохранения набора данных %s: %v", entry.ID, err)
+		return
+	}
+	var events bytes.Buffer
+	if err := gob.NewEncoder(&events).Encode(builder.Events()); err != nil {
+		log.Printf("Ошибка сохранения набора данных %s: %v", entry.ID, err)
+		return
+	}
+	if err := s.store.PutDataset(entry.ID, meta, events.Bytes()); err != nil {
+		log.Printf("Ошибка сохранения набора данных %s: %v", entry.ID, err)
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+
+	"process-mining/internal/domain/metrics"
+	"process-mining/internal/infrastructure"
+)
+
+// EmailNotifier рассылает сводку анализа на адреса to, если превышения подпадают под правило rule.
+type EmailNotifier struct {
+	sender  *infrastructure.SMTPSender
+	to      []string
+	rule    metrics.AlertRule
+	baseURL string // адрес сервера для ссылки на отчёт
+}
+
+func NewEmailNotifier(sender *infrastructure.SMTPSender, to []string, rule metrics.AlertRule, baseURL string) *EmailNotifier {
+	return &EmailNotifier{sender: sender, to: to, rule: rule, baseURL: baseURL}
+}
+
+// Notify отправляет письмо со сводкой по метрикам, подпадающим под правило.
+func (n *EmailNotifier) Notify(result AnalysisResult) error {
+	breached := n.rule.Select(result.Breached)
+	if len(breached) == 0 {
+		return nil
+	}
+	subject := fmt.Sprintf("Process Mining: превышены пороги метрик (%s)", result.Dataset.Name)
+	return n.sender.Send(n.to, subject, result.Summary(breached, n.baseURL))
+}
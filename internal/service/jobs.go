@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"process-mining/internal/domain"
+)
+
+// Состояния задачи построения графа.
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// ErrUnknownJob возвращается при запросе несуществующей задачи.
+var ErrUnknownJob = errors.New("задача не найдена")
+
+// jobRetention — сколько хранится завершённая задача: после этого она удаляется из реестра
+// при регистрации следующей задачи и GetJob отвечает ErrUnknownJob.
+const jobRetention = time.Hour
+
+// JobResult — итог успешно завершённой задачи.
+type JobResult struct {
+	Cases int `json:"cases"`
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+}
+
+// Job — фоновая задача построения графа по загруженному файлу.
+// После успешного завершения граф доступен как набор данных DatasetID.
+type Job struct {
+	ID         string     `json:"id"`
+	DatasetID  string     `json:"dataset_id"`
+	RequestID  string     `json:"request_id,omitempty"` // идентификатор запроса загрузки
+	Workspace  string     `json:"workspace,omitempty"`  // рабочая область, в которую загружен файл
+	Status     string     `json:"status"`
+	Phase      string     `json:"phase"`
+	RowsRead   int        `json:"rows_read"`
+	CasesBuilt int        `json:"cases_built"`
+	Percent    float64    `json:"percent"` // доля прочитанного файла, %
+	Warnings   []string   `json:"warnings"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Result     *JobResult `json:"result,omitempty"`
+}
+
+// Finished проверяет, что задача завершилась успешно или с ошибкой.
+func (j Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// jobRegistry хранит задачи построения графа. Задачи обновляются из фоновых горутин,
+// подписчики получают уведомление о каждом изменении.
+type jobRegistry struct {
+	mu          sync.Mutex
+	jobs        map[string]*Job
+	subscribers map[string]map[chan struct{}]struct{}
+	running     sync.WaitGroup // незавершённые задачи, которых дожидается остановка сервера
+	lastPrune   time.Time
+}
+
+// newJobID возвращает случайный идентификатор задачи.
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// pruneLocked удаляет задачи, завершённые раньше jobRetention до now и не имеющие подписчиков.
+// Вызывается под r.mu не чаще раза в jobRetention.
+func (r *jobRegistry) pruneLocked(now time.Time) {
+	if now.Sub(r.lastPrune) <= jobRetention {
+		return
+	}
+	for id, job := range r.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention && len(r.subscribers[id]) == 0 {
+			delete(r.jobs, id)
+		}
+	}
+	r.lastPrune = now
+}
+
+// update изменяет задачу под блокировкой и уведомляет подписчиков.
+func (r *jobRegistry) update(id string, change func(job *Job)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(r.jobs[id])
+	for ch := range r.subscribers[id] {
+		// Канал с буфером 1: несколько изменений подряд сливаются в одно уведомление
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// StartBuildJob запускает построение графа по файлу в новом наборе данных name рабочей области
+// workspace и сразу возвращает созданную задачу. Набор данных становится текущим в рабочей области
+// после успешного построения. Идентификатор запроса загрузки requestID сохраняется в задаче
+// и в записях журнала о ходе построения. Файл filePath — временный файл загрузки: после построения он удаляется.
+// done (если задана) вызывается по окончании задачи, успешном или нет.
+func (s *GraphService) StartBuildJob(filePath, name, workspace, requestID string, done func()) Job {
+	job, dataset := s.newBuildJob(name, workspace, requestID)
+	go func() {
+		if done != nil {
+			defer done()
+		}
+		s.runBuildJob(job.ID, requestID, dataset, filePath)
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Ошибка удаления временного файла загрузки", "job_id", job.ID, "error", err)
+		}
+	}()
+	return job
+}
+
+// newBuildJob регистрирует задачу построения графа в новом наборе данных. Задача должна быть
+// выполнена runBuildJob: остановка сервера дожидается её завершения.
+func (s *GraphService) newBuildJob(name, workspace, requestID string) (Job, *datasetEntry) {
+	id := newJobID()
+	dataset := newDatasetEntry(id, name, workspace)
+	job := &Job{ID: id, DatasetID: dataset.ID, RequestID: requestID, Workspace: workspace, Status: JobRunning, Phase: domain.PhaseReading, Warnings: []string{}, StartedAt: time.Now().UTC()}
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	if s.jobs.jobs == nil {
+		s.jobs.jobs = make(map[string]*Job)
+	}
+	s.jobs.pruneLocked(job.StartedAt)
+	s.jobs.jobs[job.ID] = job
+	s.jobs.running.Add(1)
+	return *job, dataset
+}
+
+// runBuildJob строит граф набора данных и записывает ход и итог построения в задачу id.
+// Предупреждения анализатора записываются в журнал с идентификаторами задачи и запроса requestID.
+func (s *GraphService) runBuildJob(id, requestID string, dataset *datasetEntry, filePath string) {
+	defer s.jobs.running.Done()
+	logger := slog.With("job_id", id)
+	if requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	var last domain.BuildProgress
+	logged := 0
+	s.configureBuilder(dataset.builder)
+	err := dataset.builder.BuildGraphWithProgress(filePath, func(progress domain.BuildProgress) {
+		last = progress
+		for ; logged < len(progress.Warnings); logged++ {
+			logger.Warn("Предупреждение при построении графа", "warning", progress.Warnings[logged])
+		}
+		s.jobs.update(id, func(job *Job) {
+			job.Phase = progress.Phase
+			job.RowsRead = progress.RowsRead
+			job.CasesBuilt = progress.CasesBuilt
+			job.Percent = progress.Percent
+			job.Warnings = progress.Warnings
+		})
+	})
+
+	var result *JobResult
+	if err == nil {
+		graph := dataset.builder.GetGraph()
+		result = &JobResult{Cases: last.CasesBuilt, Nodes: len(graph.Nodes), Edges: len(graph.Edges)}
+		dataset.Rows = last.RowsRead
+		dataset.Size = last.TotalBytes
+		s.persist(dataset)
+		s.datasets.add(dataset)
+	} else {
+		logger.Error("Ошибка построения графа", "error", err)
+	}
+	s.jobs.update(id, func(job *Job) {
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobDone
+		job.Result = result
+	})
+	if err == nil {
+		s.analyzeAfterBuild(dataset)
+	}
+}
+
+// WaitJobs ждёт завершения всех запущенных задач построения графа. Возвращает ошибку ctx,
+// если задачи не успели завершиться до его отмены.
+func (s *GraphService) WaitJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.jobs.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetJob возвращает текущее состояние задачи, если она доступна в scope.
+func (s *GraphService) GetJob(id string, scope Scope) (Job, error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	job, ok := s.jobs.jobs[id]
+	if !ok || !scope.allowsWorkspace(job.Workspace) {
+		return Job{}, ErrUnknownJob
+	}
+	return *job, nil
+}
+
+// SubscribeJob возвращает канал, в который приходит уведомление при каждом изменении задачи,
+// доступной в scope, и функцию отмены подписки.
+func (s *GraphService) SubscribeJob(id string, scope Scope) (<-chan struct{}, func(), error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	if job, ok := s.jobs.jobs[id]; !ok || !scope.allowsWorkspace(job.Workspace) {
+		return nil, nil, ErrUnknownJob
+	}
+	if s.jobs.subscribers == nil {
+		s.jobs.subscribers = make(map[string]map[chan struct{}]struct{})
+	}
+	if s.jobs.subscribers[id] == nil {
+		s.jobs.subscribers[id] = make(map[chan struct{}]struct{})
+	}
+	ch := make(chan struct{}, 1)
+	s.jobs.subscribers[id][ch] = struct{}{}
+
+	cancel := func() {
+		s.jobs.mu.Lock()
+		defer s.jobs.mu.Unlock()
+		delete(s.jobs.subscribers[id], ch)
+		if len(s.jobs.subscribers[id]) == 0 {
+			delete(s.jobs.subscribers, id)
+		}
+	}
+	return ch, cancel, nil
+}
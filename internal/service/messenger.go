@@ -0,0 +1,30 @@
+package service
+
+import (
+	"process-mining/internal/domain/metrics"
+)
+
+// MessageSender отправляет текстовое сообщение в мессенджер (чат Telegram, канал Slack).
+type MessageSender interface {
+	Send(text string) error
+}
+
+// MessengerNotifier отправляет сводку анализа в мессенджер, если превышения подпадают под правило rule.
+type MessengerNotifier struct {
+	sender  MessageSender
+	rule    metrics.AlertRule
+	baseURL string // адрес сервера для ссылки на отчёт
+}
+
+func NewMessengerNotifier(sender MessageSender, rule metrics.AlertRule, baseURL string) *MessengerNotifier {
+	return &MessengerNotifier{sender: sender, rule: rule, baseURL: baseURL}
+}
+
+// Notify отправляет сообщение со сводкой по метрикам, подпадающим под правило.
+func (n *MessengerNotifier) Notify(result AnalysisResult) error {
+	breached := n.rule.Select(result.Breached)
+	if len(breached) == 0 {
+		return nil
+	}
+	return n.sender.Send("⚠️ Process Mining: превышены пороги метрик\n\n" + result.Summary(breached, n.baseURL))
+}
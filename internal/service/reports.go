@@ -0,0 +1,230 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"process-mining/internal/domain/metrics"
+)
+
+// settingsKey возвращает ключ действующих настроек анализа для кеширования отчётов и сохранения их в базе.
+// Пустой ключ означает, что отчёт не кешируется: пользовательские метрики не сериализуются.
+func (s *GraphService) settingsKey() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.collectors) > 0 {
+		return ""
+	}
+	data, err := json.Marshal(struct {
+		Thresholds    map[string]float64
+		SLA           metrics.SLA
+		Calendar      *metrics.Calendar
+		Costs         metrics.CostModel
+		OutlierMethod string
+		EndActivities []string
+		TopK          int
+		Errors        metrics.ErrorSemantics
+		Automation    metrics.AutomationMapping
+	}{s.thresholds, s.sla, s.calendar, s.costModel, s.outliers, s.endActivities, s.topK, s.errorRules, s.automation})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// maxCachedReports — наибольшее число отчётов одного набора данных в памяти (разные настройки и фильтры).
+const maxCachedReports = 16
+
+// reportCache хранит рассчитанные отчёты набора данных по ключу настроек анализа и фильтра.
+// Версия увеличивается при изменении данных набора (загрузка, очистка), отчёты прежней версии удаляются.
+// Отчёты из кеша общие для всех запросов и не изменяются.
+type reportCache struct {
+	mu      sync.Mutex
+	version uint64
+	reports map[string]*metrics.MetricsReport
+	single  map[string]*metrics.InefficiencyMetric // метрики, рассчитанные без полного отчёта (см. GetMetric)
+}
+
+// currentVersion возвращает версию данных набора.
+func (c *reportCache) currentVersion() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// invalidate удаляет отчёты после изменения данных набора.
+func (c *reportCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	c.reports = nil
+	c.single = nil
+}
+
+// get возвращает отчёт с ключом key, рассчитанный по данным версии version.
+func (c *reportCache) get(version uint64, key string) *metrics.MetricsReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return nil
+	}
+	return c.reports[key]
+}
+
+// put сохраняет отчёт, если данные набора не изменились с версии version, по которой он рассчитан.
+func (c *reportCache) put(version uint64, key string, report *metrics.MetricsReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	if c.reports == nil || len(c.reports) >= maxCachedReports {
+		c.reports = make(map[string]*metrics.MetricsReport)
+	}
+	c.reports[key] = report
+}
+
+// maxCachedMetrics — наибольшее число метрик одного набора данных, рассчитанных без полного отчёта.
+const maxCachedMetrics = 256
+
+// getMetric возвращает метрику name, рассчитанную без полного отчёта по данным версии version
+// при настройках и фильтре key.
+func (c *reportCache) getMetric(version uint64, key, name string) *metrics.InefficiencyMetric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return nil
+	}
+	return c.single[key+"\x00"+name]
+}
+
+// putMetrics сохраняет метрики computed, рассчитанные без полного отчёта, если данные набора
+// не изменились с версии version.
+func (c *reportCache) putMetrics(version uint64, key string, computed []metrics.InefficiencyMetric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	if c.single == nil || len(c.single)+len(computed) > maxCachedMetrics {
+		c.single = make(map[string]*metrics.InefficiencyMetric)
+	}
+	for i := range computed {
+		c.single[key+"\x00"+computed[i].Key] = &computed[i]
+	}
+}
+
+// reportCacheKey возвращает набор данных, в кеше которого хранится отчёт сервиса, ключ отчёта
+// и версию данных. Пустой ключ означает, что отчёт не кешируется.
+func (s *GraphService) reportCacheKey() (*datasetEntry, string, uint64) {
+	entry := s.currentEntry()
+	key := s.settingsKey()
+	if entry == nil || key == "" {
+		return nil, "", 0
+	}
+	if entry.parent != nil {
+		return entry.parent, key + entry.filterKey, entry.parentVersion
+	}
+	return entry, key, entry.reports.currentVersion()
+}
+
+// ReportETag возвращает слабый ETag отчёта по метрикам: он меняется при изменении данных набора,
+// настроек анализа или фильтра. Пустая строка — отчёт не кешируется.
+func (s *GraphService) ReportETag() string {
+	entry, key, version := s.reportCacheKey()
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s", entry.ID, version, key)))
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// report возвращает отчёт по всем кейсам набора данных. Отчёт кешируется в памяти до изменения
+// данных набора; если подключена база, отчёт, рассчитанный ранее при тех же настройках,
+// читается из неё, а новый отчёт сохраняется. Возвращаемый отчёт нельзя изменять.
+func (s *GraphService) report(instances map[string]*metrics.ProcessInstance) *metrics.MetricsReport {
+	cacheEntry, key, version := s.reportCacheKey()
+	if key == "" {
+		report := s.analyze(instances)
+		s.analyzed(s.currentEntry(), report)
+		return report
+	}
+	if report := cacheEntry.reports.get(version, key); report != nil {
+		return report
+	}
+
+	report := s.storedReport(instances)
+	cacheEntry.reports.put(version, key, report)
+	return report
+}
+
+// GetMetric возвращает метрику с ключом key. Если полный отчёт уже рассчитан, метрика берётся из него;
+// иначе рассчитывается только семейство метрик key (см. metrics.Analyzer.AnalyzeMetric), и метрики
+// семейства хранятся в памяти до изменения данных набора. У метрики, рассчитанной без полного отчёта,
+// не заполнены оценка критичности и место в списке приоритетов. Возвращаемую метрику нельзя изменять.
+func (s *GraphService) GetMetric(key string) (*metrics.InefficiencyMetric, error) {
+	cacheEntry, reportKey, version := s.reportCacheKey()
+	if reportKey != "" {
+		if report := cacheEntry.reports.get(version, reportKey); report != nil {
+			return report.Metric(key)
+		}
+		if metric := cacheEntry.reports.getMetric(version, reportKey, key); metric != nil {
+			return metric, nil
+		}
+	}
+
+	family, err := s.newAnalyzer().AnalyzeMetric(s.processInstances(), s.builder().DurationIndex(), key)
+	if err != nil {
+		return nil, err
+	}
+	if reportKey != "" {
+		cacheEntry.reports.putMetrics(version, reportKey, family)
+	}
+	for i := range family {
+		if family[i].Key == key {
+			return &family[i], nil
+		}
+	}
+	return nil, metrics.ErrUnknownMetric
+}
+
+// analyze рассчитывает отчёт по экземплярам instances набора данных сервиса, беря длительности
+// этапов и кейсов из индекса длительностей его построителя.
+func (s *GraphService) analyze(instances map[string]*metrics.ProcessInstance) *metrics.MetricsReport {
+	return s.newAnalyzer().AnalyzeIndexed(instances, s.builder().DurationIndex())
+}
+
+// storedReport читает отчёт из базы или рассчитывает и сохраняет его. Отчёты отфильтрованных
+// наборов в базе не хранятся. О новом отчёте сообщается подписчикам на итоги анализа.
+func (s *GraphService) storedReport(instances map[string]*metrics.ProcessInstance) *metrics.MetricsReport {
+	entry := s.currentEntry()
+	key := s.settingsKey()
+	if s.store == nil || entry.transient {
+		report := s.analyze(instances)
+		s.analyzed(entry, report)
+		return report
+	}
+
+	if data, err := s.store.Report(entry.ID, key); err != nil {
+		log.Printf("Ошибка чтения отчёта набора данных %s: %v", entry.ID, err)
+	} else if data != nil {
+		var report metrics.MetricsReport
+		if err := json.Unmarshal(data, &report); err == nil {
+			return &report
+		}
+	}
+
+	report := s.analyze(instances)
+	s.analyzed(entry, report)
+	if data, err := json.Marshal(report); err != nil {
+		log.Printf("Ошибка сохранения отчёта набора данных %s: %v", entry.ID, err)
+	} else if err := s.store.PutReport(entry.ID, key, data); err != nil {
+		log.Printf("Ошибка сохранения отчёта набора данных %s: %v", entry.ID, err)
+	}
+	return report
+}
@@ -1,33 +1,668 @@
 package service
 
 import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
 	"process-mining/internal/domain"
+	"process-mining/internal/domain/conformance"
 	"process-mining/internal/domain/metrics"
+	"process-mining/internal/domain/organization"
+	"process-mining/internal/domain/prediction"
+	"process-mining/internal/domain/rootcause"
+	"process-mining/internal/infrastructure"
 )
 
+// serviceState — настройки анализа и реестры, общие для всех наборов данных.
+// Настройки и эталоны защищены mu, реестры задач и наборов данных имеют собственные блокировки.
+type serviceState struct {
+	mu            sync.RWMutex
+	grouping      *domain.SubprocessGrouping
+	referenceNet  *conformance.Net
+	thresholds    map[string]float64
+	collectors    []metrics.MetricCollector
+	sla           metrics.SLA
+	calendar      *metrics.Calendar
+	costModel     metrics.CostModel
+	outliers      string
+	endActivities []string
+	topK          int // сколько самых частых активностей и путей попадает в отчёт (0 — по умолчанию)
+	errorRules    metrics.ErrorSemantics
+	automation    metrics.AutomationMapping
+	columns       domain.ColumnNames
+	memoryLimit   int64                      // бюджет памяти построения графа, байт (0 — без ограничения)
+	parseCache    *infrastructure.ParseCache // кэш разбора логов (nil — без кэша)
+	baselines     map[baselineKey]*metrics.Baseline
+	jobs          jobRegistry
+	datasets      datasetRegistry
+	alerts        alertState
+	webhooks      webhookRegistry
+	notifiers     []Notifier
+	shares        shareRegistry
+	audit         auditLog
+	store         *infrastructure.DatasetStore // база наборов данных (nil — только в памяти)
+}
+
 type GraphService struct {
-	graphBuilder *domain.GraphBuilder
+	*serviceState
+	dataset *datasetEntry // набор данных сервиса, полученного через Dataset (nil — текущий набор)
+	logger  *slog.Logger  // журнал предупреждений анализатора (nil — журнал по умолчанию)
 }
 
+// NewGraphService создаёт сервис, текущим набором данных которого становится graphBuilder.
 func NewGraphService(graphBuilder *domain.GraphBuilder) *GraphService {
-	return &GraphService{graphBuilder: graphBuilder}
+	s := &GraphService{serviceState: &serviceState{}}
+	s.datasets.add(&datasetEntry{
+		Dataset: Dataset{ID: DefaultDatasetID, Name: DefaultDatasetID, UploadedAt: time.Now().UTC()},
+		builder: graphBuilder,
+	})
+	return s
+}
+
+// WithLogger возвращает сервис того же набора данных, предупреждения анализатора которого
+// записываются в logger (например, с идентификатором запроса).
+func (s *GraphService) WithLogger(logger *slog.Logger) *GraphService {
+	return &GraphService{serviceState: s.serviceState, dataset: s.dataset, logger: logger}
+}
+
+// serviceLogger возвращает журнал сервиса: заданный WithLogger или журнал по умолчанию.
+func (s *GraphService) serviceLogger() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	return slog.Default()
 }
 
 func (s *GraphService) BuildGraphFromCSV(filePath string) error {
-	return s.graphBuilder.BuildGraph(filePath)
+	builder := s.builder()
+	s.configureBuilder(builder)
+	if err := builder.BuildGraph(filePath); err != nil {
+		return err
+	}
+	entry := s.currentEntry()
+	entry.reports.invalidate()
+	s.persist(entry)
+	return nil
+}
+
+// AppendCSV добавляет события CSV-лога filePath к набору данных сервиса. Граф обновляется только
+// по кейсам, в которые попали новые события (в том числе новым кейсам), а рассчитанные отчёты сбрасываются
+// и пересчитываются при следующем обращении. Возвращает обновлённое описание набора.
+func (s *GraphService) AppendCSV(filePath string) (Dataset, error) {
+	entry := s.currentEntry()
+	if entry.transient {
+		return Dataset{}, errors.New("события нельзя добавить в отфильтрованный набор данных")
+	}
+	builder := s.builder()
+	s.configureBuilder(builder)
+	var last domain.BuildProgress
+	if err := builder.BuildGraphWithProgress(filePath, func(progress domain.BuildProgress) {
+		last = progress
+	}); err != nil {
+		return Dataset{}, err
+	}
+	entry.reports.invalidate()
+	s.datasets.mu.Lock()
+	entry.Rows += last.RowsRead
+	entry.Size += last.TotalBytes
+	dataset := entry.Dataset
+	s.datasets.mu.Unlock()
+	s.persist(entry)
+	s.analyzeAfterBuild(entry)
+	return dataset, nil
+}
+
+// ValidateLog проверяет первые rows строк CSV-лога из src, не загружая его.
+func (s *GraphService) ValidateLog(src io.Reader, rows int) (*domain.LogValidation, error) {
+	return domain.ValidateLog(infrastructure.NewCSVReader(), src, rows, s.columnNames())
+}
+
+// LogStats считает краткую сводку CSV-лога filePath за один проход, не загружая его.
+func (s *GraphService) LogStats(filePath string) (*domain.LogStats, error) {
+	return domain.ComputeLogStats(s.builder().CSVReader(), filePath, s.columnNames())
+}
+
+// SampleLog записывает в w случайную выборку кейсов CSV-лога filePath (см. domain.SampleLog), не загружая его.
+func (s *GraphService) SampleLog(filePath string, w io.Writer, fraction float64, seed int64) (sampled, total int, err error) {
+	return domain.SampleLog(s.builder().CSVReader(), filePath, w, s.columnNames(), fraction, seed)
+}
+
+// AnonymizeLog записывает в w обезличенный CSV-лог filePath (см. domain.AnonymizeLog), не загружая его.
+func (s *GraphService) AnonymizeLog(filePath string, w io.Writer, anonymizer *domain.Anonymizer) error {
+	return domain.AnonymizeLog(s.builder().CSVReader(), filePath, w, s.columnNames(), anonymizer)
+}
+
+// MergeLogs объединяет CSV-логи paths в один (см. domain.MergeLogs), не загружая их.
+func (s *GraphService) MergeLogs(paths []string, w io.Writer, keepCaseIDs bool) (renamed int, err error) {
+	return domain.MergeLogs(s.builder().CSVReader(), paths, w, s.columnNames(), keepCaseIDs)
+}
+
+// SplitLog делит CSV-лог filePath на части по периоду или атрибуту (см. domain.SplitLog), не загружая его.
+func (s *GraphService) SplitLog(filePath, period, attribute string, create func(part string) (io.Writer, error)) (map[string]int, error) {
+	return domain.SplitLog(s.builder().CSVReader(), filePath, s.columnNames(), period, attribute, create)
+}
+
+// columnNames возвращает названия столбцов лога, заданные в настройках анализа.
+func (s *GraphService) columnNames() domain.ColumnNames {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.columns
+}
+
+// SetMemoryLimit задаёт бюджет памяти (байт) под события, прочитанные при построении графа по одному логу;
+// сверх него события кейсов временно выгружаются на диск (см. domain.GraphBuilder.SetMemoryLimit). 0 — без ограничения.
+func (s *GraphService) SetMemoryLimit(limit int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memoryLimit = limit
+}
+
+// SetParseCache задаёт кэш, из которого построение графа по уже разобранному логу берёт его события
+// без разбора CSV (см. domain.GraphBuilder.SetParseCache). nil — без кэша.
+func (s *GraphService) SetParseCache(cache *infrastructure.ParseCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parseCache = cache
+}
+
+// configureBuilder передаёт построителю графа названия столбцов, бюджет памяти и кэш разбора из настроек перед построением.
+func (s *GraphService) configureBuilder(builder *domain.GraphBuilder) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	builder.SetColumns(s.columns)
+	builder.SetMemoryLimit(s.memoryLimit)
+	builder.SetParseCache(s.parseCache)
 }
 
 func (s *GraphService) GetGraphData() (*domain.Graph, error) {
-	return s.graphBuilder.GetGraph(), nil
+	return s.builder().GetGraph(), nil
+}
+
+// GetSimplifiedGraph возвращает граф набора данных, уменьшенный до бюджета budget
+// (см. domain.GraphBuilder.SimplifiedGraph); nil вместо описания упрощения — граф не упрощался.
+func (s *GraphService) GetSimplifiedGraph(budget domain.GraphBudget) (*domain.Graph, *domain.GraphSimplification) {
+	return s.builder().SimplifiedGraph(budget)
+}
+
+// SetSubprocessGrouping задаёт правила группировки активностей в подпроцессы.
+func (s *GraphService) SetSubprocessGrouping(grouping *domain.SubprocessGrouping) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grouping = grouping
+}
+
+// GetSubprocessGrouping возвращает текущие правила группировки (nil, если не заданы).
+func (s *GraphService) GetSubprocessGrouping() *domain.SubprocessGrouping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.grouping
+}
+
+// GetSubprocessGraph возвращает двухуровневый граф с развёрнутыми подпроцессами expanded.
+func (s *GraphService) GetSubprocessGraph(expanded []string) (*domain.Graph, error) {
+	grouping := s.GetSubprocessGrouping()
+	if grouping == nil {
+		return nil, errors.New("группировка подпроцессов не задана")
+	}
+	return s.builder().BuildSubprocessGraph(grouping, expanded), nil
+}
+
+// GetReplay возвращает перемещения токенов для анимации движения кейсов по карте процесса.
+func (s *GraphService) GetReplay() []domain.TokenMove {
+	return s.builder().GetReplay()
+}
+
+// SetReferenceModel разбирает эталонную модель (BPMN или PNML) и сохраняет её для проверки соответствия.
+func (s *GraphService) SetReferenceModel(r io.Reader) error {
+	net, err := conformance.ParseModel(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.referenceNet = net
+	return nil
+}
+
+// referenceModel возвращает загруженную эталонную модель (nil, если не загружена).
+func (s *GraphService) referenceModel() *conformance.Net {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.referenceNet
+}
+
+// CheckConformance воспроизводит лог на эталонной модели и возвращает fitness, precision и отклонения.
+func (s *GraphService) CheckConformance() (*conformance.ReplayResult, error) {
+	net := s.referenceModel()
+	if net == nil {
+		return nil, errors.New("эталонная модель не загружена")
+	}
+	return conformance.Replay(net, s.traces()), nil
+}
+
+// AlignTraces строит оптимальные выравнивания кейсов с эталонной моделью (ходы лога и ходы модели).
+func (s *GraphService) AlignTraces() (*conformance.AlignmentResult, error) {
+	net := s.referenceModel()
+	if net == nil {
+		return nil, errors.New("эталонная модель не загружена")
+	}
+	return conformance.Align(net, s.traces()), nil
+}
+
+// MineRoles выделяет организационные роли по профилям активностей ресурсов.
+func (s *GraphService) MineRoles(similarity float64) (*organization.RoleReport, error) {
+	return organization.MineRoles(s.builder().GetProcessInstances(), similarity)
+}
+
+// traces возвращает последовательности активностей всех кейсов.
+func (s *GraphService) traces() []conformance.Trace {
+	instances := s.builder().GetProcessInstances()
+	traces := make([]conformance.Trace, 0, len(instances))
+	for _, instance := range instances {
+		activities := make([]string, len(instance.Events))
+		for i, event := range instance.Events {
+			activities[i] = event.Description
+		}
+		traces = append(traces, conformance.Trace{CaseID: instance.ID, Activities: activities})
+	}
+	return traces
 }
 
 func (s *GraphService) ClearGraph() {
-	s.graphBuilder.ClearGraph()
+	s.builder().ClearGraph()
+	entry := s.currentEntry()
+	entry.reports.invalidate()
+	s.persist(entry)
 }
 
-func (s *GraphService) GetMetricsReport() (*metrics.MetricsReport, error) {
+// newAnalyzer создаёт анализатор с применёнными настройками сервиса.
+func (s *GraphService) newAnalyzer() *metrics.Analyzer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.newAnalyzerLocked()
+}
+
+// newAnalyzerLocked создаёт анализатор; вызывается под блокировкой s.mu.
+func (s *GraphService) newAnalyzerLocked() *metrics.Analyzer {
 	analyzer := metrics.NewAnalyzer()
-	processInstancesSlice := s.graphBuilder.GetProcessInstances()
+	for _, collector := range s.collectors {
+		// Уникальность ключей проверена в RegisterCollector
+		_ = analyzer.RegisterCollector(collector)
+	}
+	for metricType, threshold := range s.thresholds {
+		// Ключи проверены в SetThresholds
+		_ = analyzer.SetThreshold(metricType, threshold)
+	}
+	// SLA проверено в SetSLA
+	_ = analyzer.SetSLA(s.sla)
+	// Календарь проверен в SetCalendar
+	_ = analyzer.SetCalendar(s.calendar)
+	// Модель затрат проверена в SetCostModel
+	_ = analyzer.SetCostModel(s.costModel)
+	// Метод поиска выбросов проверен в SetOutlierMethod
+	_ = analyzer.SetOutlierMethod(s.outliers)
+	analyzer.SetEndActivities(s.endActivities)
+	// Число самых частых активностей и путей проверено в ApplySettings
+	_ = analyzer.SetTopK(s.topK)
+	// Правила ошибок проверены в SetErrorSemantics
+	_ = analyzer.SetErrorSemantics(s.errorRules)
+	// Разметка проверена в SetAutomation
+	_ = analyzer.SetAutomation(s.automation)
+	if s.logger != nil {
+		analyzer.Logger = s.logger
+	}
+	return analyzer
+}
+
+// RegisterCollector подключает пользовательскую метрику ко всем последующим отчётам.
+func (s *GraphService) RegisterCollector(collector metrics.MetricCollector) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	probe := s.newAnalyzerLocked()
+	if err := probe.RegisterCollector(collector); err != nil {
+		return err
+	}
+	s.collectors = append(s.collectors, collector)
+	return nil
+}
+
+// SetThresholds переопределяет пороги метрик. Ранее заданные пороги других метрик сохраняются.
+func (s *GraphService) SetThresholds(thresholds map[string]float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	probe := s.newAnalyzerLocked()
+	for metricType, threshold := range thresholds {
+		if err := probe.SetThreshold(metricType, threshold); err != nil {
+			return err
+		}
+	}
+
+	updated := make(map[string]float64, len(s.thresholds)+len(thresholds))
+	for metricType, threshold := range s.thresholds {
+		updated[metricType] = threshold
+	}
+	for metricType, threshold := range thresholds {
+		updated[metricType] = threshold
+	}
+	s.thresholds = updated
+	return nil
+}
+
+// SetSLA задаёт SLA для метрики нарушений.
+func (s *GraphService) SetSLA(sla metrics.SLA) error {
+	if err := sla.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sla = sla
+	return nil
+}
+
+// GetSLA возвращает действующее SLA.
+func (s *GraphService) GetSLA() metrics.SLA {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sla
+}
+
+// SetCalendar задаёт рабочий календарь; nil отключает расчёт рабочего времени.
+func (s *GraphService) SetCalendar(calendar *metrics.Calendar) error {
+	if calendar != nil {
+		if err := calendar.Validate(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendar = calendar
+	return nil
+}
+
+// GetCalendar возвращает действующий рабочий календарь (nil, если не задан).
+func (s *GraphService) GetCalendar() *metrics.Calendar {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.calendar
+}
+
+// SetCostModel задаёт модель затрат для расчёта стоимости потерянного времени.
+func (s *GraphService) SetCostModel(model metrics.CostModel) error {
+	if err := model.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.costModel = model
+	return nil
+}
+
+// GetCostModel возвращает действующую модель затрат.
+func (s *GraphService) GetCostModel() metrics.CostModel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.costModel
+}
+
+// SetOutlierMethod выбирает метод поиска аномально долгих этапов и кейсов.
+func (s *GraphService) SetOutlierMethod(method string) error {
+	if err := metrics.NewAnalyzer().SetOutlierMethod(method); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outliers = method
+	return nil
+}
+
+// SetEndActivities задаёт активности, которыми завершается процесс.
+func (s *GraphService) SetEndActivities(activities []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endActivities = activities
+}
+
+// GetStuckCases возвращает незавершённые кейсы без событий дольше maxAge.
+// Нулевой maxAge означает порог метрики "Stuck Case".
+func (s *GraphService) GetStuckCases(maxAge time.Duration) []metrics.StuckCase {
+	analyzer := s.newAnalyzer()
+	if maxAge == 0 {
+		maxAge = time.Duration(analyzer.Definitions()["Stuck Case"].Threshold * float64(time.Second))
+	}
+	return analyzer.StuckCases(s.processInstances(), maxAge)
+}
+
+// SetErrorSemantics задаёт, какие результаты событий считаются ошибкой.
+func (s *GraphService) SetErrorSemantics(semantics metrics.ErrorSemantics) error {
+	if _, err := metrics.NewErrorMatcher(semantics); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorRules = semantics
+	return nil
+}
+
+// GetErrorSemantics возвращает действующие правила распознавания ошибок.
+func (s *GraphService) GetErrorSemantics() metrics.ErrorSemantics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.errorRules
+}
+
+// SetAutomation задаёт разметку активностей на ручные и автоматические.
+func (s *GraphService) SetAutomation(mapping metrics.AutomationMapping) error {
+	if err := mapping.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.automation = mapping
+	return nil
+}
+
+// GetAutomation возвращает действующую разметку активностей.
+func (s *GraphService) GetAutomation() metrics.AutomationMapping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.automation == nil {
+		return metrics.AutomationMapping{}
+	}
+	return s.automation
+}
+
+// GetCaseAutomation возвращает уровень автоматизации кейсов, начиная с наименее автоматизированных.
+func (s *GraphService) GetCaseAutomation() []metrics.CaseAutomation {
+	return s.newAnalyzer().CaseAutomation(s.processInstances())
+}
+
+// GetMetricDefinitions возвращает определения метрик с действующими порогами.
+func (s *GraphService) GetMetricDefinitions() map[string]metrics.MetricDefinition {
+	return s.newAnalyzer().Definitions()
+}
+
+// GetMetricsReport возвращает отчёт по метрикам. Отчёт берётся из кеша набора данных, поэтому
+// возвращается копия, список метрик которой можно изменять (например, TruncateOccurrences).
+func (s *GraphService) GetMetricsReport() (*metrics.MetricsReport, error) {
+	report := *s.report(s.processInstances())
+	report.Metrics = slices.Clone(report.Metrics)
+	return &report, nil
+}
+
+// GetSegmentedMetricsReport возвращает отчёты по метрикам для каждого значения атрибута кейса.
+func (s *GraphService) GetSegmentedMetricsReport(attribute string) (*metrics.SegmentedReport, error) {
+	return s.newAnalyzer().AnalyzeSegments(s.processInstances(), attribute)
+}
+
+// GetRootCauses ищет значения атрибутов, непропорционально часто встречающиеся в кейсах с неэффективностями.
+func (s *GraphService) GetRootCauses(significance float64, minCases int) (*rootcause.Report, error) {
+	instances := s.processInstances()
+	report := s.report(instances)
+	return rootcause.Analyze(report, instances, significance, minCases)
+}
+
+// GetMetricOccurrences возвращает страницу вхождений метрики, отсортированных по потерям времени.
+// Полный отчёт для этого не строится (см. GetMetric).
+func (s *GraphService) GetMetricOccurrences(key string, offset, limit int) (*metrics.OccurrencePage, error) {
+	metric, err := s.GetMetric(key)
+	if err != nil {
+		return nil, err
+	}
+	return metric.OccurrencePage(offset, limit), nil
+}
+
+// GetCaseDetail возвращает трассу кейса и все найденные в нём неэффективности.
+func (s *GraphService) GetCaseDetail(id string) (*metrics.CaseDetail, error) {
+	instances := s.processInstances()
+	report := s.report(instances)
+	return report.CaseDetail(instances, id)
+}
+
+// GetWorstCases возвращает не более limit худших кейсов по критерию by.
+func (s *GraphService) GetWorstCases(by string, limit int) ([]metrics.CaseRanking, error) {
+	instances := s.processInstances()
+	report := s.report(instances)
+	return report.WorstCases(instances, by, limit)
+}
+
+// GetVariantMetrics возвращает показатели по вариантам процесса.
+func (s *GraphService) GetVariantMetrics() []metrics.VariantMetrics {
+	instances := s.processInstances()
+	return s.newAnalyzer().VariantMetrics(instances, s.report(instances))
+}
+
+// GetCaseEfficiencies возвращает эффективность кейсов (touch time / lead time), начиная с наименее эффективных.
+func (s *GraphService) GetCaseEfficiencies() []metrics.CaseEfficiency {
+	return metrics.CaseEfficiencies(s.processInstances())
+}
+
+// GetDurationDistribution возвращает гистограмму и процентили длительности кейсов.
+func (s *GraphService) GetDurationDistribution(bins int) metrics.DurationDistribution {
+	return metrics.CaseDurationDistribution(s.processInstances(), bins)
+}
+
+// ComparePeriods сравнивает показатели кейсов, начавшихся в двух периодах.
+func (s *GraphService) ComparePeriods(before, after metrics.Period) *metrics.PeriodComparison {
+	return s.newAnalyzer().ComparePeriods(s.processInstances(), before, after)
+}
+
+// CompareWith сравнивает показатели загруженного лога («до») с логом сервиса other («после»).
+func (s *GraphService) CompareWith(other *GraphService) *metrics.PeriodComparison {
+	return s.newAnalyzer().CompareLogs(s.processInstances(), other.processInstances())
+}
+
+// baselineKey — эталон name рабочей области workspace: у каждой рабочей области свои эталоны.
+type baselineKey struct {
+	workspace, name string
+}
+
+// SaveBaseline сохраняет показатели текущего отчёта как эталон name рабочей области scope,
+// заменяя её эталон с тем же именем.
+func (s *GraphService) SaveBaseline(name string, scope Scope) *metrics.Baseline {
+	baseline := metrics.NewBaseline(name, s.report(s.processInstances()), time.Now().UTC())
+	baseline.Workspace = scope.Workspace
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.baselines == nil {
+		s.baselines = make(map[baselineKey]*metrics.Baseline)
+	}
+	s.baselines[baselineKey{workspace: scope.Workspace, name: name}] = baseline
+	return baseline
+}
+
+// GetBaseline возвращает эталон name рабочей области scope, а если его нет — общий эталон
+// (сохранённый без рабочей области).
+func (s *GraphService) GetBaseline(name string, scope Scope) (*metrics.Baseline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.baselineKeyLocked(name, scope)
+	if !ok {
+		return nil, metrics.ErrUnknownBaseline
+	}
+	return s.baselines[key], nil
+}
+
+// baselineKeyLocked находит ключ эталона name, доступного в scope (см. GetBaseline); вызывается под блокировкой s.mu.
+func (s *GraphService) baselineKeyLocked(name string, scope Scope) (baselineKey, bool) {
+	for _, key := range []baselineKey{{workspace: scope.Workspace, name: name}, {name: name}} {
+		if _, ok := s.baselines[key]; ok {
+			return key, true
+		}
+	}
+	return baselineKey{}, false
+}
+
+// ListBaselines возвращает эталоны, доступные в scope, в порядке создания.
+func (s *GraphService) ListBaselines(scope Scope) []*metrics.Baseline {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	baselines := make([]*metrics.Baseline, 0, len(s.baselines))
+	for key, baseline := range s.baselines {
+		if scope.allowsWorkspace(key.workspace) {
+			baselines = append(baselines, baseline)
+		}
+	}
+	sort.Slice(baselines, func(i, j int) bool { return baselines[i].CreatedAt.Before(baselines[j].CreatedAt) })
+	return baselines
+}
+
+// DeleteBaseline удаляет эталон name, доступный в scope (см. GetBaseline).
+func (s *GraphService) DeleteBaseline(name string, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.baselineKeyLocked(name, scope)
+	if !ok {
+		return metrics.ErrUnknownBaseline
+	}
+	delete(s.baselines, key)
+	return nil
+}
+
+// CompareWithBaseline сравнивает текущий отчёт с эталоном name, доступным в scope.
+func (s *GraphService) CompareWithBaseline(name string, tolerance float64, scope Scope) (*metrics.BaselineComparison, error) {
+	baseline, err := s.GetBaseline(name, scope)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.CompareWithBaseline(baseline, s.report(s.processInstances()), tolerance), nil
+}
+
+// PredictRemainingTime прогнозирует оставшееся время незавершённого кейса по завершённым кейсам загруженного лога
+// (завершённость определяется по активностям завершения, см. SetEndActivities).
+func (s *GraphService) PredictRemainingTime(steps []prediction.Step) (*prediction.Prediction, error) {
+	model, err := prediction.TrainRemainingTime(s.builder().GetProcessInstances(), s.newAnalyzer().IsCompleted)
+	if err != nil {
+		return nil, err
+	}
+	return model.Predict(steps)
+}
+
+// PredictOutcome оценивает вероятность того, что незавершённый кейс закончится ошибкой.
+func (s *GraphService) PredictOutcome(steps []prediction.Step) (*prediction.OutcomePrediction, error) {
+	matcher, err := metrics.NewErrorMatcher(s.GetErrorSemantics())
+	if err != nil {
+		return nil, err
+	}
+	model, err := prediction.TrainOutcome(s.builder().GetProcessInstances(), matcher)
+	if err != nil {
+		return nil, err
+	}
+	return model.Predict(steps)
+}
+
+// GetBottlenecks возвращает активности, отсортированные по суммарному времени ожидания.
+func (s *GraphService) GetBottlenecks() []metrics.ActivityBottleneck {
+	return metrics.RankBottlenecks(s.processInstances())
+}
+
+// processInstances возвращает экземпляры процесса в виде мапы для анализатора.
+func (s *GraphService) processInstances() map[string]*metrics.ProcessInstance {
+	processInstancesSlice := s.builder().GetProcessInstances()
 
 	// Конвертируем слайс в мапу для анализатора
 	processInstancesMap := make(map[string]*metrics.ProcessInstance)
@@ -40,8 +675,12 @@ func (s *GraphService) GetMetricsReport() (*metrics.MetricsReport, error) {
 			metricEvents[j] = metrics.Event{
 				SessionID:   event.SessionID,
 				Timestamp:   event.Timestamp,
+				Start:       event.Start,
 				Description: event.Description,
+				Activity:    event.Activity,
 				Result:      event.Result,
+				Resource:    event.Resource,
+				Attributes:  event.Attributes,
 			}
 		}
 
@@ -52,5 +691,5 @@ func (s *GraphService) GetMetricsReport() (*metrics.MetricsReport, error) {
 		}
 	}
 
-	return analyzer.Analyze(processInstancesMap), nil
+	return processInstancesMap
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/domain/metrics"
+)
+
+// AnalysisSettings — настройки анализа, задаваемые вместе (например, разделом analysis файла конфигурации).
+type AnalysisSettings struct {
+	Thresholds    map[string]float64
+	SLA           metrics.SLA
+	Calendar      *metrics.Calendar
+	Costs         metrics.CostModel
+	OutlierMethod string
+	EndActivities []string
+	TopK          int
+	Errors        metrics.ErrorSemantics
+	Automation    metrics.AutomationMapping
+	Columns       domain.ColumnNames
+}
+
+// ApplySettings проверяет и заменяет настройки анализа целиком: пороги метрик, не указанные в settings,
+// возвращаются к значениям по умолчанию. Если хотя бы одна настройка некорректна, действующие настройки
+// не меняются. Загруженные наборы данных сохраняются, отчёты пересчитываются при следующем обращении.
+func (s *GraphService) ApplySettings(settings AnalysisSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	probe := metrics.NewAnalyzer()
+	for _, collector := range s.collectors {
+		_ = probe.RegisterCollector(collector)
+	}
+	for metricType, threshold := range settings.Thresholds {
+		if err := probe.SetThreshold(metricType, threshold); err != nil {
+			return fmt.Errorf("некорректные пороги метрик: %v", err)
+		}
+	}
+	if err := settings.SLA.Validate(); err != nil {
+		return fmt.Errorf("некорректное SLA: %v", err)
+	}
+	if settings.Calendar != nil {
+		if err := settings.Calendar.Validate(); err != nil {
+			return fmt.Errorf("некорректный рабочий календарь: %v", err)
+		}
+	}
+	if err := settings.Costs.Validate(); err != nil {
+		return fmt.Errorf("некорректная модель затрат: %v", err)
+	}
+	if err := probe.SetOutlierMethod(settings.OutlierMethod); err != nil {
+		return err
+	}
+	if err := probe.SetTopK(settings.TopK); err != nil {
+		return err
+	}
+	if _, err := metrics.NewErrorMatcher(settings.Errors); err != nil {
+		return fmt.Errorf("некорректные правила ошибок: %v", err)
+	}
+	if err := settings.Automation.Validate(); err != nil {
+		return fmt.Errorf("некорректная разметка автоматизации: %v", err)
+	}
+	if err := settings.Columns.Validate(); err != nil {
+		return fmt.Errorf("некорректные названия столбцов: %v", err)
+	}
+
+	s.thresholds = settings.Thresholds
+	s.sla = settings.SLA
+	s.calendar = settings.Calendar
+	s.costModel = settings.Costs
+	s.outliers = settings.OutlierMethod
+	s.endActivities = settings.EndActivities
+	s.topK = settings.TopK
+	s.errorRules = settings.Errors
+	s.automation = settings.Automation
+	s.columns = settings.Columns
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"process-mining/internal/domain"
+	"process-mining/internal/domain/metrics"
+)
+
+// ErrUnknownShare возвращается при обращении к несуществующей публичной ссылке.
+var ErrUnknownShare = errors.New("ссылка не найдена")
+
+// Share — снимок графа и отчёта по метрикам, доступный только для чтения по токену без входа в API.
+// Снимок не меняется при повторной загрузке, фильтрации или удалении набора данных.
+type Share struct {
+	Token     string                 `json:"token"`
+	Dataset   Dataset                `json:"dataset"`
+	CreatedAt time.Time              `json:"created_at"`
+	Graph     *domain.Graph          `json:"graph"`
+	Metrics   *metrics.MetricsReport `json:"metrics"`
+}
+
+// shareRegistry хранит сериализованные снимки публичных ссылок; при подключённой базе они
+// сохраняются и в ней.
+type shareRegistry struct {
+	mu     sync.Mutex
+	shares map[string][]byte
+}
+
+// newShareToken возвращает случайный токен ссылки, который нельзя подобрать перебором.
+func newShareToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CreateShare сохраняет снимок графа и отчёта набора данных (с учётом фильтра) и возвращает его.
+// В отчёт попадают первые metrics.DefaultTopOccurrences вхождений каждой метрики.
+func (s *GraphService) CreateShare() (Share, error) {
+	graph, err := s.GetGraphData()
+	if err != nil {
+		return Share{}, err
+	}
+	report, err := s.GetMetricsReport()
+	if err != nil {
+		return Share{}, err
+	}
+	report.TruncateOccurrences(metrics.DefaultTopOccurrences)
+	s.datasets.mu.Lock()
+	dataset := s.currentEntry().Dataset
+	s.datasets.mu.Unlock()
+
+	share := Share{Token: newShareToken(), Dataset: dataset, CreatedAt: time.Now().UTC(), Graph: graph, Metrics: report}
+	snapshot, err := json.Marshal(share)
+	if err != nil {
+		return Share{}, err
+	}
+	if s.store != nil {
+		if err := s.store.PutShare(share.Token, snapshot); err != nil {
+			return Share{}, fmt.Errorf("ошибка сохранения ссылки: %v", err)
+		}
+	}
+
+	s.shares.mu.Lock()
+	defer s.shares.mu.Unlock()
+	if s.shares.shares == nil {
+		s.shares.shares = make(map[string][]byte)
+	}
+	s.shares.shares[share.Token] = snapshot
+	return share, nil
+}
+
+// GetShare возвращает снимок публичной ссылки token.
+func (s *GraphService) GetShare(token string) (*Share, error) {
+	s.shares.mu.Lock()
+	snapshot, ok := s.shares.shares[token]
+	s.shares.mu.Unlock()
+	if !ok && s.store != nil {
+		var err error
+		if snapshot, err = s.store.Share(token); err != nil {
+			return nil, fmt.Errorf("ошибка чтения ссылки: %v", err)
+		}
+	}
+	if snapshot == nil {
+		return nil, ErrUnknownShare
+	}
+	var share Share
+	if err := json.Unmarshal(snapshot, &share); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchSettle — сколько файл не должен изменяться, чтобы считаться полностью записанным.
+const watchSettle = 10 * time.Second
+
+// FolderWatcher загружает CSV-логи, появляющиеся в каталоге (например, ночные выгрузки).
+// Каждый новый или изменённый файл строится в новом наборе данных, который становится текущим
+// в рабочей области; набор, построенный ранее по тому же файлу, после этого удаляется.
+type FolderWatcher struct {
+	service   *GraphService
+	dir       string
+	workspace string
+	files     map[string]watchedFile // путь → последняя загруженная версия файла
+	logger    *slog.Logger
+}
+
+// watchedFile — версия файла, по которой построен набор данных.
+type watchedFile struct {
+	modTime   time.Time
+	size      int64
+	datasetID string
+}
+
+// NewFolderWatcher создаёт наблюдатель за каталогом dir, загружающий логи в рабочую область workspace.
+// Файлы, по которым уже есть более новые наборы данных с тем же именем (например, восстановленные
+// из базы после перезапуска), повторно не загружаются.
+func NewFolderWatcher(service *GraphService, dir, workspace string) (*FolderWatcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s не является каталогом", dir)
+	}
+	w := &FolderWatcher{
+		service:   service,
+		dir:       dir,
+		workspace: workspace,
+		files:     make(map[string]watchedFile),
+		logger:    slog.With("watch_dir", dir),
+	}
+
+	latest := make(map[string]Dataset)
+	for _, dataset := range service.ListDatasets(Scope{Workspace: workspace}) {
+		if dataset.Workspace == workspace && dataset.UploadedAt.After(latest[dataset.Name].UploadedAt) {
+			latest[dataset.Name] = dataset
+		}
+	}
+	paths, err := w.logFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if dataset, ok := latest[filepath.Base(path)]; ok && dataset.UploadedAt.After(info.ModTime()) {
+			w.files[path] = watchedFile{modTime: info.ModTime(), size: info.Size(), datasetID: dataset.ID}
+		}
+	}
+	return w, nil
+}
+
+// logFiles возвращает пути CSV-файлов каталога.
+func (w *FolderWatcher) logFiles() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога %s: %v", w.dir, err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			paths = append(paths, filepath.Join(w.dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// Scan загружает новые и изменённые файлы каталога. Файлы, изменявшиеся в последние
+// несколько секунд, откладываются до следующей проверки. Возвращает число загруженных файлов.
+func (w *FolderWatcher) Scan(ctx context.Context) (int, error) {
+	paths, err := w.logFiles()
+	if err != nil {
+		return 0, err
+	}
+	loaded := 0
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		previous, seen := w.files[path]
+		if seen && previous.modTime.Equal(info.ModTime()) && previous.size == info.Size() {
+			continue
+		}
+		if time.Since(info.ModTime()) < watchSettle {
+			continue
+		}
+
+		job, dataset := w.service.newBuildJob(filepath.Base(path), w.workspace, "")
+		w.logger.Info("Загрузка файла из каталога", "file", path, "job_id", job.ID)
+		w.service.runBuildJob(job.ID, "", dataset, path)
+		if job, err = w.service.GetJob(job.ID, Scope{All: true}); err != nil || job.Status != JobDone {
+			// Файл помечается загруженным, чтобы не строить его повторно до следующего изменения
+			w.files[path] = watchedFile{modTime: info.ModTime(), size: info.Size(), datasetID: previous.datasetID}
+			continue
+		}
+		w.files[path] = watchedFile{modTime: info.ModTime(), size: info.Size(), datasetID: job.DatasetID}
+		loaded++
+
+		if previous.datasetID != "" {
+			err := w.service.DeleteDataset(previous.datasetID, Scope{All: true})
+			if err != nil && !errors.Is(err, ErrUnknownDataset) {
+				w.logger.Error("Ошибка удаления прежнего набора данных", "dataset_id", previous.datasetID, "error", err)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// Run проверяет каталог сразу и затем в моменты, которые возвращает next (следующий запуск после
+// переданного времени), пока не будет отменён ctx.
+func (w *FolderWatcher) Run(ctx context.Context, next func(time.Time) time.Time) {
+	for {
+		if _, err := w.Scan(ctx); err != nil {
+			w.logger.Error("Ошибка проверки каталога", "error", err)
+		}
+		timer := time.NewTimer(time.Until(next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"process-mining/internal/infrastructure"
+)
+
+// ErrUnknownWebhook возвращается при обращении к несуществующему вебхуку.
+var ErrUnknownWebhook = errors.New("вебхук не найден")
+
+// Webhook — адрес, на который отправляется уведомление, когда после анализа набора данных
+// рабочей области Workspace порог метрики оказывается превышен.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"` // ключ подписи HMAC-SHA256 (заголовок X-Signature-256)
+	Workspace string    `json:"workspace,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload — тело уведомления о превышении порогов.
+type WebhookPayload struct {
+	Event      string           `json:"event"` // threshold_breach
+	Dataset    Dataset          `json:"dataset"`
+	AnalyzedAt time.Time        `json:"analyzed_at"`
+	Metrics    []BreachedMetric `json:"metrics"`
+}
+
+// BreachedMetric — метрика, порог которой превышен.
+type BreachedMetric struct {
+	Key                 string  `json:"key"`
+	Name                string  `json:"name"`
+	Threshold           float64 `json:"threshold"`
+	TotalValue          float64 `json:"total_value"`
+	Count               int     `json:"count"`
+	Severity            float64 `json:"severity"`
+	TotalWastedDuration float64 `json:"total_wasted_duration"` // секунды
+}
+
+// webhookRegistry хранит зарегистрированные вебхуки.
+type webhookRegistry struct {
+	mu     sync.Mutex
+	hooks  map[string]*Webhook
+	sender *infrastructure.WebhookSender
+}
+
+func (r *webhookRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.hooks)
+}
+
+// notify отправляет уведомление на вебхуки рабочей области набора данных.
+func (r *webhookRegistry) notify(result AnalysisResult) {
+	r.mu.Lock()
+	var targets []Webhook
+	for _, hook := range r.hooks {
+		if (Scope{Workspace: hook.Workspace}).allows(result.Dataset) {
+			targets = append(targets, *hook)
+		}
+	}
+	sender := r.sender
+	r.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{Event: "threshold_breach", Dataset: result.Dataset, AnalyzedAt: result.AnalyzedAt}
+	for _, metric := range result.Breached {
+		payload.Metrics = append(payload.Metrics, BreachedMetric{
+			Key:                 metric.Key,
+			Name:                metric.Definition.Name,
+			Threshold:           metric.Definition.Threshold,
+			TotalValue:          metric.TotalValue,
+			Count:               metric.Count,
+			Severity:            metric.Severity,
+			TotalWastedDuration: metric.TotalWastedDuration,
+		})
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Ошибка сериализации уведомления", "error", err)
+		return
+	}
+	for _, hook := range targets {
+		if err := sender.Send(hook.URL, hook.Secret, data); err != nil {
+			slog.Error("Ошибка отправки вебхука", "webhook_id", hook.ID, "dataset_id", result.Dataset.ID, "error", err)
+		}
+	}
+}
+
+// AddWebhook регистрирует вебхук rawURL (http или https) в рабочей области workspace.
+func (s *GraphService) AddWebhook(rawURL, secret, workspace string) (Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Webhook{}, fmt.Errorf("некорректный адрес вебхука %q: ожидается http(s)-адрес", rawURL)
+	}
+	hook := &Webhook{ID: newJobID(), URL: rawURL, Secret: secret, Workspace: workspace, CreatedAt: time.Now().UTC()}
+
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+	if s.webhooks.hooks == nil {
+		s.webhooks.hooks = make(map[string]*Webhook)
+		s.webhooks.sender = infrastructure.NewWebhookSender()
+	}
+	s.webhooks.hooks[hook.ID] = hook
+	return *hook, nil
+}
+
+// ListWebhooks возвращает вебхуки, доступные в области видимости scope, по времени регистрации.
+func (s *GraphService) ListWebhooks(scope Scope) []Webhook {
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+	hooks := []Webhook{}
+	for _, hook := range s.webhooks.hooks {
+		if scope.All || hook.Workspace == scope.Workspace {
+			hooks = append(hooks, *hook)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].CreatedAt.Before(hooks[j].CreatedAt)
+	})
+	return hooks
+}
+
+// DeleteWebhook удаляет вебхук, доступный в области видимости scope.
+func (s *GraphService) DeleteWebhook(id string, scope Scope) error {
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+	hook, ok := s.webhooks.hooks[id]
+	if !ok || !(scope.All || hook.Workspace == scope.Workspace) {
+		return ErrUnknownWebhook
+	}
+	delete(s.webhooks.hooks, id)
+	return nil
+}
@@ -1,5 +1,69 @@
 let vizInstance; // Глобальная переменная для хранения экземпляра Viz.js
 let graphData; // Глобальная переменная для хранения данных графа
+let datasetId; // Набор данных, загруженный в этой вкладке
+const apiPrefix = '/api/v1'; // Версия API, с которой работает интерфейс
+
+// Добавляет к адресу API параметр набора данных (если файл уже загружен)
+function withDataset(url) {
+  if (!datasetId) {
+    return url;
+  }
+  return url + (url.includes('?') ? '&' : '?') + 'dataset=' + encodeURIComponent(datasetId);
+}
+
+// Выполняет запрос к API (адрес указывается без префикса версии) с ключом доступа; если сервер требует аутентификацию, запрашивает ключ
+async function apiFetch(url, options = {}) {
+  const request = () => {
+    const headers = new Headers(options.headers);
+    const apiKey = localStorage.getItem('apiKey');
+    if (apiKey) {
+      headers.set('Authorization', `Bearer ${apiKey}`);
+    }
+    return fetch(apiPrefix + url, { ...options, headers });
+  };
+
+  let response = await request();
+  if (response.status === 401) {
+    const apiKey = prompt('Введите API-ключ или токен доступа');
+    if (apiKey) {
+      localStorage.setItem('apiKey', apiKey);
+      response = await request();
+    }
+  }
+  return response;
+}
+
+// Добавляет ключ доступа в параметры адреса (для EventSource, который не передаёт заголовки)
+function withApiKey(url) {
+  const apiKey = localStorage.getItem('apiKey');
+  if (!apiKey) {
+    return url;
+  }
+  return url + (url.includes('?') ? '&' : '?') + 'api_key=' + encodeURIComponent(apiKey);
+}
+
+// Проверяет первые строки файла до загрузки, чтобы не ждать построения графа по некорректному логу.
+// На сервер отправляется только начало файла, обрезанное по последней целой строке.
+async function validateFile(file) {
+  let sample = file;
+  if (file.size > 1024 * 1024) {
+    const text = await file.slice(0, 1024 * 1024).text();
+    sample = new Blob([text.slice(0, text.lastIndexOf('\n') + 1)]);
+  }
+  const formData = new FormData();
+  formData.append('file', sample, file.name);
+  const response = await apiFetch('/validate', { method: 'POST', body: formData });
+  if (!response.ok) {
+    throw new Error(await response.text());
+  }
+
+  const validation = await response.json();
+  validation.warnings.forEach(warning => console.warn(`Проверка лога, строка ${warning.line || '-'}:`, warning.message));
+  if (!validation.valid) {
+    const details = validation.errors.map(error => `строка ${error.line || '-'}: ${error.message}`).join('\n');
+    throw new Error(`Файл не прошёл проверку (ошибок: ${validation.error_count}):\n${details}`);
+  }
+}
 
 // Функция для отправки файла на сервер
 async function uploadFile(file) {
@@ -7,7 +71,8 @@ async function uploadFile(file) {
   formData.append('file', file);
 
   try {
-    const response = await fetch('/upload', {
+    await validateFile(file);
+    const response = await apiFetch('/upload', {
       method: 'POST',
       body: formData,
     });
@@ -16,13 +81,25 @@ async function uploadFile(file) {
       throw new Error('Ошибка загрузки файла');
     }
 
+    // Граф строится в фоне: дожидаемся завершения задачи
+    const job = await response.json();
+    await waitForJob(job.id);
+    datasetId = job.dataset_id;
+
     // Получаем данные графа с сервера
-    const graphResponse = await fetch('/graph');
+    const graphResponse = await apiFetch(withDataset('/graph'));
     if (!graphResponse.ok) {
       throw new Error('Не удалось получить данные графа.');
     }
 
     graphData = await graphResponse.json(); // Сохраняем данные графа
+    if (graphData.simplification) {
+      const { total_nodes, total_edges } = graphData.simplification;
+      console.warn(`Граф упрощён: ${total_nodes} узлов и ${total_edges} переходов исходного графа`);
+    }
+    if (graphData.total_edges > graphData.edges.length) {
+      console.warn(`Показаны ${graphData.edges.length} самых частых переходов из ${graphData.total_edges}`);
+    }
     renderGraph(); // Рисуем граф после загрузки данных
     fetchAndDisplayMetrics(); // Получаем и отображаем метрики после загрузки графа
   } catch (error) {
@@ -31,10 +108,43 @@ async function uploadFile(file) {
   }
 }
 
+// Дожидается завершения задачи построения графа, показывая ход обработки на кнопке загрузки
+function waitForJob(id) {
+  const uploadBtn = document.getElementById('upload-btn');
+  const label = uploadBtn.textContent;
+  const phases = { reading: 'Чтение', assembling: 'Построение графа', done: 'Готово' };
+
+  return new Promise((resolve, reject) => {
+    const source = new EventSource(withApiKey(`${apiPrefix}/jobs/${id}/events`));
+    const finish = () => {
+      source.close();
+      uploadBtn.textContent = label;
+    };
+    source.addEventListener('progress', event => {
+      const job = JSON.parse(event.data);
+      uploadBtn.textContent = `${phases[job.phase] || job.phase}: ${job.percent.toFixed(0)}%`;
+    });
+    source.addEventListener('done', event => {
+      finish();
+      const job = JSON.parse(event.data);
+      job.warnings.forEach(warning => console.warn('Предупреждение разбора лога:', warning));
+      resolve(job);
+    });
+    source.addEventListener('failed', event => {
+      finish();
+      reject(new Error(`Ошибка построения графа: ${JSON.parse(event.data).error}`));
+    });
+    source.onerror = () => {
+      finish();
+      reject(new Error('Соединение с сервером прервано во время обработки файла.'));
+    };
+  });
+}
+
 // Функция для получения и отображения метрик
 async function fetchAndDisplayMetrics() {
   try {
-    const response = await fetch('/metrics');
+    const response = await apiFetch(withDataset('/metrics'));
     if (!response.ok) {
       throw new Error('Не удалось получить отчет по метрикам.');
     }
@@ -71,6 +181,9 @@ function displayMetrics(metrics) {
   html += `<p><strong>Всего событий:</strong> ${metrics.total_events || 0}</p>`;
   html += `<p><strong>Средняя длительность процесса:</strong> ${(metrics.average_process_duration || 0).toFixed(2)} сек.</p>`;
   html += `<p><strong>Медианная длительность процесса:</strong> ${(metrics.median_process_duration || 0).toFixed(2)} сек.</p>`;
+  if (metrics.first_pass_yield && metrics.first_pass_yield.completed_cases > 0) {
+    html += `<p><strong>Выход с первого прохода:</strong> ${metrics.first_pass_yield.yield.toFixed(1)}% (${metrics.first_pass_yield.first_pass_cases} из ${metrics.first_pass_yield.completed_cases})</p>`;
+  }
 
   // Вывод сгруппированных метрик
   for (const category in groupedMetrics) {
@@ -115,7 +228,15 @@ function convertToDot(data) {
   data.edges.forEach(edge => {
     const [events, time] = edge.data.label.split('\n'); // Разделение метки на события и время
     const label = events; // Показываем только количество событий
-    dot += `  "${edge.data.from}" -> "${edge.data.to}" [label="${label}"];\n`;
+    if (edge.data.parallel) {
+      // Параллельные активности рисуем одним ненаправленным ребром
+      dot += `  "${edge.data.from}" -> "${edge.data.to}" [label="${label} ∥" dir=none style=dotted];\n`;
+    } else if (edge.data.happy_path) {
+      // Самый частый вариант процесса выделяем цветом и толщиной линии
+      dot += `  "${edge.data.from}" -> "${edge.data.to}" [label="${label}" color="${edge.data.color}" penwidth=3];\n`;
+    } else {
+      dot += `  "${edge.data.from}" -> "${edge.data.to}" [label="${label}"];\n`;
+    }
   });
 
   dot += '}';
@@ -259,7 +380,7 @@ async function downloadPNG() {
 // Функция для очистки графа
 async function clearGraph() {
   try {
-    const response = await fetch('/clear', {
+    const response = await apiFetch(withDataset('/clear'), {
       method: 'POST',
     });
 
@@ -321,7 +442,7 @@ document.addEventListener('DOMContentLoaded', () => {
   // Клик на кнопку "Экспорт метрик (JSON)"
   exportMetricsBtn.addEventListener('click', async () => {
     try {
-      const response = await fetch('/metrics');
+      const response = await apiFetch(withDataset('/metrics?occurrences=all'));
       if (!response.ok) {
         throw new Error('Не удалось получить отчет по метрикам для экспорта.');
       }